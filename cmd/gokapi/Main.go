@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strconv"
 	"syscall"
 
 	"github.com/forceu/gokapi/internal/configuration"
@@ -24,9 +25,13 @@ import (
 	"github.com/forceu/gokapi/internal/environment"
 	"github.com/forceu/gokapi/internal/environment/flagparser"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/notifications"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
 	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem/sftp"
+	"github.com/forceu/gokapi/internal/storage/filesystem/webdavfilesystem/webdav"
 	"github.com/forceu/gokapi/internal/webserver"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"github.com/forceu/gokapi/internal/webserver/ssl"
@@ -71,7 +76,13 @@ func main() {
 	authentication.Init(configuration.Get().Authentication)
 	createSsl(passedFlags)
 	initCloudConfig(passedFlags)
+	handleStorageMigration(passedFlags)
+	startNotifications()
 	go storage.CleanUp(true)
+	go chunking.AbortOrphanedMultipartUploads()
+	startTiering()
+	startScrubber()
+	startRehashing()
 	logging.LogStartup()
 	go webserver.Start()
 
@@ -85,6 +96,7 @@ func main() {
 func shutdown() {
 	fmt.Println("Shutting down...")
 	webserver.Shutdown()
+	notifications.Wait()
 	logging.LogShutdown()
 	database.Close()
 }
@@ -140,6 +152,13 @@ func parseBuildSettings(infos []debug.BuildSetting) {
 
 func initCloudConfig(passedFlags flagparser.MainFlags) {
 	cConfig, ok := cloudconfig.Load()
+	if ok {
+		// Storage targets can only be registered after the drivers have been initialised
+		defer initStorageTargets(cConfig)
+	}
+	// WebDAV and SFTP are always initialised if configured, so that previously stored files can still be downloaded
+	isWebdavAvailable := ok && webdav.Init(cConfig.Webdav)
+	isSftpAvailable := ok && sftp.Init(cConfig.Sftp)
 	if ok && aws.Init(cConfig.Aws) {
 		fmt.Println("Saving new files to cloud storage")
 		filesystem.SetAws()
@@ -157,9 +176,77 @@ func initCloudConfig(passedFlags flagparser.MainFlags) {
 				}
 			}
 		}
-	} else {
-		fmt.Println("Saving new files to local storage")
+		return
+	}
+	if isWebdavAvailable {
+		fmt.Println("Saving new files to WebDAV storage")
+		filesystem.SetWebdav()
+		return
+	}
+	if isSftpAvailable {
+		fmt.Println("Saving new files to SFTP storage")
+		filesystem.SetSftp()
+		return
+	}
+	fmt.Println("Saving new files to local storage")
+}
+
+// initStorageTargets registers the named storage targets of the cloud config
+func initStorageTargets(cConfig cloudconfig.CloudConfig) {
+	for _, target := range cConfig.Targets {
+		err := filesystem.AddTarget(target, cConfig.Aws)
+		if err != nil {
+			fmt.Println("Warning: Storage target " + target.Name + " is not available")
+			fmt.Println(err.Error())
+			continue
+		}
+		fmt.Println("Storage target " + target.Name + " is available")
+	}
+}
+
+// startTiering moves old local files to S3 in the background, if a tiering policy has been set
+func startTiering() {
+	env := environment.New()
+	policy := storage.TieringPolicy{
+		MinAgeDays: env.TieringMinAgeDays,
+		IdleDays:   env.TieringIdleDays,
+		Target:     env.TieringTarget,
+	}
+	if !policy.IsEnabled() {
+		return
+	}
+	fmt.Println("Moving old local files to cloud storage in the background")
+	go storage.RunTiering(policy, true)
+}
+
+// startNotifications sets the mail server for email notifications, if one has been set
+func startNotifications() {
+	env := environment.New()
+	notifications.Init(env.GetSmtpConfig())
+	if notifications.IsEnabled() {
+		fmt.Println("Email notifications are enabled")
+	}
+}
+
+// startRehashing hashes stored files again in the background, that were hashed with a different algorithm,
+// if this has been enabled. As all affected files have to be read again, this is not done by default
+func startRehashing() {
+	env := environment.New()
+	if !env.RehashFiles {
+		return
+	}
+	fmt.Println("Hashing stored files with " + configuration.Get().HashAlgorithm + " in the background")
+	go storage.RunRehashing(true)
+}
+
+// startScrubber checks the integrity of all stored files periodically, if an interval has been set
+func startScrubber() {
+	env := environment.New()
+	if env.ScrubIntervalHours < 1 {
+		return
 	}
+	fmt.Printf("Checking the integrity of stored files every %d hours\n", env.ScrubIntervalHours)
+	storage.RunScrubber(env.ScrubIntervalHours, env.ScrubQuarantine)
 }
 
 // Checks for command line arguments that have to be parsed after loading the configuration
@@ -193,6 +280,21 @@ func handleDbMigration(passedFlags flagparser.MainFlags) {
 	osExit(0)
 }
 
+func handleStorageMigration(passedFlags flagparser.MainFlags) {
+	if !passedFlags.MigrateStorage {
+		return
+	}
+	fmt.Println("Moving stored files to the sharded directory layout...")
+	movedFiles, errorCount := storage.MigrateStorageLayout()
+	fmt.Println("Moved " + strconv.Itoa(movedFiles) + " files")
+	if errorCount > 0 {
+		fmt.Println(strconv.Itoa(errorCount) + " files could not be moved and are still read from the old location")
+		osExit(1)
+		return
+	}
+	osExit(0)
+}
+
 func handleServiceInstall(passedFlags flagparser.MainFlags) {
 	if passedFlags.InstallService && passedFlags.UninstallService {
 		fmt.Println("Error: Both install and uninstall flags are set.")
@@ -34,3 +34,7 @@ func TestCreateSsl(t *testing.T) {
 	createSsl(flagparser.MainFlags{CreateSsl: true})
 	test.FileExists(t, "test/ssl.key")
 }
+
+func TestNoStorageMigration(t *testing.T) {
+	handleStorageMigration(flagparser.MainFlags{})
+}
@@ -27,6 +27,9 @@ import (
 // MinLengthPassword is the required length of admin password in characters
 const MinLengthPassword = 8
 
+// TempDirectory is the subdirectory of the data directory that contains temporary files of uploads in progress
+const TempDirectory = "tmp"
+
 // Environment is an object containing the environment variables
 var Environment environment.Environment
 
@@ -93,7 +96,20 @@ func Load() {
 	}
 	serverSettings.LengthId = Environment.LengthId
 	serverSettings.LengthHotlinkId = Environment.LengthHotlinkId
+	serverSettings.MinFreeSpaceMB = Environment.MinFreeSpaceMB
+	serverSettings.DiskWarningPercent = Environment.DiskWarningPercent
+	serverSettings.HashAlgorithm = Environment.HashAlgorithm
+	serverSettings.Compression = Environment.Compression
+	serverSettings.CompressionTypes = Environment.GetCompressionTypes()
+	serverSettings.RemoteUploadHosts = Environment.GetRemoteUploadHosts()
+	serverSettings.BandwidthGlobalKB = Environment.BandwidthGlobalKB
+	serverSettings.BandwidthFileKB = Environment.BandwidthFileKB
+	serverSettings.BandwidthRequestKB = Environment.BandwidthRequestKB
+	serverSettings.ParallelDownloads = Environment.ParallelDownloads
+	serverSettings.ParallelDownloadIp = Environment.ParallelDownloadIp
+	serverSettings.DownloadHistoryDays = Environment.DownloadHistoryDays
 	helper.CreateDir(serverSettings.DataDir)
+	helper.CreateDir(GetTempDir())
 	filesystem.Init(serverSettings.DataDir)
 	logging.Init(Environment.DataDir)
 }
@@ -163,6 +179,11 @@ func UsesHttps() bool {
 	return usesHttps
 }
 
+// GetTempDir returns the directory for temporary files of uploads in progress
+func GetTempDir() string {
+	return serverSettings.DataDir + "/" + TempDirectory
+}
+
 // Get returns a pointer to the server configuration
 func Get() *models.Configuration {
 	return &serverSettings
@@ -32,6 +32,13 @@ func TestLoad(t *testing.T) {
 	Load()
 	test.IsEqualInt(t, serverSettings.LengthId, 20)
 	test.IsEqualInt(t, serverSettings.LengthHotlinkId, 25)
+	test.IsEqualInt(t, serverSettings.MinFreeSpaceMB, 100)
+	test.IsEqualInt(t, serverSettings.DiskWarningPercent, 90)
+	test.IsEqualString(t, serverSettings.HashAlgorithm, "sha256")
+	test.IsEqualString(t, serverSettings.Compression, "")
+	test.IsEqualInt(t, len(serverSettings.CompressionTypes), 5)
+	test.IsEqualInt(t, len(serverSettings.RemoteUploadHosts), 0)
+	test.IsEqualInt(t, serverSettings.BandwidthGlobalKB, 0)
 	_ = os.Unsetenv("GOKAPI_LENGTH_ID")
 	_ = os.Unsetenv("GOKAPI_LENGTH_HOTLINK_ID")
 	test.IsEqualInt(t, serverSettings.ConfigVersion, configupgrade.CurrentConfigVersion)
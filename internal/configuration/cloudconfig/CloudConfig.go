@@ -12,16 +12,27 @@ import (
 
 // CloudConfig contains all configuration values / credentials for cloud storage
 type CloudConfig struct {
-	Aws models.AwsConfig `yaml:"aws"`
+	Aws    models.AwsConfig    `yaml:"aws"`
+	Webdav models.WebdavConfig `yaml:"webdav"`
+	Sftp   models.SftpConfig   `yaml:"sftp"`
+	// Targets are named storage targets, which can only be set in the cloudconfig file
+	Targets []models.StorageTargetConfig `yaml:"targets"`
 }
 
 // Load loads cloud storage configuration / credentials from env variables or data/cloudconfig.yml
 func Load() (CloudConfig, bool) {
 	env := environment.New()
-	if env.IsAwsProvided() {
-		return loadFromEnv(&env), true
-	}
 	path := env.ConfigDir + "/cloudconfig.yml"
+	if env.IsAwsProvided() || env.IsWebdavProvided() || env.IsSftpProvided() {
+		result := loadFromEnv(&env)
+		if helper.FileExists(path) {
+			fileConfig, ok := loadFromFile(path)
+			if ok {
+				result.Targets = fileConfig.Targets
+			}
+		}
+		return result, true
+	}
 	if helper.FileExists(path) {
 		return loadFromFile(path)
 	}
@@ -59,13 +70,28 @@ func Delete() error {
 }
 
 func loadFromEnv(env *environment.Environment) CloudConfig {
-	return CloudConfig{Aws: models.AwsConfig{
-		Bucket:    env.AwsBucket,
-		Region:    env.AwsRegion,
-		Endpoint:  env.AwsEndpoint,
-		KeyId:     env.AwsKeyId,
-		KeySecret: env.AwsKeySecret,
-	}}
+	return CloudConfig{
+		Aws: models.AwsConfig{
+			Bucket:    env.AwsBucket,
+			Region:    env.AwsRegion,
+			Endpoint:  env.AwsEndpoint,
+			KeyId:     env.AwsKeyId,
+			KeySecret: env.AwsKeySecret,
+		},
+		Webdav: models.WebdavConfig{
+			Url:      env.WebdavUrl,
+			Username: env.WebdavUsername,
+			Password: env.WebdavPassword,
+		},
+		Sftp: models.SftpConfig{
+			Host:      env.SftpHost,
+			Username:  env.SftpUsername,
+			Password:  env.SftpPassword,
+			KeyFile:   env.SftpKeyFile,
+			HostKey:   env.SftpHostKey,
+			Directory: env.SftpDirectory,
+		},
+	}
 }
 
 func loadFromFile(path string) (CloudConfig, bool) {
@@ -35,6 +35,9 @@ func TestLoad(t *testing.T) {
 		KeyId:     "test",
 		KeySecret: "test",
 	}, true)
+	test.IsEqualInt(t, len(config.Targets), 2)
+	test.IsEqualString(t, config.Targets[0].Name, "local-fast")
+	test.IsEqualString(t, config.Targets[1].Bucket, "archive")
 	os.Unsetenv("GOKAPI_AWS_BUCKET")
 	config, ok = Load()
 	savedConfig := models.AwsConfig{
@@ -46,6 +49,8 @@ func TestLoad(t *testing.T) {
 	}
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualBool(t, config.Aws == savedConfig, true)
+	test.IsEqualInt(t, len(config.Targets), 2)
+	test.IsEqualString(t, config.Targets[1].Type, "s3")
 	os.Unsetenv("GOKAPI_AWS_REGION")
 	os.Unsetenv("GOKAPI_AWS_KEY")
 	os.Unsetenv("GOKAPI_AWS_KEY_SECRET")
@@ -79,6 +79,15 @@ func Migrate(configOld, configNew models.DbConnection) {
 		if file.HotlinkId != "" {
 			dbNew.SaveHotlink(file)
 		}
+		events := dbOld.GetDownloadEvents(file.Id)
+		// Events are returned newest first, but stored oldest first to keep their order
+		for i := len(events) - 1; i >= 0; i-- {
+			dbNew.SaveDownloadEvent(events[i])
+		}
+	}
+	bundles := dbOld.GetAllBundles()
+	for _, bundle := range bundles {
+		dbNew.SaveBundle(bundle)
 	}
 	dbOld.Close()
 	dbNew.Close()
@@ -162,6 +171,55 @@ func DeleteEnd2EndInfo(userId int) {
 	db.DeleteEnd2EndInfo(userId)
 }
 
+// Bundle Section
+
+// GetAllBundles returns a map of all bundles
+func GetAllBundles() map[string]models.Bundle {
+	return db.GetAllBundles()
+}
+
+// GetBundle returns a models.Bundle from the ID passed or false if the id is not valid
+func GetBundle(id string) (models.Bundle, bool) {
+	return db.GetBundle(id)
+}
+
+// SaveBundle stores a bundle in the database
+func SaveBundle(bundle models.Bundle) {
+	db.SaveBundle(bundle)
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	db.IncreaseBundleDownloadCount(id, decreaseRemainingDownloads)
+}
+
+// DeleteBundle deletes a bundle with the given ID. The files of the bundle are not deleted
+func DeleteBundle(id string) {
+	db.DeleteBundle(id)
+}
+
+// Download Event Section
+
+// SaveDownloadEvent stores a download of a file. A new ID is generated for the event
+func SaveDownloadEvent(event models.DownloadEvent) {
+	db.SaveDownloadEvent(event)
+}
+
+// GetDownloadEvents returns all downloads of the file with the given ID, the newest download first
+func GetDownloadEvents(fileId string) []models.DownloadEvent {
+	return db.GetDownloadEvents(fileId)
+}
+
+// DeleteDownloadEvents deletes all downloads of the file with the given ID
+func DeleteDownloadEvents(fileId string) {
+	db.DeleteDownloadEvents(fileId)
+}
+
+// DeleteDownloadEventsBefore deletes all downloads that have been started before the given timestamp
+func DeleteDownloadEventsBefore(timestamp int64) {
+	db.DeleteDownloadEventsBefore(timestamp)
+}
+
 // Hotlink Section
 
 // GetHotlink returns the id of the file associated or false if not found
@@ -211,9 +269,20 @@ func DeleteMetaData(id string) {
 	db.DeleteMetaData(id)
 }
 
-// IncreaseDownloadCount increases the download count of a file, preventing race conditions
-func IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) {
-	db.IncreaseDownloadCount(id, decreaseRemainingDownloads)
+// IncreaseDownloadCount increases the download count of a file, preventing race conditions.
+// Returns the new download count
+func IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) int {
+	return db.IncreaseDownloadCount(id, decreaseRemainingDownloads)
+}
+
+// SaveArchiveContent stores the list of entries of an archive for the file with the given ID
+func SaveArchiveContent(id, content string) {
+	db.SaveArchiveContent(id, content)
+}
+
+// SetThumbnailStatus sets if a thumbnail has been created for the file with the given ID
+func SetThumbnailStatus(id string, hasThumbnail bool) {
+	db.SetThumbnailStatus(id, hasThumbnail)
 }
 
 // Session Section
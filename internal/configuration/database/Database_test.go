@@ -207,6 +207,40 @@ func TestHotlinks(t *testing.T) {
 	runAllTypesCompareOutput(t, func() any { return GetAllHotlinks() }, []string{})
 }
 
+func TestBundles(t *testing.T) {
+	runAllTypesCompareOutput(t, func() any { return GetAllBundles() }, map[string]models.Bundle{})
+	runAllTypesCompareTwoOutputs(t, func() (any, any) { return GetBundle("testbundle") }, models.Bundle{}, false)
+	bundle := models.Bundle{
+		Id:                 "testbundle",
+		Name:               "Test bundle",
+		FileIds:            []string{"file1", "file2"},
+		ExpireAt:           time.Now().Add(10 * time.Second).Unix(),
+		DownloadsRemaining: 2,
+		UserId:             3,
+	}
+	runAllTypesNoOutput(t, func() { SaveBundle(bundle) })
+	runAllTypesCompareTwoOutputs(t, func() (any, any) { return GetBundle("testbundle") }, bundle, true)
+	runAllTypesCompareOutput(t, func() any { return GetAllBundles() }, map[string]models.Bundle{"testbundle": bundle})
+
+	increasedDownload := bundle
+	increasedDownload.DownloadCount = 1
+	increasedDownload.DownloadsRemaining = 1
+	runAllTypesNoOutput(t, func() { IncreaseBundleDownloadCount(bundle.Id, true) })
+	runAllTypesCompareTwoOutputs(t, func() (any, any) { return GetBundle("testbundle") }, increasedDownload, true)
+	runAllTypesNoOutput(t, func() { DeleteBundle("testbundle") })
+	runAllTypesCompareOutput(t, func() any { return GetAllBundles() }, map[string]models.Bundle{})
+}
+
+func TestDownloadEvents(t *testing.T) {
+	runAllTypesCompareOutput(t, func() any { return GetDownloadEvents("eventfile") }, []models.DownloadEvent{})
+	runAllTypesNoOutput(t, func() {
+		SaveDownloadEvent(models.DownloadEvent{FileId: "eventfile", Timestamp: 100, UserAgent: "agent"})
+	})
+	runAllTypesCompareOutput(t, func() any { return len(GetDownloadEvents("eventfile")) }, 1)
+	runAllTypesNoOutput(t, func() { DeleteDownloadEvents("eventfile") })
+	runAllTypesCompareOutput(t, func() any { return GetDownloadEvents("eventfile") }, []models.DownloadEvent{})
+}
+
 func TestMetaData(t *testing.T) {
 	runAllTypesCompareOutput(t, func() any { return GetAllMetaDataIds() }, []string{})
 	runAllTypesCompareOutput(t, func() any { return GetAllMetadata() }, map[string]models.File{})
@@ -250,16 +284,38 @@ func TestMetaData(t *testing.T) {
 
 	runAllTypesCompareTwoOutputs(t, func() (any, any) {
 		SaveMetaData(file)
-		IncreaseDownloadCount(file.Id, false)
-		return GetMetaDataById(file.Id)
+		test.IsEqualInt(t, IncreaseDownloadCount(file.Id, false), increasedDownload.DownloadCount)
+		retrievedFile, ok := GetMetaDataById(file.Id)
+		test.IsEqualBool(t, retrievedFile.LastDownload > 0, true)
+		retrievedFile.LastDownload = 0
+		return retrievedFile, ok
 	}, increasedDownload, true)
 
 	increasedDownload.DownloadCount = increasedDownload.DownloadCount + 1
 	increasedDownload.DownloadsRemaining = increasedDownload.DownloadsRemaining - 1
 
 	runAllTypesCompareTwoOutputs(t, func() (any, any) {
-		IncreaseDownloadCount(file.Id, true)
-		return GetMetaDataById(file.Id)
+		test.IsEqualInt(t, IncreaseDownloadCount(file.Id, true), increasedDownload.DownloadCount)
+		retrievedFile, ok := GetMetaDataById(file.Id)
+		test.IsEqualBool(t, retrievedFile.LastDownload > 0, true)
+		retrievedFile.LastDownload = 0
+		return retrievedFile, ok
+	}, increasedDownload, true)
+
+	increasedDownload.ArchiveContent = "[]"
+	runAllTypesCompareTwoOutputs(t, func() (any, any) {
+		SaveArchiveContent(file.Id, "[]")
+		retrievedFile, ok := GetMetaDataById(file.Id)
+		retrievedFile.LastDownload = 0
+		return retrievedFile, ok
+	}, increasedDownload, true)
+
+	increasedDownload.HasThumbnail = true
+	runAllTypesCompareTwoOutputs(t, func() (any, any) {
+		SetThumbnailStatus(file.Id, true)
+		retrievedFile, ok := GetMetaDataById(file.Id)
+		retrievedFile.LastDownload = 0
+		return retrievedFile, ok
 	}, increasedDownload, true)
 	runAllTypesNoOutput(t, func() { DeleteMetaData(file.Id) })
 }
@@ -489,6 +545,9 @@ func TestMigration(t *testing.T) {
 	dbOld.SaveMetaData(testFile)
 	dbOld.SaveHotlink(testFile)
 	dbOld.SaveApiKey(models.ApiKey{Id: "api123"})
+	dbOld.SaveBundle(models.Bundle{Id: "bundle123", FileIds: []string{"file1234"}})
+	dbOld.SaveDownloadEvent(models.DownloadEvent{FileId: "file1234", Timestamp: 100, UserAgent: "first"})
+	dbOld.SaveDownloadEvent(models.DownloadEvent{FileId: "file1234", Timestamp: 100, UserAgent: "second"})
 	dbOld.SaveHotlink(testFile)
 	dbOld.Close()
 
@@ -502,4 +561,11 @@ func TestMigration(t *testing.T) {
 	test.IsEqualBool(t, ok, true)
 	_, ok = dbNew.GetMetaDataById("file1234")
 	test.IsEqualBool(t, ok, true)
+	bundle, ok := dbNew.GetBundle("bundle123")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, bundle.FileIds[0], "file1234")
+	events := dbNew.GetDownloadEvents("file1234")
+	test.IsEqualInt(t, len(events), 2)
+	test.IsEqualString(t, events[0].UserAgent, "second")
+	test.IsEqualString(t, events[1].UserAgent, "first")
 }
@@ -55,6 +55,26 @@ type Database interface {
 	// DeleteEnd2EndInfo resets the encrypted e2e info
 	DeleteEnd2EndInfo(userId int)
 
+	// GetAllBundles returns a map of all bundles
+	GetAllBundles() map[string]models.Bundle
+	// GetBundle returns a models.Bundle from the ID passed or false if the id is not valid
+	GetBundle(id string) (models.Bundle, bool)
+	// SaveBundle stores a bundle in the database
+	SaveBundle(bundle models.Bundle)
+	// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+	IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool)
+	// DeleteBundle deletes a bundle with the given ID. The files of the bundle are not deleted
+	DeleteBundle(id string)
+
+	// SaveDownloadEvent stores a download of a file. A new ID is generated for the event
+	SaveDownloadEvent(event models.DownloadEvent)
+	// GetDownloadEvents returns all downloads of the file with the given ID, the newest download first
+	GetDownloadEvents(fileId string) []models.DownloadEvent
+	// DeleteDownloadEvents deletes all downloads of the file with the given ID
+	DeleteDownloadEvents(fileId string)
+	// DeleteDownloadEventsBefore deletes all downloads that have been started before the given timestamp
+	DeleteDownloadEventsBefore(timestamp int64)
+
 	// GetHotlink returns the id of the file associated or false if not found
 	GetHotlink(id string) (string, bool)
 	// GetAllHotlinks returns an array with all hotlink ids
@@ -74,8 +94,13 @@ type Database interface {
 	SaveMetaData(file models.File)
 	// DeleteMetaData deletes information about a file
 	DeleteMetaData(id string)
-	// IncreaseDownloadCount increases the download count of a file, preventing race conditions
-	IncreaseDownloadCount(id string, decreaseRemainingDownloads bool)
+	// IncreaseDownloadCount increases the download count of a file, preventing race conditions.
+	// Returns the new download count
+	IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) int
+	// SaveArchiveContent stores the list of entries of an archive for the file with the given ID
+	SaveArchiveContent(id, content string)
+	// SetThumbnailStatus sets if a thumbnail has been created for the file with the given ID
+	SetThumbnailStatus(id string, hasThumbnail bool)
 
 	// GetSession returns the session with the given ID or false if not a valid ID
 	GetSession(id string) (models.Session, bool)
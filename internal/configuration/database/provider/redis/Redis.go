@@ -19,7 +19,7 @@ type DatabaseProvider struct {
 }
 
 // DatabaseSchemeVersion contains the version number to be expected from the current database. If lower, an upgrade will be performed
-const DatabaseSchemeVersion = 5
+const DatabaseSchemeVersion = 6
 
 // New returns an instance
 func New(dbConfig models.DbConnection) (DatabaseProvider, error) {
@@ -121,6 +121,14 @@ func (p DatabaseProvider) Upgrade(currentDbVersion int) {
 			}
 		}
 	}
+	// < v2.1.0
+	if currentDbVersion < 6 {
+		for _, file := range p.GetAllMetadata() {
+			for _, event := range p.GetDownloadEvents(file.Id) {
+				p.setHashmapField(getDownloadEventPrefix(file.Id)+strconv.FormatInt(event.Id, 10), "UserId", file.UserId)
+			}
+		}
+	}
 }
 
 const keyDbVersion = "dbversion"
@@ -299,11 +307,12 @@ func (p DatabaseProvider) deleteKey(id string) {
 	helper.Check(err)
 }
 
-func (p DatabaseProvider) increaseHashmapIntField(id string, field string) {
+func (p DatabaseProvider) increaseHashmapIntField(id string, field string) int {
 	conn := p.pool.Get()
 	defer conn.Close()
-	_, err := conn.Do("HINCRBY", p.dbPrefix+id, field, 1)
+	value, err := redigo.Int(conn.Do("HINCRBY", p.dbPrefix+id, field, 1))
 	helper.Check(err)
+	return value
 }
 
 func (p DatabaseProvider) decreaseHashmapIntField(id string, field string) {
@@ -320,6 +329,20 @@ func (p DatabaseProvider) setHashmapField(id string, field string, content any)
 	helper.Check(err)
 }
 
+// setExistingHashmapFieldScript sets a field of a hashmap, but only if the hashmap exists.
+// This prevents a hashmap from being recreated, if it has been deleted in the meantime
+var setExistingHashmapFieldScript = redigo.NewScript(1, `if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+return 0`)
+
+func (p DatabaseProvider) setExistingHashmapField(id string, field string, content any) {
+	conn := p.pool.Get()
+	defer conn.Close()
+	_, err := setExistingHashmapFieldScript.Do(conn, p.dbPrefix+id, field, content)
+	helper.Check(err)
+}
+
 func (p DatabaseProvider) getIncreasedInt(id string) int {
 	conn := p.pool.Get()
 	defer conn.Close()
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+	"strings"
+)
+
+const (
+	prefixBundles = "bundle:"
+)
+
+func dbToBundle(id string, input []any) (models.Bundle, error) {
+	var result models.Bundle
+	err := redigo.ScanStruct(input, &result)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+	result.Id = strings.Replace(id, prefixBundles, "", 1)
+	result.FileIds = models.SplitFileIds(result.InternalRedisFileIds)
+	result.InternalRedisFileIds = ""
+	return result, nil
+}
+
+// GetAllBundles returns a map of all bundles
+func (p DatabaseProvider) GetAllBundles() map[string]models.Bundle {
+	result := make(map[string]models.Bundle)
+	maps := p.getAllHashesWithPrefix(prefixBundles)
+	for k, v := range maps {
+		bundle, err := dbToBundle(k, v)
+		helper.Check(err)
+		result[bundle.Id] = bundle
+	}
+	return result
+}
+
+// GetBundle returns a models.Bundle from the ID passed or false if the id is not valid
+func (p DatabaseProvider) GetBundle(id string) (models.Bundle, bool) {
+	result, ok := p.getHashMap(prefixBundles + id)
+	if !ok {
+		return models.Bundle{}, false
+	}
+	bundle, err := dbToBundle(id, result)
+	helper.Check(err)
+	return bundle, true
+}
+
+// SaveBundle stores a bundle in the database
+func (p DatabaseProvider) SaveBundle(bundle models.Bundle) {
+	bundle.InternalRedisFileIds = bundle.JoinFileIds()
+	p.setHashMap(p.buildArgs(prefixBundles + bundle.Id).AddFlat(bundle))
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func (p DatabaseProvider) IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		p.decreaseHashmapIntField(prefixBundles+id, "DownloadsRemaining")
+	}
+	p.increaseHashmapIntField(prefixBundles+id, "DownloadCount")
+}
+
+// DeleteBundle deletes a bundle with the given ID. The files of the bundle are not deleted
+func (p DatabaseProvider) DeleteBundle(id string) {
+	p.deleteKey(prefixBundles + id)
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"cmp"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+	"slices"
+	"strconv"
+)
+
+const (
+	prefixDownloadEvents         = "dlevent:"
+	prefixDownloadEventIdCounter = "dleventid_max"
+)
+
+func getDownloadEventPrefix(fileId string) string {
+	return prefixDownloadEvents + fileId + ":"
+}
+
+// SaveDownloadEvent stores a download of a file. A new ID is generated for the event
+func (p DatabaseProvider) SaveDownloadEvent(event models.DownloadEvent) {
+	event.Id = int64(p.getIncreasedInt(prefixDownloadEventIdCounter))
+	p.setHashMap(p.buildArgs(getDownloadEventPrefix(event.FileId) + strconv.FormatInt(event.Id, 10)).AddFlat(event))
+}
+
+// GetDownloadEvents returns all downloads of the file with the given ID, the newest download first
+func (p DatabaseProvider) GetDownloadEvents(fileId string) []models.DownloadEvent {
+	result := make([]models.DownloadEvent, 0)
+	maps := p.getAllHashesWithPrefix(getDownloadEventPrefix(fileId))
+	for _, v := range maps {
+		var event models.DownloadEvent
+		err := redigo.ScanStruct(v, &event)
+		helper.Check(err)
+		result = append(result, event)
+	}
+	slices.SortFunc(result, func(a, b models.DownloadEvent) int {
+		return cmp.Or(
+			cmp.Compare(b.Timestamp, a.Timestamp),
+			cmp.Compare(b.Id, a.Id),
+		)
+	})
+	return result
+}
+
+// DeleteDownloadEvents deletes all downloads of the file with the given ID
+func (p DatabaseProvider) DeleteDownloadEvents(fileId string) {
+	p.deleteAllWithPrefix(getDownloadEventPrefix(fileId))
+}
+
+// DeleteDownloadEventsBefore deletes all downloads that have been started before the given timestamp
+func (p DatabaseProvider) DeleteDownloadEventsBefore(timestamp int64) {
+	for key, values := range p.getAllHashesWithPrefix(prefixDownloadEvents) {
+		var event models.DownloadEvent
+		err := redigo.ScanStruct(values, &event)
+		helper.Check(err)
+		if event.Timestamp < timestamp {
+			p.deleteKey(key)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/forceu/gokapi/internal/models"
 	redigo "github.com/gomodule/redigo/redis"
 	"strings"
+	"time"
 )
 
 const (
@@ -95,10 +96,25 @@ func (p DatabaseProvider) DeleteMetaData(id string) {
 	p.deleteKey(prefixMetaData + id)
 }
 
-// IncreaseDownloadCount increases the download count of a file, preventing race conditions
-func (p DatabaseProvider) IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) {
+// SaveArchiveContent stores the list of entries of an archive for the file with the given ID.
+// Nothing is stored, if the file has been deleted in the meantime
+func (p DatabaseProvider) SaveArchiveContent(id, content string) {
+	p.setExistingHashmapField(prefixMetaData+id, "ArchiveContent", content)
+}
+
+// SetThumbnailStatus sets if a thumbnail has been created for the file with the given ID.
+// Nothing is stored, if the file has been deleted in the meantime
+func (p DatabaseProvider) SetThumbnailStatus(id string, hasThumbnail bool) {
+	p.setExistingHashmapField(prefixMetaData+id, "HasThumbnail", hasThumbnail)
+}
+
+// IncreaseDownloadCount increases the download count of a file, preventing race conditions.
+// The time of the last download is set to the current time. Returns the new download count
+func (p DatabaseProvider) IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) int {
 	if decreaseRemainingDownloads {
 		p.decreaseHashmapIntField(prefixMetaData+id, "DownloadsRemaining")
 	}
-	p.increaseHashmapIntField(prefixMetaData+id, "DownloadCount")
+	downloadCount := p.increaseHashmapIntField(prefixMetaData+id, "DownloadCount")
+	p.setHashmapField(prefixMetaData+id, "LastDownload", time.Now().Unix())
+	return downloadCount
 }
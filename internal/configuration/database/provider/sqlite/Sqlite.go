@@ -20,7 +20,7 @@ type DatabaseProvider struct {
 }
 
 // DatabaseSchemeVersion contains the version number to be expected from the current database. If lower, an upgrade will be performed
-const DatabaseSchemeVersion = 10
+const DatabaseSchemeVersion = 25
 
 // New returns an instance
 func New(dbConfig models.DbConnection) (DatabaseProvider, error) {
@@ -102,6 +102,113 @@ func (p DatabaseProvider) Upgrade(currentDbVersion int) {
 		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN PendingDeletion INTEGER NOT NULL DEFAULT 0;`)
 		helper.Check(err)
 	}
+	// < v2.1.0
+	if currentDbVersion < 11 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN StorageDriver TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 12 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN StorageTarget TEXT NOT NULL DEFAULT '';
+									 ALTER TABLE "Users" ADD COLUMN DefaultStorageTarget TEXT NOT NULL DEFAULT '';
+									 ALTER TABLE "Users" ADD COLUMN StorageTargets TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 13 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN LastDownload INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 14 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN IsQuarantined INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 15 {
+		err := p.rawSqlite(`ALTER TABLE "Users" ADD COLUMN QuotaBytes INTEGER NOT NULL DEFAULT 0;
+									 ALTER TABLE "Users" ADD COLUMN QuotaFiles INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 16 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN HashAlgorithm TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 17 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN Compression TEXT NOT NULL DEFAULT '';
+									 ALTER TABLE "FileMetaData" ADD COLUMN CompressedBytes INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 18 {
+		err := p.rawSqlite(`CREATE TABLE "Bundles" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"Name"	TEXT NOT NULL,
+			"FileIds"	TEXT NOT NULL,
+			"PasswordHash"	TEXT NOT NULL,
+			"ExpireAtString"	TEXT NOT NULL,
+			"ExpireAt"	INTEGER NOT NULL,
+			"CreationDate"	INTEGER NOT NULL,
+			"DownloadsRemaining"	INTEGER NOT NULL,
+			"DownloadCount"	INTEGER NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"UnlimitedDownloads"	INTEGER NOT NULL,
+			"UnlimitedTime"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 19 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN ArchiveContent TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 20 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN HasThumbnail INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 21 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN BandwidthLimitKB INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 22 {
+		err := p.rawSqlite(`ALTER TABLE "FileMetaData" ADD COLUMN ParallelDownloads INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 23 {
+		err := p.rawSqlite(`CREATE TABLE "DownloadEvents" (
+			"Id"	INTEGER NOT NULL UNIQUE,
+			"FileId"	TEXT NOT NULL,
+			"Timestamp"	INTEGER NOT NULL,
+			"Ip"	TEXT NOT NULL,
+			"UserAgent"	TEXT NOT NULL,
+			"BytesSent"	INTEGER NOT NULL,
+			"IsComplete"	INTEGER NOT NULL,
+			PRIMARY KEY("Id" AUTOINCREMENT)
+		);
+		CREATE INDEX "DownloadEventsFileId" ON "DownloadEvents" ("FileId");`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 24 {
+		err := p.rawSqlite(`ALTER TABLE "Users" ADD COLUMN NotificationEmail TEXT NOT NULL DEFAULT '';
+									 ALTER TABLE "Users" ADD COLUMN NotifyDownload INTEGER NOT NULL DEFAULT 0;
+									 ALTER TABLE "Users" ADD COLUMN NotifyExpiry INTEGER NOT NULL DEFAULT 0;
+									 ALTER TABLE "Users" ADD COLUMN NotifyDeletion INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.1.0
+	if currentDbVersion < 25 {
+		err := p.rawSqlite(`ALTER TABLE "DownloadEvents" ADD COLUMN UserId INTEGER NOT NULL DEFAULT 0;
+									 UPDATE "DownloadEvents" SET UserId = COALESCE((SELECT UserId FROM FileMetaData WHERE FileMetaData.Id = DownloadEvents.FileId), 0);`)
+		helper.Check(err)
+	}
 }
 
 func getLegacyE2EConfig(p DatabaseProvider) models.E2EInfoEncrypted {
@@ -205,6 +312,33 @@ func (p DatabaseProvider) createNewDatabase() error {
 			"PublicId" TEXT NOT NULL UNIQUE ,
 			PRIMARY KEY("Id")
 		) WITHOUT ROWID;
+		CREATE TABLE "Bundles" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"Name"	TEXT NOT NULL,
+			"FileIds"	TEXT NOT NULL,
+			"PasswordHash"	TEXT NOT NULL,
+			"ExpireAtString"	TEXT NOT NULL,
+			"ExpireAt"	INTEGER NOT NULL,
+			"CreationDate"	INTEGER NOT NULL,
+			"DownloadsRemaining"	INTEGER NOT NULL,
+			"DownloadCount"	INTEGER NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"UnlimitedDownloads"	INTEGER NOT NULL,
+			"UnlimitedTime"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE TABLE "DownloadEvents" (
+			"Id"	INTEGER NOT NULL UNIQUE,
+			"FileId"	TEXT NOT NULL,
+			"Timestamp"	INTEGER NOT NULL,
+			"Ip"	TEXT NOT NULL,
+			"UserAgent"	TEXT NOT NULL,
+			"BytesSent"	INTEGER NOT NULL,
+			"IsComplete"	INTEGER NOT NULL,
+			"UserId"	INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY("Id" AUTOINCREMENT)
+		);
+		CREATE INDEX "DownloadEventsFileId" ON "DownloadEvents" ("FileId");
 		CREATE TABLE "E2EConfig" (
 			"id"	INTEGER NOT NULL UNIQUE,
 			"Config"	BLOB NOT NULL,
@@ -231,6 +365,17 @@ func (p DatabaseProvider) createNewDatabase() error {
 			"UserId"	INTEGER NOT NULL,
 			"UploadDate"	INTEGER NOT NULL,
 			"PendingDeletion"	INTEGER NOT NULL,
+			"StorageDriver"	TEXT NOT NULL DEFAULT '',
+			"StorageTarget"	TEXT NOT NULL DEFAULT '',
+			"LastDownload"	INTEGER NOT NULL DEFAULT 0,
+			"IsQuarantined"	INTEGER NOT NULL DEFAULT 0,
+			"HashAlgorithm"	TEXT NOT NULL DEFAULT '',
+			"Compression"	TEXT NOT NULL DEFAULT '',
+			"CompressedBytes"	INTEGER NOT NULL DEFAULT 0,
+			"ArchiveContent"	TEXT NOT NULL DEFAULT '',
+			"HasThumbnail"	INTEGER NOT NULL DEFAULT 0,
+			"BandwidthLimitKB"	INTEGER NOT NULL DEFAULT 0,
+			"ParallelDownloads"	INTEGER NOT NULL DEFAULT 0,
 			PRIMARY KEY("Id")
 		);
 		CREATE TABLE "Hotlinks" (
@@ -253,6 +398,14 @@ func (p DatabaseProvider) createNewDatabase() error {
 			"Userlevel"	INTEGER NOT NULL,
 			"LastOnline"	INTEGER NOT NULL DEFAULT 0,
 			"ResetPassword"	INTEGER NOT NULL DEFAULT 0,
+			"DefaultStorageTarget"	TEXT NOT NULL DEFAULT '',
+			"StorageTargets"	TEXT NOT NULL DEFAULT '',
+			"QuotaBytes"	INTEGER NOT NULL DEFAULT 0,
+			"QuotaFiles"	INTEGER NOT NULL DEFAULT 0,
+			"NotificationEmail"	TEXT NOT NULL DEFAULT '',
+			"NotifyDownload"	INTEGER NOT NULL DEFAULT 0,
+			"NotifyExpiry"	INTEGER NOT NULL DEFAULT 0,
+			"NotifyDeletion"	INTEGER NOT NULL DEFAULT 0,
 			PRIMARY KEY("Id" AUTOINCREMENT)
 		);
 `
@@ -190,6 +190,8 @@ func TestDatabaseProvider_IncreaseDownloadCount(t *testing.T) {
 		HotlinkId:          "newHotlink",
 		ContentType:        "newContent",
 		AwsBucket:          "newAws",
+		StorageDriver:      "newDriver",
+		StorageTarget:      "newTarget",
 		ExpireAt:           123456,
 		SizeBytes:          456789,
 		DownloadsRemaining: 11,
@@ -202,17 +204,26 @@ func TestDatabaseProvider_IncreaseDownloadCount(t *testing.T) {
 		},
 		UnlimitedDownloads: true,
 		UnlimitedTime:      true,
+		IsQuarantined:      true,
+		HashAlgorithm:      "sha256",
+		Compression:        "zstd",
+		CompressedBytes:    5,
+		BandwidthLimitKB:   512,
+		ParallelDownloads:  3,
 	}
 	dbInstance.SaveMetaData(newFile)
-	dbInstance.IncreaseDownloadCount(newFile.Id, false)
+	test.IsEqualInt(t, dbInstance.IncreaseDownloadCount(newFile.Id, false), 3)
 	retrievedFile, ok := dbInstance.GetMetaDataById(newFile.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualInt(t, retrievedFile.DownloadCount, 3)
 	test.IsEqualInt(t, retrievedFile.DownloadsRemaining, 11)
+	test.IsEqualBool(t, retrievedFile.LastDownload > 0, true)
 	newFile.DownloadCount = 3
+	newFile.LastDownload = retrievedFile.LastDownload
 	test.IsEqual(t, retrievedFile, newFile)
+	test.IsEqualInt(t, dbInstance.IncreaseDownloadCount("invalid", true), 0)
 
-	dbInstance.IncreaseDownloadCount(newFile.Id, true)
+	test.IsEqualInt(t, dbInstance.IncreaseDownloadCount(newFile.Id, true), 4)
 	retrievedFile, ok = dbInstance.GetMetaDataById(newFile.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualInt(t, retrievedFile.DownloadCount, 4)
@@ -220,9 +231,109 @@ func TestDatabaseProvider_IncreaseDownloadCount(t *testing.T) {
 	newFile.DownloadCount = 4
 	newFile.DownloadsRemaining = 10
 	test.IsEqual(t, retrievedFile, newFile)
+
+	dbInstance.SaveArchiveContent(newFile.Id, `[{"Name":"test.txt"}]`)
+	retrievedFile, ok = dbInstance.GetMetaDataById(newFile.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.ArchiveContent, `[{"Name":"test.txt"}]`)
+	test.IsEqualInt(t, retrievedFile.DownloadsRemaining, 10)
+
+	dbInstance.SetThumbnailStatus(newFile.Id, true)
+	retrievedFile, _ = dbInstance.GetMetaDataById(newFile.Id)
+	test.IsEqualBool(t, retrievedFile.HasThumbnail, true)
+	dbInstance.SetThumbnailStatus(newFile.Id, false)
+	retrievedFile, _ = dbInstance.GetMetaDataById(newFile.Id)
+	test.IsEqualBool(t, retrievedFile.HasThumbnail, false)
 	dbInstance.DeleteMetaData(newFile.Id)
 }
 
+func TestBundles(t *testing.T) {
+	bundle := models.Bundle{
+		Id:                 "testbundle",
+		Name:               "Test bundle",
+		FileIds:            []string{"file1", "file2"},
+		PasswordHash:       "pwhash",
+		ExpireAtString:     "In 10 seconds",
+		ExpireAt:           123456,
+		CreationDate:       12345,
+		DownloadsRemaining: 3,
+		DownloadCount:      1,
+		UserId:             5,
+		UnlimitedTime:      true,
+	}
+	_, ok := dbInstance.GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, false)
+	dbInstance.SaveBundle(bundle)
+	retrieved, ok := dbInstance.GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqual(t, retrieved, bundle)
+
+	dbInstance.SaveBundle(models.Bundle{Id: "emptybundle", FileIds: []string{}, UnlimitedDownloads: true})
+	bundles := dbInstance.GetAllBundles()
+	test.IsEqualInt(t, len(bundles), 2)
+	test.IsEqual(t, bundles["testbundle"], bundle)
+	test.IsEqualBool(t, bundles["emptybundle"].UnlimitedDownloads, true)
+	test.IsEqualInt(t, len(bundles["emptybundle"].FileIds), 0)
+
+	dbInstance.IncreaseBundleDownloadCount(bundle.Id, false)
+	retrieved, _ = dbInstance.GetBundle(bundle.Id)
+	test.IsEqualInt(t, retrieved.DownloadCount, 2)
+	test.IsEqualInt(t, retrieved.DownloadsRemaining, 3)
+	dbInstance.IncreaseBundleDownloadCount(bundle.Id, true)
+	retrieved, _ = dbInstance.GetBundle(bundle.Id)
+	test.IsEqualInt(t, retrieved.DownloadCount, 3)
+	test.IsEqualInt(t, retrieved.DownloadsRemaining, 2)
+
+	dbInstance.DeleteBundle(bundle.Id)
+	dbInstance.DeleteBundle("emptybundle")
+	_, ok = dbInstance.GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualInt(t, len(dbInstance.GetAllBundles()), 0)
+}
+
+func TestDownloadEvents(t *testing.T) {
+	test.IsEqualInt(t, len(dbInstance.GetDownloadEvents("eventfile")), 0)
+	first := models.DownloadEvent{
+		FileId:     "eventfile",
+		Timestamp:  100,
+		Ip:         "127.0.0.1",
+		UserAgent:  "agent1",
+		BytesSent:  10,
+		IsComplete: true,
+		UserId:     5,
+	}
+	dbInstance.SaveDownloadEvent(first)
+	dbInstance.SaveDownloadEvent(models.DownloadEvent{FileId: "eventfile", Timestamp: 200, UserAgent: "agent2", BytesSent: 5})
+	dbInstance.SaveDownloadEvent(models.DownloadEvent{FileId: "otherfile", Timestamp: 150})
+
+	events := dbInstance.GetDownloadEvents("eventfile")
+	test.IsEqualInt(t, len(events), 2)
+	test.IsEqualString(t, events[0].UserAgent, "agent2")
+	test.IsEqualInt64(t, events[0].BytesSent, 5)
+	test.IsEqualBool(t, events[0].IsComplete, false)
+	test.IsEqualBool(t, events[0].Id != events[1].Id, true)
+	first.Id = events[1].Id
+	test.IsEqual(t, events[1], first)
+	test.IsEqualInt(t, len(dbInstance.GetDownloadEvents("otherfile")), 1)
+
+	dbInstance.DeleteDownloadEvents("eventfile")
+	test.IsEqualInt(t, len(dbInstance.GetDownloadEvents("eventfile")), 0)
+	test.IsEqualInt(t, len(dbInstance.GetDownloadEvents("otherfile")), 1)
+	dbInstance.DeleteDownloadEvents("otherfile")
+	test.IsEqualInt(t, len(dbInstance.GetDownloadEvents("otherfile")), 0)
+
+	// Downloads that are older than the retention period are removed, independently of the file
+	dbInstance.SaveDownloadEvent(models.DownloadEvent{FileId: "eventfile", Timestamp: 100})
+	dbInstance.SaveDownloadEvent(models.DownloadEvent{FileId: "eventfile", Timestamp: 300})
+	dbInstance.SaveDownloadEvent(models.DownloadEvent{FileId: "otherfile", Timestamp: 199})
+	dbInstance.DeleteDownloadEventsBefore(200)
+	events = dbInstance.GetDownloadEvents("eventfile")
+	test.IsEqualInt(t, len(events), 1)
+	test.IsEqualInt64(t, events[0].Timestamp, 300)
+	test.IsEqualInt(t, len(dbInstance.GetDownloadEvents("otherfile")), 0)
+	dbInstance.DeleteDownloadEvents("eventfile")
+}
+
 func TestApiKey(t *testing.T) {
 	key1 := models.ApiKey{
 		Id:           "newkey",
@@ -576,13 +687,21 @@ func TestUsers(t *testing.T) {
 	users := dbInstance.GetAllUsers()
 	test.IsEqualInt(t, len(users), 0)
 	user := models.User{
-		Id:            2,
-		Name:          "test",
-		Permissions:   models.UserPermissionAll,
-		UserLevel:     models.UserLevelUser,
-		LastOnline:    1337,
-		Password:      "123456",
-		ResetPassword: true,
+		Id:                   2,
+		Name:                 "test",
+		Permissions:          models.UserPermissionAll,
+		UserLevel:            models.UserLevelUser,
+		LastOnline:           1337,
+		Password:             "123456",
+		ResetPassword:        true,
+		DefaultStorageTarget: "local-fast",
+		StorageTargets:       "local-fast,s3-archive",
+		QuotaBytes:           1024,
+		QuotaFiles:           5,
+		NotificationEmail:    "test@example.com",
+		NotifyDownload:       models.NotifyDownloadFirst,
+		NotifyExpiry:         true,
+		NotifyDeletion:       true,
 	}
 	dbInstance.SaveUser(user, false)
 	retrievedUser, ok := dbInstance.GetUser(2)
@@ -653,6 +772,8 @@ func TestDatabaseProvider_Upgrade(t *testing.T) {
 	test.IsNil(t, err)
 	err = instance.rawSqlite(`
 		DROP TABLE IF EXISTS ApiKeys;
+		DROP TABLE IF EXISTS Bundles;
+		DROP TABLE IF EXISTS DownloadEvents;
 		DROP TABLE IF EXISTS E2EConfig;
 		DROP TABLE IF EXISTS FileMetaData;
 		DROP TABLE IF EXISTS Hotlinks;
@@ -676,6 +797,10 @@ func TestDatabaseProvider_Upgrade(t *testing.T) {
 	instance.SetDbVersion(6)
 	instance.Upgrade(instance.GetDbVersion())
 	test.IsEqualInt(t, exitCode, 0)
+	instance.SaveDownloadEvent(models.DownloadEvent{FileId: "upgradeevents", Timestamp: 100, UserId: 7})
+	events := instance.GetDownloadEvents("upgradeevents")
+	test.IsEqualInt(t, len(events), 1)
+	test.IsEqualInt(t, events[0].UserId, 7)
 
 }
 
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+type schemaBundles struct {
+	Id                 string
+	Name               string
+	FileIds            string
+	PasswordHash       string
+	ExpireAtString     string
+	ExpireAt           int64
+	CreationDate       int64
+	DownloadsRemaining int
+	DownloadCount      int
+	UserId             int
+	UnlimitedDownloads int
+	UnlimitedTime      int
+}
+
+func (rowData schemaBundles) ToBundle() models.Bundle {
+	return models.Bundle{
+		Id:                 rowData.Id,
+		Name:               rowData.Name,
+		FileIds:            models.SplitFileIds(rowData.FileIds),
+		PasswordHash:       rowData.PasswordHash,
+		ExpireAtString:     rowData.ExpireAtString,
+		ExpireAt:           rowData.ExpireAt,
+		CreationDate:       rowData.CreationDate,
+		DownloadsRemaining: rowData.DownloadsRemaining,
+		DownloadCount:      rowData.DownloadCount,
+		UserId:             rowData.UserId,
+		UnlimitedDownloads: rowData.UnlimitedDownloads == 1,
+		UnlimitedTime:      rowData.UnlimitedTime == 1,
+	}
+}
+
+func (rowData *schemaBundles) scanArgs() []any {
+	return []any{&rowData.Id, &rowData.Name, &rowData.FileIds, &rowData.PasswordHash, &rowData.ExpireAtString,
+		&rowData.ExpireAt, &rowData.CreationDate, &rowData.DownloadsRemaining, &rowData.DownloadCount,
+		&rowData.UserId, &rowData.UnlimitedDownloads, &rowData.UnlimitedTime}
+}
+
+// GetAllBundles returns a map of all bundles
+func (p DatabaseProvider) GetAllBundles() map[string]models.Bundle {
+	result := make(map[string]models.Bundle)
+	rows, err := p.sqliteDb.Query("SELECT * FROM Bundles")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		rowData := schemaBundles{}
+		err = rows.Scan(rowData.scanArgs()...)
+		helper.Check(err)
+		result[rowData.Id] = rowData.ToBundle()
+	}
+	return result
+}
+
+// GetBundle returns a models.Bundle from the ID passed or false if the id is not valid
+func (p DatabaseProvider) GetBundle(id string) (models.Bundle, bool) {
+	rowData := schemaBundles{}
+	row := p.sqliteDb.QueryRow("SELECT * FROM Bundles WHERE Id = ?", id)
+	err := row.Scan(rowData.scanArgs()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Bundle{}, false
+		}
+		helper.Check(err)
+		return models.Bundle{}, false
+	}
+	return rowData.ToBundle(), true
+}
+
+// SaveBundle stores a bundle in the database
+func (p DatabaseProvider) SaveBundle(bundle models.Bundle) {
+	newData := schemaBundles{
+		Id:                 bundle.Id,
+		Name:               bundle.Name,
+		FileIds:            bundle.JoinFileIds(),
+		PasswordHash:       bundle.PasswordHash,
+		ExpireAtString:     bundle.ExpireAtString,
+		ExpireAt:           bundle.ExpireAt,
+		CreationDate:       bundle.CreationDate,
+		DownloadsRemaining: bundle.DownloadsRemaining,
+		DownloadCount:      bundle.DownloadCount,
+		UserId:             bundle.UserId,
+	}
+	if bundle.UnlimitedDownloads {
+		newData.UnlimitedDownloads = 1
+	}
+	if bundle.UnlimitedTime {
+		newData.UnlimitedTime = 1
+	}
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO Bundles (Id, Name, FileIds, PasswordHash, ExpireAtString, ExpireAt,
+                     CreationDate, DownloadsRemaining, DownloadCount, UserId, UnlimitedDownloads, UnlimitedTime)
+                     VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		newData.Id, newData.Name, newData.FileIds, newData.PasswordHash, newData.ExpireAtString, newData.ExpireAt,
+		newData.CreationDate, newData.DownloadsRemaining, newData.DownloadCount, newData.UserId,
+		newData.UnlimitedDownloads, newData.UnlimitedTime)
+	helper.Check(err)
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func (p DatabaseProvider) IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		_, err := p.sqliteDb.Exec(`UPDATE Bundles SET DownloadCount = DownloadCount + 1,
+                        DownloadsRemaining = DownloadsRemaining - 1 WHERE Id = ?`, id)
+		helper.Check(err)
+	} else {
+		_, err := p.sqliteDb.Exec(`UPDATE Bundles SET DownloadCount = DownloadCount + 1 WHERE Id = ?`, id)
+		helper.Check(err)
+	}
+}
+
+// DeleteBundle deletes a bundle with the given ID. The files of the bundle are not deleted
+func (p DatabaseProvider) DeleteBundle(id string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM Bundles WHERE Id = ?", id)
+	helper.Check(err)
+}
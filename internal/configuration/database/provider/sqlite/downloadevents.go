@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+type schemaDownloadEvents struct {
+	Id         int64
+	FileId     string
+	Timestamp  int64
+	Ip         string
+	UserAgent  string
+	BytesSent  int64
+	IsComplete int
+	UserId     int
+}
+
+func (rowData schemaDownloadEvents) ToDownloadEvent() models.DownloadEvent {
+	return models.DownloadEvent{
+		Id:         rowData.Id,
+		FileId:     rowData.FileId,
+		Timestamp:  rowData.Timestamp,
+		Ip:         rowData.Ip,
+		UserAgent:  rowData.UserAgent,
+		BytesSent:  rowData.BytesSent,
+		IsComplete: rowData.IsComplete == 1,
+		UserId:     rowData.UserId,
+	}
+}
+
+// SaveDownloadEvent stores a download of a file. A new ID is generated for the event
+func (p DatabaseProvider) SaveDownloadEvent(event models.DownloadEvent) {
+	newData := schemaDownloadEvents{
+		FileId:    event.FileId,
+		Timestamp: event.Timestamp,
+		Ip:        event.Ip,
+		UserAgent: event.UserAgent,
+		BytesSent: event.BytesSent,
+		UserId:    event.UserId,
+	}
+	if event.IsComplete {
+		newData.IsComplete = 1
+	}
+	_, err := p.sqliteDb.Exec(`INSERT INTO DownloadEvents (FileId, Timestamp, Ip, UserAgent, BytesSent, IsComplete, UserId)
+                     VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		newData.FileId, newData.Timestamp, newData.Ip, newData.UserAgent, newData.BytesSent, newData.IsComplete, newData.UserId)
+	helper.Check(err)
+}
+
+// GetDownloadEvents returns all downloads of the file with the given ID, the newest download first
+func (p DatabaseProvider) GetDownloadEvents(fileId string) []models.DownloadEvent {
+	result := make([]models.DownloadEvent, 0)
+	rows, err := p.sqliteDb.Query(`SELECT Id, FileId, Timestamp, Ip, UserAgent, BytesSent, IsComplete, UserId
+                     FROM DownloadEvents WHERE FileId = ?
+                     ORDER BY Timestamp DESC, Id DESC`, fileId)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		rowData := schemaDownloadEvents{}
+		err = rows.Scan(&rowData.Id, &rowData.FileId, &rowData.Timestamp, &rowData.Ip, &rowData.UserAgent,
+			&rowData.BytesSent, &rowData.IsComplete, &rowData.UserId)
+		helper.Check(err)
+		result = append(result, rowData.ToDownloadEvent())
+	}
+	return result
+}
+
+// DeleteDownloadEvents deletes all downloads of the file with the given ID
+func (p DatabaseProvider) DeleteDownloadEvents(fileId string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM DownloadEvents WHERE FileId = ?", fileId)
+	helper.Check(err)
+}
+
+// DeleteDownloadEventsBefore deletes all downloads that have been started before the given timestamp
+func (p DatabaseProvider) DeleteDownloadEventsBefore(timestamp int64) {
+	_, err := p.sqliteDb.Exec("DELETE FROM DownloadEvents WHERE Timestamp < ?", timestamp)
+	helper.Check(err)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
+	"time"
 )
 
 type schemaMetaData struct {
@@ -29,6 +30,17 @@ type schemaMetaData struct {
 	UserId             int
 	UploadDate         int64
 	PendingDeletion    int64
+	StorageDriver      string
+	StorageTarget      string
+	LastDownload       int64
+	IsQuarantined      int
+	HashAlgorithm      string
+	Compression        string
+	CompressedBytes    int64
+	ArchiveContent     string
+	HasThumbnail       int
+	BandwidthLimitKB   int
+	ParallelDownloads  int
 }
 
 func (rowData schemaMetaData) ToFileModel() (models.File, error) {
@@ -52,6 +64,17 @@ func (rowData schemaMetaData) ToFileModel() (models.File, error) {
 		UserId:             rowData.UserId,
 		UploadDate:         rowData.UploadDate,
 		PendingDeletion:    rowData.PendingDeletion,
+		StorageDriver:      rowData.StorageDriver,
+		StorageTarget:      rowData.StorageTarget,
+		LastDownload:       rowData.LastDownload,
+		IsQuarantined:      rowData.IsQuarantined == 1,
+		HasThumbnail:       rowData.HasThumbnail == 1,
+		HashAlgorithm:      rowData.HashAlgorithm,
+		Compression:        rowData.Compression,
+		CompressedBytes:    rowData.CompressedBytes,
+		ArchiveContent:     rowData.ArchiveContent,
+		BandwidthLimitKB:   rowData.BandwidthLimitKB,
+		ParallelDownloads:  rowData.ParallelDownloads,
 	}
 
 	buf := bytes.NewBuffer(rowData.Encryption)
@@ -71,7 +94,10 @@ func (p DatabaseProvider) GetAllMetadata() map[string]models.File {
 		err = rows.Scan(&rowData.Id, &rowData.Name, &rowData.Size, &rowData.SHA1, &rowData.ExpireAt, &rowData.SizeBytes,
 			&rowData.ExpireAtString, &rowData.DownloadsRemaining, &rowData.DownloadCount, &rowData.PasswordHash,
 			&rowData.HotlinkId, &rowData.ContentType, &rowData.AwsBucket, &rowData.Encryption,
-			&rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId, &rowData.UploadDate, &rowData.PendingDeletion)
+			&rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId, &rowData.UploadDate, &rowData.PendingDeletion,
+			&rowData.StorageDriver, &rowData.StorageTarget, &rowData.LastDownload, &rowData.IsQuarantined,
+			&rowData.HashAlgorithm, &rowData.Compression, &rowData.CompressedBytes, &rowData.ArchiveContent,
+			&rowData.HasThumbnail, &rowData.BandwidthLimitKB, &rowData.ParallelDownloads)
 		helper.Check(err)
 		var metaData models.File
 		metaData, err = rowData.ToFileModel()
@@ -105,7 +131,10 @@ func (p DatabaseProvider) GetMetaDataById(id string) (models.File, bool) {
 	err := row.Scan(&rowData.Id, &rowData.Name, &rowData.Size, &rowData.SHA1, &rowData.ExpireAt, &rowData.SizeBytes,
 		&rowData.ExpireAtString, &rowData.DownloadsRemaining, &rowData.DownloadCount, &rowData.PasswordHash,
 		&rowData.HotlinkId, &rowData.ContentType, &rowData.AwsBucket, &rowData.Encryption,
-		&rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId, &rowData.UploadDate, &rowData.PendingDeletion)
+		&rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId, &rowData.UploadDate, &rowData.PendingDeletion,
+		&rowData.StorageDriver, &rowData.StorageTarget, &rowData.LastDownload, &rowData.IsQuarantined,
+		&rowData.HashAlgorithm, &rowData.Compression, &rowData.CompressedBytes, &rowData.ArchiveContent,
+		&rowData.HasThumbnail, &rowData.BandwidthLimitKB, &rowData.ParallelDownloads)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return result, false
@@ -137,6 +166,15 @@ func (p DatabaseProvider) SaveMetaData(file models.File) {
 		UserId:             file.UserId,
 		UploadDate:         file.UploadDate,
 		PendingDeletion:    file.PendingDeletion,
+		StorageDriver:      file.StorageDriver,
+		StorageTarget:      file.StorageTarget,
+		LastDownload:       file.LastDownload,
+		HashAlgorithm:      file.HashAlgorithm,
+		Compression:        file.Compression,
+		CompressedBytes:    file.CompressedBytes,
+		ArchiveContent:     file.ArchiveContent,
+		BandwidthLimitKB:   file.BandwidthLimitKB,
+		ParallelDownloads:  file.ParallelDownloads,
 	}
 
 	if file.UnlimitedDownloads {
@@ -145,6 +183,12 @@ func (p DatabaseProvider) SaveMetaData(file models.File) {
 	if file.UnlimitedTime {
 		newData.UnlimitedTime = 1
 	}
+	if file.IsQuarantined {
+		newData.IsQuarantined = 1
+	}
+	if file.HasThumbnail {
+		newData.HasThumbnail = 1
+	}
 
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
@@ -154,24 +198,55 @@ func (p DatabaseProvider) SaveMetaData(file models.File) {
 
 	_, err = p.sqliteDb.Exec(`INSERT OR REPLACE INTO FileMetaData (Id, Name, Size, SHA1, ExpireAt, SizeBytes, ExpireAtString, 
                                    DownloadsRemaining, DownloadCount, PasswordHash, HotlinkId, ContentType, AwsBucket, Encryption,
-                                   UnlimitedDownloads, UnlimitedTime, UserId, UploadDate, PendingDeletion)
-          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                                   UnlimitedDownloads, UnlimitedTime, UserId, UploadDate, PendingDeletion, StorageDriver, StorageTarget,
+                                   LastDownload, IsQuarantined, HashAlgorithm, Compression, CompressedBytes, ArchiveContent, HasThumbnail,
+                                   BandwidthLimitKB, ParallelDownloads)
+          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		newData.Id, newData.Name, newData.Size, newData.SHA1, newData.ExpireAt, newData.SizeBytes, newData.ExpireAtString,
 		newData.DownloadsRemaining, newData.DownloadCount, newData.PasswordHash, newData.HotlinkId, newData.ContentType,
-		newData.AwsBucket, newData.Encryption, newData.UnlimitedDownloads, newData.UnlimitedTime, newData.UserId, newData.UploadDate, newData.PendingDeletion)
+		newData.AwsBucket, newData.Encryption, newData.UnlimitedDownloads, newData.UnlimitedTime, newData.UserId, newData.UploadDate, newData.PendingDeletion,
+		newData.StorageDriver, newData.StorageTarget, newData.LastDownload, newData.IsQuarantined, newData.HashAlgorithm,
+		newData.Compression, newData.CompressedBytes, newData.ArchiveContent, newData.HasThumbnail,
+		newData.BandwidthLimitKB, newData.ParallelDownloads)
 	helper.Check(err)
 }
 
-// IncreaseDownloadCount increases the download count of a file, preventing race conditions
-func (p DatabaseProvider) IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) {
+// IncreaseDownloadCount increases the download count of a file, preventing race conditions.
+// The time of the last download is set to the current time. Returns the new download count
+// or 0, if the file does not exist
+func (p DatabaseProvider) IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) int {
+	var row *sql.Row
 	if decreaseRemainingDownloads {
-		_, err := p.sqliteDb.Exec(`UPDATE FileMetaData SET DownloadCount = DownloadCount + 1,
-                        DownloadsRemaining = DownloadsRemaining - 1 WHERE id = ?`, id)
-		helper.Check(err)
+		row = p.sqliteDb.QueryRow(`UPDATE FileMetaData SET DownloadCount = DownloadCount + 1,
+                        DownloadsRemaining = DownloadsRemaining - 1, LastDownload = ? WHERE id = ? RETURNING DownloadCount`,
+			time.Now().Unix(), id)
 	} else {
-		_, err := p.sqliteDb.Exec(`UPDATE FileMetaData SET DownloadCount = DownloadCount + 1 WHERE id = ?`, id)
-		helper.Check(err)
+		row = p.sqliteDb.QueryRow(`UPDATE FileMetaData SET DownloadCount = DownloadCount + 1, LastDownload = ? WHERE id = ?
+                        RETURNING DownloadCount`, time.Now().Unix(), id)
 	}
+	var downloadCount int
+	err := row.Scan(&downloadCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0
+	}
+	helper.Check(err)
+	return downloadCount
+}
+
+// SaveArchiveContent stores the list of entries of an archive for the file with the given ID
+func (p DatabaseProvider) SaveArchiveContent(id, content string) {
+	_, err := p.sqliteDb.Exec("UPDATE FileMetaData SET ArchiveContent = ? WHERE Id = ?", content, id)
+	helper.Check(err)
+}
+
+// SetThumbnailStatus sets if a thumbnail has been created for the file with the given ID
+func (p DatabaseProvider) SetThumbnailStatus(id string, hasThumbnail bool) {
+	value := 0
+	if hasThumbnail {
+		value = 1
+	}
+	_, err := p.sqliteDb.Exec("UPDATE FileMetaData SET HasThumbnail = ? WHERE Id = ?", value, id)
+	helper.Check(err)
 }
 
 // DeleteMetaData deletes information about a file
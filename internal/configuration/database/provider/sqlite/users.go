@@ -16,6 +16,14 @@ type schemaUser struct {
 	UserLevel     models.UserRank
 	LastOnline    int64
 	ResetPassword int
+	DefaultTarget string
+	Targets       string
+	QuotaBytes    int64
+	QuotaFiles    int
+	NotifyEmail   string
+	NotifyDl      int
+	NotifyExpiry  int
+	NotifyDelete  int
 }
 
 func (s schemaUser) ToUser() models.User {
@@ -24,13 +32,21 @@ func (s schemaUser) ToUser() models.User {
 		pw = s.Password.String
 	}
 	return models.User{
-		Id:            s.Id,
-		Name:          s.Name,
-		Permissions:   s.Permissions,
-		UserLevel:     s.UserLevel,
-		LastOnline:    s.LastOnline,
-		Password:      pw,
-		ResetPassword: s.ResetPassword == 1,
+		Id:                   s.Id,
+		Name:                 s.Name,
+		Permissions:          s.Permissions,
+		UserLevel:            s.UserLevel,
+		LastOnline:           s.LastOnline,
+		Password:             pw,
+		ResetPassword:        s.ResetPassword == 1,
+		DefaultStorageTarget: s.DefaultTarget,
+		StorageTargets:       s.Targets,
+		QuotaBytes:           s.QuotaBytes,
+		QuotaFiles:           s.QuotaFiles,
+		NotificationEmail:    s.NotifyEmail,
+		NotifyDownload:       s.NotifyDl,
+		NotifyExpiry:         s.NotifyExpiry == 1,
+		NotifyDeletion:       s.NotifyDelete == 1,
 	}
 }
 
@@ -42,7 +58,7 @@ func (p DatabaseProvider) GetAllUsers() []models.User {
 	defer rows.Close()
 	for rows.Next() {
 		row := schemaUser{}
-		err = rows.Scan(&row.Id, &row.Name, &row.Password, &row.Permissions, &row.UserLevel, &row.LastOnline, &row.ResetPassword)
+		err = rows.Scan(&row.Id, &row.Name, &row.Password, &row.Permissions, &row.UserLevel, &row.LastOnline, &row.ResetPassword, &row.DefaultTarget, &row.Targets, &row.QuotaBytes, &row.QuotaFiles, &row.NotifyEmail, &row.NotifyDl, &row.NotifyExpiry, &row.NotifyDelete)
 		helper.Check(err)
 		result = append(result, row.ToUser())
 	}
@@ -56,7 +72,7 @@ func (p DatabaseProvider) getUserWithConstraint(isName bool, searchValue any) (m
 		query = "SELECT * FROM Users WHERE Name = ?"
 	}
 	row := p.sqliteDb.QueryRow(query, searchValue)
-	err := row.Scan(&rowResult.Id, &rowResult.Name, &rowResult.Password, &rowResult.Permissions, &rowResult.UserLevel, &rowResult.LastOnline, &rowResult.ResetPassword)
+	err := row.Scan(&rowResult.Id, &rowResult.Name, &rowResult.Password, &rowResult.Permissions, &rowResult.UserLevel, &rowResult.LastOnline, &rowResult.ResetPassword, &rowResult.DefaultTarget, &rowResult.Targets, &rowResult.QuotaBytes, &rowResult.QuotaFiles, &rowResult.NotifyEmail, &rowResult.NotifyDl, &rowResult.NotifyExpiry, &rowResult.NotifyDelete)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, false
@@ -84,13 +100,21 @@ func (p DatabaseProvider) SaveUser(user models.User, isNewUser bool) {
 	if user.ResetPassword {
 		resetpw = 1
 	}
+	notifyExpiry := 0
+	if user.NotifyExpiry {
+		notifyExpiry = 1
+	}
+	notifyDeletion := 0
+	if user.NotifyDeletion {
+		notifyDeletion = 1
+	}
 	if isNewUser {
-		_, err := p.sqliteDb.Exec("INSERT INTO Users (Name, Password, Permissions, Userlevel, LastOnline, ResetPassword) VALUES  (?, ?, ?, ?, ?, ?)",
-			user.Name, user.Password, user.Permissions, user.UserLevel, user.LastOnline, resetpw)
+		_, err := p.sqliteDb.Exec("INSERT INTO Users (Name, Password, Permissions, Userlevel, LastOnline, ResetPassword, DefaultStorageTarget, StorageTargets, QuotaBytes, QuotaFiles, NotificationEmail, NotifyDownload, NotifyExpiry, NotifyDeletion) VALUES  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			user.Name, user.Password, user.Permissions, user.UserLevel, user.LastOnline, resetpw, user.DefaultStorageTarget, user.StorageTargets, user.QuotaBytes, user.QuotaFiles, user.NotificationEmail, user.NotifyDownload, notifyExpiry, notifyDeletion)
 		helper.Check(err)
 	} else {
-		_, err := p.sqliteDb.Exec("INSERT OR REPLACE INTO Users (Id, Name, Password, Permissions, Userlevel, LastOnline, ResetPassword) VALUES  (?, ?, ?, ?, ?, ?, ?)",
-			user.Id, user.Name, user.Password, user.Permissions, user.UserLevel, user.LastOnline, resetpw)
+		_, err := p.sqliteDb.Exec("INSERT OR REPLACE INTO Users (Id, Name, Password, Permissions, Userlevel, LastOnline, ResetPassword, DefaultStorageTarget, StorageTargets, QuotaBytes, QuotaFiles, NotificationEmail, NotifyDownload, NotifyExpiry, NotifyDeletion) VALUES  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			user.Id, user.Name, user.Password, user.Permissions, user.UserLevel, user.LastOnline, resetpw, user.DefaultStorageTarget, user.StorageTargets, user.QuotaBytes, user.QuotaFiles, user.NotificationEmail, user.NotifyDownload, notifyExpiry, notifyDeletion)
 		helper.Check(err)
 	}
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem/sftp"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"html/template"
 	"io"
@@ -548,10 +549,63 @@ func parseCloudSettings(formObjects *[]jsonFormObject) (*cloudconfig.CloudConfig
 	if err != nil {
 		return nil, err
 	}
+	var result *cloudconfig.CloudConfig
 	if useCloud == "cloud" {
-		return getCloudConfig(formObjects)
+		result, err = getCloudConfig(formObjects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return addExistingRemoteConfigs(result, formObjects)
+}
+
+// getExistingSftpConfig returns the SFTP configuration of the cloud config file, if it is kept during the setup
+func getExistingSftpConfig() (models.SftpConfig, bool) {
+	existingConfig, ok := cloudconfig.Load()
+	if !ok {
+		return models.SftpConfig{}, false
+	}
+	env := environment.New()
+	if env.IsWebdavProvided() || env.IsSftpProvided() || !existingConfig.Sftp.IsAllProvided() {
+		return models.SftpConfig{}, false
+	}
+	return existingConfig.Sftp, true
+}
+
+// addExistingRemoteConfigs keeps WebDAV and SFTP configurations and storage targets that were saved in the
+// cloud config file, as they cannot be set during the setup. The host key of the SFTP server is taken from the setup
+func addExistingRemoteConfigs(config *cloudconfig.CloudConfig, formObjects *[]jsonFormObject) (*cloudconfig.CloudConfig, error) {
+	existingConfig, ok := cloudconfig.Load()
+	if !ok {
+		return config, nil
+	}
+	env := environment.New()
+	keepRemote := !env.IsWebdavProvided() && !env.IsSftpProvided() &&
+		(existingConfig.Webdav.IsAllProvided() || existingConfig.Sftp.IsAllProvided())
+	if !keepRemote && len(existingConfig.Targets) == 0 {
+		return config, nil
+	}
+	if config == nil {
+		config = &cloudconfig.CloudConfig{}
+	}
+	if keepRemote {
+		config.Webdav = existingConfig.Webdav
+		config.Sftp = existingConfig.Sftp
+	}
+	if keepRemote && existingConfig.Sftp.IsAllProvided() {
+		hostKey, err := getFormValueString(formObjects, "sftp_hostkey")
+		if err != nil {
+			return nil, err
+		}
+		hostKey = strings.TrimSpace(hostKey)
+		_, err = sftp.ParseHostKey(hostKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Sftp.HostKey = hostKey
 	}
-	return nil, nil
+	config.Targets = existingConfig.Targets
+	return config, nil
 }
 
 func getCloudConfig(formObjects *[]jsonFormObject) (*cloudconfig.CloudConfig, error) {
@@ -696,6 +750,8 @@ type setupView struct {
 	HasAwsFeature      bool
 	IsDocker           bool
 	S3EnvProvided      bool
+	SftpConfigured     bool
+	SftpHostKey        string
 	IsDataNotMounted   bool
 	IsConfigNotMounted bool
 	Port               int
@@ -716,6 +772,9 @@ func (v *setupView) loadFromConfig() {
 	}
 	v.HasAwsFeature = aws.IsIncludedInBuild
 	v.ProtectedUrls = protectedUrls
+	sftpConfig, sftpConfigured := getExistingSftpConfig()
+	v.SftpConfigured = sftpConfigured
+	v.SftpHostKey = sftpConfig.HostKey
 	if isInitialSetup {
 		return
 	}
@@ -746,3 +746,61 @@ func TestIsErrorAddressAlreadyInUse(t *testing.T) {
 	test.IsEqualBool(t, isErrorAddressAlreadyInUse(err), false)
 	l.Close()
 }
+
+func TestAddExistingRemoteConfigs(t *testing.T) {
+	test.IsNil(t, cloudconfig.Delete())
+	formObjects := &[]jsonFormObject{}
+	result, err := addExistingRemoteConfigs(nil, formObjects)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, result == nil, true)
+	err = cloudconfig.Write(cloudconfig.CloudConfig{Webdav: models.WebdavConfig{
+		Url:      "http://127.0.0.1/dav/",
+		Username: "user",
+		Password: "password",
+	}})
+	test.IsNil(t, err)
+	result, err = addExistingRemoteConfigs(nil, formObjects)
+	test.IsNil(t, err)
+	test.IsNotNil(t, result)
+	test.IsEqualString(t, result.Webdav.Url, "http://127.0.0.1/dav/")
+	test.IsEqualString(t, result.Webdav.Username, "user")
+	result, err = addExistingRemoteConfigs(&cloudconfig.CloudConfig{Aws: models.AwsConfig{Bucket: "test"}}, formObjects)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.Aws.Bucket, "test")
+	test.IsEqualString(t, result.Webdav.Url, "http://127.0.0.1/dav/")
+	_, isSftpConfigured := getExistingSftpConfig()
+	test.IsEqualBool(t, isSftpConfigured, false)
+	err = cloudconfig.Write(cloudconfig.CloudConfig{Sftp: models.SftpConfig{
+		Host:     "127.0.0.1:22",
+		Username: "user",
+		Password: "password",
+	}})
+	test.IsNil(t, err)
+	_, isSftpConfigured = getExistingSftpConfig()
+	test.IsEqualBool(t, isSftpConfigured, true)
+	_, err = addExistingRemoteConfigs(nil, formObjects)
+	test.IsNotNil(t, err)
+	formObjects = &[]jsonFormObject{{Name: "sftp_hostkey", Value: "invalid"}}
+	_, err = addExistingRemoteConfigs(nil, formObjects)
+	test.IsNotNil(t, err)
+	hostKey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIG7e2mxKxzpzP9Qw9kCdYVHZMUFoQ8zCvQmTgzt1Lp0E"
+	formObjects = &[]jsonFormObject{{Name: "sftp_hostkey", Value: " " + hostKey + "\n"}}
+	result, err = addExistingRemoteConfigs(nil, formObjects)
+	test.IsNil(t, err)
+	test.IsNotNil(t, result)
+	test.IsEqualString(t, result.Sftp.Host, "127.0.0.1:22")
+	test.IsEqualString(t, result.Sftp.HostKey, hostKey)
+	test.IsEqualString(t, result.Webdav.Url, "")
+	err = cloudconfig.Write(cloudconfig.CloudConfig{Targets: []models.StorageTargetConfig{{
+		Name: "local-fast",
+		Type: models.StorageTypeLocal,
+	}}})
+	test.IsNil(t, err)
+	result, err = addExistingRemoteConfigs(nil, formObjects)
+	test.IsNil(t, err)
+	test.IsNotNil(t, result)
+	test.IsEqualInt(t, len(result.Targets), 1)
+	test.IsEqualString(t, result.Targets[0].Name, "local-fast")
+	test.IsEqualString(t, result.Sftp.Host, "")
+	test.IsNil(t, cloudconfig.Delete())
+}
@@ -14,7 +14,6 @@ import (
 	"golang.org/x/crypto/scrypt"
 	"io"
 	"log"
-	"os"
 	"time"
 )
 
@@ -172,8 +171,20 @@ func DecryptReader(encInfo models.EncryptionInfo, input io.Reader, output io.Wri
 	return err
 }
 
+// GetDecryptReaderAt returns a reader that decrypts any part of an encrypted file. Only the
+// encrypted packages that contain the requested bytes are read from the input
+func GetDecryptReaderAt(encInfo models.EncryptionInfo, input io.ReaderAt) (io.ReaderAt, error) {
+	key, err := GetCipherFromFile(encInfo)
+	if err != nil {
+		return nil, err
+	}
+	stream := getStream(key)
+	nonce := make([]byte, stream.NonceSize()) // Nonce is not used
+	return stream.DecryptReaderAt(input, nonce, nil), nil
+}
+
 // IsCorrectKey checks if correct key is being used. This does not check for complete file authentication.
-func IsCorrectKey(encInfo models.EncryptionInfo, input *os.File) bool {
+func IsCorrectKey(encInfo models.EncryptionInfo, input io.Reader) bool {
 	_, err := createDecryptReader(encInfo, input)
 	if err != nil {
 		fmt.Println(err)
@@ -196,6 +207,11 @@ func GetDecryptReader(cipherKey []byte, input io.Reader) (io.Reader, error) {
 	return stream.DecryptReader(input, nonce, nil), nil
 }
 
+// IsNotAuthentic returns true if decrypting failed, because the encrypted data has been modified
+func IsNotAuthentic(err error) bool {
+	return errors.Is(err, sio.NotAuthentic)
+}
+
 // GetEncryptReader returns a reader that can encrypt plain files
 func GetEncryptReader(cipherKey []byte, input io.Reader) (io.Reader, error) {
 	stream := getStream(cipherKey)
@@ -11,6 +11,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/secure-io/sio-go"
 	"golang.org/x/crypto/scrypt"
 )
 
@@ -110,6 +111,59 @@ func TestEncryptDecrypt(t *testing.T) {
 	test.IsEqualByteSlice(t, plaintext, decrypted.Bytes())
 }
 
+func TestGetDecryptReaderAt(t *testing.T) {
+	plaintext := make([]byte, 3*sio.BufSize+1000)
+	_, err := rand.Read(plaintext)
+	test.IsNil(t, err)
+	var encrypted bytes.Buffer
+	encInfo := &models.EncryptionInfo{}
+	err = Encrypt(encInfo, bytes.NewReader(plaintext), &encrypted)
+	test.IsNil(t, err)
+
+	reader, err := GetDecryptReaderAt(*encInfo, bytes.NewReader(encrypted.Bytes()))
+	test.IsNil(t, err)
+	windows := []struct {
+		start, length int64
+	}{
+		{0, 10},
+		{100, 1000},
+		{sio.BufSize - 5, 10},
+		{sio.BufSize, sio.BufSize},
+		{sio.BufSize + 1, 2*sio.BufSize + 999},
+		{int64(len(plaintext)) - 1, 1},
+		{0, int64(len(plaintext))},
+	}
+	for _, window := range windows {
+		result := make([]byte, window.length)
+		_, err = io.ReadFull(io.NewSectionReader(reader, window.start, window.length), result)
+		test.IsNil(t, err)
+		test.IsEqualByteSlice(t, result, plaintext[window.start:window.start+window.length])
+	}
+	_, err = reader.ReadAt(make([]byte, 10), int64(len(plaintext)))
+	test.IsEqual(t, err, io.EOF)
+
+	encInfo.DecryptionKey = []byte("invalid")
+	_, err = GetDecryptReaderAt(*encInfo, bytes.NewReader(encrypted.Bytes()))
+	test.IsNotNil(t, err)
+}
+
+func TestIsNotAuthentic(t *testing.T) {
+	plaintext := []byte("this is some plaintext")
+	var encrypted bytes.Buffer
+	encInfo := &models.EncryptionInfo{}
+	err := Encrypt(encInfo, bytes.NewReader(plaintext), &encrypted)
+	test.IsNil(t, err)
+	modified := encrypted.Bytes()
+	modified[0] = modified[0] ^ 0xff
+
+	var decrypted bytes.Buffer
+	err = DecryptReader(*encInfo, bytes.NewReader(modified), &decrypted)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, IsNotAuthentic(err), true)
+	test.IsEqualBool(t, IsNotAuthentic(io.ErrUnexpectedEOF), false)
+	test.IsEqualBool(t, IsNotAuthentic(nil), false)
+}
+
 func TestGetRandomData(t *testing.T) {
 	data, err := getRandomData(32)
 	test.IsNil(t, err)
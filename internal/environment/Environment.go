@@ -5,6 +5,9 @@ import (
 	envParser "github.com/caarlos0/env/v6"
 	"github.com/forceu/gokapi/internal/environment/flagparser"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/compression"
+	"github.com/forceu/gokapi/internal/storage/hashing"
 	"os"
 	"path"
 	"strings"
@@ -15,27 +18,61 @@ const DefaultPort = 53842
 
 // Environment is a struct containing available env variables
 type Environment struct {
-	ChunkSizeMB        int    `env:"CHUNK_SIZE_MB" envDefault:"45"`
-	ConfigDir          string `env:"CONFIG_DIR" envDefault:"config"`
-	ConfigFile         string `env:"CONFIG_FILE" envDefault:"config.json"`
-	ConfigPath         string
-	DataDir            string `env:"DATA_DIR" envDefault:"data"`
-	DatabaseUrl        string `env:"DATABASE_URL" envDefault:"sqlite://[data]/gokapi.sqlite"`
-	LengthId           int    `env:"LENGTH_ID" envDefault:"15"`
-	LengthHotlinkId    int    `env:"LENGTH_HOTLINK_ID" envDefault:"40"`
-	MaxFileSize        int    `env:"MAX_FILESIZE" envDefault:"102400"` // 102400==100GB
-	MaxMemory          int    `env:"MAX_MEMORY_UPLOAD" envDefault:"50"`
-	MaxParallelUploads int    `env:"MAX_PARALLEL_UPLOADS" envDefault:"4"`
-	WebserverPort      int    `env:"PORT" envDefault:"53842"`
-	DisableCorsCheck   bool   `env:"DISABLE_CORS_CHECK" envDefault:"false"`
-	LogToStdout        bool   `env:"LOG_STDOUT" envDefault:"false"`
-	HotlinkVideos      bool   `env:"ENABLE_HOTLINK_VIDEOS" envDefault:"false"`
-	AwsBucket          string `env:"AWS_BUCKET"`
-	AwsRegion          string `env:"AWS_REGION"`
-	AwsKeyId           string `env:"AWS_KEY"`
-	AwsKeySecret       string `env:"AWS_KEY_SECRET"`
-	AwsEndpoint        string `env:"AWS_ENDPOINT"`
-	AwsProxyDownload   bool   `env:"AWS_PROXY_DOWNLOAD" envDefault:"false"`
+	ChunkSizeMB         int    `env:"CHUNK_SIZE_MB" envDefault:"45"`
+	ConfigDir           string `env:"CONFIG_DIR" envDefault:"config"`
+	ConfigFile          string `env:"CONFIG_FILE" envDefault:"config.json"`
+	ConfigPath          string
+	DataDir             string `env:"DATA_DIR" envDefault:"data"`
+	DatabaseUrl         string `env:"DATABASE_URL" envDefault:"sqlite://[data]/gokapi.sqlite"`
+	LengthId            int    `env:"LENGTH_ID" envDefault:"15"`
+	LengthHotlinkId     int    `env:"LENGTH_HOTLINK_ID" envDefault:"40"`
+	MaxFileSize         int    `env:"MAX_FILESIZE" envDefault:"102400"` // 102400==100GB
+	MaxMemory           int    `env:"MAX_MEMORY_UPLOAD" envDefault:"50"`
+	MaxParallelUploads  int    `env:"MAX_PARALLEL_UPLOADS" envDefault:"4"`
+	WebserverPort       int    `env:"PORT" envDefault:"53842"`
+	DisableCorsCheck    bool   `env:"DISABLE_CORS_CHECK" envDefault:"false"`
+	LogToStdout         bool   `env:"LOG_STDOUT" envDefault:"false"`
+	HotlinkVideos       bool   `env:"ENABLE_HOTLINK_VIDEOS" envDefault:"false"`
+	AwsBucket           string `env:"AWS_BUCKET"`
+	AwsRegion           string `env:"AWS_REGION"`
+	AwsKeyId            string `env:"AWS_KEY"`
+	AwsKeySecret        string `env:"AWS_KEY_SECRET"`
+	AwsEndpoint         string `env:"AWS_ENDPOINT"`
+	AwsProxyDownload    bool   `env:"AWS_PROXY_DOWNLOAD" envDefault:"false"`
+	WebdavUrl           string `env:"WEBDAV_URL"`
+	WebdavUsername      string `env:"WEBDAV_USER"`
+	WebdavPassword      string `env:"WEBDAV_PASSWORD"`
+	SftpHost            string `env:"SFTP_HOST"`
+	SftpUsername        string `env:"SFTP_USER"`
+	SftpPassword        string `env:"SFTP_PASSWORD"`
+	SftpKeyFile         string `env:"SFTP_KEY_FILE"`
+	SftpHostKey         string `env:"SFTP_HOST_KEY"`
+	SftpDirectory       string `env:"SFTP_DIRECTORY"`
+	TieringMinAgeDays   int    `env:"TIERING_MIN_AGE_DAYS" envDefault:"0"`
+	TieringIdleDays     int    `env:"TIERING_IDLE_DAYS" envDefault:"0"`
+	TieringTarget       string `env:"TIERING_TARGET"`
+	ScrubIntervalHours  int    `env:"SCRUB_INTERVAL_HOURS" envDefault:"0"`
+	ScrubQuarantine     bool   `env:"SCRUB_QUARANTINE" envDefault:"false"`
+	MinFreeSpaceMB      int    `env:"MIN_FREE_SPACE_MB" envDefault:"100"`
+	DiskWarningPercent  int    `env:"DISK_WARNING_PERCENT" envDefault:"90"`
+	HashAlgorithm       string `env:"HASH_ALGORITHM" envDefault:"sha256"`
+	RehashFiles         bool   `env:"REHASH_FILES" envDefault:"false"`
+	Compression         string `env:"COMPRESSION"`
+	CompressionTypes    string `env:"COMPRESSION_TYPES" envDefault:"text/,application/json,application/xml,application/x-ndjson,application/csv"`
+	RemoteUploadHosts   string `env:"REMOTE_UPLOAD_HOSTS"`
+	BandwidthGlobalKB   int    `env:"BANDWIDTH_TOTAL_KB" envDefault:"0"`
+	BandwidthFileKB     int    `env:"BANDWIDTH_FILE_KB" envDefault:"0"`
+	BandwidthRequestKB  int    `env:"BANDWIDTH_DOWNLOAD_KB" envDefault:"0"`
+	ParallelDownloads   int    `env:"PARALLEL_DOWNLOADS" envDefault:"0"`
+	ParallelDownloadIp  int    `env:"PARALLEL_DOWNLOADS_IP" envDefault:"0"`
+	DownloadHistoryDays int    `env:"DOWNLOAD_HISTORY_DAYS" envDefault:"90"`
+	SmtpHost            string `env:"SMTP_HOST"`
+	SmtpPort            int    `env:"SMTP_PORT" envDefault:"587"`
+	SmtpUsername        string `env:"SMTP_USER"`
+	SmtpPassword        string `env:"SMTP_PASSWORD"`
+	SmtpSender          string `env:"SMTP_SENDER"`
+	SmtpImplicitTls     bool   `env:"SMTP_TLS" envDefault:"false"`
+	MailTemplateDir     string `env:"MAIL_TEMPLATE_DIR"`
 }
 
 // New parses the env variables
@@ -84,6 +121,49 @@ func New() Environment {
 	if result.MaxFileSize < 1 {
 		result.MaxFileSize = 5
 	}
+	if result.TieringMinAgeDays < 0 {
+		result.TieringMinAgeDays = 0
+	}
+	if result.TieringIdleDays < 0 {
+		result.TieringIdleDays = 0
+	}
+	if result.ScrubIntervalHours < 0 {
+		result.ScrubIntervalHours = 0
+	}
+	if result.MinFreeSpaceMB < 0 {
+		result.MinFreeSpaceMB = 0
+	}
+	if result.DiskWarningPercent < 0 {
+		result.DiskWarningPercent = 0
+	}
+	if result.BandwidthGlobalKB < 0 {
+		result.BandwidthGlobalKB = 0
+	}
+	if result.BandwidthFileKB < 0 {
+		result.BandwidthFileKB = 0
+	}
+	if result.BandwidthRequestKB < 0 {
+		result.BandwidthRequestKB = 0
+	}
+	if result.ParallelDownloads < 0 {
+		result.ParallelDownloads = 0
+	}
+	if result.ParallelDownloadIp < 0 {
+		result.ParallelDownloadIp = 0
+	}
+	if result.DownloadHistoryDays < 0 {
+		result.DownloadHistoryDays = 0
+	}
+	result.HashAlgorithm = strings.ToLower(result.HashAlgorithm)
+	if !hashing.IsValidAlgorithm(result.HashAlgorithm) {
+		fmt.Println("Warning: Invalid hash algorithm " + result.HashAlgorithm + ", using " + hashing.DefaultAlgorithm)
+		result.HashAlgorithm = hashing.DefaultAlgorithm
+	}
+	result.Compression = strings.ToLower(result.Compression)
+	if result.Compression != "" && !compression.IsValidAlgorithm(result.Compression) {
+		fmt.Println("Warning: Invalid compression algorithm " + result.Compression + ", compression is disabled")
+		result.Compression = ""
+	}
 
 	if flags.IsDatabaseUrlSet {
 		result.DatabaseUrl = flags.DatabaseUrl
@@ -93,6 +173,29 @@ func New() Environment {
 	return result
 }
 
+// GetCompressionTypes returns the content types of files that are compressed, if compression is enabled
+func (e *Environment) GetCompressionTypes() []string {
+	return splitList(e.CompressionTypes)
+}
+
+// GetRemoteUploadHosts returns the hosts that files can be uploaded from by passing a URL.
+// Uploading from a URL is disabled, if no hosts are set
+func (e *Environment) GetRemoteUploadHosts() []string {
+	return splitList(strings.ToLower(e.RemoteUploadHosts))
+}
+
+// splitList returns the non-empty entries of a comma separated list
+func splitList(input string) []string {
+	result := make([]string, 0)
+	for _, entry := range strings.Split(input, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
 // IsAwsProvided returns true if all required env variables have been set for using AWS S3 / Backblaze
 func (e *Environment) IsAwsProvided() bool {
 	return e.AwsBucket != "" &&
@@ -101,6 +204,31 @@ func (e *Environment) IsAwsProvided() bool {
 		e.AwsKeySecret != ""
 }
 
+// IsWebdavProvided returns true if all required env variables have been set for using WebDAV
+func (e *Environment) IsWebdavProvided() bool {
+	return e.WebdavUrl != ""
+}
+
+// IsSftpProvided returns true if all required env variables have been set for using SFTP
+func (e *Environment) IsSftpProvided() bool {
+	return e.SftpHost != "" &&
+		e.SftpUsername != "" &&
+		(e.SftpPassword != "" || e.SftpKeyFile != "")
+}
+
+// GetSmtpConfig returns the settings of the mail server that notifications are sent with
+func (e *Environment) GetSmtpConfig() models.SmtpConfig {
+	return models.SmtpConfig{
+		Host:        e.SmtpHost,
+		Port:        e.SmtpPort,
+		Username:    e.SmtpUsername,
+		Password:    e.SmtpPassword,
+		Sender:      e.SmtpSender,
+		ImplicitTls: e.SmtpImplicitTls,
+		TemplateDir: e.MailTemplateDir,
+	}
+}
+
 // GetConfigPaths returns the config paths to config files and the directory containing the files. The following results are returned:
 // Path to config file, Path to directory containing config file, Name of config file, Path to AWS config file
 func GetConfigPaths() (pathConfigFile, pathConfigDir, nameConfigFile, pathAwsConfig string) {
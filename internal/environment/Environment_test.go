@@ -3,6 +3,7 @@ package environment
 import (
 	"github.com/forceu/gokapi/internal/test"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -60,6 +61,141 @@ func TestEnvLoad(t *testing.T) {
 	os.Unsetenv("GOKAPI_MAX_FILESIZE")
 }
 
+func TestTiering(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, env.TieringMinAgeDays, 0)
+	test.IsEqualInt(t, env.TieringIdleDays, 0)
+	test.IsEqualString(t, env.TieringTarget, "")
+	os.Setenv("GOKAPI_TIERING_MIN_AGE_DAYS", "30")
+	os.Setenv("GOKAPI_TIERING_IDLE_DAYS", "-4")
+	os.Setenv("GOKAPI_TIERING_TARGET", "s3-archive")
+	env = New()
+	test.IsEqualInt(t, env.TieringMinAgeDays, 30)
+	test.IsEqualInt(t, env.TieringIdleDays, 0)
+	test.IsEqualString(t, env.TieringTarget, "s3-archive")
+	os.Unsetenv("GOKAPI_TIERING_MIN_AGE_DAYS")
+	os.Unsetenv("GOKAPI_TIERING_IDLE_DAYS")
+	os.Unsetenv("GOKAPI_TIERING_TARGET")
+}
+
+func TestScrub(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, env.ScrubIntervalHours, 0)
+	test.IsEqualBool(t, env.ScrubQuarantine, false)
+	os.Setenv("GOKAPI_SCRUB_INTERVAL_HOURS", "-1")
+	env = New()
+	test.IsEqualInt(t, env.ScrubIntervalHours, 0)
+	os.Setenv("GOKAPI_SCRUB_INTERVAL_HOURS", "168")
+	os.Setenv("GOKAPI_SCRUB_QUARANTINE", "true")
+	env = New()
+	test.IsEqualInt(t, env.ScrubIntervalHours, 168)
+	test.IsEqualBool(t, env.ScrubQuarantine, true)
+	os.Unsetenv("GOKAPI_SCRUB_INTERVAL_HOURS")
+	os.Unsetenv("GOKAPI_SCRUB_QUARANTINE")
+}
+
+func TestDiskSpace(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, env.MinFreeSpaceMB, 100)
+	test.IsEqualInt(t, env.DiskWarningPercent, 90)
+	os.Setenv("GOKAPI_MIN_FREE_SPACE_MB", "-1")
+	os.Setenv("GOKAPI_DISK_WARNING_PERCENT", "-1")
+	env = New()
+	test.IsEqualInt(t, env.MinFreeSpaceMB, 0)
+	test.IsEqualInt(t, env.DiskWarningPercent, 0)
+	os.Setenv("GOKAPI_MIN_FREE_SPACE_MB", "2048")
+	os.Setenv("GOKAPI_DISK_WARNING_PERCENT", "80")
+	env = New()
+	test.IsEqualInt(t, env.MinFreeSpaceMB, 2048)
+	test.IsEqualInt(t, env.DiskWarningPercent, 80)
+	os.Unsetenv("GOKAPI_MIN_FREE_SPACE_MB")
+	os.Unsetenv("GOKAPI_DISK_WARNING_PERCENT")
+}
+
+func TestBandwidthLimits(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, env.BandwidthGlobalKB, 0)
+	test.IsEqualInt(t, env.BandwidthFileKB, 0)
+	test.IsEqualInt(t, env.BandwidthRequestKB, 0)
+	os.Setenv("GOKAPI_BANDWIDTH_TOTAL_KB", "10240")
+	os.Setenv("GOKAPI_BANDWIDTH_FILE_KB", "2048")
+	os.Setenv("GOKAPI_BANDWIDTH_DOWNLOAD_KB", "-1")
+	env = New()
+	test.IsEqualInt(t, env.BandwidthGlobalKB, 10240)
+	test.IsEqualInt(t, env.BandwidthFileKB, 2048)
+	test.IsEqualInt(t, env.BandwidthRequestKB, 0)
+	os.Unsetenv("GOKAPI_BANDWIDTH_TOTAL_KB")
+	os.Unsetenv("GOKAPI_BANDWIDTH_FILE_KB")
+	os.Unsetenv("GOKAPI_BANDWIDTH_DOWNLOAD_KB")
+}
+
+func TestParallelDownloads(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, env.ParallelDownloads, 0)
+	test.IsEqualInt(t, env.ParallelDownloadIp, 0)
+	os.Setenv("GOKAPI_PARALLEL_DOWNLOADS", "10")
+	os.Setenv("GOKAPI_PARALLEL_DOWNLOADS_IP", "-2")
+	env = New()
+	test.IsEqualInt(t, env.ParallelDownloads, 10)
+	test.IsEqualInt(t, env.ParallelDownloadIp, 0)
+	os.Unsetenv("GOKAPI_PARALLEL_DOWNLOADS")
+	os.Unsetenv("GOKAPI_PARALLEL_DOWNLOADS_IP")
+}
+
+func TestDownloadHistoryDays(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, env.DownloadHistoryDays, 90)
+	os.Setenv("GOKAPI_DOWNLOAD_HISTORY_DAYS", "0")
+	env = New()
+	test.IsEqualInt(t, env.DownloadHistoryDays, 0)
+	os.Setenv("GOKAPI_DOWNLOAD_HISTORY_DAYS", "-5")
+	env = New()
+	test.IsEqualInt(t, env.DownloadHistoryDays, 0)
+	os.Unsetenv("GOKAPI_DOWNLOAD_HISTORY_DAYS")
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	env := New()
+	test.IsEqualString(t, env.HashAlgorithm, "sha256")
+	os.Setenv("GOKAPI_HASH_ALGORITHM", "BLAKE3")
+	env = New()
+	test.IsEqualString(t, env.HashAlgorithm, "blake3")
+	os.Setenv("GOKAPI_HASH_ALGORITHM", "md5")
+	env = New()
+	test.IsEqualString(t, env.HashAlgorithm, "sha256")
+	os.Unsetenv("GOKAPI_HASH_ALGORITHM")
+	test.IsEqualBool(t, env.RehashFiles, false)
+	os.Setenv("GOKAPI_REHASH_FILES", "true")
+	env = New()
+	test.IsEqualBool(t, env.RehashFiles, true)
+	os.Unsetenv("GOKAPI_REHASH_FILES")
+}
+
+func TestCompression(t *testing.T) {
+	env := New()
+	test.IsEqualString(t, env.Compression, "")
+	test.IsEqualInt(t, len(env.GetCompressionTypes()), 5)
+	os.Setenv("GOKAPI_COMPRESSION", "ZSTD")
+	os.Setenv("GOKAPI_COMPRESSION_TYPES", "text/csv, ,application/json")
+	env = New()
+	test.IsEqualString(t, env.Compression, "zstd")
+	test.IsEqualString(t, strings.Join(env.GetCompressionTypes(), ","), "text/csv,application/json")
+	os.Setenv("GOKAPI_COMPRESSION", "lzma")
+	env = New()
+	test.IsEqualString(t, env.Compression, "")
+	os.Unsetenv("GOKAPI_COMPRESSION")
+	os.Unsetenv("GOKAPI_COMPRESSION_TYPES")
+}
+
+func TestRemoteUploadHosts(t *testing.T) {
+	env := New()
+	test.IsEqualInt(t, len(env.GetRemoteUploadHosts()), 0)
+	os.Setenv("GOKAPI_REMOTE_UPLOAD_HOSTS", "Build.Internal, *.artifacts.local,,ci:8080")
+	env = New()
+	test.IsEqualString(t, strings.Join(env.GetRemoteUploadHosts(), ","), "build.internal,*.artifacts.local,ci:8080")
+	os.Unsetenv("GOKAPI_REMOTE_UPLOAD_HOSTS")
+}
+
 func TestIsAwsProvided(t *testing.T) {
 	os.Unsetenv("GOKAPI_AWS_BUCKET")
 	os.Unsetenv("GOKAPI_AWS_REGION")
@@ -75,6 +211,66 @@ func TestIsAwsProvided(t *testing.T) {
 	test.IsEqualBool(t, env.IsAwsProvided(), true)
 }
 
+func TestIsWebdavProvided(t *testing.T) {
+	os.Unsetenv("GOKAPI_WEBDAV_URL")
+	env := New()
+	test.IsEqualBool(t, env.IsWebdavProvided(), false)
+	os.Setenv("GOKAPI_WEBDAV_URL", "http://127.0.0.1/dav/")
+	env = New()
+	test.IsEqualBool(t, env.IsWebdavProvided(), true)
+	test.IsEqualString(t, env.WebdavUrl, "http://127.0.0.1/dav/")
+	os.Unsetenv("GOKAPI_WEBDAV_URL")
+}
+
+func TestIsSftpProvided(t *testing.T) {
+	os.Unsetenv("GOKAPI_SFTP_HOST")
+	os.Unsetenv("GOKAPI_SFTP_USER")
+	os.Unsetenv("GOKAPI_SFTP_PASSWORD")
+	env := New()
+	test.IsEqualBool(t, env.IsSftpProvided(), false)
+	os.Setenv("GOKAPI_SFTP_HOST", "127.0.0.1:22")
+	os.Setenv("GOKAPI_SFTP_USER", "user")
+	env = New()
+	test.IsEqualBool(t, env.IsSftpProvided(), false)
+	os.Setenv("GOKAPI_SFTP_PASSWORD", "password")
+	env = New()
+	test.IsEqualBool(t, env.IsSftpProvided(), true)
+	test.IsEqualString(t, env.SftpHost, "127.0.0.1:22")
+	os.Unsetenv("GOKAPI_SFTP_HOST")
+	os.Unsetenv("GOKAPI_SFTP_USER")
+	os.Unsetenv("GOKAPI_SFTP_PASSWORD")
+}
+
+func TestGetSmtpConfig(t *testing.T) {
+	env := New()
+	config := env.GetSmtpConfig()
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+	test.IsEqualInt(t, config.Port, 587)
+	os.Setenv("GOKAPI_SMTP_HOST", "mail.example.com")
+	os.Setenv("GOKAPI_SMTP_PORT", "465")
+	os.Setenv("GOKAPI_SMTP_USER", "user")
+	os.Setenv("GOKAPI_SMTP_PASSWORD", "password")
+	os.Setenv("GOKAPI_SMTP_SENDER", "gokapi@example.com")
+	os.Setenv("GOKAPI_SMTP_TLS", "true")
+	os.Setenv("GOKAPI_MAIL_TEMPLATE_DIR", "/app/config/templates")
+	env = New()
+	config = env.GetSmtpConfig()
+	test.IsEqualBool(t, config.IsAllProvided(), true)
+	test.IsEqualString(t, config.GetAddress(), "mail.example.com:465")
+	test.IsEqualString(t, config.Username, "user")
+	test.IsEqualString(t, config.Password, "password")
+	test.IsEqualString(t, config.Sender, "gokapi@example.com")
+	test.IsEqualBool(t, config.ImplicitTls, true)
+	test.IsEqualString(t, config.TemplateDir, "/app/config/templates")
+	os.Unsetenv("GOKAPI_SMTP_HOST")
+	os.Unsetenv("GOKAPI_SMTP_PORT")
+	os.Unsetenv("GOKAPI_SMTP_USER")
+	os.Unsetenv("GOKAPI_SMTP_PASSWORD")
+	os.Unsetenv("GOKAPI_SMTP_SENDER")
+	os.Unsetenv("GOKAPI_SMTP_TLS")
+	os.Unsetenv("GOKAPI_MAIL_TEMPLATE_DIR")
+}
+
 func TestGetConfigPaths(t *testing.T) {
 	configPath, configDir, configFile, awsConfig := GetConfigPaths()
 	test.IsEqualString(t, configPath, "test/test2")
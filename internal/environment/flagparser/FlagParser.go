@@ -66,6 +66,7 @@ func ParseFlags() MainFlags {
 	installService := passedFlags.Bool("install-service", false, "Installs Gokapi as a systemd service")
 	uninstallService := passedFlags.Bool("uninstall-service", false, "Uninstalls the Gokapi systemd service")
 	deploymentPassword := passedFlags.String("deployment-password", "", "Sets a new password. This should only be used for non-interactive deployment")
+	migrateStorage := passedFlags.Bool("migrate-storage", false, "Moves stored files to the sharded directory layout and exits")
 
 	passedFlags.Usage = showUsage(passedFlags, aliases)
 	err := passedFlags.Parse(os.Args[1:])
@@ -90,6 +91,7 @@ func ParseFlags() MainFlags {
 		InstallService:     *installService,
 		UninstallService:   *uninstallService,
 		DeploymentPassword: *deploymentPassword,
+		MigrateStorage:     *migrateStorage,
 	}
 	result.setBoolValues()
 	return result
@@ -205,6 +207,7 @@ type MainFlags struct {
 	DisableCorsCheck   bool
 	InstallService     bool
 	UninstallService   bool
+	MigrateStorage     bool
 	Port               int
 	Migration          MigrateFlags
 }
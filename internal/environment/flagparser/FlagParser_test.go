@@ -128,6 +128,13 @@ func TestParseFlags(t *testing.T) {
 				test.IsEqualBool(t, flags.UninstallService, true)
 			},
 		},
+		{
+			name: "MigrateStorageFlag",
+			args: []string{"--migrate-storage"},
+			assertion: func(flags MainFlags) {
+				test.IsEqualBool(t, flags.MigrateStorage, true)
+			},
+		},
 	}
 
 	for _, testCase := range tests {
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package helper
+
+import "errors"
+
+// GetDiskSpace returns the free and the total space in bytes of the filesystem that contains path.
+// Not supported on this OS
+func GetDiskSpace(path string) (uint64, uint64, error) {
+	return 0, 0, errors.New("reading the disk space is not supported on this OS")
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd || windows
+
+package helper
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestGetDiskSpace(t *testing.T) {
+	free, total, err := GetDiskSpace(".")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, total > 0, true)
+	test.IsEqualBool(t, free <= total, true)
+	_, _, err = GetDiskSpace("invalid/folder")
+	test.IsNotNil(t, err)
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package helper
+
+import "syscall"
+
+// GetDiskSpace returns the free and the total space in bytes of the filesystem that contains path.
+// The free space only includes the space that is available to unprivileged users
+func GetDiskSpace(path string) (uint64, uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package helper
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// GetDiskSpace returns the free and the total space in bytes of the filesystem that contains path.
+// The free space only includes the space that is available to the current user
+func GetDiskSpace(path string) (uint64, uint64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var free, total uint64
+	proc := syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+	result, _, err := proc.Call(uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&free)),
+		uintptr(unsafe.Pointer(&total)),
+		0)
+	if result == 0 {
+		return 0, 0, err
+	}
+	return free, total, nil
+}
@@ -9,7 +9,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
-	"math/rand"
+	"math/big"
 	"regexp"
 )
 
@@ -104,12 +104,21 @@ func LogUserCreation(modifiedUser, userEditor models.User) {
 // LogDownload adds a log entry when a download was requested. Non-Blocking
 func LogDownload(file models.File, r *http.Request, saveIp bool) {
 	if saveIp {
-		createLogEntry(categoryDownload, fmt.Sprintf("%s, IP %s, ID %s, Useragent %s", file.Name, getIpAddress(r), file.Id, r.UserAgent()), false)
+		createLogEntry(categoryDownload, fmt.Sprintf("%s, IP %s, ID %s, Useragent %s", file.Name, GetIpAddress(r), file.Id, r.UserAgent()), false)
 	} else {
 		createLogEntry(categoryDownload, fmt.Sprintf("%s, ID %s, Useragent %s", file.Name, file.Id, r.UserAgent()), false)
 	}
 }
 
+// LogBundleDownload adds a log entry when the download of a bundle was requested. Non-Blocking
+func LogBundleDownload(bundle models.Bundle, r *http.Request, saveIp bool) {
+	if saveIp {
+		createLogEntry(categoryDownload, fmt.Sprintf("Bundle %s, IP %s, ID %s, Useragent %s", bundle.Name, GetIpAddress(r), bundle.Id, r.UserAgent()), false)
+	} else {
+		createLogEntry(categoryDownload, fmt.Sprintf("Bundle %s, ID %s, Useragent %s", bundle.Name, bundle.Id, r.UserAgent()), false)
+	}
+}
+
 // LogUpload adds a log entry when an upload was created. Non-Blocking
 func LogUpload(file models.File, user models.User) {
 	createLogEntry(categoryUpload, fmt.Sprintf("%s, ID %s, uploaded by %s (user #%d)", file.Name, file.Id, user.Name, user.Id), false)
@@ -136,6 +145,33 @@ func LogRestore(file models.File, user models.User) {
 	createLogEntry(categoryEdit, fmt.Sprintf("%s, ID %s, restored by %s (user #%d)", file.Name, file.Id, user.Name, user.Id), false)
 }
 
+// LogScrubProblem adds a log entry when stored content failed the integrity check. Non-Blocking
+func LogScrubProblem(problem models.ScrubProblem) {
+	text := fmt.Sprintf("Integrity check failed for stored content %s (%s): %s. Affected file IDs: %s",
+		problem.SHA1, problem.Type, problem.Details, strings.Join(problem.FileIds, ", "))
+	if problem.IsQuarantined {
+		text = text + ". Files have been quarantined"
+	}
+	createLogEntry(categoryWarning, text, false)
+}
+
+// LogScrubFinished adds a log entry when the integrity check of all stored files has finished. Non-Blocking
+func LogScrubFinished(report models.ScrubReport) {
+	createLogEntry(categoryInfo, fmt.Sprintf("Integrity check finished, %d stored files checked, %d problems found",
+		report.CheckedFiles, len(report.Problems)), false)
+}
+
+// LogDiskSpaceWarning adds a log entry when the usage of the data directory passed the warning threshold. Non-Blocking
+func LogDiskSpaceWarning(space models.DiskSpace) {
+	createLogEntry(categoryWarning, fmt.Sprintf("Disk usage of the data directory is at %d%%, only %s of %s are free",
+		space.GetUsedPercent(), space.GetReadableFree(), space.GetReadableTotal()), false)
+}
+
+// LogNotificationError adds a log entry when an email notification could not be sent. Non-Blocking
+func LogNotificationError(recipient string, err error) {
+	createLogEntry(categoryWarning, fmt.Sprintf("Email notification to %s could not be sent: %s", recipient, err.Error()), false)
+}
+
 // UpgradeToV2 adds tags to existing logs
 // deprecated
 func UpgradeToV2() {
@@ -200,7 +236,7 @@ func parseTimeLogEntry(input string) (time.Time, error) {
 
 func getLogDeletionMessage(userName string, userId int, r *http.Request, timestamp time.Time) string {
 	return createLogFormatCustomTimestamp(categoryWarning, fmt.Sprintf("Previous logs deleted by %s (user #%d) on %s. IP: %s\n",
-		userName, userId, getDate(time.Now()), getIpAddress(r)), timestamp)
+		userName, userId, getDate(time.Now()), GetIpAddress(r)), timestamp)
 }
 
 func deleteAllLogs(userName string, userId int, r *http.Request) {
@@ -225,7 +261,9 @@ func getDate(timestamp time.Time) string {
 	return timestamp.UTC().Format(time.RFC1123)
 }
 
-func getIpAddress(r *http.Request) string {
+// GetIpAddress returns the IP address of the client. If a reverse proxy passes the address
+// of the client in the headers, this address is used
+func GetIpAddress(r *http.Request) string {
 	// Get IP from X-FORWARDED-FOR header
 	ips := r.Header.Get("X-FORWARDED-FOR")
 	splitIps := strings.Split(ips, ",")
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"errors"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
@@ -20,16 +21,16 @@ func TestMain(m *testing.M) {
 
 func TestGetIpAddress(t *testing.T) {
 	r := httptest.NewRequest("GET", "/test", nil)
-	test.IsEqualString(t, getIpAddress(r), "192.0.2.1")
+	test.IsEqualString(t, GetIpAddress(r), "192.0.2.1")
 	r = httptest.NewRequest("GET", "/test", nil)
 	r.RemoteAddr = "127.0.0.1:1234"
-	test.IsEqualString(t, getIpAddress(r), "127.0.0.1")
+	test.IsEqualString(t, GetIpAddress(r), "127.0.0.1")
 	r.RemoteAddr = "invalid"
-	test.IsEqualString(t, getIpAddress(r), "Unknown IP")
+	test.IsEqualString(t, GetIpAddress(r), "Unknown IP")
 	r.Header.Add("X-REAL-IP", "1.1.1.1")
-	test.IsEqualString(t, getIpAddress(r), "1.1.1.1")
+	test.IsEqualString(t, GetIpAddress(r), "1.1.1.1")
 	r.Header.Add("X-FORWARDED-FOR", "1.1.1.2")
-	test.IsEqualString(t, getIpAddress(r), "1.1.1.2")
+	test.IsEqualString(t, GetIpAddress(r), "1.1.1.2")
 }
 
 func TestInit(t *testing.T) {
@@ -64,4 +65,50 @@ func TestAddDownload(t *testing.T) {
 	time.Sleep(500 * time.Millisecond)
 	content, _ = os.ReadFile("test/log.txt")
 	test.IsEqualBool(t, strings.Contains(string(content), "2.2.2.2"), false)
+
+	bundle := models.Bundle{Id: "bundleId", Name: "bundleName"}
+	r.Header.Set("X-REAL-IP", "3.3.3.3")
+	LogBundleDownload(bundle, r, true)
+	LogBundleDownload(bundle, r, false)
+	// Need sleep, as LogBundleDownload() is non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ = os.ReadFile("test/log.txt")
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [download] Bundle bundleName, IP 3.3.3.3, ID bundleId, Useragent testAgent"), true)
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [download] Bundle bundleName, ID bundleId, Useragent testAgent"), true)
+}
+
+func TestLogScrub(t *testing.T) {
+	problem := models.ScrubProblem{
+		SHA1:    "testsha",
+		Type:    models.ScrubProblemMismatch,
+		Details: "hash does not match",
+		FileIds: []string{"id1", "id2"},
+	}
+	LogScrubProblem(problem)
+	problem.SHA1 = "testsha2"
+	problem.IsQuarantined = true
+	LogScrubProblem(problem)
+	LogScrubFinished(models.ScrubReport{CheckedFiles: 5, Problems: []models.ScrubProblem{problem, problem}})
+	// Need sleep, as the functions are non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ := os.ReadFile("test/log.txt")
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [warning] Integrity check failed for stored content testsha (mismatch): hash does not match. Affected file IDs: id1, id2\n"), true)
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [warning] Integrity check failed for stored content testsha2 (mismatch): hash does not match. Affected file IDs: id1, id2. Files have been quarantined"), true)
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [info] Integrity check finished, 5 stored files checked, 2 problems found"), true)
+}
+
+func TestLogDiskSpaceWarning(t *testing.T) {
+	LogDiskSpaceWarning(models.DiskSpace{FreeBytes: 1024, TotalBytes: 10240})
+	// Need sleep, as the function is non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ := os.ReadFile("test/log.txt")
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [warning] Disk usage of the data directory is at 90%, only 1.0 kB of 10.0 kB are free\n"), true)
+}
+
+func TestLogNotificationError(t *testing.T) {
+	LogNotificationError("user@example.com", errors.New("connection refused"))
+	// Need sleep, as the function is non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ := os.ReadFile("test/log.txt")
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [warning] Email notification to user@example.com could not be sent: connection refused\n"), true)
 }
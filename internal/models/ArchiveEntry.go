@@ -0,0 +1,11 @@
+package models
+
+// ArchiveEntry is a file or directory that is contained in an uploaded archive
+type ArchiveEntry struct {
+	Name          string `json:"Name"`          // The path of the entry inside the archive
+	Size          string `json:"Size"`          // Uncompressed size in a human-readable format
+	ModTimeString string `json:"ModTimeString"` // Time of the last modification in a human-readable format in local time
+	SizeBytes     int64  `json:"SizeBytes"`     // Uncompressed size in bytes
+	ModTime       int64  `json:"ModTime"`       // UTC timestamp of the last modification
+	IsDirectory   bool   `json:"IsDirectory"`   // True if the entry is a directory
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+)
+
+// Bundle is a collection of existing files, that are shared with a single download link.
+// It has its own expiry, download limit and password, independent of the files it contains
+type Bundle struct {
+	Id                   string   `json:"Id" redis:"Id"`                                 // The ID of the bundle
+	Name                 string   `json:"Name" redis:"Name"`                             // The name of the bundle, also used as the name of the ZIP file
+	FileIds              []string `json:"FileIds" redis:"-"`                             // The IDs of the files in the bundle
+	PasswordHash         string   `json:"PasswordHash" redis:"PasswordHash"`             // The hash of the password (if the bundle is password-protected)
+	ExpireAtString       string   `json:"ExpireAtString" redis:"ExpireAtString"`         // Time expiry in a human-readable format in local time
+	ExpireAt             int64    `json:"ExpireAt" redis:"ExpireAt"`                     // UTC timestamp of bundle expiry
+	CreationDate         int64    `json:"CreationDate" redis:"CreationDate"`             // UTC timestamp of the creation of the bundle
+	DownloadsRemaining   int      `json:"DownloadsRemaining" redis:"DownloadsRemaining"` // The remaining downloads for this bundle
+	DownloadCount        int      `json:"DownloadCount" redis:"DownloadCount"`           // The amount of times the bundle has been downloaded
+	UserId               int      `json:"UserId" redis:"UserId"`                         // The user ID of the creator
+	UnlimitedDownloads   bool     `json:"UnlimitedDownloads" redis:"UnlimitedDownloads"` // True if the creator did not limit the downloads
+	UnlimitedTime        bool     `json:"UnlimitedTime" redis:"UnlimitedTime"`           // True if the creator did not limit the time
+	InternalRedisFileIds string   `json:"-" redis:"FileIds"`                             // This field is an internal field, used to store the FileIds in a Redis Hashmap
+}
+
+// BundleApiOutput will be displayed for public outputs of a bundle, hiding sensitive information
+type BundleApiOutput struct {
+	Id                  string   `json:"Id"`                  // The ID of the bundle
+	Name                string   `json:"Name"`                // The name of the bundle
+	FileIds             []string `json:"FileIds"`             // The IDs of the files in the bundle
+	ExpireAtString      string   `json:"ExpireAtString"`      // Time expiry in a human-readable format in local time
+	UrlDownload         string   `json:"UrlDownload"`         // The public download URL for the bundle
+	ExpireAt            int64    `json:"ExpireAt"`            // UTC timestamp of bundle expiry
+	CreationDate        int64    `json:"CreationDate"`        // UTC timestamp of the creation of the bundle
+	DownloadsRemaining  int      `json:"DownloadsRemaining"`  // The remaining downloads for this bundle
+	DownloadCount       int      `json:"DownloadCount"`       // The number of times the bundle has been downloaded
+	UnlimitedDownloads  bool     `json:"UnlimitedDownloads"`  // True if the creator did not limit the downloads
+	UnlimitedTime       bool     `json:"UnlimitedTime"`       // True if the creator did not limit the time
+	IsPasswordProtected bool     `json:"IsPasswordProtected"` // True if a password has to be entered before downloading the bundle
+	UploaderId          int      `json:"UploaderId"`          // The user ID of the creator
+}
+
+// ToBundleApiOutput returns the bundle without sensitive information
+func (b *Bundle) ToBundleApiOutput(serverUrl string) BundleApiOutput {
+	fileIds := b.FileIds
+	if fileIds == nil {
+		fileIds = []string{}
+	}
+	return BundleApiOutput{
+		Id:                  b.Id,
+		Name:                b.Name,
+		FileIds:             fileIds,
+		ExpireAtString:      b.ExpireAtString,
+		UrlDownload:         serverUrl + "b?id=" + b.Id,
+		ExpireAt:            b.ExpireAt,
+		CreationDate:        b.CreationDate,
+		DownloadsRemaining:  b.DownloadsRemaining,
+		DownloadCount:       b.DownloadCount,
+		UnlimitedDownloads:  b.UnlimitedDownloads,
+		UnlimitedTime:       b.UnlimitedTime,
+		IsPasswordProtected: b.PasswordHash != "",
+		UploaderId:          b.UserId,
+	}
+}
+
+// JoinFileIds returns the file IDs as a comma-separated string, used for storing them in a database
+func (b *Bundle) JoinFileIds() string {
+	return strings.Join(b.FileIds, ",")
+}
+
+// SplitFileIds returns the file IDs of a comma-separated string, that was created with JoinFileIds
+func SplitFileIds(fileIds string) []string {
+	if fileIds == "" {
+		return []string{}
+	}
+	return strings.Split(fileIds, ",")
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestToBundleApiOutput(t *testing.T) {
+	bundle := Bundle{
+		Id:                 "bundleId",
+		Name:               "Holiday",
+		FileIds:            []string{"file1", "file2"},
+		PasswordHash:       "pwhash",
+		ExpireAtString:     "Wed Jun 25 2025 11:48:28",
+		ExpireAt:           1750852108,
+		CreationDate:       1748180908,
+		DownloadsRemaining: 4,
+		DownloadCount:      1,
+		UserId:             2,
+	}
+	output, err := json.Marshal(bundle.ToBundleApiOutput("serverurl/"))
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(output), `{"Id":"bundleId","Name":"Holiday","FileIds":["file1","file2"],"ExpireAtString":"Wed Jun 25 2025 11:48:28","UrlDownload":"serverurl/b?id=bundleId","ExpireAt":1750852108,"CreationDate":1748180908,"DownloadsRemaining":4,"DownloadCount":1,"UnlimitedDownloads":false,"UnlimitedTime":false,"IsPasswordProtected":true,"UploaderId":2}`)
+
+	bundle = Bundle{Id: "empty"}
+	output, err = json.Marshal(bundle.ToBundleApiOutput("serverurl/"))
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(output), `{"Id":"empty","Name":"","FileIds":[],"ExpireAtString":"","UrlDownload":"serverurl/b?id=empty","ExpireAt":0,"CreationDate":0,"DownloadsRemaining":0,"DownloadCount":0,"UnlimitedDownloads":false,"UnlimitedTime":false,"IsPasswordProtected":false,"UploaderId":0}`)
+}
+
+func TestJoinFileIds(t *testing.T) {
+	bundle := Bundle{FileIds: []string{"file1", "file2", "file3"}}
+	test.IsEqualString(t, bundle.JoinFileIds(), "file1,file2,file3")
+	test.IsEqualInt(t, len(SplitFileIds(bundle.JoinFileIds())), 3)
+	test.IsEqualString(t, SplitFileIds(bundle.JoinFileIds())[1], "file2")
+	test.IsEqualInt(t, len(SplitFileIds("")), 0)
+	bundle = Bundle{}
+	test.IsEqualString(t, bundle.JoinFileIds(), "")
+}
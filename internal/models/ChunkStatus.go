@@ -0,0 +1,23 @@
+package models
+
+// ChunkStatus contains the byte ranges of a chunked upload that have been received by the server
+type ChunkStatus struct {
+	// Uuid is the ID of the chunked upload
+	Uuid string `json:"Uuid"`
+	// TotalSize is the size of the complete file in bytes
+	TotalSize int64 `json:"TotalSize"`
+	// ReceivedBytes is the number of bytes that have been received
+	ReceivedBytes int64 `json:"ReceivedBytes"`
+	// IsComplete is true if all bytes of the file have been received
+	IsComplete bool `json:"IsComplete"`
+	// Ranges contains the received byte ranges, sorted by their start
+	Ranges []ByteRange `json:"Ranges"`
+	// UserId is the ID of the user that started the upload
+	UserId int `json:"-"`
+}
+
+// ByteRange is a range of bytes of a file. Start is inclusive, End is exclusive
+type ByteRange struct {
+	Start int64 `json:"Start"`
+	End   int64 `json:"End"`
+}
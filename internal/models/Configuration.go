@@ -21,6 +21,18 @@ type Configuration struct {
 	MaxParallelUploads  int                  `json:"MaxParallelUploads"`
 	LengthId            int                  `json:"-"`
 	LengthHotlinkId     int                  `json:"-"`
+	MinFreeSpaceMB      int                  `json:"-"`
+	DiskWarningPercent  int                  `json:"-"`
+	HashAlgorithm       string               `json:"-"`
+	Compression         string               `json:"-"`
+	CompressionTypes    []string             `json:"-"`
+	RemoteUploadHosts   []string             `json:"-"`
+	BandwidthGlobalKB   int                  `json:"-"`
+	BandwidthFileKB     int                  `json:"-"`
+	BandwidthRequestKB  int                  `json:"-"`
+	ParallelDownloads   int                  `json:"-"`
+	ParallelDownloadIp  int                  `json:"-"`
+	DownloadHistoryDays int                  `json:"-"`
 	Encryption          Encryption           `json:"Encryption"`
 	UseSsl              bool                 `json:"UseSsl"`
 	PicturesAlwaysLocal bool                 `json:"PicturesAlwaysLocal"`
@@ -0,0 +1,36 @@
+package models
+
+import "github.com/forceu/gokapi/internal/helper"
+
+// DiskSpace contains information about the free space of the data directory
+type DiskSpace struct {
+	// FreeBytes is the space in bytes that is available for new files
+	FreeBytes int64 `json:"FreeBytes"`
+	// TotalBytes is the size of the filesystem in bytes
+	TotalBytes int64 `json:"TotalBytes"`
+	// ReserveBytes is the space in bytes that is always kept free. Uploads that would use this space are rejected
+	ReserveBytes int64 `json:"ReserveBytes"`
+}
+
+// GetUsedPercent returns the percentage of the filesystem that is used
+func (d DiskSpace) GetUsedPercent() int {
+	if d.TotalBytes == 0 {
+		return 0
+	}
+	return int((d.TotalBytes - d.FreeBytes) * 100 / d.TotalBytes)
+}
+
+// GetReadableFree returns the free space in a human-readable format
+func (d DiskSpace) GetReadableFree() string {
+	return helper.ByteCountSI(d.FreeBytes)
+}
+
+// GetReadableTotal returns the size of the filesystem in a human-readable format
+func (d DiskSpace) GetReadableTotal() string {
+	return helper.ByteCountSI(d.TotalBytes)
+}
+
+// GetReadableReserve returns the reserved space in a human-readable format
+func (d DiskSpace) GetReadableReserve() string {
+	return helper.ByteCountSI(d.ReserveBytes)
+}
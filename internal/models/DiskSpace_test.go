@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestDiskSpace(t *testing.T) {
+	space := DiskSpace{}
+	test.IsEqualInt(t, space.GetUsedPercent(), 0)
+	space = DiskSpace{FreeBytes: 1024, TotalBytes: 4096, ReserveBytes: 2 * 1024 * 1024}
+	test.IsEqualInt(t, space.GetUsedPercent(), 75)
+	test.IsEqualString(t, space.GetReadableFree(), "1.0 kB")
+	test.IsEqualString(t, space.GetReadableTotal(), "4.0 kB")
+	test.IsEqualString(t, space.GetReadableReserve(), "2.0 MB")
+}
@@ -0,0 +1,13 @@
+package models
+
+// DownloadEvent is a single download of a file, that is stored as part of the download history
+type DownloadEvent struct {
+	Id         int64  `json:"Id" redis:"Id"`                 // The ID of the event
+	FileId     string `json:"FileId" redis:"FileId"`         // The ID of the downloaded file
+	Timestamp  int64  `json:"Timestamp" redis:"Timestamp"`   // UTC timestamp of the start of the download
+	Ip         string `json:"Ip" redis:"Ip"`                 // The IP address of the client. Empty, if IP addresses are not saved
+	UserAgent  string `json:"UserAgent" redis:"UserAgent"`   // The user agent of the client
+	BytesSent  int64  `json:"BytesSent" redis:"BytesSent"`   // The number of bytes that have been sent to the client
+	IsComplete bool   `json:"IsComplete" redis:"IsComplete"` // True if the requested content has been sent completely
+	UserId     int    `json:"-" redis:"UserId"`              // The ID of the user that uploaded the file, so that the history can be viewed after the file has been removed
+}
@@ -12,22 +12,33 @@ type File struct {
 	Id                      string         `json:"Id" redis:"Id"`                                 // The internal ID of the file
 	Name                    string         `json:"Name" redis:"Name"`                             // The filename. Will be 'Encrypted file' for end-to-end encrypted files
 	Size                    string         `json:"Size" redis:"Size"`                             // Filesize in a human-readable format
-	SHA1                    string         `json:"SHA1" redis:"SHA1"`                             // The hash of the file, used for deduplication
+	SHA1                    string         `json:"SHA1" redis:"SHA1"`                             // The hash of the file, used for deduplication and as the name of the stored file. Despite the name, it is created with HashAlgorithm
+	HashAlgorithm           string         `json:"HashAlgorithm" redis:"HashAlgorithm"`           // The algorithm that was used to create the hash. Empty for files that were hashed with SHA1
 	PasswordHash            string         `json:"PasswordHash" redis:"PasswordHash"`             // The hash of the password (if the file is password-protected)
 	HotlinkId               string         `json:"HotlinkId" redis:"HotlinkId"`                   // If file is a picture file and can be hotlinked, this is the ID for the hotlink
 	ContentType             string         `json:"ContentType" redis:"ContentType"`               // The MIME type for the file
 	AwsBucket               string         `json:"AwsBucket" redis:"AwsBucket"`                   // If the file is stored in the cloud, this is the bucket that is being used
+	StorageDriver           string         `json:"StorageDriver" redis:"StorageDriver"`           // If the file is stored on a remote storage other than AWS, this is the name of the driver
+	StorageTarget           string         `json:"StorageTarget" redis:"StorageTarget"`           // If the file is stored on a named storage target, this is the name of the target
+	Compression             string         `json:"Compression" redis:"Compression"`               // The algorithm the stored content is compressed with. Empty if the file is not compressed
+	ArchiveContent          string         `json:"ArchiveContent" redis:"ArchiveContent"`         // If the file is an archive, the JSON encoded list of its entries. Empty if the archive has not been read yet
 	ExpireAtString          string         `json:"ExpireAtString" redis:"ExpireAtString"`         // Time expiry in a human-readable format in local time
 	ExpireAt                int64          `json:"ExpireAt" redis:"ExpireAt"`                     // UTC timestamp of file expiry
 	PendingDeletion         int64          `json:"PendingDeletion" redis:"PendingDeletion"`       // UTC timestamp when the file will be deleted, if pending. Otherwise 0
 	SizeBytes               int64          `json:"SizeBytes" redis:"SizeBytes"`                   // Filesize in bytes
+	CompressedBytes         int64          `json:"CompressedBytes" redis:"CompressedBytes"`       // Size of the compressed content before encryption in bytes. 0 if the file is not compressed
 	UploadDate              int64          `json:"UploadDate" redis:"UploadDate"`                 // UTC timestamp of upload time
+	LastDownload            int64          `json:"LastDownload" redis:"LastDownload"`             // UTC timestamp of the last download, 0 if never downloaded
 	DownloadsRemaining      int            `json:"DownloadsRemaining" redis:"DownloadsRemaining"` // The remaining downloads for this file
 	DownloadCount           int            `json:"DownloadCount" redis:"DownloadCount"`           // The amount of times the file has been downloaded
 	UserId                  int            `json:"UserId" redis:"UserId"`                         // The user ID of the uploader
+	BandwidthLimitKB        int            `json:"BandwidthLimitKB" redis:"BandwidthLimitKB"`     // The download bandwidth of the file in KB/s, replacing the default limit per file. 0 if the default is used
+	ParallelDownloads       int            `json:"ParallelDownloads" redis:"ParallelDownloads"`   // The maximum number of simultaneous downloads of the file, replacing the default. 0 if the default is used
 	Encryption              EncryptionInfo `json:"Encryption" redis:"-"`                          // If the file is encrypted, this stores all info for decrypting
 	UnlimitedDownloads      bool           `json:"UnlimitedDownloads" redis:"UnlimitedDownloads"` // True if the uploader did not limit the downloads
 	UnlimitedTime           bool           `json:"UnlimitedTime" redis:"UnlimitedTime"`           // True if the uploader did not limit the time
+	IsQuarantined           bool           `json:"IsQuarantined" redis:"IsQuarantined"`           // True if the stored content failed an integrity check and the file is not served anymore
+	HasThumbnail            bool           `json:"HasThumbnail" redis:"HasThumbnail"`             // True if a thumbnail has been created for the file
 	InternalRedisEncryption []byte         `redis:"EncryptionRedis"`                              // This field is an internal field, used to store the EncryptionInfo in a Redis Hashmap
 }
 
@@ -41,11 +52,14 @@ type FileApiOutput struct {
 	ExpireAtString               string `json:"ExpireAtString"`               // Time expiry in a human-readable format in local time
 	UrlDownload                  string `json:"UrlDownload"`                  // The public download URL for the file
 	UrlHotlink                   string `json:"UrlHotlink"`                   // The public hotlink URL for the file
+	UrlThumbnail                 string `json:"UrlThumbnail"`                 // The URL of the thumbnail for the file. Empty if no thumbnail is available
 	UploadDate                   int64  `json:"UploadDate"`                   // UTC timestamp of upload time
 	ExpireAt                     int64  `json:"ExpireAt"`                     // UTC timestamp of file expiry
 	SizeBytes                    int64  `json:"SizeBytes"`                    // Filesize in bytes
 	DownloadsRemaining           int    `json:"DownloadsRemaining"`           // The remaining downloads for this file
 	DownloadCount                int    `json:"DownloadCount"`                // The number of times the file has been downloaded
+	BandwidthLimitKB             int    `json:"BandwidthLimitKB"`             // The download bandwidth of the file in KB/s, replacing the default limit per file. 0 if the default is used
+	ParallelDownloads            int    `json:"ParallelDownloads"`            // The maximum number of simultaneous downloads of the file, replacing the default. 0 if the default is used
 	UnlimitedDownloads           bool   `json:"UnlimitedDownloads"`           // True if the uploader did not limit the downloads
 	UnlimitedTime                bool   `json:"UnlimitedTime"`                // True if the uploader did not limit the time
 	RequiresClientSideDecryption bool   `json:"RequiresClientSideDecryption"` // True if the file has to be decrypted client-side
@@ -54,6 +68,7 @@ type FileApiOutput struct {
 	IsPasswordProtected          bool   `json:"IsPasswordProtected"`          // True if a password has to be entered before downloading the file
 	IsSavedOnLocalStorage        bool   `json:"IsSavedOnLocalStorage"`        // True if the file does not use cloud storage
 	IsPendingDeletion            bool   `json:"IsPendingDeletion"`            // True if the file is about to be deleted
+	IsQuarantined                bool   `json:"IsQuarantined"`                // True if the file failed an integrity check and cannot be downloaded
 	UploaderId                   int    `json:"UploaderId"`                   // The user ID of the uploader
 }
 
@@ -67,7 +82,7 @@ type EncryptionInfo struct {
 
 // IsLocalStorage returns true if the file is not stored on a remote storage
 func (f *File) IsLocalStorage() bool {
-	return f.AwsBucket == ""
+	return f.AwsBucket == "" && f.StorageDriver == ""
 }
 
 // IsPendingForDeletion returns true if the file is pending to be deleted
@@ -84,13 +99,16 @@ func (f *File) ToFileApiOutput(serverUrl string, useFilenameInUrl bool) (FileApi
 	}
 	result.IsPasswordProtected = f.PasswordHash != ""
 	result.IsEncrypted = f.Encryption.IsEncrypted
-	result.IsSavedOnLocalStorage = f.AwsBucket == ""
+	result.IsSavedOnLocalStorage = f.IsLocalStorage()
 	if f.Encryption.IsEndToEndEncrypted || f.RequiresClientDecryption() {
 		result.RequiresClientSideDecryption = true
 	}
 	result.IsEndToEndEncrypted = f.Encryption.IsEndToEndEncrypted
 	result.UrlHotlink = getHotlinkUrl(result, serverUrl, useFilenameInUrl)
 	result.UrlDownload = getDownloadUrl(result, serverUrl, useFilenameInUrl)
+	if f.HasThumbnail && !f.Encryption.IsEndToEndEncrypted {
+		result.UrlThumbnail = serverUrl + "thumb/" + f.Id
+	}
 	result.UploaderId = f.UserId
 	result.IsPendingDeletion = f.IsPendingForDeletion()
 
@@ -136,12 +154,13 @@ func (f *File) ToJsonResult(serverUrl string, includeFilename bool) string {
 }
 
 // RequiresClientDecryption checks if the file needs to be decrypted by the client
-// (if remote storage or end-to-end encryption)
+// (if stored on AWS or end-to-end encryption). Other remote storages are always
+// proxied and can therefore be decrypted by the server
 func (f *File) RequiresClientDecryption() bool {
 	if !f.Encryption.IsEncrypted {
 		return false
 	}
-	return !f.IsLocalStorage() || f.Encryption.IsEndToEndEncrypted
+	return f.AwsBucket != "" || f.Encryption.IsEndToEndEncrypted
 }
 func errorAsJson(err error) string {
 	fmt.Println(err)
@@ -158,7 +177,9 @@ type Result struct {
 
 // DownloadStatus contains current downloads, so they do not get removed during cleanup
 type DownloadStatus struct {
-	Id       string
-	FileId   string
-	ExpireAt int64
+	Id           string
+	FileId       string
+	ClientIp     string
+	ExpireAt     int64
+	IsRedirected bool // True if the client has been redirected to download the file from a different server
 }
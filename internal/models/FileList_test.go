@@ -32,8 +32,23 @@ func TestToJsonResult(t *testing.T) {
 		UnlimitedTime:      true,
 		PendingDeletion:    100,
 	}
-	test.IsEqualString(t, file.ToJsonResult("serverurl/", false), `{"Result":"OK","FileInfo":{"Id":"testId","Name":"testName","Size":"10 B","HotlinkId":"hotlinkid","ContentType":"text/html","ExpireAtString":"Wed Jun 25 2025 11:48:28","UrlDownload":"serverurl/d?id=testId","UrlHotlink":"","UploadDate":1748180908,"ExpireAt":1750852108,"SizeBytes":10,"DownloadsRemaining":1,"DownloadCount":3,"UnlimitedDownloads":true,"UnlimitedTime":true,"RequiresClientSideDecryption":true,"IsEncrypted":true,"IsEndToEndEncrypted":false,"IsPasswordProtected":true,"IsSavedOnLocalStorage":false,"IsPendingDeletion":true,"UploaderId":2},"IncludeFilename":false}`)
-	test.IsEqualString(t, file.ToJsonResult("serverurl/", true), `{"Result":"OK","FileInfo":{"Id":"testId","Name":"testName","Size":"10 B","HotlinkId":"hotlinkid","ContentType":"text/html","ExpireAtString":"Wed Jun 25 2025 11:48:28","UrlDownload":"serverurl/d/testId/testName","UrlHotlink":"","UploadDate":1748180908,"ExpireAt":1750852108,"SizeBytes":10,"DownloadsRemaining":1,"DownloadCount":3,"UnlimitedDownloads":true,"UnlimitedTime":true,"RequiresClientSideDecryption":true,"IsEncrypted":true,"IsEndToEndEncrypted":false,"IsPasswordProtected":true,"IsSavedOnLocalStorage":false,"IsPendingDeletion":true,"UploaderId":2},"IncludeFilename":true}`)
+	test.IsEqualString(t, file.ToJsonResult("serverurl/", false), `{"Result":"OK","FileInfo":{"Id":"testId","Name":"testName","Size":"10 B","HotlinkId":"hotlinkid","ContentType":"text/html","ExpireAtString":"Wed Jun 25 2025 11:48:28","UrlDownload":"serverurl/d?id=testId","UrlHotlink":"","UrlThumbnail":"","UploadDate":1748180908,"ExpireAt":1750852108,"SizeBytes":10,"DownloadsRemaining":1,"DownloadCount":3,"BandwidthLimitKB":0,"ParallelDownloads":0,"UnlimitedDownloads":true,"UnlimitedTime":true,"RequiresClientSideDecryption":true,"IsEncrypted":true,"IsEndToEndEncrypted":false,"IsPasswordProtected":true,"IsSavedOnLocalStorage":false,"IsPendingDeletion":true,"IsQuarantined":false,"UploaderId":2},"IncludeFilename":false}`)
+	test.IsEqualString(t, file.ToJsonResult("serverurl/", true), `{"Result":"OK","FileInfo":{"Id":"testId","Name":"testName","Size":"10 B","HotlinkId":"hotlinkid","ContentType":"text/html","ExpireAtString":"Wed Jun 25 2025 11:48:28","UrlDownload":"serverurl/d/testId/testName","UrlHotlink":"","UrlThumbnail":"","UploadDate":1748180908,"ExpireAt":1750852108,"SizeBytes":10,"DownloadsRemaining":1,"DownloadCount":3,"BandwidthLimitKB":0,"ParallelDownloads":0,"UnlimitedDownloads":true,"UnlimitedTime":true,"RequiresClientSideDecryption":true,"IsEncrypted":true,"IsEndToEndEncrypted":false,"IsPasswordProtected":true,"IsSavedOnLocalStorage":false,"IsPendingDeletion":true,"IsQuarantined":false,"UploaderId":2},"IncludeFilename":true}`)
+}
+
+func TestThumbnailUrl(t *testing.T) {
+	file := File{Id: "testId", HasThumbnail: true}
+	output, err := file.ToFileApiOutput("serverurl/", false)
+	test.IsNil(t, err)
+	test.IsEqualString(t, output.UrlThumbnail, "serverurl/thumb/testId")
+	file.Encryption.IsEndToEndEncrypted = true
+	output, err = file.ToFileApiOutput("serverurl/", false)
+	test.IsNil(t, err)
+	test.IsEqualString(t, output.UrlThumbnail, "")
+	file = File{Id: "testId"}
+	output, err = file.ToFileApiOutput("serverurl/", false)
+	test.IsNil(t, err)
+	test.IsEqualString(t, output.UrlThumbnail, "")
 }
 
 func TestIsLocalStorage(t *testing.T) {
@@ -41,6 +56,8 @@ func TestIsLocalStorage(t *testing.T) {
 	test.IsEqualBool(t, file.IsLocalStorage(), false)
 	file.AwsBucket = ""
 	test.IsEqualBool(t, file.IsLocalStorage(), true)
+	file.StorageDriver = "webdav"
+	test.IsEqualBool(t, file.IsLocalStorage(), false)
 }
 
 func TestErrorAsJson(t *testing.T) {
@@ -63,6 +80,10 @@ func TestRequiresClientDecryption(t *testing.T) {
 	test.IsEqualBool(t, file.RequiresClientDecryption(), false)
 	file.Encryption.IsEncrypted = true
 	test.IsEqualBool(t, file.RequiresClientDecryption(), false)
+	file.StorageDriver = "webdav"
+	test.IsEqualBool(t, file.RequiresClientDecryption(), false)
+	file.Encryption.IsEndToEndEncrypted = true
+	test.IsEqualBool(t, file.RequiresClientDecryption(), true)
 }
 
 func TestGetHolinkUrl(t *testing.T) {
@@ -13,4 +13,7 @@ type UploadRequest struct {
 	IsEndToEndEncrypted bool
 	Password            string
 	ExternalUrl         string
+	StorageTarget       string
+	// Checksum of the complete file in the format algorithm:hexvalue. Verified before the file is stored, if set
+	Checksum string
 }
@@ -0,0 +1,38 @@
+package models
+
+// ScrubProblemMissing is the type of a scrub problem, where the stored content does not exist anymore
+const ScrubProblemMissing = "missing"
+
+// ScrubProblemMismatch is the type of a scrub problem, where the hash of the stored content does not match
+const ScrubProblemMismatch = "mismatch"
+
+// ScrubProblemError is the type of a scrub problem, where the stored content could not be read
+const ScrubProblemError = "error"
+
+// ScrubReport contains the result of the last integrity check of the stored files
+type ScrubReport struct {
+	// StartTime is the UTC timestamp when the last check was started. 0 if no check has been run yet
+	StartTime int64 `json:"StartTime"`
+	// EndTime is the UTC timestamp when the last check finished. 0 if the check is still running
+	EndTime int64 `json:"EndTime"`
+	// CheckedFiles is the number of unique stored files that have been checked
+	CheckedFiles int `json:"CheckedFiles"`
+	// IsRunning is true while the check is in progress
+	IsRunning bool `json:"IsRunning"`
+	// Problems contains all stored files that failed the check
+	Problems []ScrubProblem `json:"Problems"`
+}
+
+// ScrubProblem contains information about stored content that failed the integrity check
+type ScrubProblem struct {
+	// SHA1 is the hash of the stored content
+	SHA1 string `json:"SHA1"`
+	// Type is either ScrubProblemMissing, ScrubProblemMismatch or ScrubProblemError
+	Type string `json:"Type"`
+	// Details contains a human-readable description of the problem
+	Details string `json:"Details"`
+	// FileIds contains the IDs of all files that share the stored content
+	FileIds []string `json:"FileIds"`
+	// IsQuarantined is true if the affected files are not served anymore
+	IsQuarantined bool `json:"IsQuarantined"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// SftpConfig contains all configuration values / credentials for SFTP storage
+type SftpConfig struct {
+	Host      string `yaml:"Host"`
+	Username  string `yaml:"Username"`
+	Password  string `yaml:"Password"`
+	KeyFile   string `yaml:"KeyFile"`
+	HostKey   string `yaml:"HostKey"`
+	Directory string `yaml:"Directory"`
+}
+
+// IsAllProvided returns true if all required variables have been set for using SFTP
+func (c *SftpConfig) IsAllProvided() bool {
+	return c.Host != "" &&
+		c.Username != "" &&
+		(c.Password != "" || c.KeyFile != "")
+}
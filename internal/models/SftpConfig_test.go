@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestIsSftpProvided(t *testing.T) {
+	config := SftpConfig{}
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+	config = SftpConfig{
+		Host:     "127.0.0.1:22",
+		Username: "user",
+	}
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+	config.Password = "password"
+	test.IsEqualBool(t, config.IsAllProvided(), true)
+	config.Password = ""
+	config.KeyFile = "/app/config/id_ed25519"
+	test.IsEqualBool(t, config.IsAllProvided(), true)
+}
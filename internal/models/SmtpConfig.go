@@ -0,0 +1,27 @@
+package models
+
+import (
+	"net"
+	"strconv"
+)
+
+// SmtpConfig contains the settings of the mail server that notifications are sent with
+type SmtpConfig struct {
+	Host        string
+	Port        int
+	Username    string
+	Password    string
+	Sender      string
+	ImplicitTls bool   // If true, TLS is used from the start of the connection. Otherwise, STARTTLS is used if offered by the server
+	TemplateDir string // Directory with templates that replace the default templates. The default templates are used if empty
+}
+
+// IsAllProvided returns true if all required variables have been set for sending emails
+func (c *SmtpConfig) IsAllProvided() bool {
+	return c.Host != "" && c.Port > 0 && c.Sender != ""
+}
+
+// GetAddress returns the address of the mail server in the format host:port
+func (c *SmtpConfig) GetAddress() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
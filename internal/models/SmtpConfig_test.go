@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestIsSmtpProvided(t *testing.T) {
+	config := SmtpConfig{}
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+	config = SmtpConfig{
+		Host: "mail.example.com",
+		Port: 587,
+	}
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+	config.Sender = "gokapi@example.com"
+	test.IsEqualBool(t, config.IsAllProvided(), true)
+	config.Port = 0
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+}
+
+func TestGetSmtpAddress(t *testing.T) {
+	config := SmtpConfig{Host: "mail.example.com", Port: 465}
+	test.IsEqualString(t, config.GetAddress(), "mail.example.com:465")
+	config.Host = "::1"
+	test.IsEqualString(t, config.GetAddress(), "[::1]:465")
+}
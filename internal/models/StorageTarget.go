@@ -0,0 +1,49 @@
+package models
+
+// StorageTypeLocal is the type of a storage target that stores files on the local filesystem
+const StorageTypeLocal = "local"
+
+// StorageTypeS3 is the type of a storage target that stores files in an S3 bucket
+const StorageTypeS3 = "s3"
+
+// StorageTypeWebdav is the type of a storage target that stores files on the configured WebDAV server
+const StorageTypeWebdav = "webdav"
+
+// StorageTypeSftp is the type of a storage target that stores files on the configured SFTP server
+const StorageTypeSftp = "sftp"
+
+// StorageTargetConfig contains the configuration of a named storage target
+type StorageTargetConfig struct {
+	Name string `yaml:"Name"`
+	Type string `yaml:"Type"`
+	// Directory is only used for local targets. If empty, the data directory is used
+	Directory string `yaml:"Directory"`
+	// The following values are only used for S3 targets. If empty, the value of the aws section is used
+	Bucket    string `yaml:"Bucket"`
+	Region    string `yaml:"Region"`
+	KeyId     string `yaml:"KeyId"`
+	KeySecret string `yaml:"KeySecret"`
+	Endpoint  string `yaml:"Endpoint"`
+}
+
+// ToAwsConfig returns the S3 configuration of the target. Values that are not set
+// for the target are taken from defaultConfig
+func (c *StorageTargetConfig) ToAwsConfig(defaultConfig AwsConfig) AwsConfig {
+	result := defaultConfig
+	if c.Bucket != "" {
+		result.Bucket = c.Bucket
+	}
+	if c.Region != "" {
+		result.Region = c.Region
+	}
+	if c.KeyId != "" {
+		result.KeyId = c.KeyId
+	}
+	if c.KeySecret != "" {
+		result.KeySecret = c.KeySecret
+	}
+	if c.Endpoint != "" {
+		result.Endpoint = c.Endpoint
+	}
+	return result
+}
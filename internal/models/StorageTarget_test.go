@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestToAwsConfig(t *testing.T) {
+	defaultConfig := AwsConfig{
+		Bucket:        "gokapi",
+		Region:        "eu-central-1",
+		KeyId:         "keyid",
+		KeySecret:     "secret",
+		Endpoint:      "",
+		ProxyDownload: true,
+	}
+	target := StorageTargetConfig{Name: "s3-default", Type: StorageTypeS3}
+	test.IsEqual(t, target.ToAwsConfig(defaultConfig), defaultConfig)
+
+	target = StorageTargetConfig{
+		Name:      "s3-eu",
+		Type:      StorageTypeS3,
+		Bucket:    "gokapi-eu",
+		Region:    "eu-west-1",
+		KeyId:     "keyid2",
+		KeySecret: "secret2",
+		Endpoint:  "s3.example.com",
+	}
+	test.IsEqual(t, target.ToAwsConfig(defaultConfig), AwsConfig{
+		Bucket:        "gokapi-eu",
+		Region:        "eu-west-1",
+		KeyId:         "keyid2",
+		KeySecret:     "secret2",
+		Endpoint:      "s3.example.com",
+		ProxyDownload: true,
+	})
+}
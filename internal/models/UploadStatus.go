@@ -14,4 +14,7 @@ type UploadStatus struct {
 	ErrorMessage string `json:"errormessage"`
 	// Creation is the unix time when the status was created and is populated automatically
 	Creation int64
+	// TransferredBytes and TotalBytes contain the progress, while a file is downloaded from a remote URL
+	TransferredBytes int64
+	TotalBytes       int64
 }
@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"github.com/forceu/gokapi/internal/helper"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -11,13 +13,58 @@ type UserPermission uint16
 
 // User contains information about the Gokapi user
 type User struct {
-	Id            int            `json:"id" redis:"id"`
-	Name          string         `json:"name" redis:"Name"`
-	Permissions   UserPermission `json:"permissions" redis:"Permissions"`
-	UserLevel     UserRank       `json:"userLevel" redis:"UserLevel"`
-	LastOnline    int64          `json:"lastOnline" redis:"LastOnline"`
-	Password      string         `json:"-" redis:"Password"`
-	ResetPassword bool           `json:"resetPassword" redis:"ResetPassword"`
+	Id                   int            `json:"id" redis:"id"`
+	Name                 string         `json:"name" redis:"Name"`
+	Permissions          UserPermission `json:"permissions" redis:"Permissions"`
+	UserLevel            UserRank       `json:"userLevel" redis:"UserLevel"`
+	LastOnline           int64          `json:"lastOnline" redis:"LastOnline"`
+	Password             string         `json:"-" redis:"Password"`
+	ResetPassword        bool           `json:"resetPassword" redis:"ResetPassword"`
+	DefaultStorageTarget string         `json:"defaultStorageTarget" redis:"DefaultStorageTarget"`
+	StorageTargets       string         `json:"storageTargets" redis:"StorageTargets"` // Comma-separated list of storage targets the user may use. If empty, all targets are allowed
+	QuotaBytes           int64          `json:"quotaBytes" redis:"QuotaBytes"`         // Maximum total size of all files of the user in bytes. Unlimited if 0
+	QuotaFiles           int            `json:"quotaFiles" redis:"QuotaFiles"`         // Maximum number of files of the user. Unlimited if 0
+	NotificationEmail    string         `json:"notificationEmail" redis:"NotificationEmail"`
+	NotifyDownload       int            `json:"notifyDownload" redis:"NotifyDownload"`
+	NotifyExpiry         bool           `json:"notifyExpiry" redis:"NotifyExpiry"`
+	NotifyDeletion       bool           `json:"notifyDeletion" redis:"NotifyDeletion"`
+}
+
+// NotifyDownloadNever disables notifications about downloads
+const NotifyDownloadNever = 0
+
+// NotifyDownloadFirst sends a notification for the first download of a file
+const NotifyDownloadFirst = 1
+
+// NotifyDownloadEvery sends a notification for every download of a file
+const NotifyDownloadEvery = 2
+
+// StorageUsage contains the number and the total size of the files that a user has uploaded
+type StorageUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int   `json:"files"`
+}
+
+// GetReadableSize returns the total size of the files in a human-readable format
+func (s StorageUsage) GetReadableSize() string {
+	return helper.ByteCountSI(s.Bytes)
+}
+
+// QuotaInfo contains the quota of a user and the current usage
+type QuotaInfo struct {
+	UserId     int   `json:"userId"`
+	QuotaBytes int64 `json:"quotaBytes"`
+	QuotaFiles int   `json:"quotaFiles"`
+	UsedBytes  int64 `json:"usedBytes"`
+	UsedFiles  int   `json:"usedFiles"`
+}
+
+// NotificationSettings contains the email address of a user and the events that the user is notified about
+type NotificationSettings struct {
+	NotificationEmail string `json:"notificationEmail"`
+	NotifyDownload    int    `json:"notifyDownload"`
+	NotifyExpiry      bool   `json:"notifyExpiry"`
+	NotifyDeletion    bool   `json:"notifyDeletion"`
 }
 
 // GetReadableDate returns the date as YYYY-MM-DD HH:MM
@@ -52,6 +99,62 @@ func (u *User) ToJson() string {
 	return string(result)
 }
 
+// GetStorageTargets returns the names of the storage targets the user is allowed to use.
+// If the list is empty, the user may use all targets
+func (u *User) GetStorageTargets() []string {
+	result := make([]string, 0)
+	for _, target := range strings.Split(u.StorageTargets, ",") {
+		target = strings.TrimSpace(target)
+		if target != "" {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
+// HasQuota returns true if the total size or the number of files of the user is limited
+func (u *User) HasQuota() bool {
+	return u.QuotaBytes > 0 || u.QuotaFiles > 0
+}
+
+// GetReadableQuotaBytes returns the maximum total size of all files of the user in a human-readable format
+func (u *User) GetReadableQuotaBytes() string {
+	if u.QuotaBytes == 0 {
+		return "Unlimited"
+	}
+	return helper.ByteCountSI(u.QuotaBytes)
+}
+
+// GetQuotaInfo returns the quota of the user together with the passed usage
+func (u *User) GetQuotaInfo(usage StorageUsage) QuotaInfo {
+	return QuotaInfo{
+		UserId:     u.Id,
+		QuotaBytes: u.QuotaBytes,
+		QuotaFiles: u.QuotaFiles,
+		UsedBytes:  usage.Bytes,
+		UsedFiles:  usage.Files,
+	}
+}
+
+// GetNotificationSettings returns the events that the user is notified about by email
+func (u *User) GetNotificationSettings() NotificationSettings {
+	return NotificationSettings{
+		NotificationEmail: u.NotificationEmail,
+		NotifyDownload:    u.NotifyDownload,
+		NotifyExpiry:      u.NotifyExpiry,
+		NotifyDeletion:    u.NotifyDeletion,
+	}
+}
+
+// IsAllowedStorageTarget returns true if the user is allowed to store files on the given target
+func (u *User) IsAllowedStorageTarget(name string) bool {
+	allowedTargets := u.GetStorageTargets()
+	if len(allowedTargets) == 0 {
+		return true
+	}
+	return slices.Contains(allowedTargets, name)
+}
+
 // UserLevelSuperAdmin indicates that this is the single user with the most permissions
 const UserLevelSuperAdmin UserRank = 0
 
@@ -276,5 +276,55 @@ func TestUser_ToJson(t *testing.T) {
 		Password:      "1234",
 		ResetPassword: true,
 	}
-	test.IsEqualString(t, user.ToJson(), `{"id":4,"name":"Test User","permissions":255,"userLevel":1,"lastOnline":1337,"resetPassword":true}`)
+	test.IsEqualString(t, user.ToJson(), `{"id":4,"name":"Test User","permissions":255,"userLevel":1,"lastOnline":1337,"resetPassword":true,"defaultStorageTarget":"","storageTargets":"","quotaBytes":0,"quotaFiles":0,"notificationEmail":"","notifyDownload":0,"notifyExpiry":false,"notifyDeletion":false}`)
+}
+
+func TestUser_GetStorageTargets(t *testing.T) {
+	user := &User{}
+	test.IsEqualInt(t, len(user.GetStorageTargets()), 0)
+	user.StorageTargets = "local-fast, s3-archive,,"
+	test.IsEqual(t, user.GetStorageTargets(), []string{"local-fast", "s3-archive"})
+}
+
+func TestUser_IsAllowedStorageTarget(t *testing.T) {
+	user := &User{}
+	test.IsEqualBool(t, user.IsAllowedStorageTarget("local-fast"), true)
+	test.IsEqualBool(t, user.IsAllowedStorageTarget("s3-eu"), true)
+	user.StorageTargets = "local-fast,s3-archive"
+	test.IsEqualBool(t, user.IsAllowedStorageTarget("local-fast"), true)
+	test.IsEqualBool(t, user.IsAllowedStorageTarget("s3-eu"), false)
+}
+
+func TestUser_HasQuota(t *testing.T) {
+	user := &User{}
+	test.IsEqualBool(t, user.HasQuota(), false)
+	test.IsEqualString(t, user.GetReadableQuotaBytes(), "Unlimited")
+	user.QuotaFiles = 10
+	test.IsEqualBool(t, user.HasQuota(), true)
+	user = &User{QuotaBytes: 2 * 1024 * 1024}
+	test.IsEqualBool(t, user.HasQuota(), true)
+	test.IsEqualString(t, user.GetReadableQuotaBytes(), "2.0 MB")
+}
+
+func TestUser_GetNotificationSettings(t *testing.T) {
+	user := &User{Id: 5, NotificationEmail: "user@example.com", NotifyDownload: NotifyDownloadFirst, NotifyDeletion: true}
+	test.IsEqual(t, user.GetNotificationSettings(), NotificationSettings{
+		NotificationEmail: "user@example.com",
+		NotifyDownload:    NotifyDownloadFirst,
+		NotifyExpiry:      false,
+		NotifyDeletion:    true,
+	})
+}
+
+func TestUser_GetQuotaInfo(t *testing.T) {
+	user := &User{Id: 5, QuotaBytes: 2000, QuotaFiles: 3}
+	usage := StorageUsage{Bytes: 1536, Files: 2}
+	test.IsEqualString(t, usage.GetReadableSize(), "1.5 kB")
+	test.IsEqual(t, user.GetQuotaInfo(usage), QuotaInfo{
+		UserId:     5,
+		QuotaBytes: 2000,
+		QuotaFiles: 3,
+		UsedBytes:  1536,
+		UsedFiles:  2,
+	})
 }
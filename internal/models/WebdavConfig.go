@@ -0,0 +1,13 @@
+package models
+
+// WebdavConfig contains all configuration values / credentials for WebDAV storage
+type WebdavConfig struct {
+	Url      string `yaml:"Url"`
+	Username string `yaml:"Username"`
+	Password string `yaml:"Password"`
+}
+
+// IsAllProvided returns true if all required variables have been set for using WebDAV
+func (c *WebdavConfig) IsAllProvided() bool {
+	return c.Url != ""
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestIsWebdavProvided(t *testing.T) {
+	config := WebdavConfig{}
+	test.IsEqualBool(t, config.IsAllProvided(), false)
+	config = WebdavConfig{
+		Url:      "http://127.0.0.1/dav/",
+		Username: "",
+		Password: "",
+	}
+	test.IsEqualBool(t, config.IsAllProvided(), true)
+}
@@ -0,0 +1,242 @@
+package notifications
+
+/**
+Sending emails to uploaders, when their files have been downloaded, have expired or have been deleted
+*/
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	templateDownload = "download.tmpl"
+	templateExpiry   = "expiry.tmpl"
+	templateDeletion = "deletion.tmpl"
+)
+
+// maxQueuedMails is the number of emails that can wait to be sent. Further emails are discarded
+const maxQueuedMails = 1000
+
+// mailWorkers is the number of emails that are sent at the same time
+const mailWorkers = 2
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+var smtpConfig models.SmtpConfig
+var templates = make(map[string]*template.Template)
+var pendingMails sync.WaitGroup
+var mailQueue = make(chan queuedMail, maxQueuedMails)
+var startWorkers sync.Once
+
+// queuedMail is a rendered email that waits to be sent
+type queuedMail struct {
+	config    models.SmtpConfig
+	recipient string
+	subject   string
+	body      string
+}
+
+// templateData contains the values that can be used in the templates
+type templateData struct {
+	PublicName    string
+	UserName      string
+	FileName      string
+	FileId        string
+	FileSize      string
+	FileUrl       string
+	DownloadCount int
+	Time          string
+	Ip            string // Only set for downloads, if IP addresses are saved
+	UserAgent     string // Only set for downloads
+}
+
+// Init sets the mail server that notifications are sent with and loads the templates.
+// A template that exists in the template directory replaces the default template.
+// Notifications are disabled, if not all required settings have been provided
+func Init(config models.SmtpConfig) {
+	smtpConfig = config
+	templates = make(map[string]*template.Template)
+	if !IsEnabled() {
+		return
+	}
+	if config.TemplateDir != "" && !helper.FolderExists(config.TemplateDir) {
+		fmt.Println("Warning: Email template directory " + config.TemplateDir + " does not exist, using default templates")
+	}
+	for _, name := range []string{templateDownload, templateExpiry, templateDeletion} {
+		templates[name] = loadTemplate(name, config.TemplateDir)
+	}
+}
+
+// loadTemplate returns the template from the template directory. If it does not exist there
+// or is invalid, the default template is returned
+func loadTemplate(name, templateDir string) *template.Template {
+	defaultTemplate := template.Must(template.ParseFS(defaultTemplates, "templates/"+name))
+	if templateDir == "" {
+		return defaultTemplate
+	}
+	path := filepath.Join(templateDir, name)
+	if !helper.FileExists(path) {
+		return defaultTemplate
+	}
+	customTemplate, err := template.ParseFiles(path)
+	if err == nil && (customTemplate.Lookup("subject") == nil || customTemplate.Lookup("body") == nil) {
+		err = fmt.Errorf("subject or body is not defined")
+	}
+	if err != nil {
+		fmt.Println("Warning: Invalid email template " + path + ", using default template: " + err.Error())
+		return defaultTemplate
+	}
+	return customTemplate
+}
+
+// IsEnabled returns true, if a mail server has been set
+func IsEnabled() bool {
+	return smtpConfig.IsAllProvided()
+}
+
+// Wait blocks until all notifications that have been queued are sent
+func Wait() {
+	pendingMails.Wait()
+}
+
+// SendDownloadNotification notifies the uploader about a download of the file, if the uploader
+// chose to be notified about every download or if it is the first download of the file
+func SendDownloadNotification(file models.File, event models.DownloadEvent) {
+	if !IsEnabled() {
+		return
+	}
+	user, ok := getRecipient(file)
+	if !ok {
+		return
+	}
+	switch user.NotifyDownload {
+	case models.NotifyDownloadEvery:
+	case models.NotifyDownloadFirst:
+		if file.DownloadCount != 1 {
+			return
+		}
+	default:
+		return
+	}
+	data := newTemplateData(file, user, event.Timestamp)
+	data.Ip = event.Ip
+	data.UserAgent = event.UserAgent
+	queueMail(user.NotificationEmail, templateDownload, data)
+}
+
+// SendExpiryNotification notifies the uploader that the file has expired, if the uploader chose so
+func SendExpiryNotification(file models.File) {
+	if !IsEnabled() {
+		return
+	}
+	user, ok := getRecipient(file)
+	if !ok || !user.NotifyExpiry {
+		return
+	}
+	queueMail(user.NotificationEmail, templateExpiry, newTemplateData(file, user, time.Now().Unix()))
+}
+
+// SendDeletionNotification notifies the uploader that the file has been deleted, if the uploader chose so
+func SendDeletionNotification(file models.File) {
+	if !IsEnabled() {
+		return
+	}
+	user, ok := getRecipient(file)
+	if !ok || !user.NotifyDeletion {
+		return
+	}
+	queueMail(user.NotificationEmail, templateDeletion, newTemplateData(file, user, time.Now().Unix()))
+}
+
+// getRecipient returns the uploader of the file, if the uploader has set an email address for notifications
+func getRecipient(file models.File) (models.User, bool) {
+	user, ok := database.GetUser(file.UserId)
+	if !ok || user.NotificationEmail == "" {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func newTemplateData(file models.File, user models.User, timestamp int64) templateData {
+	config := configuration.Get()
+	fileUrl := ""
+	output, err := file.ToFileApiOutput(config.ServerUrl, config.IncludeFilename)
+	if err == nil {
+		fileUrl = output.UrlDownload
+	}
+	return templateData{
+		PublicName:    config.PublicName,
+		UserName:      user.Name,
+		FileName:      file.Name,
+		FileId:        file.Id,
+		FileSize:      file.Size,
+		FileUrl:       fileUrl,
+		DownloadCount: file.DownloadCount,
+		Time:          time.Unix(timestamp, 0).Format("2006-01-02 15:04:05 MST"),
+	}
+}
+
+// queueMail renders the template and adds the email to the queue, which is sent in the background.
+// If the queue is full, the email is discarded
+func queueMail(recipient, templateName string, data templateData) {
+	subject, body, err := renderTemplate(templates[templateName], data)
+	if err != nil {
+		logging.LogNotificationError(recipient, err)
+		return
+	}
+	startMailWorkers()
+	pendingMails.Add(1)
+	select {
+	case mailQueue <- queuedMail{config: smtpConfig, recipient: recipient, subject: subject, body: body}:
+	default:
+		pendingMails.Done()
+		logging.LogNotificationError(recipient, errors.New("too many pending notifications"))
+	}
+}
+
+// startMailWorkers starts the goroutines that send the emails of the queue, if they are not running yet
+func startMailWorkers() {
+	startWorkers.Do(func() {
+		for range mailWorkers {
+			go processMailQueue(mailQueue)
+		}
+	})
+}
+
+// processMailQueue sends the emails of the queue one after another
+func processMailQueue(queue chan queuedMail) {
+	for mail := range queue {
+		err := sendMail(mail.config, mail.recipient, mail.subject, mail.body)
+		if err != nil {
+			logging.LogNotificationError(mail.recipient, err)
+		}
+		pendingMails.Done()
+	}
+}
+
+// renderTemplate returns the subject and the body of the email
+func renderTemplate(tmpl *template.Template, data templateData) (string, string, error) {
+	var subject, body bytes.Buffer
+	err := tmpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return "", "", err
+	}
+	err = tmpl.ExecuteTemplate(&body, "body", data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
@@ -0,0 +1,211 @@
+package notifications
+
+import (
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var smtpListener net.Listener
+var smtpMessages chan testconfiguration.SmtpTestMessage
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	smtpListener, smtpMessages = testconfiguration.StartSmtpTestServer()
+	exitVal := m.Run()
+	_ = smtpListener.Close()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+// getSentMessages returns the emails that have been received by the test server after all pending emails have been sent
+func getSentMessages() []testconfiguration.SmtpTestMessage {
+	Wait()
+	result := make([]testconfiguration.SmtpTestMessage, 0)
+	for {
+		select {
+		case message := <-smtpMessages:
+			result = append(result, message)
+		default:
+			return result
+		}
+	}
+}
+
+func setPreferences(t *testing.T, email string, download int, expiry, deletion bool) {
+	t.Helper()
+	user, ok := database.GetUser(7)
+	test.IsEqualBool(t, ok, true)
+	user.NotificationEmail = email
+	user.NotifyDownload = download
+	user.NotifyExpiry = expiry
+	user.NotifyDeletion = deletion
+	database.SaveUser(user, false)
+}
+
+func getTestFile(downloadCount int) models.File {
+	return models.File{
+		Id:            "notificationfile",
+		Name:          "notification.txt",
+		Size:          "1 kB",
+		UserId:        7,
+		DownloadCount: downloadCount,
+	}
+}
+
+func TestInit(t *testing.T) {
+	Init(models.SmtpConfig{})
+	test.IsEqualBool(t, IsEnabled(), false)
+	test.IsEqualInt(t, len(templates), 0)
+	setPreferences(t, "user@example.com", models.NotifyDownloadEvery, true, true)
+	SendDownloadNotification(getTestFile(1), models.DownloadEvent{})
+	SendExpiryNotification(getTestFile(1))
+	SendDeletionNotification(getTestFile(1))
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+
+	Init(testconfiguration.GetSmtpTestConfig(smtpListener))
+	test.IsEqualBool(t, IsEnabled(), true)
+	test.IsEqualInt(t, len(templates), 3)
+}
+
+func TestSendDownloadNotification(t *testing.T) {
+	Init(testconfiguration.GetSmtpTestConfig(smtpListener))
+	event := models.DownloadEvent{
+		Timestamp: time.Now().Unix(),
+		Ip:        "1.2.3.4",
+		UserAgent: "TestAgent",
+	}
+
+	setPreferences(t, "user@example.com", models.NotifyDownloadNever, true, true)
+	SendDownloadNotification(getTestFile(1), event)
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+
+	setPreferences(t, "user@example.com", models.NotifyDownloadFirst, false, false)
+	SendDownloadNotification(getTestFile(1), event)
+	messages := getSentMessages()
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualString(t, messages[0].From, "gokapi@example.com")
+	test.IsEqualString(t, messages[0].To[0], "user@example.com")
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Subject: notification.txt has been downloaded\n"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "IP address: 1.2.3.4\n"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "User agent: TestAgent\n"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "downloaded 1 time(s) so far"), true)
+	SendDownloadNotification(getTestFile(2), event)
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+
+	setPreferences(t, "user@example.com", models.NotifyDownloadEvery, false, false)
+	SendDownloadNotification(getTestFile(1), event)
+	SendDownloadNotification(getTestFile(2), event)
+	test.IsEqualInt(t, len(getSentMessages()), 2)
+
+	setPreferences(t, "", models.NotifyDownloadEvery, false, false)
+	SendDownloadNotification(getTestFile(1), event)
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+
+	file := getTestFile(1)
+	file.UserId = 999
+	SendDownloadNotification(file, event)
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+}
+
+func TestSendExpiryNotification(t *testing.T) {
+	Init(testconfiguration.GetSmtpTestConfig(smtpListener))
+	setPreferences(t, "user@example.com", models.NotifyDownloadEvery, false, true)
+	SendExpiryNotification(getTestFile(3))
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+
+	setPreferences(t, "user@example.com", models.NotifyDownloadNever, true, false)
+	SendExpiryNotification(getTestFile(3))
+	messages := getSentMessages()
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Subject: notification.txt has expired\n"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "It has been downloaded 3 time(s)."), true)
+}
+
+func TestSendDeletionNotification(t *testing.T) {
+	Init(testconfiguration.GetSmtpTestConfig(smtpListener))
+	setPreferences(t, "user@example.com", models.NotifyDownloadEvery, true, false)
+	SendDeletionNotification(getTestFile(0))
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+
+	setPreferences(t, "user@example.com", models.NotifyDownloadNever, false, true)
+	SendDeletionNotification(getTestFile(0))
+	messages := getSentMessages()
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Subject: notification.txt has been deleted\n"), true)
+}
+
+func TestCustomTemplates(t *testing.T) {
+	templateDir := "test/templates"
+	err := os.MkdirAll(templateDir, 0700)
+	test.IsNil(t, err)
+	defer os.RemoveAll(templateDir)
+	err = os.WriteFile(templateDir+"/download.tmpl", []byte(`{{ define "subject" }}Custom: {{ .FileName }}{{ end }}{{ define "body" }}Downloaded from {{ .PublicName }}{{ end }}`), 0600)
+	test.IsNil(t, err)
+	err = os.WriteFile(templateDir+"/expiry.tmpl", []byte(`{{ define "subject" }}Missing body{{ end }}`), 0600)
+	test.IsNil(t, err)
+	err = os.WriteFile(templateDir+"/deletion.tmpl", []byte(`{{ define "subject" }}Invalid{{ end `), 0600)
+	test.IsNil(t, err)
+
+	config := testconfiguration.GetSmtpTestConfig(smtpListener)
+	config.TemplateDir = templateDir
+	Init(config)
+	setPreferences(t, "user@example.com", models.NotifyDownloadEvery, true, true)
+	SendDownloadNotification(getTestFile(1), models.DownloadEvent{})
+	messages := getSentMessages()
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Subject: Custom: notification.txt\n"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Downloaded from "+configuration.Get().PublicName), true)
+
+	// Invalid templates are replaced by the default templates
+	SendExpiryNotification(getTestFile(1))
+	SendDeletionNotification(getTestFile(1))
+	messages = getSentMessages()
+	test.IsEqualInt(t, len(messages), 2)
+
+	config.TemplateDir = "test/invalid"
+	Init(config)
+	test.IsEqualInt(t, len(templates), 3)
+	setPreferences(t, "", models.NotifyDownloadNever, false, false)
+}
+
+func TestFailedNotification(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.IsNil(t, err)
+	config := testconfiguration.GetSmtpTestConfig(listener)
+	_ = listener.Close()
+	Init(config)
+	setPreferences(t, "user@example.com", models.NotifyDownloadNever, false, true)
+	test.CompletesWithinTime(t, func() {
+		SendDeletionNotification(getTestFile(0))
+		Wait()
+	}, 10*time.Second)
+	setPreferences(t, "", models.NotifyDownloadNever, false, false)
+	Init(models.SmtpConfig{})
+}
+
+func TestQueueLimit(t *testing.T) {
+	Init(testconfiguration.GetSmtpTestConfig(smtpListener))
+	setPreferences(t, "user@example.com", models.NotifyDownloadNever, false, true)
+	startMailWorkers()
+	originalQueue := mailQueue
+	mailQueue = make(chan queuedMail, 1)
+	SendDeletionNotification(getTestFile(0))
+	SendDeletionNotification(getTestFile(0))
+	test.IsEqualInt(t, len(mailQueue), 1)
+	<-mailQueue
+	pendingMails.Done()
+	mailQueue = originalQueue
+	test.IsEqualInt(t, len(getSentMessages()), 0)
+	setPreferences(t, "", models.NotifyDownloadNever, false, false)
+	Init(models.SmtpConfig{})
+}
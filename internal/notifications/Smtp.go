@@ -0,0 +1,120 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"github.com/forceu/gokapi/internal/models"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// timeout is the maximum duration for sending a single email
+const timeout = 30 * time.Second
+
+// sendMail connects to the mail server and sends a plain text email to the recipient.
+// If the server offers STARTTLS, the connection is encrypted before logging in
+func sendMail(config models.SmtpConfig, recipient, subject, body string) error {
+	if strings.ContainsAny(recipient, "\r\n") || strings.ContainsAny(config.Sender, "\r\n") {
+		return errors.New("invalid email address")
+	}
+	message, err := buildMessage(config.Sender, recipient, subject, body)
+	if err != nil {
+		return err
+	}
+	client, err := connect(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if !config.ImplicitTls {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			err = client.StartTLS(&tls.Config{ServerName: config.Host})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if config.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(config.Sender)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(recipient)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(message)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// connect opens a connection to the mail server, which is closed after the timeout at the latest
+func connect(config models.SmtpConfig) (*smtp.Client, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if config.ImplicitTls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", config.GetAddress(), &tls.Config{ServerName: config.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", config.GetAddress())
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// buildMessage returns the headers and the quoted-printable encoded body of the email
+func buildMessage(sender, recipient, subject, body string) ([]byte, error) {
+	subject = strings.Join(strings.Fields(subject), " ")
+	var message bytes.Buffer
+	message.WriteString("From: " + sender + "\r\n")
+	message.WriteString("To: " + recipient + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	message.WriteString("\r\n")
+	writer := quotedprintable.NewWriter(&message)
+	body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	_, err := writer.Write([]byte(body))
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
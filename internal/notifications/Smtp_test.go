@@ -0,0 +1,52 @@
+package notifications
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	message, err := buildMessage("gokapi@example.com", "user@example.com", "Test\r\nBcc: other@example.com", "Line 1\nLine 2\r\n")
+	test.IsNil(t, err)
+	output := string(message)
+	test.IsEqualBool(t, strings.HasPrefix(output, "From: gokapi@example.com\r\nTo: user@example.com\r\nSubject: Test Bcc: other@example.com\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "\r\nBcc:"), false)
+	test.IsEqualBool(t, strings.HasSuffix(output, "\r\n\r\nLine 1\r\nLine 2\r\n"), true)
+
+	message, err = buildMessage("gokapi@example.com", "user@example.com", "Übersicht.txt", "Größe")
+	test.IsNil(t, err)
+	output = string(message)
+	test.IsEqualBool(t, strings.Contains(output, "Subject: =?utf-8?q?=C3=9Cbersicht.txt?=\r\n"), true)
+	test.IsEqualBool(t, strings.HasSuffix(output, "\r\n\r\nGr=C3=B6=C3=9Fe"), true)
+}
+
+func TestSendMail(t *testing.T) {
+	listener, messages := testconfiguration.StartSmtpTestServer()
+	defer listener.Close()
+	config := testconfiguration.GetSmtpTestConfig(listener)
+
+	err := sendMail(config, "user@example.com", "Subject", "Body\n")
+	test.IsNil(t, err)
+	message := <-messages
+	test.IsEqualString(t, message.From, "gokapi@example.com")
+	test.IsEqualString(t, message.To[0], "user@example.com")
+	test.IsEqualBool(t, strings.HasSuffix(message.Data, "\n\nBody\n"), true)
+
+	err = sendMail(config, "user@example.com\r\nRCPT TO:<other@example.com>", "Subject", "Body")
+	test.IsNotNil(t, err)
+
+	// The test server does not support TLS
+	config.ImplicitTls = true
+	err = sendMail(config, "user@example.com", "Subject", "Body")
+	test.IsNotNil(t, err)
+
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.IsNil(t, err)
+	config = testconfiguration.GetSmtpTestConfig(closedListener)
+	_ = closedListener.Close()
+	err = sendMail(config, "user@example.com", "Subject", "Body")
+	test.IsNotNil(t, err)
+}
@@ -0,0 +1,255 @@
+package storage
+
+/**
+Listing the content of uploaded ZIP and TAR archives
+*/
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxArchiveEntries is the maximum number of entries that are listed for an archive
+const maxArchiveEntries = 1000
+
+// archiveContentFailed is stored as the archive content, if the archive could not be read.
+// This prevents the archive from being read again on every request
+const archiveContentFailed = "failed"
+
+// archiveJobs contains the IDs of the files whose archive content is currently being read
+var archiveJobs = make(map[string]bool)
+var archiveJobsMutex sync.Mutex
+
+const (
+	archiveTypeNone = iota
+	archiveTypeZip
+	archiveTypeTar
+	archiveTypeTarGz
+)
+
+// getArchiveType returns the type of the archive, based on the extension of the filename
+func getArchiveType(filename string) int {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return archiveTypeZip
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTypeTar
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTypeTarGz
+	default:
+		return archiveTypeNone
+	}
+}
+
+// IsArchive returns true, if the file is a ZIP or TAR archive and its content can be listed.
+// End-to-end encrypted files cannot be read by the server
+func IsArchive(file models.File) bool {
+	return !file.Encryption.IsEndToEndEncrypted && getArchiveType(file.Name) != archiveTypeNone
+}
+
+// GetArchiveEntries returns the entries of an archive that have been stored in the metadata.
+// The content of the file is never read by this function. If the entries have not been stored yet,
+// they are read in the background. Returns false, if the file is not an archive, the archive has
+// not been read yet, cannot be read or it does not contain any entries
+func GetArchiveEntries(file models.File) ([]models.ArchiveEntry, bool) {
+	if !IsArchive(file) {
+		return nil, false
+	}
+	if file.ArchiveContent == "" {
+		createArchiveContentInBackground(file)
+		return nil, false
+	}
+	if file.ArchiveContent == archiveContentFailed {
+		return nil, false
+	}
+	var result []models.ArchiveEntry
+	err := json.Unmarshal([]byte(file.ArchiveContent), &result)
+	if err != nil || len(result) == 0 {
+		return nil, false
+	}
+	return result, true
+}
+
+// IsArchiveContentPending returns true, if the file is an archive and its entries have not been read yet
+func IsArchiveContentPending(file models.File) bool {
+	return IsArchive(file) && file.ArchiveContent == ""
+}
+
+// createArchiveContentInBackground reads the entries of an archive without blocking. If the entries
+// of the file are already being read, no further job is started
+func createArchiveContentInBackground(file models.File) {
+	if !IsArchiveContentPending(file) {
+		return
+	}
+	archiveJobsMutex.Lock()
+	if archiveJobs[file.Id] {
+		archiveJobsMutex.Unlock()
+		return
+	}
+	archiveJobs[file.Id] = true
+	archiveJobsMutex.Unlock()
+	go func() {
+		createArchiveContent(file)
+		archiveJobsMutex.Lock()
+		delete(archiveJobs, file.Id)
+		archiveJobsMutex.Unlock()
+	}()
+}
+
+// createArchiveContent reads the entries of an archive and stores them in the metadata. If the
+// archive cannot be read, archiveContentFailed is stored instead
+func createArchiveContent(file models.File) {
+	content := archiveContentFailed
+	entries, err := readArchiveEntries(file)
+	if err != nil {
+		fmt.Println("Warning: Could not read content of archive " + file.Id + ": " + err.Error())
+	} else {
+		result, err := json.Marshal(entries)
+		helper.Check(err)
+		content = string(result)
+	}
+	database.SaveArchiveContent(file.Id, content)
+}
+
+// readArchiveEntries reads the stored content of the archive and returns its entries
+func readArchiveEntries(file models.File) ([]models.ArchiveEntry, error) {
+	switch getArchiveType(file.Name) {
+	case archiveTypeZip:
+		return readZipEntries(file)
+	case archiveTypeTar:
+		return readTarEntries(file, false)
+	case archiveTypeTarGz:
+		return readTarEntries(file, true)
+	default:
+		return nil, errors.New("unsupported archive type")
+	}
+}
+
+// readZipEntries returns the entries of a ZIP file. The list of entries is located at the end of
+// a ZIP file. If the content is stored unencrypted and uncompressed, only the required parts are read
+// from the storage. Otherwise the content is written to a temporary file first
+func readZipEntries(file models.File) ([]models.ArchiveEntry, error) {
+	if !file.Encryption.IsEncrypted && file.Compression == "" {
+		return listZipEntries(&storedContentReaderAt{file: file}, file.SizeBytes)
+	}
+	tempFile, err := os.CreateTemp(configuration.GetTempDir(), "archive")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+	err = readStoredContent(file, tempFile)
+	if err != nil {
+		return nil, err
+	}
+	info, err := tempFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return listZipEntries(tempFile, info.Size())
+}
+
+// listZipEntries returns up to maxArchiveEntries entries of the ZIP file
+func listZipEntries(reader io.ReaderAt, size int64) ([]models.ArchiveEntry, error) {
+	zipReader, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.ArchiveEntry, 0)
+	for _, entry := range zipReader.File {
+		if len(result) == maxArchiveEntries {
+			break
+		}
+		result = append(result, newArchiveEntry(entry.Name, int64(entry.UncompressedSize64), entry.Modified, entry.FileInfo().IsDir()))
+	}
+	return result, nil
+}
+
+// readTarEntries returns the entries of a TAR file, which is read as a stream. If isGzip is true,
+// the TAR file is decompressed with gzip first
+func readTarEntries(file models.File, isGzip bool) ([]models.ArchiveEntry, error) {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_ = pipeWriter.CloseWithError(readStoredContent(file, pipeWriter))
+	}()
+	// Closing the reader stops readStoredContent, if not all content has been read
+	defer pipeReader.Close()
+	var input io.Reader = pipeReader
+	if isGzip {
+		gzipReader, err := gzip.NewReader(pipeReader)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+	return listTarEntries(input)
+}
+
+// listTarEntries returns up to maxArchiveEntries entries of the TAR stream
+func listTarEntries(input io.Reader) ([]models.ArchiveEntry, error) {
+	tarReader := tar.NewReader(input)
+	result := make([]models.ArchiveEntry, 0)
+	for len(result) < maxArchiveEntries {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, newArchiveEntry(header.Name, header.Size, header.ModTime, header.Typeflag == tar.TypeDir))
+	}
+	return result, nil
+}
+
+func newArchiveEntry(name string, size int64, modTime time.Time, isDirectory bool) models.ArchiveEntry {
+	result := models.ArchiveEntry{
+		Name:        name,
+		SizeBytes:   size,
+		Size:        helper.ByteCountSI(size),
+		IsDirectory: isDirectory,
+	}
+	if !modTime.IsZero() {
+		result.ModTime = modTime.Unix()
+		result.ModTimeString = FormatTimestamp(result.ModTime)
+	}
+	return result
+}
+
+// storedContentReaderAt reads parts of unencrypted and uncompressed content from the storage
+// of the file, without reading the whole content
+type storedContentReaderAt struct {
+	file models.File
+}
+
+// ReadAt reads len(p) bytes of the stored content, starting at offset off
+func (s *storedContentReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	reader, err := filesystem.GetForFile(s.file).OpenFile(s.file, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	n, err := io.ReadFull(reader, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
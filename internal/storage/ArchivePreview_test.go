@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+	"time"
+)
+
+var testArchiveTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func createTestArchive(filename string, content []byte) (models.File, error) {
+	header, request := createRawTestFile(content)
+	header.Filename = filename
+	return NewFile(bytes.NewReader(content), &header, 63, request)
+}
+
+func createZipContent(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	_, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "folder/", Modified: testArchiveTime})
+	test.IsNil(t, err)
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "folder/test.txt", Method: zip.Deflate, Modified: testArchiveTime})
+	test.IsNil(t, err)
+	_, err = writer.Write([]byte("This is a file in a ZIP archive"))
+	test.IsNil(t, err)
+	test.IsNil(t, zipWriter.Close())
+	return buf.Bytes()
+}
+
+func createTarContent(t *testing.T, isGzip bool) []byte {
+	var buf bytes.Buffer
+	var tarWriter *tar.Writer
+	var gzipWriter *gzip.Writer
+	if isGzip {
+		gzipWriter = gzip.NewWriter(&buf)
+		tarWriter = tar.NewWriter(gzipWriter)
+	} else {
+		tarWriter = tar.NewWriter(&buf)
+	}
+	content := []byte("This is a file in a TAR archive")
+	err := tarWriter.WriteHeader(&tar.Header{Name: "test.txt", Size: int64(len(content)), Mode: 0600, ModTime: testArchiveTime})
+	test.IsNil(t, err)
+	_, err = tarWriter.Write(content)
+	test.IsNil(t, err)
+	test.IsNil(t, tarWriter.Close())
+	if isGzip {
+		test.IsNil(t, gzipWriter.Close())
+	}
+	return buf.Bytes()
+}
+
+func TestIsArchive(t *testing.T) {
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.zip"}), true)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "TEST.ZIP"}), true)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.tar"}), true)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.tar.gz"}), true)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.tgz"}), true)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.gz"}), false)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.dat"}), false)
+	test.IsEqualBool(t, IsArchive(models.File{Name: "test.zip", Encryption: models.EncryptionInfo{IsEndToEndEncrypted: true}}), false)
+}
+
+// waitForArchiveContent waits until the entries of the archive have been read in the background
+func waitForArchiveContent(t *testing.T, id string) models.File {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		file, ok := database.GetMetaDataById(id)
+		test.IsEqualBool(t, ok, true)
+		if file.ArchiveContent != "" {
+			return file
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("archive content has not been read")
+	return models.File{}
+}
+
+func TestGetArchiveEntries(t *testing.T) {
+	_, ok := GetArchiveEntries(models.File{Name: "test.dat"})
+	test.IsEqualBool(t, ok, false)
+
+	// The entries are read in the background after the upload
+	zipFile, err := createTestArchive("archive.zip", createZipContent(t))
+	test.IsNil(t, err)
+	zipFile = waitForArchiveContent(t, zipFile.Id)
+	entries, ok := GetArchiveEntries(zipFile)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(entries), 2)
+	test.IsEqualString(t, entries[0].Name, "folder/")
+	test.IsEqualBool(t, entries[0].IsDirectory, true)
+	test.IsEqualString(t, entries[1].Name, "folder/test.txt")
+	test.IsEqualBool(t, entries[1].IsDirectory, false)
+	test.IsEqualInt64(t, entries[1].SizeBytes, 31)
+	test.IsEqualString(t, entries[1].Size, "31 B")
+	test.IsEqualInt64(t, entries[1].ModTime, testArchiveTime.Unix())
+
+	zipFile.ArchiveContent = `[{"Name":"cached.txt"}]`
+	entries, ok = GetArchiveEntries(zipFile)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, entries[0].Name, "cached.txt")
+	zipFile.ArchiveContent = "[]"
+	_, ok = GetArchiveEntries(zipFile)
+	test.IsEqualBool(t, ok, false)
+
+	// Archives without stored entries are not read synchronously, but in the background
+	database.SaveArchiveContent(zipFile.Id, "")
+	zipFile.ArchiveContent = ""
+	test.IsEqualBool(t, IsArchiveContentPending(zipFile), true)
+	_, ok = GetArchiveEntries(zipFile)
+	test.IsEqualBool(t, ok, false)
+	zipFile = waitForArchiveContent(t, zipFile.Id)
+	test.IsEqualBool(t, IsArchiveContentPending(zipFile), false)
+	entries, ok = GetArchiveEntries(zipFile)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(entries), 2)
+
+	for _, isGzip := range []bool{false, true} {
+		filename := "archive.tar"
+		if isGzip {
+			filename = "archive.tar.gz"
+		}
+		tarFile, err := createTestArchive(filename, createTarContent(t, isGzip))
+		test.IsNil(t, err)
+		tarFile = waitForArchiveContent(t, tarFile.Id)
+		entries, ok = GetArchiveEntries(tarFile)
+		test.IsEqualBool(t, ok, true)
+		test.IsEqualInt(t, len(entries), 1)
+		test.IsEqualString(t, entries[0].Name, "test.txt")
+		test.IsEqualInt64(t, entries[0].SizeBytes, 31)
+		test.IsEqualInt64(t, entries[0].ModTime, testArchiveTime.Unix())
+		database.DeleteMetaData(tarFile.Id)
+	}
+
+	// A failure marker is stored, so that the archive is not read again
+	invalidFile, err := createTestArchive("invalid.zip", []byte("This is not a ZIP file"))
+	test.IsNil(t, err)
+	invalidFile = waitForArchiveContent(t, invalidFile.Id)
+	test.IsEqualString(t, invalidFile.ArchiveContent, archiveContentFailed)
+	test.IsEqualBool(t, IsArchiveContentPending(invalidFile), false)
+	_, ok = GetArchiveEntries(invalidFile)
+	test.IsEqualBool(t, ok, false)
+	database.DeleteMetaData(invalidFile.Id)
+	database.DeleteMetaData(zipFile.Id)
+}
+
+func TestCreateArchiveContentInBackground(t *testing.T) {
+	file := models.File{Id: "archivejobtest", Name: "archive.zip", ExpireAt: 2147483646}
+	database.SaveMetaData(file)
+
+	// No further job is started, if the archive is already being read
+	archiveJobsMutex.Lock()
+	archiveJobs[file.Id] = true
+	archiveJobsMutex.Unlock()
+	createArchiveContentInBackground(file)
+	time.Sleep(200 * time.Millisecond)
+	file, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.ArchiveContent, "")
+	archiveJobsMutex.Lock()
+	delete(archiveJobs, file.Id)
+	archiveJobsMutex.Unlock()
+
+	createArchiveContentInBackground(file)
+	file = waitForArchiveContent(t, file.Id)
+	test.IsEqualString(t, file.ArchiveContent, archiveContentFailed)
+	database.DeleteMetaData(file.Id)
+}
+
+func TestListTarEntriesLimit(t *testing.T) {
+	var buf bytes.Buffer
+	tarWriter := tar.NewWriter(&buf)
+	for i := 0; i < maxArchiveEntries+5; i++ {
+		err := tarWriter.WriteHeader(&tar.Header{Name: "file", Mode: 0600})
+		test.IsNil(t, err)
+	}
+	test.IsNil(t, tarWriter.Close())
+	entries, err := listTarEntries(&buf)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(entries), maxArchiveEntries)
+}
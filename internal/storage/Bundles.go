@@ -0,0 +1,167 @@
+package storage
+
+/**
+Bundles share several existing files with a single download link
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/notifications"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrorBundleNoFiles is raised when a bundle is created without any files
+var ErrorBundleNoFiles = errors.New("a bundle requires at least one file")
+
+// ErrorBundleE2EFile is raised when an end-to-end encrypted file is added to a bundle. These files cannot
+// be added to the ZIP file, as they can only be decrypted by the client
+var ErrorBundleE2EFile = errors.New("end-to-end encrypted files cannot be added to a bundle")
+
+// ErrorBundlePasswordFile is raised when a password-protected file is added to a bundle. The bundle
+// would otherwise share the file without its password
+var ErrorBundlePasswordFile = errors.New("password-protected files cannot be added to a bundle")
+
+// GetFilesForBundle returns the files with the given IDs, so that they can be added to a bundle.
+// An error is returned, if a file does not exist, cannot be downloaded anymore or was uploaded by another
+// user and the user is not allowed to edit other uploads. Listing other uploads is not sufficient,
+// as the bundle shares the files with a new public link
+func GetFilesForBundle(ids []string, user models.User) ([]models.File, error) {
+	return getFilesWithPermission(ids, user, models.UserPermEditOtherUploads)
+}
+
+// CreateBundle creates and saves a new bundle with the given files. The expiry, download limit and
+// password are taken from uploadRequest
+func CreateBundle(name string, files []models.File, userId int, uploadRequest models.UploadRequest) (models.Bundle, error) {
+	bundle := models.Bundle{
+		Id:                 createNewId(),
+		Name:               name,
+		CreationDate:       time.Now().Unix(),
+		ExpireAt:           uploadRequest.ExpiryTimestamp,
+		ExpireAtString:     FormatTimestamp(uploadRequest.ExpiryTimestamp),
+		DownloadsRemaining: uploadRequest.AllowedDownloads,
+		UnlimitedDownloads: uploadRequest.UnlimitedDownload,
+		UnlimitedTime:      uploadRequest.UnlimitedTime,
+		UserId:             userId,
+	}
+	if uploadRequest.Password != "" {
+		bundle.PasswordHash = configuration.HashPassword(uploadRequest.Password, true)
+	}
+	err := SetBundleFiles(&bundle, files)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+	database.SaveBundle(bundle)
+	return bundle, nil
+}
+
+// SetBundleFiles replaces the files of the bundle. Returns an error, if no files are passed or
+// a file cannot be added to a bundle. Files that are passed more than once are only added once
+func SetBundleFiles(bundle *models.Bundle, files []models.File) error {
+	if len(files) == 0 {
+		return ErrorBundleNoFiles
+	}
+	fileIds := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.Encryption.IsEndToEndEncrypted {
+			return ErrorBundleE2EFile
+		}
+		if file.PasswordHash != "" {
+			return ErrorBundlePasswordFile
+		}
+		if !slices.Contains(fileIds, file.Id) {
+			fileIds = append(fileIds, file.Id)
+		}
+	}
+	bundle.FileIds = fileIds
+	return nil
+}
+
+// GetBundle returns the bundle with the given ID. Returns false, if the bundle does not exist or has expired
+func GetBundle(id string) (models.Bundle, bool) {
+	if id == "" {
+		return models.Bundle{}, false
+	}
+	bundle, ok := database.GetBundle(id)
+	if !ok || IsExpiredBundle(bundle, time.Now().Unix()) {
+		return models.Bundle{}, false
+	}
+	return bundle, true
+}
+
+// IsExpiredBundle returns true, if the bundle has expired or no downloads are remaining
+func IsExpiredBundle(bundle models.Bundle, timeNow int64) bool {
+	return (bundle.ExpireAt < timeNow && !bundle.UnlimitedTime) ||
+		(bundle.DownloadsRemaining < 1 && !bundle.UnlimitedDownloads)
+}
+
+// GetBundleFiles returns the files of the bundle that can still be downloaded. Files that have
+// expired, have been deleted or have been protected with a password since the bundle was created
+// are not returned
+func GetBundleFiles(bundle models.Bundle) []models.File {
+	result := make([]models.File, 0, len(bundle.FileIds))
+	for _, id := range bundle.FileIds {
+		file, ok := GetFile(id)
+		if ok && !file.Encryption.IsEndToEndEncrypted && file.PasswordHash == "" {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// GetBundleSize returns the total size of the files in a human-readable format
+func GetBundleSize(files []models.File) string {
+	var size int64
+	for _, file := range files {
+		size = size + file.SizeBytes
+	}
+	return helper.ByteCountSI(size)
+}
+
+// ServeBundle subtracts a download allowance of the bundle and of every file in it and sends the files
+// as a single ZIP file. The ZIP file is streamed to the client without creating a temporary file.
+// The download is recorded in the download history of every file and the uploaders are notified.
+// If one of the files or the client already has the maximum number of simultaneous downloads,
+// ErrorTooManyDownloads is returned before the download allowances are subtracted
+func ServeBundle(bundle models.Bundle, files []models.File, w http.ResponseWriter, r *http.Request) error {
+	statusIds, err := setZipDownloads(files, r)
+	if err != nil {
+		return err
+	}
+	defer setZipDownloadsComplete(statusIds)
+	database.IncreaseBundleDownloadCount(bundle.Id, !bundle.UnlimitedDownloads)
+	logging.LogBundleDownload(bundle, r, configuration.Get().SaveIp)
+	events := make([]models.DownloadEvent, len(files))
+	for i, file := range files {
+		file = countZipFileDownload(file)
+		events[i] = newDownloadEvent(file, r)
+		notifications.SendDownloadNotification(file, events[i])
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+getZipFilename(bundle.Name)+"\"")
+	w, releaseBandwidth := limitZipWriter(w)
+	defer releaseBandwidth()
+	err = writeZipAndSaveEvents(w, files, events, r)
+	if err != nil {
+		fmt.Println("Error while sending bundle " + bundle.Id + ": " + err.Error())
+	}
+	return nil
+}
+
+// cleanExpiredBundles removes bundles that have expired or have no downloads remaining
+func cleanExpiredBundles() {
+	timeNow := time.Now().Unix()
+	for _, bundle := range database.GetAllBundles() {
+		if IsExpiredBundle(bundle, timeNow) {
+			database.DeleteBundle(bundle.Id)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateBundle(t *testing.T) {
+	_, err := CreateBundle("empty", []models.File{}, 5, models.UploadRequest{})
+	test.IsEqual(t, err, ErrorBundleNoFiles)
+	e2eFile := models.File{Id: "bundlee2e", Encryption: models.EncryptionInfo{IsEndToEndEncrypted: true}}
+	_, err = CreateBundle("e2e", []models.File{e2eFile}, 5, models.UploadRequest{})
+	test.IsEqual(t, err, ErrorBundleE2EFile)
+	passwordFile := models.File{Id: "bundlepassword", PasswordHash: "hash"}
+	_, err = CreateBundle("password", []models.File{passwordFile}, 5, models.UploadRequest{})
+	test.IsEqual(t, err, ErrorBundlePasswordFile)
+
+	request := models.UploadRequest{AllowedDownloads: 3, ExpiryTimestamp: 2147483600, Password: "secret"}
+	bundle, err := CreateBundle("Holiday", []models.File{{Id: "file1"}, {Id: "file2"}, {Id: "file1"}}, 5, request)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(bundle.Id), 15)
+	test.IsEqualString(t, bundle.Name, "Holiday")
+	test.IsEqualInt(t, len(bundle.FileIds), 2)
+	test.IsEqualInt(t, bundle.DownloadsRemaining, 3)
+	test.IsEqualInt(t, bundle.UserId, 5)
+	test.IsEqualBool(t, bundle.PasswordHash != "", true)
+	test.IsEqualString(t, bundle.ExpireAtString, FormatTimestamp(2147483600))
+
+	retrieved, ok := GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrieved.Name, "Holiday")
+	test.IsEqualString(t, retrieved.JoinFileIds(), "file1,file2")
+	database.DeleteBundle(bundle.Id)
+	_, ok = GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, false)
+	_, ok = GetBundle("")
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestGetFilesForBundle(t *testing.T) {
+	newFile, err := createTestFile()
+	test.IsNil(t, err)
+	files, err := GetFilesForBundle([]string{newFile.File.Id}, models.User{Id: 63})
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(files), 1)
+
+	otherUser := models.User{Id: 64, UserLevel: models.UserLevelUser}
+	otherUser.GrantPermission(models.UserPermListOtherUploads)
+	_, err = GetFilesForBundle([]string{newFile.File.Id}, otherUser)
+	test.IsEqualBool(t, errors.Is(err, ErrorNoFilePermission), true)
+	otherUser.GrantPermission(models.UserPermEditOtherUploads)
+	_, err = GetFilesForBundle([]string{newFile.File.Id}, otherUser)
+	test.IsNil(t, err)
+	database.DeleteMetaData(newFile.File.Id)
+}
+
+func TestIsExpiredBundle(t *testing.T) {
+	timeNow := time.Now().Unix()
+	test.IsEqualBool(t, IsExpiredBundle(models.Bundle{ExpireAt: timeNow + 10, DownloadsRemaining: 1}, timeNow), false)
+	test.IsEqualBool(t, IsExpiredBundle(models.Bundle{ExpireAt: timeNow - 10, DownloadsRemaining: 1}, timeNow), true)
+	test.IsEqualBool(t, IsExpiredBundle(models.Bundle{ExpireAt: timeNow + 10, DownloadsRemaining: 0}, timeNow), true)
+	test.IsEqualBool(t, IsExpiredBundle(models.Bundle{UnlimitedTime: true, UnlimitedDownloads: true}, timeNow), false)
+}
+
+func TestServeBundle(t *testing.T) {
+	newFile1, err := createTestFile()
+	test.IsNil(t, err)
+	newFile2, err := createTestFile()
+	test.IsNil(t, err)
+	bundle, err := CreateBundle("TestBundle", []models.File{newFile1.File, newFile2.File, {Id: "invalidfile"}}, 5,
+		models.UploadRequest{AllowedDownloads: 1, ExpiryTimestamp: 2147483600})
+	test.IsNil(t, err)
+
+	files := GetBundleFiles(bundle)
+	test.IsEqualInt(t, len(files), 2)
+	test.IsEqualString(t, GetBundleSize(files), "70 B")
+
+	// Files that have been protected with a password since the bundle was created are not returned
+	newFile2.File.PasswordHash = "hash"
+	database.SaveMetaData(newFile2.File)
+	test.IsEqualInt(t, len(GetBundleFiles(bundle)), 1)
+	newFile2.File.PasswordHash = ""
+	database.SaveMetaData(newFile2.File)
+
+	// Nothing is sent, if one of the files has the maximum number of simultaneous downloads
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	statusId, ok := downloadstatus.SetDownloadIfAllowed(newFile2.File, "192.0.2.1", 0, 0)
+	test.IsEqualBool(t, ok, true)
+	files[1].ParallelDownloads = 1
+	err = ServeBundle(bundle, files, w, r)
+	test.IsEqual(t, err, ErrorTooManyDownloads)
+	test.IsEqualInt(t, w.Body.Len(), 0)
+	_, ok = GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, true)
+	downloadstatus.SetComplete(statusId)
+
+	w = httptest.NewRecorder()
+	err = ServeBundle(bundle, files, w, r)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, downloadstatus.IsCurrentlyDownloading(newFile1.File), false)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Type"), "application/zip")
+	test.IsEqualString(t, w.Result().Header.Get("Content-Disposition"), "attachment; filename=\"TestBundle.zip\"")
+	content, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(zipReader.File), 2)
+	test.IsEqualString(t, zipReader.File[0].Name, "test.dat")
+	test.IsEqualString(t, zipReader.File[1].Name, "test (1).dat")
+	entry, err := zipReader.File[1].Open()
+	test.IsNil(t, err)
+	entryContent, err := io.ReadAll(entry)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(entryContent), "This is a file for testing purposes")
+
+	// The counters of the bundle and of the files are reduced
+	_, ok = GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, false)
+	file, ok := database.GetMetaDataById(newFile1.File.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, file.DownloadsRemaining, 0)
+	test.IsEqualInt(t, file.DownloadCount, 1)
+	_, ok = GetFile(newFile1.File.Id)
+	test.IsEqualBool(t, ok, false)
+	// The download is recorded for every file
+	for _, id := range []string{newFile1.File.Id, newFile2.File.Id} {
+		events := database.GetDownloadEvents(id)
+		test.IsEqualInt(t, len(events), 1)
+		test.IsEqualInt64(t, events[0].BytesSent, 35)
+		test.IsEqualBool(t, events[0].IsComplete, true)
+		database.DeleteDownloadEvents(id)
+	}
+
+	cleanExpiredBundles()
+	_, ok = database.GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, false)
+	database.DeleteMetaData(newFile1.File.Id)
+	database.DeleteMetaData(newFile2.File.Id)
+}
@@ -0,0 +1,92 @@
+package storage
+
+/**
+Recording every download of a file, so that uploaders can see who downloaded their files
+*/
+
+import (
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxUserAgentLength is the maximum number of characters of the user agent that are stored
+const maxUserAgentLength = 512
+
+// countingWriter counts the bytes that have been written to the underlying writer
+type countingWriter struct {
+	io.Writer
+	bytesWritten int64
+	hasFailed    bool
+}
+
+// Write writes p to the underlying writer and adds the number of written bytes
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.bytesWritten = c.bytesWritten + int64(n)
+	if err != nil {
+		c.hasFailed = true
+	}
+	return n, err
+}
+
+// countingResponseWriter is a http.ResponseWriter that counts the bytes of the response body
+type countingResponseWriter struct {
+	http.ResponseWriter
+	countingWriter
+}
+
+// Write sends p to the client and adds the number of sent bytes
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	return c.countingWriter.Write(p)
+}
+
+func newCountingResponseWriter(w http.ResponseWriter) *countingResponseWriter {
+	return &countingResponseWriter{
+		ResponseWriter: w,
+		countingWriter: countingWriter{Writer: w},
+	}
+}
+
+// newDownloadEvent returns an event for a download of the file that has been started by the request.
+// The IP address is only included, if IP addresses are saved
+func newDownloadEvent(file models.File, r *http.Request) models.DownloadEvent {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	event := models.DownloadEvent{
+		FileId:    file.Id,
+		Timestamp: time.Now().Unix(),
+		UserAgent: userAgent,
+		UserId:    file.UserId,
+	}
+	if configuration.Get().SaveIp {
+		event.Ip = logging.GetIpAddress(r)
+	}
+	return event
+}
+
+// saveDownloadEvent stores the event with the number of bytes that have been sent. The download is
+// marked as complete, if isComplete is true and the client has not disconnected
+func saveDownloadEvent(event models.DownloadEvent, bytesSent int64, r *http.Request, isComplete bool) {
+	event.BytesSent = bytesSent
+	event.IsComplete = isComplete && r.Context().Err() == nil
+	database.SaveDownloadEvent(event)
+}
+
+// cleanDownloadEvents removes downloads that are older than the retention period of the download history.
+// The history is kept independently of the file, so that it can still be viewed after the file has been removed.
+// If the retention period is 0, downloads are kept until the file is deleted manually
+func cleanDownloadEvents(timeNow int64) {
+	const secondsPerDay = 24 * 60 * 60
+	retentionDays := configuration.Get().DownloadHistoryDays
+	if retentionDays < 1 {
+		return
+	}
+	database.DeleteDownloadEventsBefore(timeNow - int64(retentionDays)*secondsPerDay)
+}
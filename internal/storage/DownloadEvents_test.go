@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingWriter struct{}
+
+func (f failingWriter) Write(p []byte) (int, error) {
+	return 2, errors.New("connection closed")
+}
+
+func TestCountingWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	counter := newCountingResponseWriter(w)
+	counter.Header().Set("Content-Type", "text/plain")
+	_, err := counter.Write([]byte("1234"))
+	test.IsNil(t, err)
+	_, err = counter.Write([]byte("56"))
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, counter.bytesWritten, 6)
+	test.IsEqualBool(t, counter.hasFailed, false)
+	test.IsEqualString(t, w.Body.String(), "123456")
+	test.IsEqualString(t, w.Header().Get("Content-Type"), "text/plain")
+
+	failing := &countingWriter{Writer: failingWriter{}}
+	_, err = failing.Write([]byte("1234"))
+	test.IsNotNil(t, err)
+	test.IsEqualInt64(t, failing.bytesWritten, 2)
+	test.IsEqualBool(t, failing.hasFailed, true)
+}
+
+func TestNewDownloadEvent(t *testing.T) {
+	file := models.File{Id: "eventTestFile", UserId: 5}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", "testagent")
+	r.Header.Set("X-Forwarded-For", "192.0.2.10")
+
+	configuration.Get().SaveIp = false
+	event := newDownloadEvent(file, r)
+	test.IsEqualString(t, event.FileId, "eventTestFile")
+	test.IsEqualString(t, event.UserAgent, "testagent")
+	test.IsEqualString(t, event.Ip, "")
+	test.IsEqualBool(t, event.Timestamp > 0, true)
+	test.IsEqualInt(t, event.UserId, 5)
+
+	configuration.Get().SaveIp = true
+	defer func() { configuration.Get().SaveIp = false }()
+	event = newDownloadEvent(file, r)
+	test.IsEqualString(t, event.Ip, "192.0.2.10")
+
+	r.Header.Set("User-Agent", strings.Repeat("a", 1000))
+	event = newDownloadEvent(file, r)
+	test.IsEqualInt(t, len(event.UserAgent), maxUserAgentLength)
+}
+
+func TestSaveDownloadEvent(t *testing.T) {
+	event := models.DownloadEvent{FileId: "eventTestSave", Timestamp: 100}
+	r := httptest.NewRequest("GET", "/", nil)
+	saveDownloadEvent(event, 20, r, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	saveDownloadEvent(event, 10, r.WithContext(ctx), true)
+
+	events := database.GetDownloadEvents("eventTestSave")
+	test.IsEqualInt(t, len(events), 2)
+	test.IsEqualInt64(t, events[0].BytesSent, 10)
+	test.IsEqualBool(t, events[0].IsComplete, false)
+	test.IsEqualInt64(t, events[1].BytesSent, 20)
+	test.IsEqualBool(t, events[1].IsComplete, true)
+	database.DeleteDownloadEvents("eventTestSave")
+}
+
+func TestCleanDownloadEvents(t *testing.T) {
+	now := time.Now().Unix()
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: "eventTestClean", Timestamp: now - 10*24*60*60})
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: "eventTestClean", Timestamp: now - 24*60*60})
+
+	configuration.Get().DownloadHistoryDays = 0
+	cleanDownloadEvents(now)
+	test.IsEqualInt(t, len(database.GetDownloadEvents("eventTestClean")), 2)
+
+	configuration.Get().DownloadHistoryDays = 5
+	defer func() { configuration.Get().DownloadHistoryDays = 0 }()
+	cleanDownloadEvents(now)
+	events := database.GetDownloadEvents("eventTestClean")
+	test.IsEqualInt(t, len(events), 1)
+	test.IsEqualInt64(t, events[0].Timestamp, now-24*60*60)
+	database.DeleteDownloadEvents("eventTestClean")
+}
@@ -6,7 +6,6 @@ Serving and processing uploaded files
 
 import (
 	"bytes"
-	"crypto/sha1"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -17,10 +16,18 @@ import (
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
 	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/notifications"
 	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/compression"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
 	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem/sftp"
+	"github.com/forceu/gokapi/internal/storage/filesystem/webdavfilesystem/webdav"
+	"github.com/forceu/gokapi/internal/storage/hashing"
 	"github.com/forceu/gokapi/internal/storage/processingstatus"
+	"github.com/forceu/gokapi/internal/webserver/bandwidth"
 	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
 	"github.com/forceu/gokapi/internal/webserver/headers"
 	"github.com/forceu/gokapi/internal/webserver/sse"
@@ -42,74 +49,54 @@ var ErrorFileTooLarge = errors.New("upload limit exceeded")
 // ErrorReplaceE2EFile is caused when an end-to-end encrypted file is replaced
 var ErrorReplaceE2EFile = errors.New("end-to-end encrypted files cannot be replaced")
 
+// ErrorInvalidStorageTarget is raised when a storage target is requested that does not exist or that the user is not allowed to use
+var ErrorInvalidStorageTarget = errors.New("invalid storage target requested")
+
 // ErrorFileNotFound is raised when an invalid ID is passed or the file has expired
 var ErrorFileNotFound = errors.New("file not found")
 
-// NewFile creates a new file in the system. Called after an upload from the API has been completed. If a file with the same sha1 hash
+// NewFile creates a new file in the system. Called after an upload from the API has been completed. If a file with the same hash
 // already exists, it is deduplicated. This function gathers information about the file, creates an ID and saves
 // it into the global configuration. It is now only used by the API, the web UI uses NewFileFromChunk
 func NewFile(fileContent io.Reader, fileHeader *multipart.FileHeader, userId int, uploadRequest models.UploadRequest) (models.File, error) {
 	if !isAllowedFileSize(fileHeader.Size) {
 		return models.File{}, ErrorFileTooLarge
 	}
-	var hasBeenRenamed bool
-	reader, hash, tempFile, encInfo := generateHashAndEncrypt(fileContent, fileHeader)
-	defer deleteTempFile(tempFile, &hasBeenRenamed)
 	header, err := chunking.ParseMultipartHeader(fileHeader)
 	if err != nil {
 		return models.File{}, err
 	}
-	file := createNewMetaData(hex.EncodeToString(hash), header, userId, uploadRequest)
+	file := createNewMetaData("", header, userId, uploadRequest)
+	var hasBeenRenamed bool
+	legacyHashes := newLegacyHashes()
+	reader, hash, tempFile, encInfo, compressedSize := generateHashAndEncrypt(io.TeeReader(fileContent, legacyHashes), fileHeader, file)
+	defer deleteTempFile(tempFile, &hasBeenRenamed)
+	file.SHA1 = hex.EncodeToString(hash)
 	file.Encryption = encInfo
-	filename := configuration.Get().DataDir + "/" + file.SHA1
-	dataDir := configuration.Get().DataDir
-
-	fileWithHashExists := FileExists(file, configuration.Get().DataDir)
-
-	if !file.IsLocalStorage() {
-		if !fileWithHashExists {
-			_, err = aws.Upload(reader, file)
-			if err != nil {
-				return models.File{}, err
-			}
-		}
-		database.SaveMetaData(file)
-		return file, nil
-	}
+	file.CompressedBytes = compressedSize
+	applyLegacyHash(&file, legacyHashes.Sums(getHashSalt(file)))
 
+	fileWithHashExists := FileExists(file)
 	if fileWithHashExists {
-		encryptionLevel := configuration.Get().Encryption.Level
-		previousEncryption, ok := getEncInfoFromExistingFile(file.SHA1)
-		if !ok && encryptionLevel != encryption.NoEncryption && encryptionLevel != encryption.EndToEndEncryption {
-			err = os.Remove(dataDir + "/" + file.SHA1)
-			helper.Check(err)
-			fileWithHashExists = false
-		} else {
-			file.Encryption = previousEncryption
-		}
+		fileWithHashExists = copyCompressionInfo(&file) && copyEncryptionInfo(&file)
 	}
 
 	if !fileWithHashExists {
+		storageDriver := filesystem.GetForFile(file)
 		if tempFile != nil {
-			err = tempFile.Close()
-			helper.Check(err)
-			err = os.Rename(tempFile.Name(), dataDir+"/"+file.SHA1)
-			helper.Check(err)
+			// The driver takes care of closing and removing the temporary file
 			hasBeenRenamed = true
-			database.SaveMetaData(file)
-			return file, nil
-		}
-		destinationFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-		if err != nil {
-			return models.File{}, err
+			err = storageDriver.MoveToFilesystem(tempFile, file)
+		} else {
+			err = storageDriver.WriteToFilesystem(reader, file)
 		}
-		defer destinationFile.Close()
-		_, err = io.Copy(destinationFile, reader)
 		if err != nil {
 			return models.File{}, err
 		}
 	}
 	database.SaveMetaData(file)
+	createThumbnailInBackground(file)
+	createArchiveContentInBackground(file)
 	return file, nil
 }
 
@@ -133,23 +120,13 @@ func validateChunkInfo(file *os.File, fileHeader chunking.FileHeader) error {
 	return nil
 }
 
-// GetUploadCounts returns the currently uploaded files per user
-func GetUploadCounts() map[int]int {
-	result := make(map[int]int)
-	timeNow := time.Now().Unix()
-	files := database.GetAllMetadata()
-	for _, file := range files {
-		if !IsExpiredFile(file, timeNow) {
-			result[file.UserId] = result[file.UserId] + 1
-		}
-	}
-	return result
-}
-
-// NewFileFromChunk creates a new file in the system after a chunk upload has fully completed. If a file with the same sha1 hash
+// NewFileFromChunk creates a new file in the system after a chunk upload has fully completed. If a file with the same hash
 // already exists, it is deduplicated. This function gathers information about the file, creates an ID and saves
 // it into the global configuration.
 func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int, uploadRequest models.UploadRequest) (models.File, error) {
+	if chunking.IsMultipartUpload(chunkId) {
+		return newFileFromMultipartUpload(chunkId, fileHeader, userId, uploadRequest)
+	}
 	file, err := chunking.GetFileByChunkId(chunkId)
 	if err != nil {
 		return models.File{}, err
@@ -161,14 +138,26 @@ func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int
 	}
 
 	processingstatus.Set(chunkId, processingstatus.StatusHashingOrEncrypting, models.File{}, nil)
-	hash, err := getChunkFileHash(file, uploadRequest.IsEndToEndEncrypted)
+	err = verifyFileChecksum(file, uploadRequest.Checksum)
+	if err != nil {
+		return models.File{}, err
+	}
+	destination := models.File{Name: fileHeader.Filename, ContentType: fileHeader.ContentType}
+	addStorageLocation(&destination, uploadRequest.StorageTarget)
+	legacyHashes := newLegacyHashes()
+	hash, err := getChunkFileHash(file, uploadRequest.IsEndToEndEncrypted, isEncryptionRequested(destination), legacyHashes)
 	if err != nil {
 		return models.File{}, err
 	}
 	metaData := createNewMetaData(hash, fileHeader, userId, uploadRequest)
-	fileExists := FileExists(metaData, configuration.Get().DataDir)
+	applyLegacyHash(&metaData, legacyHashes.Sums(getHashSalt(metaData)))
+	fileExists := FileExists(metaData)
+	if fileExists {
+		fileExists = copyCompressionInfo(&metaData) && copyEncryptionInfo(&metaData)
+	}
+	// The chunk file is only removed, if the stored file is reused. Otherwise, e.g. if the stored file
+	// was removed by the functions above, the content of the chunk file is stored instead
 	if fileExists {
-		fileExists = copyEncryptionInfo(&metaData)
 		err = file.Close()
 		if err != nil {
 			return models.File{}, err
@@ -181,33 +170,112 @@ func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int
 
 	if !fileExists {
 		fileToMove := file
-		if !isEncryptionRequested() {
-			_, err = file.Seek(0, io.SeekStart)
+		if metaData.Compression != "" {
+			fileToMove, err = compressChunkFile(file, &metaData)
+			if err != nil {
+				return models.File{}, err
+			}
+		}
+		if !isEncryptionRequested(metaData) {
+			_, err = fileToMove.Seek(0, io.SeekStart)
 			if err != nil {
 				return models.File{}, err
 			}
 		} else {
-			tempFile, err := encryptChunkFile(file, &metaData)
+			tempFile, err := encryptChunkFile(fileToMove, &metaData)
 			if err != nil {
 				return models.File{}, err
 			}
 			fileToMove = tempFile
 		}
 		processingstatus.Set(chunkId, processingstatus.StatusUploading, models.File{}, nil)
-		if metaData.IsLocalStorage() {
-			err = filesystem.GetLocal().MoveToFilesystem(fileToMove, metaData)
-		} else {
-			err = filesystem.ActiveStorageSystem.MoveToFilesystem(fileToMove, metaData)
+		err = filesystem.GetForFile(metaData).MoveToFilesystem(fileToMove, metaData)
+		if err != nil {
+			return models.File{}, err
+		}
+	}
+	chunking.DeleteManifest(chunkId)
+	diskspace.Release(chunkId)
+	database.SaveMetaData(metaData)
+	processingstatus.Set(chunkId, processingstatus.StatusFinished, metaData, nil)
+	createThumbnailInBackground(metaData)
+	createArchiveContentInBackground(metaData)
+	return metaData, nil
+}
+
+// newFileFromMultipartUpload creates a new file after all chunks have been streamed to S3. If the file is not
+// stored in the bucket the chunks were uploaded to, the content is downloaded and processed like a local chunk file
+func newFileFromMultipartUpload(chunkId string, fileHeader chunking.FileHeader, userId int, uploadRequest models.UploadRequest) (models.File, error) {
+	checksum, err := chunking.ParseChecksum(uploadRequest.Checksum)
+	if err != nil {
+		return models.File{}, err
+	}
+	processingstatus.Set(chunkId, processingstatus.StatusHashingOrEncrypting, models.File{}, nil)
+	hashes, object, err := chunking.CompleteMultipartUpload(chunkId, fileHeader.Size)
+	if err != nil {
+		return models.File{}, err
+	}
+	hash := hashes[configuration.Get().HashAlgorithm]
+	if !isAllowedFileSize(fileHeader.Size) {
+		deleteTempObject(object)
+		return models.File{}, ErrorFileTooLarge
+	}
+	// The checksum can only be compared with the hash of the upload, if both use the same algorithm.
+	// Otherwise the content is verified after it has been downloaded
+	isChecksumVerified := checksum.IsEmpty()
+	if !isChecksumVerified && checksum.Algorithm == configuration.Get().HashAlgorithm {
+		if hash != hex.EncodeToString(checksum.Value) {
+			deleteTempObject(object)
+			return models.File{}, chunking.ErrorChecksumMismatch
+		}
+		isChecksumVerified = true
+	}
+
+	destination := models.File{Name: fileHeader.Filename, ContentType: fileHeader.ContentType}
+	addStorageLocation(&destination, uploadRequest.StorageTarget)
+	// Files are compressed before they are stored, which requires the content to be processed locally
+	isCompressionRequested := !uploadRequest.IsEndToEndEncrypted && getCompressionAlgorithm(destination) != ""
+	if !isSameStorageLocation(destination, object) || isEncryptionRequested(destination) || isCompressionRequested || !isChecksumVerified {
+		err = chunking.StoreObjectAsChunkFile(chunkId, object)
+		if err != nil {
+			return models.File{}, err
 		}
+		return NewFileFromChunk(chunkId, fileHeader, userId, uploadRequest)
+	}
+
+	if uploadRequest.IsEndToEndEncrypted {
+		hash = "e2e-" + helper.GenerateRandomString(20)
+	}
+	metaData := createNewMetaData(hash, fileHeader, userId, uploadRequest)
+	applyLegacyHash(&metaData, hashes)
+	fileExists := FileExists(metaData)
+	if fileExists {
+		fileExists = copyCompressionInfo(&metaData) && copyEncryptionInfo(&metaData)
+	}
+	if !fileExists {
+		processingstatus.Set(chunkId, processingstatus.StatusUploading, models.File{}, nil)
+		err = aws.CopyObject(object, metaData)
 		if err != nil {
+			deleteTempObject(object)
 			return models.File{}, err
 		}
 	}
+	deleteTempObject(object)
 	database.SaveMetaData(metaData)
 	processingstatus.Set(chunkId, processingstatus.StatusFinished, metaData, nil)
+	createThumbnailInBackground(metaData)
+	createArchiveContentInBackground(metaData)
 	return metaData, nil
 }
 
+// deleteTempObject removes the temporary S3 object of a completed multipart upload
+func deleteTempObject(object models.File) {
+	_, err := aws.DeleteObject(object)
+	if err != nil {
+		fmt.Println("Warning, cannot delete temporary object " + object.SHA1 + ": " + err.Error())
+	}
+}
+
 // copyEncryptionInfo copies encryption info from an existing file,
 // if possible. If not possible due to incompatible encryption level,
 // the old file is removed.
@@ -215,9 +283,9 @@ func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int
 // The function returns false if the old file was removed.
 func copyEncryptionInfo(metaData *models.File) bool {
 	encryptionLevel := configuration.Get().Encryption.Level
-	previousEncryption, ok := getEncInfoFromExistingFile(metaData.SHA1)
+	previousEncryption, ok := getEncInfoFromExistingFile(*metaData)
 	if !ok && encryptionLevel != encryption.NoEncryption && encryptionLevel != encryption.EndToEndEncryption {
-		err := os.Remove(configuration.Get().DataDir + "/" + metaData.SHA1)
+		err := filesystem.GetForFile(*metaData).DeleteFile(*metaData)
 		helper.Check(err)
 		return false
 	}
@@ -225,11 +293,49 @@ func copyEncryptionInfo(metaData *models.File) bool {
 	return true
 }
 
-func getChunkFileHash(file *os.File, isEndToEndEncryted bool) (string, error) {
+// copyCompressionInfo copies the compression info from an existing file with the same content.
+// If no such file exists and the new file would have been compressed, the stored file is removed,
+// as it is unknown if the stored content is compressed.
+//
+// The function returns false if the old file was removed.
+func copyCompressionInfo(metaData *models.File) bool {
+	for _, existingFile := range database.GetAllMetadata() {
+		if existingFile.SHA1 == metaData.SHA1 && isSameStorageLocation(existingFile, *metaData) {
+			metaData.Compression = existingFile.Compression
+			metaData.CompressedBytes = existingFile.CompressedBytes
+			return true
+		}
+	}
+	if metaData.Compression == "" {
+		return true
+	}
+	err := filesystem.GetForFile(*metaData).DeleteFile(*metaData)
+	helper.Check(err)
+	return false
+}
+
+// verifyFileChecksum returns chunking.ErrorChecksumMismatch, if the content of the file does not match the
+// checksum sent by the client. The file is reset to the start afterwards
+func verifyFileChecksum(file *os.File, checksum string) error {
+	parsed, err := chunking.ParseChecksum(checksum)
+	if err != nil || parsed.IsEmpty() {
+		return err
+	}
+	err = parsed.Verify(file)
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return err
+}
+
+// getChunkFileHash returns the hash of the chunk file, created with the configured hash algorithm.
+// The content is written to legacyHashes as well
+func getChunkFileHash(file *os.File, isEndToEndEncryted, isEncrypted bool, legacyHashes io.Writer) (string, error) {
 	if isEndToEndEncryted {
 		return "e2e-" + helper.GenerateRandomString(20), nil
 	}
-	hash, err := hashFile(file, isEncryptionRequested())
+	hash, err := hashFile(io.TeeReader(file, legacyHashes), isEncrypted)
 	if err != nil {
 		_ = file.Close()
 		return "", err
@@ -258,7 +364,7 @@ func encryptChunkFile(file *os.File, metadata *models.File) (*os.File, error) {
 		removeTempFiles()
 		return nil, err
 	}
-	tempFileEnc, err := os.CreateTemp(configuration.Get().DataDir, "upload")
+	tempFileEnc, err := os.CreateTemp(configuration.GetTempDir(), "upload")
 	if err != nil {
 		removeTempFiles()
 		return nil, err
@@ -286,6 +392,35 @@ func encryptChunkFile(file *os.File, metadata *models.File) (*os.File, error) {
 	return tempFileEnc, nil
 }
 
+// compressChunkFile compresses the chunk file with the compression algorithm of the file and returns a
+// temporary file with the compressed content. The chunk file is removed afterwards
+func compressChunkFile(file *os.File, metadata *models.File) (*os.File, error) {
+	defer func() {
+		_ = file.Close()
+		err := os.Remove(file.Name())
+		if err != nil {
+			fmt.Println("Warning: cannot remove uncompressed file")
+			fmt.Println(err)
+		}
+	}()
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	tempFileCompressed, err := os.CreateTemp(configuration.GetTempDir(), "upload")
+	if err != nil {
+		return nil, err
+	}
+	size, err := compression.Compress(metadata.Compression, file, tempFileCompressed)
+	if err != nil {
+		_ = tempFileCompressed.Close()
+		_ = os.Remove(tempFileCompressed.Name())
+		return nil, err
+	}
+	metadata.CompressedBytes = size
+	return tempFileCompressed, nil
+}
+
 // FormatTimestamp converts a timestamp to a string in the format YYYY-MM-DD HH:MM
 func FormatTimestamp(timestamp int64) string {
 	return time.Unix(timestamp, 0).Format("2006-01-02 15:04")
@@ -296,6 +431,7 @@ func createNewMetaData(hash string, fileHeader chunking.FileHeader, userId int,
 		Id:                 createNewId(),
 		Name:               fileHeader.Filename,
 		SHA1:               hash,
+		HashAlgorithm:      configuration.Get().HashAlgorithm,
 		Size:               helper.ByteCountSI(fileHeader.Size),
 		SizeBytes:          fileHeader.Size,
 		ContentType:        fileHeader.ContentType,
@@ -312,16 +448,76 @@ func createNewMetaData(hash string, fileHeader chunking.FileHeader, userId int,
 		file.Encryption = models.EncryptionInfo{IsEndToEndEncrypted: true, IsEncrypted: true}
 		file.Size = helper.ByteCountSI(uploadRequest.RealSize)
 	}
-	if isEncryptionRequested() {
+	addStorageLocation(&file, uploadRequest.StorageTarget)
+	if isEncryptionRequested(file) {
 		file.Encryption.IsEncrypted = true
 	}
+	file.Compression = getCompressionAlgorithm(file)
+	AddHotlink(&file)
+	return file
+}
+
+// getCompressionAlgorithm returns the algorithm the file is compressed with before it is stored.
+// Returns an empty string if the file is not compressed
+func getCompressionAlgorithm(file models.File) string {
+	algorithm := configuration.Get().Compression
+	// Files that are decrypted by the browser cannot be decompressed
+	if algorithm == "" || file.RequiresClientDecryption() {
+		return ""
+	}
+	if !compression.IsCompressibleType(file.ContentType, configuration.Get().CompressionTypes) {
+		return ""
+	}
+	return algorithm
+}
+
+// addStorageLocation marks the file to be stored on the requested storage target or the default storage
+func addStorageLocation(file *models.File, storageTarget string) {
+	if configuration.Get().PicturesAlwaysLocal && isPictureFile(file.Name) {
+		return
+	}
+	if !filesystem.ApplyTarget(storageTarget, file) {
+		addDefaultStorage(file)
+	}
+}
+
+// addDefaultStorage marks the file to be stored on the default storage of the instance
+func addDefaultStorage(file *models.File) {
 	if aws.IsAvailable() {
-		if !configuration.Get().PicturesAlwaysLocal || !isPictureFile(file.Name) {
-			aws.AddBucketName(&file)
+		aws.AddBucketName(file)
+	} else if webdav.IsAvailable() {
+		webdav.AddStorageDriver(file)
+	} else if sftp.IsAvailable() {
+		sftp.AddStorageDriver(file)
+	}
+}
+
+// GetStorageTargetForUpload returns the name of the storage target a new file of the user is stored on.
+// If no target is requested, the default target of the user is used. An empty string is returned
+// if the file is stored on the default storage of the instance
+func GetStorageTargetForUpload(user models.User, requestedTarget string) (string, error) {
+	if requestedTarget == "" {
+		defaultTarget := user.DefaultStorageTarget
+		if defaultTarget == "" || !filesystem.IsValidTarget(defaultTarget) || !user.IsAllowedStorageTarget(defaultTarget) {
+			return "", nil
 		}
+		return defaultTarget, nil
 	}
-	AddHotlink(&file)
-	return file
+	if !filesystem.IsValidTarget(requestedTarget) || !user.IsAllowedStorageTarget(requestedTarget) {
+		return "", ErrorInvalidStorageTarget
+	}
+	return requestedTarget, nil
+}
+
+// GetStorageTargetsForUser returns the names of all storage targets the user is allowed to store new files on
+func GetStorageTargetsForUser(user models.User) []string {
+	result := make([]string, 0)
+	for _, target := range filesystem.GetTargetNames() {
+		if user.IsAllowedStorageTarget(target) {
+			result = append(result, target)
+		}
+	}
+	return result
 }
 
 // createNewId returns a random ID
@@ -329,20 +525,50 @@ func createNewId() string {
 	return helper.GenerateRandomString(configuration.Get().LengthId)
 }
 
-func getEncInfoFromExistingFile(hash string) (models.EncryptionInfo, bool) {
+func getEncInfoFromExistingFile(file models.File) (models.EncryptionInfo, bool) {
 	encryptionLevel := configuration.Get().Encryption.Level
 	if encryptionLevel == encryption.NoEncryption || encryptionLevel == encryption.EndToEndEncryption {
 		return models.EncryptionInfo{}, true
 	}
 	allFiles := database.GetAllMetadata()
 	for _, existingFile := range allFiles {
-		if existingFile.SHA1 == hash {
+		if existingFile.SHA1 == file.SHA1 && isSameStorageLocation(existingFile, file) {
 			return existingFile.Encryption, true
 		}
 	}
 	return models.EncryptionInfo{}, false
 }
 
+// isSameStorageLocation returns true if both files are stored on the same storage
+func isSameStorageLocation(file1, file2 models.File) bool {
+	return filesystem.GetForFile(file1) == filesystem.GetForFile(file2) && file1.AwsBucket == file2.AwsBucket
+}
+
+// getSiblingGroups returns all files for which isIncluded returns true, grouped by the stored content.
+// Files of a group are deduplicated and share the same stored file
+func getSiblingGroups(isIncluded func(file models.File) bool) [][]models.File {
+	result := make([][]models.File, 0)
+	groupIndex := make(map[string][]int)
+	for _, file := range database.GetAllMetadata() {
+		if file.SHA1 == "" || !isIncluded(file) {
+			continue
+		}
+		isAdded := false
+		for _, index := range groupIndex[file.SHA1] {
+			if isSameStorageLocation(result[index][0], file) {
+				result[index] = append(result[index], file)
+				isAdded = true
+				break
+			}
+		}
+		if !isAdded {
+			groupIndex[file.SHA1] = append(groupIndex[file.SHA1], len(result))
+			result = append(result, []models.File{file})
+		}
+	}
+	return result
+}
+
 func deleteTempFile(file *os.File, hasBeenRenamed *bool) {
 	if file != nil && !*hasBeenRenamed {
 		err := file.Close()
@@ -381,10 +607,15 @@ func ReplaceFile(fileId, newFileContentId string, delete bool) (models.File, err
 	file.Name = newFileContent.Name
 	file.Size = newFileContent.Size
 	file.SHA1 = newFileContent.SHA1
+	file.HashAlgorithm = newFileContent.HashAlgorithm
 	file.ContentType = newFileContent.ContentType
 	file.AwsBucket = newFileContent.AwsBucket
 	file.SizeBytes = newFileContent.SizeBytes
 	file.Encryption = newFileContent.Encryption
+	file.Compression = newFileContent.Compression
+	file.CompressedBytes = newFileContent.CompressedBytes
+	file.ArchiveContent = newFileContent.ArchiveContent
+	file.HasThumbnail = newFileContent.HasThumbnail
 	database.SaveMetaData(file)
 	if delete {
 		DeleteFile(newFileContent.Id, false)
@@ -437,8 +668,9 @@ func DuplicateFile(file models.File, parametersToChange int, newFileName string,
 	return newFile, nil
 }
 
+// hashFile returns the hash of the input, created with the configured hash algorithm
 func hashFile(input io.Reader, useSalt bool) (string, error) {
-	hash := sha1.New()
+	hash := hashing.New(configuration.Get().HashAlgorithm)
 	_, err := io.Copy(hash, input)
 	if err != nil {
 		return "", err
@@ -449,25 +681,35 @@ func hashFile(input io.Reader, useSalt bool) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// Generates the SHA1 hash of an uploaded file and returns a reader for the file, the hash and if a temporary file was created the
-// reference to that file.
-func generateHashAndEncrypt(fileContent io.Reader, fileHeader *multipart.FileHeader) (io.Reader, []byte, *os.File, models.EncryptionInfo) {
-	hash := sha1.New()
+// Generates the hash of an uploaded file with the configured hash algorithm and returns a reader for the file, the hash and if a temporary file was created the
+// reference to that file. If the file has a compression algorithm, the content is compressed before encrypting it and the compressed size is returned.
+// The content is only encrypted, if encryption is requested for the storage location of the file
+func generateHashAndEncrypt(fileContent io.Reader, fileHeader *multipart.FileHeader, file models.File) (io.Reader, []byte, *os.File, models.EncryptionInfo, int64) {
+	compressionAlgorithm := file.Compression
+	isEncrypted := isEncryptionRequested(file)
+	hash := hashing.New(configuration.Get().HashAlgorithm)
 	encInfo := models.EncryptionInfo{}
+	var compressedSize int64
 	if fileHeader.Size <= int64(configuration.Get().MaxMemory)*1024*1024 {
 		content, err := io.ReadAll(fileContent)
 		helper.Check(err)
 		hash.Write(content)
-		if isEncryptionRequested() {
+		if compressionAlgorithm != "" {
+			compressedContent := new(bytes.Buffer)
+			compressedSize, err = compression.Compress(compressionAlgorithm, bytes.NewReader(content), compressedContent)
+			helper.Check(err)
+			content = compressedContent.Bytes()
+		}
+		if isEncrypted {
 			encContent := new(bytes.Buffer)
 			err = encryption.Encrypt(&encInfo, bytes.NewReader(content), encContent)
 			helper.Check(err)
 			hash.Write([]byte(configuration.Get().Authentication.SaltFiles))
-			return bytes.NewReader(encContent.Bytes()), hash.Sum(nil), nil, encInfo
+			return bytes.NewReader(encContent.Bytes()), hash.Sum(nil), nil, encInfo, compressedSize
 		}
-		return bytes.NewReader(content), hash.Sum(nil), nil, encInfo
+		return bytes.NewReader(content), hash.Sum(nil), nil, encInfo, compressedSize
 	}
-	tempFile, err := os.CreateTemp(configuration.Get().DataDir, "upload")
+	tempFile, err := os.CreateTemp(configuration.GetTempDir(), "upload")
 	helper.Check(err)
 	var multiWriter io.Writer
 
@@ -477,8 +719,22 @@ func generateHashAndEncrypt(fileContent io.Reader, fileHeader *multipart.FileHea
 	_, err = tempFile.Seek(0, io.SeekStart)
 	helper.Check(err)
 
-	if isEncryptionRequested() {
-		tempFileEnc, err := os.CreateTemp(configuration.Get().DataDir, "upload")
+	if compressionAlgorithm != "" {
+		tempFileCompressed, err := os.CreateTemp(configuration.GetTempDir(), "upload")
+		helper.Check(err)
+		compressedSize, err = compression.Compress(compressionAlgorithm, tempFile, tempFileCompressed)
+		helper.Check(err)
+		err = tempFile.Close()
+		helper.Check(err)
+		err = os.Remove(tempFile.Name())
+		helper.Check(err)
+		_, err = tempFileCompressed.Seek(0, io.SeekStart)
+		helper.Check(err)
+		tempFile = tempFileCompressed
+	}
+
+	if isEncrypted {
+		tempFileEnc, err := os.CreateTemp(configuration.GetTempDir(), "upload")
 		helper.Check(err)
 		err = encryption.Encrypt(&encInfo, tempFile, tempFileEnc)
 		helper.Check(err)
@@ -488,15 +744,17 @@ func generateHashAndEncrypt(fileContent io.Reader, fileHeader *multipart.FileHea
 		tempFile = tempFileEnc
 	}
 	// Instead of returning a reference to the file as the 3rd result, one could use reflections. However, that would be more expensive.
-	return tempFile, hash.Sum(nil), tempFile, encInfo
+	return tempFile, hash.Sum(nil), tempFile, encInfo, compressedSize
 }
 
-func isEncryptionRequested() bool {
+// isEncryptionRequested returns true, if the content of the file is encrypted before it is stored.
+// With local encryption, only files that are stored by a local driver are encrypted
+func isEncryptionRequested(file models.File) bool {
 	switch configuration.Get().Encryption.Level {
 	case encryption.NoEncryption:
 		return false
 	case encryption.LocalEncryptionStored, encryption.LocalEncryptionInput:
-		return !aws.IsAvailable()
+		return filesystem.GetForFile(file).GetSystemName() == interfaces.DriverLocal
 	case encryption.FullEncryptionStored, encryption.FullEncryptionInput:
 		return true
 	case encryption.EndToEndEncryption:
@@ -559,7 +817,7 @@ func isVideoFile(filename string) bool {
 	return helper.IsInArray(videoFileExtensions, extension)
 }
 
-// GetFile gets the file by id. Returns (empty File, false) if invalid / expired file
+// GetFile gets the file by id. Returns (empty File, false) if invalid / expired / quarantined file
 // or (file, true) if valid file
 func GetFile(id string) (models.File, bool) {
 	var emptyResult = models.File{}
@@ -573,7 +831,10 @@ func GetFile(id string) (models.File, bool) {
 	if IsExpiredFile(file, time.Now().Unix()) {
 		return emptyResult, false
 	}
-	if !FileExists(file, configuration.Get().DataDir) {
+	if file.IsQuarantined {
+		return emptyResult, false
+	}
+	if !FileExists(file) {
 		return emptyResult, false
 	}
 	return file, true
@@ -593,73 +854,178 @@ func GetFileByHotlink(id string) (models.File, bool) {
 	return GetFile(fileId)
 }
 
-// ServeFile subtracts a download allowance and serves the file to the browser
-func ServeFile(file models.File, w http.ResponseWriter, r *http.Request, forceDownload bool) {
-	file.DownloadsRemaining = file.DownloadsRemaining - 1
-	file.DownloadCount = file.DownloadCount + 1
-	database.IncreaseDownloadCount(file.Id, !file.UnlimitedDownloads)
-	logging.LogDownload(file, r, configuration.Get().SaveIp)
-	go sse.PublishDownloadCount(file)
+// ErrorTooManyDownloads is returned, if the file or the client already has the maximum number of simultaneous downloads
+var ErrorTooManyDownloads = errors.New("too many simultaneous downloads")
 
-	if !file.IsLocalStorage() {
-		// If non-blocking, we are not setting a download complete status as there is no reliable way to
-		// confirm that the file has been completely downloaded. It expires automatically after 24 hours.
-		statusId := downloadstatus.SetDownload(file)
-		isBlocking, err := aws.ServeFile(w, r, file, forceDownload)
-		helper.Check(err)
+// RetryAfterTooManyDownloads is the number of seconds after which a client should retry a download,
+// if the maximum number of simultaneous downloads has been reached
+const RetryAfterTooManyDownloads = "30"
+
+// ServeFile subtracts a download allowance and serves the file to the browser. If the file or the client
+// already has the maximum number of simultaneous downloads, ErrorTooManyDownloads is returned before
+// the download allowance is subtracted
+func ServeFile(file models.File, w http.ResponseWriter, r *http.Request, forceDownload bool) error {
+	maxFile, maxClient := getParallelDownloadLimits(file)
+	statusId, ok := downloadstatus.SetDownloadIfAllowed(file, logging.GetIpAddress(r), maxFile, maxClient)
+	if !ok {
+		return ErrorTooManyDownloads
+	}
+	event := newDownloadEvent(file, r)
+	counter := newCountingResponseWriter(w)
+	// If non-blocking, we are not setting a download complete status as there is no reliable way to
+	// confirm that the file has been completely downloaded. It expires automatically after 24 hours.
+	isBlocking := true
+	defer func() {
 		if isBlocking {
 			downloadstatus.SetComplete(statusId)
+		} else {
+			downloadstatus.SetRedirected(statusId)
 		}
-		return
+		saveDownloadEvent(event, counter.bytesWritten, r, isBlocking && !counter.hasFailed)
+	}()
+
+	file.DownloadsRemaining = file.DownloadsRemaining - 1
+	file.DownloadCount = database.IncreaseDownloadCount(file.Id, !file.UnlimitedDownloads)
+	logging.LogDownload(file, r, configuration.Get().SaveIp)
+	go sse.PublishDownloadCount(file)
+	notifications.SendDownloadNotification(file, event)
+	w, releaseBandwidth := bandwidth.LimitWriter(counter, file.Id, getBandwidthLimits(file))
+	defer releaseBandwidth()
+
+	if file.Compression != "" {
+		serveCompressedFile(file, w, r, forceDownload)
+		return nil
 	}
-	fileData, size := getFileHandler(file, configuration.Get().DataDir)
 	if file.Encryption.IsEncrypted && !file.RequiresClientDecryption() {
+		serveDecryptedFile(file, w, r, forceDownload)
+		return nil
+	}
+	var err error
+	isBlocking, err = filesystem.GetForFile(file).ServeFile(w, r, file, forceDownload)
+	helper.Check(err)
+	return nil
+}
+
+// getParallelDownloadLimits returns the maximum number of simultaneous downloads for the file
+// and for a single client. A limit that has been set for the file replaces the default limit
+func getParallelDownloadLimits(file models.File) (int, int) {
+	maxFile := configuration.Get().ParallelDownloads
+	if file.ParallelDownloads > 0 {
+		maxFile = file.ParallelDownloads
+	}
+	return maxFile, configuration.Get().ParallelDownloadIp
+}
+
+// getBandwidthLimits returns the bandwidth limits for downloading the file. A limit that
+// has been set for the file replaces the default limit per file
+func getBandwidthLimits(file models.File) bandwidth.Limits {
+	config := configuration.Get()
+	limits := bandwidth.Limits{
+		Global:   config.BandwidthGlobalKB,
+		File:     config.BandwidthFileKB,
+		Download: config.BandwidthRequestKB,
+	}
+	if file.BandwidthLimitKB > 0 {
+		limits.File = file.BandwidthLimitKB
+	}
+	return limits
+}
+
+// serveDecryptedFile reads a file that was encrypted on the server from its storage and
+// decrypts it while sending it to the client. If the storage allows random access, single
+// range requests are served, so that downloads can be resumed
+func serveDecryptedFile(file models.File, w http.ResponseWriter, r *http.Request, forceDownload bool) {
+	fileData, err := filesystem.GetForFile(file).OpenFile(file, 0, -1)
+	helper.Check(err)
+	defer fileData.Close()
+	if !encryption.IsCorrectKey(file.Encryption, fileData) {
+		w.Write([]byte("Internal error - Error decrypting file, source data might be damaged or an incorrect key has been used"))
+		return
+	}
+	headers.Write(file, w, forceDownload)
+	encryptedContent, ok := fileData.(io.ReaderAt)
+	if ok {
+		serveDecryptedRange(file, w, r, encryptedContent)
+		return
+	}
+	w.Header().Set("Accept-Ranges", "none")
+	w.Header().Set("Content-Length", strconv.FormatInt(file.SizeBytes, 10))
+	err = encryption.DecryptReader(file.Encryption, fileData, w)
+	if err != nil {
+		w.Write([]byte("Error decrypting file"))
+		fmt.Println(err)
+	}
+}
+
+// serveDecryptedRange sends the decrypted content, or the requested range of it, to the client.
+// Only the encrypted packages that contain the requested range are read and decrypted.
+// Multiple ranges in one request are answered with the complete content
+func serveDecryptedRange(file models.File, w http.ResponseWriter, r *http.Request, encryptedContent io.ReaderAt) {
+	decrypted, err := encryption.GetDecryptReaderAt(file.Encryption, encryptedContent)
+	helper.Check(err)
+	if strings.Contains(r.Header.Get("Range"), ",") {
+		r.Header.Del("Range")
+	}
+	// The hash of the content is used as ETag, so that If-Range can be validated
+	w.Header().Set("ETag", "\""+file.SHA1+"\"")
+	http.ServeContent(w, r, file.Name, time.Unix(file.UploadDate, 0), io.NewSectionReader(decrypted, 0, file.SizeBytes))
+}
+
+// serveCompressedFile reads a file that was compressed on the server from its storage and sends it to the browser.
+// If the client accepts the compression algorithm, the compressed content is sent, otherwise it is decompressed
+func serveCompressedFile(file models.File, w http.ResponseWriter, r *http.Request, forceDownload bool) {
+	fileData, err := filesystem.GetForFile(file).OpenFile(file, 0, -1)
+	helper.Check(err)
+	defer fileData.Close()
+	var content io.Reader = fileData
+	if file.Encryption.IsEncrypted {
 		if !encryption.IsCorrectKey(file.Encryption, fileData) {
 			w.Write([]byte("Internal error - Error decrypting file, source data might be damaged or an incorrect key has been used"))
 			return
 		}
+		cipher, err := encryption.GetCipherFromFile(file.Encryption)
+		helper.Check(err)
+		content, err = encryption.GetDecryptReader(cipher, fileData)
+		helper.Check(err)
 	}
-	statusId := downloadstatus.SetDownload(file)
 	headers.Write(file, w, forceDownload)
-	if file.Encryption.IsEncrypted && !file.RequiresClientDecryption() {
-		err := encryption.DecryptReader(file.Encryption, fileData, w)
+	// Ranges of the original content cannot be served from the compressed content
+	w.Header().Set("Accept-Ranges", "none")
+	w.Header().Set("Vary", "Accept-Encoding")
+	if compression.IsAcceptedByClient(file.Compression, r) {
+		w.Header().Set("Content-Encoding", file.Compression)
+		w.Header().Set("Content-Length", strconv.FormatInt(file.CompressedBytes, 10))
+	} else {
+		decompressed, err := compression.GetDecompressReader(file.Compression, content)
 		if err != nil {
-			w.Write([]byte("Error decrypting file"))
+			w.Write([]byte("Error decompressing file"))
 			fmt.Println(err)
 			return
 		}
-	} else {
-		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
-		http.ServeContent(w, r, file.Name, time.Now(), fileData)
+		defer decompressed.Close()
+		content = decompressed
+		w.Header().Set("Content-Length", strconv.FormatInt(file.SizeBytes, 10))
+	}
+	_, err = io.Copy(w, content)
+	if err != nil {
+		fmt.Println(err)
 	}
-	downloadstatus.SetComplete(statusId)
-}
-
-func getFileHandler(file models.File, dataDir string) (*os.File, int64) {
-	storageData, err := os.OpenFile(dataDir+"/"+file.SHA1, os.O_RDONLY, 0644)
-	helper.Check(err)
-	size, err := helper.GetFileSize(storageData)
-	helper.Check(err)
-	return storageData, size
 }
 
-// FileExists checks if the file exists locally or in S3
-func FileExists(file models.File, dataDir string) bool {
-	if !file.IsLocalStorage() {
-		exists, size, err := aws.FileExists(file)
-		if err != nil {
-			fmt.Println("Warning, cannot check file " + file.Id + ": " + err.Error())
-			return true
-		}
-		if !exists {
-			return false
-		}
-		if size == 0 && file.Size != "0 B" {
-			return false
-		}
+// FileExists checks if the file exists on the filesystem it is stored on
+func FileExists(file models.File) bool {
+	exists, size, err := filesystem.GetForFile(file).StatFile(file)
+	if err != nil {
+		fmt.Println("Warning, cannot check file " + file.Id + ": " + err.Error())
 		return true
 	}
-	return helper.FileExists(dataDir + "/" + file.SHA1)
+	if !exists {
+		return false
+	}
+	if size == 0 && file.Size != "0 B" {
+		return false
+	}
+	return true
 }
 
 // CleanUp removes expired files from the config and from the filesystem if they are not referenced by other files anymore
@@ -670,21 +1036,22 @@ func CleanUp(periodic bool) {
 	timeNow := time.Now().Unix()
 	wasItemDeleted := false
 	for key, element := range database.GetAllMetadata() {
-		fileExists := FileExists(element, configuration.Get().DataDir)
+		fileExists := FileExists(element)
 		if !fileExists || isExpiredFileWithoutDownload(element, timeNow) || isPendingToBeDeleted(element, timeNow) {
 			deleteFile := true
 			for _, secondLoopElement := range database.GetAllMetadata() {
-				if (element.Id != secondLoopElement.Id) && (element.SHA1 == secondLoopElement.SHA1) {
+				if (element.Id != secondLoopElement.Id) && (element.SHA1 == secondLoopElement.SHA1) && isSameStorageLocation(element, secondLoopElement) {
 					deleteFile = false
 					break
 				}
 			}
 			if deleteFile && fileExists {
-				deleteSource(element, configuration.Get().DataDir)
+				deleteSource(element)
 			}
 			if element.HotlinkId != "" {
 				database.DeleteHotlink(element.HotlinkId)
 			}
+			sendRemovalNotification(element, timeNow)
 			database.DeleteMetaData(key)
 			wasItemDeleted = true
 		}
@@ -693,7 +1060,10 @@ func CleanUp(periodic bool) {
 		CleanUp(false)
 	}
 	cleanOldTempFiles()
+	chunking.AbortStaleMultipartUploads()
 	cleanHotlinks()
+	cleanExpiredBundles()
+	cleanDownloadEvents(timeNow)
 	database.RunGarbageCollection()
 
 	if periodic {
@@ -719,27 +1089,33 @@ func cleanHotlinks() {
 
 // cleanOldTempFiles removes temporary chunk or upload files that are older than 24 hours
 func cleanOldTempFiles() {
-	tmpfiles, err := os.ReadDir(configuration.Get().DataDir)
+	tmpfiles, err := os.ReadDir(configuration.GetTempDir())
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 	for _, file := range tmpfiles {
 		if isOldTempFile(file) {
-			err = os.Remove(configuration.Get().DataDir + "/" + file.Name())
+			err = os.Remove(configuration.GetTempDir() + "/" + file.Name())
 			if err != nil {
 				fmt.Println(err)
 			}
+			diskspace.Release(strings.TrimPrefix(file.Name(), "chunk-"))
 		}
 	}
 }
 
-// isOldTempFile returns true if a file is older than 24 hours and starts with the name upload or chunk
-func isOldTempFile(file os.DirEntry) bool {
+// isTempFile returns true if a file starts with the name upload or chunk
+func isTempFile(file os.DirEntry) bool {
 	if file.IsDir() {
 		return false
 	}
-	if !strings.HasPrefix(file.Name(), "upload") && !strings.HasPrefix(file.Name(), "chunk-") {
+	return strings.HasPrefix(file.Name(), "upload") || strings.HasPrefix(file.Name(), "chunk-")
+}
+
+// isOldTempFile returns true if a file is older than 24 hours and starts with the name upload or chunk
+func isOldTempFile(file os.DirEntry) bool {
+	if !isTempFile(file) {
 		return false
 	}
 	info, err := file.Info()
@@ -765,6 +1141,22 @@ func IsExpiredFile(file models.File, timeNow int64) bool {
 		(file.DownloadsRemaining < 1 && !file.UnlimitedDownloads)
 }
 
+// isDeletedByUser returns true if the file has been deleted with DeleteFile, which sets the expiry to 0
+func isDeletedByUser(file models.File) bool {
+	return file.ExpireAt == 0 && !file.UnlimitedTime
+}
+
+// sendRemovalNotification notifies the uploader, if the file is removed because it has been deleted or has expired
+func sendRemovalNotification(file models.File, timeNow int64) {
+	if isDeletedByUser(file) || isPendingToBeDeleted(file, timeNow) {
+		notifications.SendDeletionNotification(file)
+		return
+	}
+	if IsExpiredFile(file, timeNow) {
+		notifications.SendExpiryNotification(file)
+	}
+}
+
 // isExpiredFileWithoutDownload returns true if there is no active download for an expired file
 func isExpiredFileWithoutDownload(file models.File, timeNow int64) bool {
 	if downloadstatus.IsCurrentlyDownloading(file) {
@@ -774,13 +1166,9 @@ func isExpiredFileWithoutDownload(file models.File, timeNow int64) bool {
 }
 
 // deleteSource removes the source file from the file system or cloud storage.
-func deleteSource(file models.File, dataDir string) {
-	var err error
-	if !file.IsLocalStorage() {
-		_, err = aws.DeleteObject(file)
-	} else {
-		err = os.Remove(dataDir + "/" + file.SHA1)
-	}
+func deleteSource(file models.File) {
+	deleteThumbnail(file)
+	err := filesystem.GetForFile(file).DeleteFile(file)
 	if err != nil {
 		fmt.Println("Warning, cannot delete file " + file.Id + ": " + err.Error())
 	}
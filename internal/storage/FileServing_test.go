@@ -2,23 +2,35 @@ package storage
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
 	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/encryption"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
 	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/notifications"
 	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/compression"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"github.com/forceu/gokapi/internal/webserver/bandwidth"
 	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
 	"net/textproto"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -72,7 +84,7 @@ func TestGetFile(t *testing.T) {
 
 func TestGetEncInfoFromExistingFile(t *testing.T) {
 	configuration.Get().Encryption.Level = 0
-	_, result := getEncInfoFromExistingFile("testhash")
+	_, result := getEncInfoFromExistingFile(models.File{SHA1: "testhash"})
 	test.IsEqualBool(t, result, true)
 	file := models.File{
 		Id:   "testhash",
@@ -87,14 +99,16 @@ func TestGetEncInfoFromExistingFile(t *testing.T) {
 		UnlimitedTime:      true,
 	}
 	database.SaveMetaData(file)
-	encinfo, result := getEncInfoFromExistingFile("testhash")
+	encinfo, result := getEncInfoFromExistingFile(models.File{SHA1: "testhash"})
 	test.IsEqualBool(t, encinfo.IsEncrypted, false)
 	test.IsEqualBool(t, result, true)
 	configuration.Get().Encryption.Level = 1
-	encinfo, result = getEncInfoFromExistingFile("testhash")
+	encinfo, result = getEncInfoFromExistingFile(models.File{SHA1: "testhash"})
 	test.IsEqualBool(t, result, true)
 	test.IsEqualBool(t, encinfo.IsEncrypted, true)
-	_, result = getEncInfoFromExistingFile("testhashinvalid")
+	_, result = getEncInfoFromExistingFile(models.File{SHA1: "testhash", AwsBucket: "otherbucket"})
+	test.IsEqualBool(t, result, false)
+	_, result = getEncInfoFromExistingFile(models.File{SHA1: "testhashinvalid"})
 	test.IsEqualBool(t, result, false)
 	configuration.Get().Encryption.Level = 0
 }
@@ -180,7 +194,7 @@ func createTestChunk() (string, chunking.FileHeader, models.UploadRequest, error
 		ContentType: header.Header.Get("Content-Type"),
 		Size:        header.Size,
 	}
-	err := os.WriteFile("test/data/chunk-"+chunkId, content, 0600)
+	err := os.WriteFile("test/data/tmp/chunk-"+chunkId, content, 0600)
 	if err != nil {
 		return "", chunking.FileHeader{}, models.UploadRequest{}, err
 	}
@@ -198,7 +212,7 @@ func TestNewFile(t *testing.T) {
 	retrievedFile, ok := database.GetMetaDataById(file.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualString(t, retrievedFile.Name, "test.dat")
-	test.IsEqualString(t, retrievedFile.SHA1, "f1474c19eff0fc8998fa6e1b1f7bf31793b103a6")
+	test.IsEqualString(t, retrievedFile.SHA1, "6126d84e704140deca66d08f0b79d9b24d05e628e72f8236b5ed81a7c4a5873b")
 	test.IsEqualString(t, retrievedFile.HotlinkId, "")
 	test.IsEqualString(t, retrievedFile.PasswordHash, "")
 	test.IsEqualString(t, retrievedFile.Size, "35 B")
@@ -253,13 +267,13 @@ func TestNewFile(t *testing.T) {
 	retrievedFile, ok = database.GetMetaDataById(file.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualString(t, retrievedFile.Name, "bigfile")
-	test.IsEqualString(t, retrievedFile.SHA1, "9674344c90c2f0646f0b78026e127c9b86e3ad77")
+	test.IsEqualString(t, retrievedFile.SHA1, "cd52d81e25f372e6fa4db2c0dfceb59862c1969cab17096da352b34950c973cc")
 	test.IsEqualString(t, retrievedFile.Size, "20.0 MB")
 	_, err = bigFile.Seek(0, io.SeekStart)
 	test.IsNil(t, err)
 	// Testing removal of temp file
 	test.IsEqualString(t, retrievedFile.Name, "bigfile")
-	test.IsEqualString(t, retrievedFile.SHA1, "9674344c90c2f0646f0b78026e127c9b86e3ad77")
+	test.IsEqualString(t, retrievedFile.SHA1, "cd52d81e25f372e6fa4db2c0dfceb59862c1969cab17096da352b34950c973cc")
 	test.IsEqualString(t, retrievedFile.Size, "20.0 MB")
 	bigFile.Close()
 	os.Remove("bigfile")
@@ -301,7 +315,7 @@ func TestNewFile(t *testing.T) {
 	test.IsNil(t, err)
 	retrievedFile, ok = database.GetMetaDataById(newFile.File.Id)
 	test.IsEqualBool(t, ok, true)
-	test.IsEqualString(t, retrievedFile.SHA1, "5bbfa18805eb12c678cfd284c956718d57039e37")
+	test.IsEqualString(t, retrievedFile.SHA1, "e41fe128892c6ef57f0ce13f9351e3c916bd1cde3459014df424c7a33faf1c14")
 
 	createBigFile("bigfile", 20)
 	header.Size = int64(20) * 1024 * 1024
@@ -311,7 +325,7 @@ func TestNewFile(t *testing.T) {
 	retrievedFile, ok = database.GetMetaDataById(file.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualString(t, retrievedFile.Name, "bigfile")
-	test.IsEqualString(t, retrievedFile.SHA1, "c1c165c30d0def15ba2bc8f1bd243be13b8c8fe7")
+	test.IsEqualString(t, retrievedFile.SHA1, "8cb03be5a20ec0bb3e2a9c5599a848c65d864b2d1898ce84bf9fd7fcbdcba92b")
 
 	bigFile.Close()
 	database.DeleteMetaData(retrievedFile.Id)
@@ -348,21 +362,21 @@ func TestNewFile(t *testing.T) {
 		retrievedFile, ok = database.GetMetaDataById(file.Id)
 		test.IsEqualBool(t, ok, true)
 		test.IsEqualString(t, retrievedFile.Name, "bigfile")
-		test.IsEqualString(t, retrievedFile.SHA1, "f1474c19eff0fc8998fa6e1b1f7bf31793b103a6")
+		test.IsEqualString(t, retrievedFile.SHA1, "6126d84e704140deca66d08f0b79d9b24d05e628e72f8236b5ed81a7c4a5873b")
 		test.IsEqualString(t, retrievedFile.Size, "20.0 MB")
 		testconfiguration.DisableS3()
 	}
 }
 
 func TestNewFileFromChunk(t *testing.T) {
-	test.FileDoesNotExist(t, "test/data/6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
+	test.FileDoesNotExist(t, "test/data/blobs/62/29/62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
 	id, header, request, err := createTestChunk()
 	test.IsNil(t, err)
 	file, err := NewFileFromChunk(id, header, 99, request)
 	test.IsNil(t, err)
 	test.IsEqualString(t, file.Name, "test.dat")
 	test.IsEqualString(t, file.Size, "41 B")
-	test.IsEqualString(t, file.SHA1, "6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
+	test.IsEqualString(t, file.SHA1, "62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
 	test.IsEqualString(t, file.ExpireAtString, "2038-01-19 03:13")
 	test.IsEqualInt64(t, file.ExpireAt, 2147483600)
 	test.IsEqualInt(t, file.DownloadsRemaining, 1)
@@ -372,8 +386,8 @@ func TestNewFileFromChunk(t *testing.T) {
 	test.IsEqualString(t, file.ContentType, "text/plain")
 	test.IsEqualBool(t, file.UnlimitedTime, false)
 	test.IsEqualBool(t, file.UnlimitedDownloads, false)
-	test.FileExists(t, "test/data/6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
-	test.FileDoesNotExist(t, "test/data/chunk-"+id)
+	test.FileExists(t, "test/data/blobs/62/29/62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+id)
 	retrievedFile, ok := database.GetMetaDataById(file.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqual(t, file, retrievedFile)
@@ -387,7 +401,7 @@ func TestNewFileFromChunk(t *testing.T) {
 	test.IsNil(t, err)
 	test.IsEqualString(t, file.Name, "newfile")
 	test.IsEqualString(t, file.Size, "41 B")
-	test.IsEqualString(t, file.SHA1, "6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
+	test.IsEqualString(t, file.SHA1, "62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
 	test.IsEqualString(t, file.ExpireAtString, "2038-01-19 03:13")
 	test.IsEqualInt64(t, file.ExpireAt, 2147483600)
 	test.IsEqualInt(t, file.DownloadsRemaining, 1)
@@ -397,14 +411,14 @@ func TestNewFileFromChunk(t *testing.T) {
 	test.IsEqualString(t, file.ContentType, "text/plain")
 	test.IsEqualBool(t, file.UnlimitedTime, true)
 	test.IsEqualBool(t, file.UnlimitedDownloads, true)
-	test.FileExists(t, "test/data/6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
-	test.FileDoesNotExist(t, "test/data/chunk-"+id)
+	test.FileExists(t, "test/data/blobs/62/29/62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+id)
 	retrievedFile, ok = database.GetMetaDataById(file.Id)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqual(t, file, retrievedFile)
 	withinLastSecond := file.UploadDate >= time.Now().Add(-1*time.Second).Unix() && file.UploadDate <= time.Now().Unix()
 	test.IsEqualBool(t, withinLastSecond, true)
-	err = os.Remove("test/data/6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
+	err = os.Remove("test/data/blobs/62/29/62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
 	test.IsNil(t, err)
 
 	_, err = NewFileFromChunk("invalid", header, 99, request)
@@ -429,7 +443,7 @@ func TestNewFileFromChunk(t *testing.T) {
 		file, err = NewFileFromChunk(id, header, 99, request)
 		test.IsNil(t, err)
 		test.IsEqualBool(t, file.AwsBucket != "", true)
-		test.IsEqualString(t, file.SHA1, "6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
+		test.IsEqualString(t, file.SHA1, "62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
 		retrievedFile, ok = database.GetMetaDataById(file.Id)
 		test.IsEqual(t, file, retrievedFile)
 		test.IsEqualBool(t, ok, true)
@@ -437,6 +451,182 @@ func TestNewFileFromChunk(t *testing.T) {
 	}
 }
 
+func uploadMultipartTestChunks(t *testing.T, content []byte, uuid string) (chunking.FileHeader, models.UploadRequest) {
+	chunkSize := len(content)/2 + 1
+	for offset := 0; offset < len(content); offset = offset + chunkSize {
+		end := min(offset+chunkSize, len(content))
+		err := chunking.NewChunk(bytes.NewReader(content[offset:end]), &multipart.FileHeader{Size: int64(end - offset)}, chunking.ChunkInfo{
+			TotalFilesizeBytes: int64(len(content)),
+			Offset:             int64(offset),
+			UUID:               uuid,
+		})
+		test.IsNil(t, err)
+	}
+	test.IsEqualBool(t, chunking.IsMultipartUpload(uuid), true)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid)
+	header := chunking.FileHeader{
+		Filename:    "multipart.dat",
+		ContentType: "text/plain",
+		Size:        int64(len(content)),
+	}
+	request := models.UploadRequest{
+		AllowedDownloads: 1,
+		Expiry:           999,
+		ExpiryTimestamp:  2147483600,
+	}
+	return header, request
+}
+
+func TestNewFileFromChunkChecksum(t *testing.T) {
+	id, header, request, err := createTestChunk()
+	test.IsNil(t, err)
+	request.Checksum = "crc32c:00000000"
+	_, err = NewFileFromChunk(id, header, 99, request)
+	test.IsEqual(t, err, chunking.ErrorChecksumMismatch)
+	// The chunk file is kept, so that the upload can be repaired
+	test.FileExists(t, "test/data/tmp/chunk-"+id)
+
+	request.Checksum = "crc32c:1fa935a1"
+	file, err := NewFileFromChunk(id, header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.SHA1, "62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33")
+	database.DeleteMetaData(file.Id)
+
+	id, header, request, err = createTestChunk()
+	test.IsNil(t, err)
+	request.Checksum = "sha256:62292dca557ae5e7c51c98ddfeae8f501e03a3baa908ffe4c1499a2cd3032b33"
+	file, err = NewFileFromChunk(id, header, 99, request)
+	test.IsNil(t, err)
+	database.DeleteMetaData(file.Id)
+	deleteSource(file)
+
+	id, header, request, err = createTestChunk()
+	test.IsNil(t, err)
+	request.Checksum = "invalid"
+	_, err = NewFileFromChunk(id, header, 99, request)
+	test.IsEqual(t, err, chunking.ErrorInvalidChecksum)
+}
+
+func TestNewFileFromMultipartUpload(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	testconfiguration.EnableS3()
+	config, ok := cloudconfig.Load()
+	test.IsEqualBool(t, ok, true)
+	ok = aws.Init(config.Aws)
+	test.IsEqualBool(t, ok, true)
+	filesystem.SetAws()
+	defer testconfiguration.DisableS3()
+
+	content := []byte(helper.GenerateRandomString(6 * 1024 * 1024))
+	hash := sha256.Sum256(content)
+	expectedHash := hex.EncodeToString(hash[:])
+	tempObject := models.File{AwsBucket: aws.GetDefaultBucketName(), SHA1: "chunk-multipartfile1"}
+
+	header, request := uploadMultipartTestChunks(t, content, "multipartfile1")
+	file, err := NewFileFromChunk("multipartfile1", header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.SHA1, expectedHash)
+	test.IsEqualString(t, file.AwsBucket, aws.GetDefaultBucketName())
+	test.IsEqualString(t, file.Size, "6.0 MB")
+	test.IsEqualBool(t, chunking.IsMultipartUpload("multipartfile1"), false)
+	test.IsEqualBool(t, FileExists(file), true)
+	exists, _, err := aws.FileExists(tempObject)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqual(t, file, retrievedFile)
+
+	// Same content is deduplicated
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile2")
+	file2, err := NewFileFromChunk("multipartfile2", header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file2.SHA1, expectedHash)
+	test.IsEqualBool(t, file.Id != file2.Id, true)
+	exists, _, err = aws.FileExists(models.File{AwsBucket: aws.GetDefaultBucketName(), SHA1: "chunk-multipartfile2"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	database.DeleteMetaData(file.Id)
+	database.DeleteMetaData(file2.Id)
+	deleteSource(file)
+
+	// Files that are stored on a different storage are downloaded again
+	err = filesystem.AddTarget(models.StorageTargetConfig{
+		Name:      "local-fast",
+		Type:      models.StorageTypeLocal,
+		Directory: "test/fast",
+	}, models.AwsConfig{})
+	test.IsNil(t, err)
+	defer filesystem.ClearTargets()
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile3")
+	request.StorageTarget = "local-fast"
+	file, err = NewFileFromChunk("multipartfile3", header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.SHA1, expectedHash)
+	test.IsEqualBool(t, file.IsLocalStorage(), true)
+	test.FileExists(t, getBlobPath("test/fast", expectedHash))
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-multipartfile3")
+	exists, _, err = aws.FileExists(models.File{AwsBucket: aws.GetDefaultBucketName(), SHA1: "chunk-multipartfile3"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	database.DeleteMetaData(file.Id)
+	deleteSource(file)
+
+	// The checksum is compared with the hash of the upload, if both use the same algorithm
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile5")
+	request.Checksum = "sha256:" + strings.Repeat("0", 64)
+	_, err = NewFileFromChunk("multipartfile5", header, 99, request)
+	test.IsEqual(t, err, chunking.ErrorChecksumMismatch)
+	exists, _, err = aws.FileExists(models.File{AwsBucket: aws.GetDefaultBucketName(), SHA1: "chunk-multipartfile5"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile6")
+	request.Checksum = "sha256:" + expectedHash
+	file, err = NewFileFromChunk("multipartfile6", header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, file.IsLocalStorage(), false)
+	database.DeleteMetaData(file.Id)
+	deleteSource(file)
+
+	// Otherwise the content is downloaded and verified locally
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile7")
+	request.Checksum = "crc32c:00000000"
+	_, err = NewFileFromChunk("multipartfile7", header, 99, request)
+	test.IsEqual(t, err, chunking.ErrorChecksumMismatch)
+	chunking.DeleteChunk("multipartfile7")
+
+	header, _ = uploadMultipartTestChunks(t, content, "multipartfile4")
+	header.Size = 100
+	_, err = NewFileFromChunk("multipartfile4", header, 99, request)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, chunking.IsMultipartUpload("multipartfile4"), false)
+
+	// The compression info is copied, if the same content has been stored compressed before
+	configuration.Get().Compression = compression.AlgorithmZstd
+	defer func() { configuration.Get().Compression = "" }()
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile8")
+	header.Filename = "data.csv"
+	file, err = NewFileFromChunk("multipartfile8", header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.Compression, compression.AlgorithmZstd)
+	header, request = uploadMultipartTestChunks(t, content, "multipartfile9")
+	header.Filename = "data.bin"
+	header.ContentType = "application/octet-stream"
+	file2, err = NewFileFromChunk("multipartfile9", header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file2.SHA1, file.SHA1)
+	test.IsEqualString(t, file2.Compression, compression.AlgorithmZstd)
+	test.IsEqualInt64(t, file2.CompressedBytes, file.CompressedBytes)
+	w := httptest.NewRecorder()
+	ServeFile(file2, w, httptest.NewRequest("GET", "/", nil), false)
+	test.IsEqualBool(t, bytes.Equal(w.Body.Bytes(), content), true)
+	database.DeleteMetaData(file.Id)
+	database.DeleteMetaData(file2.Id)
+	deleteSource(file)
+}
+
 func TestDuplicateFile(t *testing.T) {
 
 	tempFile, err := createTestFile()
@@ -566,6 +756,11 @@ func TestServeFile(t *testing.T) {
 	content, err := io.ReadAll(w.Result().Body)
 	test.IsNil(t, err)
 	test.IsEqualString(t, string(content), "This is a file for testing purposes")
+	events := database.GetDownloadEvents(idNewFile)
+	test.IsEqualInt(t, len(events), 1)
+	test.IsEqualInt64(t, events[0].BytesSent, 35)
+	test.IsEqualBool(t, events[0].IsComplete, true)
+	database.DeleteDownloadEvents(idNewFile)
 
 	if aws.IsIncludedInBuild {
 		testconfiguration.EnableS3()
@@ -583,6 +778,9 @@ func TestServeFile(t *testing.T) {
 		} else {
 			test.ResponseBodyContains(t, w, "<a href=\"http")
 		}
+		// Redirected downloads cannot be confirmed as complete
+		events = database.GetDownloadEvents("awsTest1234567890123")
+		test.IsEqualBool(t, events[0].IsComplete, false)
 		testconfiguration.DisableS3()
 	}
 	newFile, err := createTestFile()
@@ -606,6 +804,503 @@ func TestServeFile(t *testing.T) {
 	test.ResponseBodyContains(t, w, "Error decrypting file")
 }
 
+func TestServeFileParallelLimit(t *testing.T) {
+	newFile, err := createTestFile()
+	test.IsNil(t, err)
+	file := newFile.File
+	file.UnlimitedDownloads = false
+	file.DownloadsRemaining = 5
+	file.ParallelDownloads = 1
+	database.SaveMetaData(file)
+
+	statusId, ok := downloadstatus.SetDownloadIfAllowed(file, "192.0.2.1", 0, 0)
+	test.IsEqualBool(t, ok, true)
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	err = ServeFile(file, w, r, true)
+	test.IsEqual(t, err, ErrorTooManyDownloads)
+	test.IsEqualInt(t, w.Body.Len(), 0)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, retrievedFile.DownloadsRemaining, 5)
+	test.IsEqualInt(t, len(database.GetDownloadEvents(file.Id)), 0)
+	downloadstatus.SetComplete(statusId)
+
+	w = httptest.NewRecorder()
+	err = ServeFile(file, w, r, true)
+	test.IsNil(t, err)
+	test.ResponseBodyContains(t, w, "This is a file for testing purposes")
+	test.IsEqualBool(t, downloadstatus.IsCurrentlyDownloading(file), false)
+	retrievedFile, _ = database.GetMetaDataById(file.Id)
+	test.IsEqualInt(t, retrievedFile.DownloadsRemaining, 4)
+
+	// The limit per client applies to all files
+	configuration.Get().ParallelDownloadIp = 1
+	defer func() { configuration.Get().ParallelDownloadIp = 0 }()
+	statusId, ok = downloadstatus.SetDownloadIfAllowed(models.File{Id: "otherfile"}, logging.GetIpAddress(r), 0, 0)
+	test.IsEqualBool(t, ok, true)
+	file.ParallelDownloads = 0
+	err = ServeFile(file, httptest.NewRecorder(), r, true)
+	test.IsEqual(t, err, ErrorTooManyDownloads)
+	downloadstatus.SetComplete(statusId)
+	database.DeleteMetaData(file.Id)
+}
+
+func TestGetParallelDownloadLimits(t *testing.T) {
+	config := configuration.Get()
+	maxFile, maxClient := getParallelDownloadLimits(models.File{})
+	test.IsEqualInt(t, maxFile, 0)
+	test.IsEqualInt(t, maxClient, 0)
+	config.ParallelDownloads = 10
+	config.ParallelDownloadIp = 2
+	defer func() {
+		config.ParallelDownloads = 0
+		config.ParallelDownloadIp = 0
+	}()
+	maxFile, maxClient = getParallelDownloadLimits(models.File{})
+	test.IsEqualInt(t, maxFile, 10)
+	test.IsEqualInt(t, maxClient, 2)
+	maxFile, _ = getParallelDownloadLimits(models.File{ParallelDownloads: 3})
+	test.IsEqualInt(t, maxFile, 3)
+}
+
+func TestCompressedFile(t *testing.T) {
+	configuration.Get().Compression = compression.AlgorithmZstd
+	defer func() { configuration.Get().Compression = "" }()
+	content := []byte(strings.Repeat("This is a file for compression testing\n", 100))
+	header, request := createRawTestFile(content)
+	request.UnlimitedDownload = true
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.Compression, compression.AlgorithmZstd)
+	test.IsEqualInt64(t, file.SizeBytes, int64(len(content)))
+	test.IsEqualBool(t, file.CompressedBytes > 0 && file.CompressedBytes < file.SizeBytes, true)
+	stored, err := os.ReadFile(getBlobPath("test/data", file.SHA1))
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, int64(len(stored)), file.CompressedBytes)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqual(t, file, retrievedFile)
+	_, ok = checkStoredContent(file)
+	test.IsEqualBool(t, ok, true)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, true)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Encoding"), "")
+	test.IsEqualString(t, w.Result().Header.Get("Content-Length"), strconv.Itoa(len(content)))
+	test.IsEqualString(t, w.Result().Header.Get("Accept-Ranges"), "none")
+	result, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(result), string(content))
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip, zstd")
+	w = httptest.NewRecorder()
+	ServeFile(file, w, r, true)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Encoding"), compression.AlgorithmZstd)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Length"), strconv.FormatInt(file.CompressedBytes, 10))
+	result, err = io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(result), string(stored))
+
+	// Deduplicated files use the compression of the stored file
+	configuration.Get().Compression = compression.AlgorithmGzip
+	file2, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file2.Compression, compression.AlgorithmZstd)
+	test.IsEqualInt64(t, file2.CompressedBytes, file.CompressedBytes)
+
+	header.Header.Set("Content-Type", "image/png")
+	file3, err := NewFile(bytes.NewReader([]byte("This is not a compressible file")), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file3.Compression, "")
+	test.IsEqualInt64(t, file3.CompressedBytes, 0)
+
+	id, chunkHeader, chunkRequest, err := createTestChunk()
+	test.IsNil(t, err)
+	file4, err := NewFileFromChunk(id, chunkHeader, 63, chunkRequest)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file4.Compression, compression.AlgorithmGzip)
+	test.IsEqualBool(t, file4.CompressedBytes > 0, true)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+id)
+	_, ok = checkStoredContent(file4)
+	test.IsEqualBool(t, ok, true)
+
+	// A stored file without metadata, e.g. of a deleted user, is replaced with the uploaded content
+	for _, existingFile := range database.GetAllMetadata() {
+		if existingFile.SHA1 == file4.SHA1 {
+			database.DeleteMetaData(existingFile.Id)
+		}
+	}
+	id, chunkHeader, chunkRequest, err = createTestChunk()
+	test.IsNil(t, err)
+	file5, err := NewFileFromChunk(id, chunkHeader, 63, chunkRequest)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file5.SHA1, file4.SHA1)
+	test.IsEqualString(t, file5.Compression, compression.AlgorithmGzip)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+id)
+	_, ok = checkStoredContent(file5)
+	test.IsEqualBool(t, ok, true)
+
+	for _, compressedFile := range []models.File{file, file2, file3, file5} {
+		deleteSource(compressedFile)
+		database.DeleteMetaData(compressedFile.Id)
+	}
+}
+
+func TestCompressedFileEncrypted(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.LocalEncryptionStored,
+		Cipher: cipher,
+	}})
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionStored
+	configuration.Get().Compression = compression.AlgorithmGzip
+	content := []byte(strings.Repeat("This is an encrypted file for compression testing\n", 100))
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	configuration.Get().Encryption.Level = encryption.NoEncryption
+	configuration.Get().Compression = ""
+	test.IsNil(t, err)
+	test.IsEqualBool(t, file.Encryption.IsEncrypted, true)
+	test.IsEqualString(t, file.Compression, compression.AlgorithmGzip)
+	_, ok := checkStoredContent(file)
+	test.IsEqualBool(t, ok, true)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, true)
+	result, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(result), string(content))
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+}
+
+func TestIsEncryptionRequested(t *testing.T) {
+	err := filesystem.AddTarget(models.StorageTargetConfig{
+		Name:      "local-fast",
+		Type:      models.StorageTypeLocal,
+		Directory: "test/fast",
+	}, models.AwsConfig{})
+	test.IsNil(t, err)
+	defer filesystem.ClearTargets()
+	localFile := models.File{StorageTarget: "local-fast"}
+	remoteFile := models.File{AwsBucket: "gokapi-test"}
+
+	test.IsEqualBool(t, isEncryptionRequested(localFile), false)
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionStored
+	test.IsEqualBool(t, isEncryptionRequested(localFile), true)
+	test.IsEqualBool(t, isEncryptionRequested(models.File{}), true)
+	test.IsEqualBool(t, isEncryptionRequested(remoteFile), false)
+	configuration.Get().Encryption.Level = encryption.FullEncryptionStored
+	test.IsEqualBool(t, isEncryptionRequested(remoteFile), true)
+	configuration.Get().Encryption.Level = encryption.NoEncryption
+}
+
+func TestServeFileEncryptedRange(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.LocalEncryptionStored,
+		Cipher: cipher,
+	}})
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionStored
+	content := make([]byte, 200*1024+123)
+	_, err = rand.Read(content)
+	test.IsNil(t, err)
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	configuration.Get().Encryption.Level = encryption.NoEncryption
+	test.IsNil(t, err)
+	test.IsEqualBool(t, file.Encryption.IsEncrypted, true)
+	file.UnlimitedDownloads = true
+	size := int64(len(content))
+
+	serve := func(requestHeaders map[string]string) *http.Response {
+		r := httptest.NewRequest("GET", "/", nil)
+		for key, value := range requestHeaders {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		ServeFile(file, w, r, true)
+		return w.Result()
+	}
+
+	response := serve(nil)
+	test.IsEqualInt(t, response.StatusCode, http.StatusOK)
+	test.IsEqualString(t, response.Header.Get("Accept-Ranges"), "bytes")
+	test.IsEqualString(t, response.Header.Get("Content-Length"), strconv.FormatInt(size, 10))
+	result, err := io.ReadAll(response.Body)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, result, content)
+	etag := response.Header.Get("ETag")
+	lastModified := response.Header.Get("Last-Modified")
+	test.IsEqualString(t, etag, "\""+file.SHA1+"\"")
+
+	windows := []struct {
+		header     string
+		start, end int64
+	}{
+		{"bytes=0-0", 0, 0},
+		{"bytes=10-99", 10, 99},
+		{"bytes=65530-65545", 65530, 65545},
+		{"bytes=16380-150000", 16380, 150000},
+		{"bytes=100000-", 100000, size - 1},
+		{"bytes=-500", size - 500, size - 1},
+		{"bytes=200000-999999999", 200000, size - 1},
+	}
+	for _, window := range windows {
+		response = serve(map[string]string{"Range": window.header})
+		test.IsEqualInt(t, response.StatusCode, http.StatusPartialContent)
+		test.IsEqualString(t, response.Header.Get("Content-Range"),
+			"bytes "+strconv.FormatInt(window.start, 10)+"-"+strconv.FormatInt(window.end, 10)+"/"+strconv.FormatInt(size, 10))
+		test.IsEqualString(t, response.Header.Get("Content-Length"), strconv.FormatInt(window.end-window.start+1, 10))
+		result, err = io.ReadAll(response.Body)
+		test.IsNil(t, err)
+		test.IsEqualByteSlice(t, result, content[window.start:window.end+1])
+	}
+
+	response = serve(map[string]string{"Range": "bytes=" + strconv.FormatInt(size, 10) + "-"})
+	test.IsEqualInt(t, response.StatusCode, http.StatusRequestedRangeNotSatisfiable)
+	test.IsEqualString(t, response.Header.Get("Content-Range"), "bytes */"+strconv.FormatInt(size, 10))
+
+	// Multiple ranges are answered with the complete content
+	response = serve(map[string]string{"Range": "bytes=0-10,20-30"})
+	test.IsEqualInt(t, response.StatusCode, http.StatusOK)
+	test.IsEqualString(t, response.Header.Get("Content-Length"), strconv.FormatInt(size, 10))
+
+	response = serve(map[string]string{"Range": "bytes=10-19", "If-Range": etag})
+	test.IsEqualInt(t, response.StatusCode, http.StatusPartialContent)
+	response = serve(map[string]string{"Range": "bytes=10-19", "If-Range": lastModified})
+	test.IsEqualInt(t, response.StatusCode, http.StatusPartialContent)
+	response = serve(map[string]string{"Range": "bytes=10-19", "If-Range": "\"outdated\""})
+	test.IsEqualInt(t, response.StatusCode, http.StatusOK)
+	result, err = io.ReadAll(response.Body)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, result, content)
+
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+}
+
+func TestGetBandwidthLimits(t *testing.T) {
+	config := configuration.Get()
+	test.IsEqual(t, getBandwidthLimits(models.File{}), bandwidth.Limits{})
+	config.BandwidthGlobalKB = 10240
+	config.BandwidthFileKB = 2048
+	config.BandwidthRequestKB = 512
+	defer func() {
+		config.BandwidthGlobalKB = 0
+		config.BandwidthFileKB = 0
+		config.BandwidthRequestKB = 0
+	}()
+	test.IsEqual(t, getBandwidthLimits(models.File{}), bandwidth.Limits{Global: 10240, File: 2048, Download: 512})
+	test.IsEqual(t, getBandwidthLimits(models.File{BandwidthLimitKB: 100}), bandwidth.Limits{Global: 10240, File: 100, Download: 512})
+}
+
+func TestGetCompressionAlgorithm(t *testing.T) {
+	file := models.File{ContentType: "text/csv"}
+	test.IsEqualString(t, getCompressionAlgorithm(file), "")
+	configuration.Get().Compression = compression.AlgorithmZstd
+	defer func() { configuration.Get().Compression = "" }()
+	test.IsEqualString(t, getCompressionAlgorithm(file), compression.AlgorithmZstd)
+	file.ContentType = "video/mp4"
+	test.IsEqualString(t, getCompressionAlgorithm(file), "")
+	file.ContentType = "text/csv"
+	file.Encryption = models.EncryptionInfo{IsEncrypted: true, IsEndToEndEncrypted: true}
+	test.IsEqualString(t, getCompressionAlgorithm(file), "")
+	file.Encryption = models.EncryptionInfo{IsEncrypted: true}
+	file.AwsBucket = "gokapi-test"
+	test.IsEqualString(t, getCompressionAlgorithm(file), "")
+}
+
+func TestWebdavStorage(t *testing.T) {
+	server := testconfiguration.StartWebdavTestServer()
+	defer server.Close()
+	testconfiguration.EnableWebdav(server)
+	defer testconfiguration.DisableWebdav()
+
+	newFile, err := createTestFile()
+	test.IsNil(t, err)
+	file := newFile.File
+	test.IsEqualString(t, file.StorageDriver, "webdav")
+	test.IsEqualBool(t, file.IsLocalStorage(), false)
+	test.IsEqualBool(t, FileExists(file), true)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=10-13")
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, false)
+	test.IsEqualInt(t, w.Code, 206)
+	test.IsEqualString(t, w.Body.String(), "file")
+	test.IsEqualString(t, w.Result().Header.Get("Content-Range"), "bytes 10-13/35")
+
+	deleteSource(file)
+	test.IsEqualBool(t, FileExists(file), false)
+}
+
+func TestSftpStorage(t *testing.T) {
+	listener, hostKey := testconfiguration.StartSftpTestServer()
+	defer listener.Close()
+	testconfiguration.EnableSftp(listener, hostKey)
+	defer testconfiguration.DisableSftp()
+
+	newFile, err := createTestFile()
+	test.IsNil(t, err)
+	file := newFile.File
+	test.IsEqualString(t, file.StorageDriver, "sftp")
+	test.IsEqualBool(t, file.IsLocalStorage(), false)
+	test.IsEqualBool(t, FileExists(file), true)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=10-13")
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, false)
+	test.IsEqualInt(t, w.Code, 206)
+	test.IsEqualString(t, w.Body.String(), "file")
+
+	deleteSource(file)
+	test.IsEqualBool(t, FileExists(file), false)
+}
+
+func TestStorageTargets(t *testing.T) {
+	err := filesystem.AddTarget(models.StorageTargetConfig{
+		Name:      "local-fast",
+		Type:      models.StorageTypeLocal,
+		Directory: "test/fast",
+	}, models.AwsConfig{})
+	test.IsNil(t, err)
+	defer filesystem.ClearTargets()
+
+	user := models.User{Id: 63}
+	target, err := GetStorageTargetForUpload(user, "")
+	test.IsNil(t, err)
+	test.IsEqualString(t, target, "")
+	_, err = GetStorageTargetForUpload(user, "invalid")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidStorageTarget), true)
+	target, err = GetStorageTargetForUpload(user, "local-fast")
+	test.IsNil(t, err)
+	test.IsEqualString(t, target, "local-fast")
+	user.DefaultStorageTarget = "local-fast"
+	target, err = GetStorageTargetForUpload(user, "")
+	test.IsNil(t, err)
+	test.IsEqualString(t, target, "local-fast")
+	test.IsEqualInt(t, len(GetStorageTargetsForUser(user)), 1)
+	user.StorageTargets = "s3-archive"
+	target, err = GetStorageTargetForUpload(user, "")
+	test.IsNil(t, err)
+	test.IsEqualString(t, target, "")
+	_, err = GetStorageTargetForUpload(user, "local-fast")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidStorageTarget), true)
+	test.IsEqualInt(t, len(GetStorageTargetsForUser(user)), 0)
+
+	content := []byte("This is a file for storage target testing")
+	header, request := createRawTestFile(content)
+	request.StorageTarget = "local-fast"
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.StorageTarget, "local-fast")
+	test.IsEqualBool(t, file.IsLocalStorage(), true)
+	test.FileExists(t, getBlobPath("test/fast", file.SHA1))
+	test.FileDoesNotExist(t, getBlobPath("test/data", file.SHA1))
+	test.IsEqualBool(t, FileExists(file), true)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, false)
+	test.IsEqualString(t, w.Body.String(), string(content))
+
+	deleteSource(file)
+	test.IsEqualBool(t, FileExists(file), false)
+}
+
+func TestNotifications(t *testing.T) {
+	listener, messages := testconfiguration.StartSmtpTestServer()
+	defer listener.Close()
+	notifications.Init(testconfiguration.GetSmtpTestConfig(listener))
+	defer notifications.Init(models.SmtpConfig{})
+	user, ok := database.GetUser(7)
+	test.IsEqualBool(t, ok, true)
+	user.NotificationEmail = "user@example.com"
+	user.NotifyDownload = models.NotifyDownloadFirst
+	user.NotifyExpiry = true
+	user.NotifyDeletion = true
+	database.SaveUser(user, false)
+	defer func() {
+		user.NotificationEmail = ""
+		database.SaveUser(user, false)
+	}()
+	getSubjects := func() []string {
+		notifications.Wait()
+		result := make([]string, 0)
+		for {
+			select {
+			case message := <-messages:
+				for _, line := range strings.Split(message.Data, "\n") {
+					if strings.HasPrefix(line, "Subject: ") {
+						result = append(result, strings.TrimPrefix(line, "Subject: "))
+					}
+				}
+			default:
+				return result
+			}
+		}
+	}
+
+	file, ok := GetFile("Wzol7LyY2QVczXynJtVo")
+	test.IsEqualBool(t, ok, true)
+	file.Id = "notifyfile1234567890"
+	file.Name = "notify.txt"
+	file.UserId = 7
+	file.DownloadsRemaining = 3
+	file.DownloadCount = 0
+	database.SaveMetaData(file)
+	ServeFile(file, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), true)
+	// The file is not reloaded on purpose, the download count of the database decides if it is the first download
+	ServeFile(file, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), true)
+	isEqualSubjects := func(got []string, want ...string) {
+		t.Helper()
+		test.IsEqualInt(t, len(got), len(want))
+		for i := range want {
+			test.IsEqualString(t, got[i], want[i])
+		}
+	}
+	isEqualSubjects(getSubjects(), "notify.txt has been downloaded")
+
+	user.NotifyDownload = models.NotifyDownloadEvery
+	database.SaveUser(user, false)
+	file, _ = GetFile(file.Id)
+	bundle := models.Bundle{Id: "notifybundle", Name: "notify", UnlimitedDownloads: true}
+	err := ServeBundle(bundle, []models.File{file}, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	test.IsNil(t, err)
+	isEqualSubjects(getSubjects(), "notify.txt has been downloaded")
+
+	database.DeleteMetaData(file.Id)
+	database.DeleteDownloadEvents(file.Id)
+
+	timeNow := time.Now().Unix()
+	file.ExpireAt = timeNow + 60
+	file.DownloadsRemaining = 1
+	sendRemovalNotification(file, timeNow)
+	test.IsEqualInt(t, len(getSubjects()), 0)
+	file.DownloadsRemaining = 0
+	sendRemovalNotification(file, timeNow)
+	file.DownloadsRemaining = 1
+	file.ExpireAt = timeNow - 60
+	sendRemovalNotification(file, timeNow)
+	isEqualSubjects(getSubjects(), "notify.txt has expired", "notify.txt has expired")
+
+	file.ExpireAt = 0
+	sendRemovalNotification(file, timeNow)
+	file.ExpireAt = timeNow + 60
+	file.PendingDeletion = timeNow - 60
+	sendRemovalNotification(file, timeNow)
+	isEqualSubjects(getSubjects(), "notify.txt has been deleted", "notify.txt has been deleted")
+}
+
 func TestCleanUp(t *testing.T) {
 	files := database.GetAllMetadata()
 	downloadstatus.DeleteAll()
@@ -636,11 +1331,20 @@ func TestCleanUp(t *testing.T) {
 	file, _ := GetFile("n1tSTAGj8zan9KaT4u6p")
 	file.DownloadsRemaining = 0
 	database.SaveMetaData(file)
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: file.Id, Timestamp: 100})
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: file.Id, Timestamp: time.Now().Unix()})
+	configuration.Get().DownloadHistoryDays = 90
 
 	CleanUp(false)
+	configuration.Get().DownloadHistoryDays = 0
 	files = database.GetAllMetadata()
 	test.FileDoesNotExist(t, "test/data/a8fdc205a9f19cc1c7507a60c4f01b13d11d7fd0")
 	test.IsEqualString(t, files["n1tSTAGj8zan9KaT4u6p"].Name, "")
+	// The download history is kept after the file has been removed, until the retention period has passed
+	events := database.GetDownloadEvents("n1tSTAGj8zan9KaT4u6p")
+	test.IsEqualInt(t, len(events), 1)
+	test.IsEqualBool(t, events[0].Timestamp > 100, true)
+	database.DeleteDownloadEvents("n1tSTAGj8zan9KaT4u6p")
 	test.IsEqualString(t, files["deletedfile123456789"].Name, "")
 	test.IsEqualString(t, files["Wzol7LyY2QVczXynJtVo"].Name, "smallfile2")
 	test.IsEqualString(t, files["e4TjE7CokWK0giiLNxDL"].Name, "smallfile2")
@@ -0,0 +1,132 @@
+package storage
+
+/**
+Extracting a preview image from uploaded PDF files
+*/
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// pdfMinImageSize is the minimum width and height of an embedded image, so that it is used
+// for the thumbnail. Smaller images are usually logos or icons
+const pdfMinImageSize = 100
+
+// pdfImageStreamPattern matches the dictionary of a stream object, followed by the stream keyword.
+// Dictionaries may contain one level of nested dictionaries, e.g. for DecodeParms
+var pdfImageStreamPattern = regexp.MustCompile(`<<((?:[^<>]|<<[^<>]*>>)*)>>\s*stream\r?\n`)
+
+var pdfSubtypeImagePattern = regexp.MustCompile(`/Subtype\s*/Image\b`)
+var pdfImageMaskPattern = regexp.MustCompile(`/ImageMask\s+true\b`)
+var pdfDctFilterPattern = regexp.MustCompile(`/Filter\s*(?:\[\s*)?/DCTDecode\s*\]?`)
+var pdfFlateFilterPattern = regexp.MustCompile(`/Filter\s*(?:\[\s*)?/FlateDecode\s*\]?`)
+var pdfDecodeParmsPattern = regexp.MustCompile(`/DecodeParms\b`)
+var pdfWidthPattern = regexp.MustCompile(`/Width\s+(\d+)\b`)
+var pdfHeightPattern = regexp.MustCompile(`/Height\s+(\d+)\b`)
+var pdfBitsPerComponentPattern = regexp.MustCompile(`/BitsPerComponent\s+(\d+)\b`)
+var pdfColorSpacePattern = regexp.MustCompile(`/ColorSpace\s*/(DeviceRGB|DeviceGray)\b`)
+
+// errorNoPdfImage is returned, if a PDF file does not contain an image that can be used as a thumbnail
+var errorNoPdfImage = errors.New("PDF file does not contain a supported image")
+
+// extractPdfImage returns the first image embedded in the PDF content that is large enough
+// to be used as a thumbnail. PDF files are not rendered, so only JPEG images and uncompressed
+// or zlib compressed 8-bit RGB and greyscale images are supported. This covers most scanned documents
+func extractPdfImage(content []byte) (image.Image, error) {
+	for _, match := range pdfImageStreamPattern.FindAllSubmatchIndex(content, -1) {
+		dictionary := content[match[2]:match[3]]
+		if !pdfSubtypeImagePattern.Match(dictionary) || pdfImageMaskPattern.Match(dictionary) {
+			continue
+		}
+		width := getPdfDictionaryInt(dictionary, pdfWidthPattern)
+		height := getPdfDictionaryInt(dictionary, pdfHeightPattern)
+		if width < pdfMinImageSize || height < pdfMinImageSize ||
+			int64(width)*int64(height) > maxThumbnailSourcePixels {
+			continue
+		}
+		stream := content[match[1]:]
+		var result image.Image
+		var err error
+		switch {
+		case pdfDctFilterPattern.Match(dictionary):
+			result, err = decodePdfJpeg(stream)
+		case pdfFlateFilterPattern.Match(dictionary):
+			var reader io.Reader
+			reader, err = zlib.NewReader(bytes.NewReader(stream))
+			if err == nil {
+				result, err = decodePdfRawImage(dictionary, reader, width, height)
+			}
+		case !bytes.Contains(dictionary, []byte("/Filter")):
+			result, err = decodePdfRawImage(dictionary, bytes.NewReader(stream), width, height)
+		default:
+			continue
+		}
+		if err == nil {
+			return result, nil
+		}
+	}
+	return nil, errorNoPdfImage
+}
+
+// getPdfDictionaryInt returns the integer value matched by pattern, or 0, if the value is not set
+// or an indirect reference
+func getPdfDictionaryInt(dictionary []byte, pattern *regexp.Regexp) int {
+	match := pattern.FindSubmatch(dictionary)
+	if match == nil {
+		return 0
+	}
+	result, err := strconv.Atoi(string(match[1]))
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// decodePdfJpeg decodes a JPEG image that is stored in a stream. The JPEG decoder stops at the
+// end of the image, so the length of the stream is not required
+func decodePdfJpeg(stream []byte) (image.Image, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxThumbnailSourcePixels {
+		return nil, errors.New("image resolution is too high")
+	}
+	return jpeg.Decode(bytes.NewReader(stream))
+}
+
+// decodePdfRawImage reads the pixels of an 8-bit RGB or greyscale image. Images with other colour
+// spaces or a predictor are not supported
+func decodePdfRawImage(dictionary []byte, input io.Reader, width, height int) (image.Image, error) {
+	if getPdfDictionaryInt(dictionary, pdfBitsPerComponentPattern) != 8 || pdfDecodeParmsPattern.Match(dictionary) {
+		return nil, errorNoPdfImage
+	}
+	colorSpace := pdfColorSpacePattern.FindSubmatch(dictionary)
+	if colorSpace == nil {
+		return nil, errorNoPdfImage
+	}
+	bounds := image.Rect(0, 0, width, height)
+	if string(colorSpace[1]) == "DeviceGray" {
+		result := image.NewGray(bounds)
+		_, err := io.ReadFull(input, result.Pix)
+		return result, err
+	}
+	pixels := make([]byte, width*height*3)
+	_, err := io.ReadFull(input, pixels)
+	if err != nil {
+		return nil, err
+	}
+	result := image.NewRGBA(bounds)
+	for i := 0; i < width*height; i++ {
+		copy(result.Pix[i*4:i*4+3], pixels[i*3:i*3+3])
+		result.Pix[i*4+3] = 0xff
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/test"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// createTestPdf returns a minimal PDF file that contains the image stream with the given dictionary
+func createTestPdf(dictionary string, stream []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	buf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	buf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im1 4 0 R >> >> >>\nendobj\n")
+	buf.WriteString("4 0 obj\n<< " + dictionary + " /Length " + strconv.Itoa(len(stream)) + " >>\nstream\n")
+	buf.Write(stream)
+	buf.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func createTestJpeg(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{B: 0xff, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, nil)
+	test.IsNil(t, err)
+	return buf.Bytes()
+}
+
+func TestExtractPdfImage(t *testing.T) {
+	jpegPdf := createTestPdf("/Type /XObject /Subtype /Image /Width 400 /Height 200 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode",
+		createTestJpeg(t, 400, 200))
+	result, err := extractPdfImage(jpegPdf)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.Bounds().Dx(), 400)
+	test.IsEqualInt(t, result.Bounds().Dy(), 200)
+
+	pixels := bytes.Repeat([]byte{0xff, 0x00, 0x00}, 150*120)
+	var compressed bytes.Buffer
+	zlibWriter := zlib.NewWriter(&compressed)
+	_, err = zlibWriter.Write(pixels)
+	test.IsNil(t, err)
+	test.IsNil(t, zlibWriter.Close())
+	flatePdf := createTestPdf("/Type /XObject /Subtype /Image /Width 150 /Height 120 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode",
+		compressed.Bytes())
+	result, err = extractPdfImage(flatePdf)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.Bounds().Dx(), 150)
+	r, g, b, _ := result.At(10, 10).RGBA()
+	test.IsEqualBool(t, r == 0xffff && g == 0 && b == 0, true)
+
+	grayPdf := createTestPdf("/Subtype /Image /Width 100 /Height 100 /ColorSpace /DeviceGray /BitsPerComponent 8",
+		bytes.Repeat([]byte{0x80}, 100*100))
+	result, err = extractPdfImage(grayPdf)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.Bounds().Dy(), 100)
+
+	// Small images, image masks and unsupported formats are skipped
+	invalidPdfs := [][]byte{
+		createTestPdf("/Subtype /Image /Width 50 /Height 50 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode", createTestJpeg(t, 50, 50)),
+		createTestPdf("/Subtype /Image /ImageMask true /Width 400 /Height 200 /Filter /DCTDecode", createTestJpeg(t, 400, 200)),
+		createTestPdf("/Subtype /Image /Width 400 /Height 200 /ColorSpace /DeviceCMYK /BitsPerComponent 8", bytes.Repeat([]byte{0}, 400*200*4)),
+		createTestPdf("/Subtype /Image /Width 400 /Height 200 /BitsPerComponent 1 /Filter /CCITTFaxDecode", []byte("fax")),
+		createTestPdf("/Subtype /Image /Width 400 /Height 200 /Filter /DCTDecode", []byte("This is not a JPEG")),
+		createTestPdf("/Length1 100", []byte("This is a font")),
+		[]byte("This is not a PDF file"),
+	}
+	for _, content := range invalidPdfs {
+		_, err = extractPdfImage(content)
+		test.IsEqual(t, err, errorNoPdfImage)
+	}
+}
+
+func TestCreatePdfThumbnail(t *testing.T) {
+	content := createTestPdf("/Type /XObject /Subtype /Image /Width 640 /Height 320 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode",
+		createTestJpeg(t, 640, 320))
+	file := storeTestImage(t, "thumbnailpdf", "document.PDF", content)
+	test.IsEqualBool(t, IsThumbnailSupported(file), true)
+	err := createThumbnail(file)
+	test.IsNil(t, err)
+	file, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, file.HasThumbnail, true)
+	w := httptest.NewRecorder()
+	err = ServeThumbnail(file, w)
+	test.IsNil(t, err)
+	thumbnail, err := jpeg.Decode(w.Body)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, thumbnail.Bounds().Dx(), 320)
+	test.IsEqualInt(t, thumbnail.Bounds().Dy(), 160)
+	deleteThumbnail(file)
+
+	noImage := storeTestImage(t, "thumbnailpdfnoimage", "document.pdf", createTestPdf("/Length1 100", []byte("font")))
+	test.IsEqual(t, createThumbnail(noImage), errorNoPdfImage)
+	noImage, _ = database.GetMetaDataById(noImage.Id)
+	test.IsEqualBool(t, noImage.HasThumbnail, false)
+
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+	deleteSource(noImage)
+	database.DeleteMetaData(noImage.Id)
+}
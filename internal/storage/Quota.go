@@ -0,0 +1,56 @@
+package storage
+
+/**
+Limiting the total size and the number of files per user
+*/
+
+import (
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"time"
+)
+
+// ErrorQuotaSizeExceeded is raised when a new file would exceed the maximum total size of files of the user
+var ErrorQuotaSizeExceeded = errors.New("storage quota exceeded, maximum total size of files reached")
+
+// ErrorQuotaFilesExceeded is raised when a new file would exceed the maximum number of files of the user
+var ErrorQuotaFilesExceeded = errors.New("storage quota exceeded, maximum number of files reached")
+
+// GetStorageUsage returns the number and the total size of all files per user. Expired files and files
+// that are about to be deleted are not included
+func GetStorageUsage() map[int]models.StorageUsage {
+	result := make(map[int]models.StorageUsage)
+	timeNow := time.Now().Unix()
+	for _, file := range database.GetAllMetadata() {
+		if IsExpiredFile(file, timeNow) || file.IsPendingForDeletion() {
+			continue
+		}
+		usage := result[file.UserId]
+		usage.Bytes = usage.Bytes + file.SizeBytes
+		usage.Files++
+		result[file.UserId] = usage
+	}
+	return result
+}
+
+// GetStorageUsageForUser returns the number and the total size of all files of the user
+func GetStorageUsageForUser(userId int) models.StorageUsage {
+	return GetStorageUsage()[userId]
+}
+
+// CheckQuota returns an error, if storing an additional file with the given size would exceed the quota of the user
+func CheckQuota(userId int, size int64) error {
+	user, ok := database.GetUser(userId)
+	if !ok || !user.HasQuota() {
+		return nil
+	}
+	usage := GetStorageUsageForUser(userId)
+	if user.QuotaFiles > 0 && usage.Files+1 > user.QuotaFiles {
+		return ErrorQuotaFilesExceeded
+	}
+	if user.QuotaBytes > 0 && usage.Bytes+size > user.QuotaBytes {
+		return ErrorQuotaSizeExceeded
+	}
+	return nil
+}
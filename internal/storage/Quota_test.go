@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestGetStorageUsage(t *testing.T) {
+	const userId = 71
+	test.IsEqual(t, GetStorageUsageForUser(userId), models.StorageUsage{})
+	database.SaveMetaData(models.File{Id: "quotatest1", SizeBytes: 100, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true})
+	database.SaveMetaData(models.File{Id: "quotatest2", SizeBytes: 50, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true})
+	database.SaveMetaData(models.File{Id: "quotatest3", SizeBytes: 20, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true, PendingDeletion: 1})
+	database.SaveMetaData(models.File{Id: "quotatest4", SizeBytes: 20, UserId: userId, ExpireAt: 1, UnlimitedDownloads: true})
+	test.IsEqual(t, GetStorageUsageForUser(userId), models.StorageUsage{Bytes: 150, Files: 2})
+	test.IsEqual(t, GetStorageUsage()[userId], models.StorageUsage{Bytes: 150, Files: 2})
+	for _, id := range []string{"quotatest1", "quotatest2", "quotatest3", "quotatest4"} {
+		database.DeleteMetaData(id)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	const userId = 72
+	test.IsNil(t, CheckQuota(userId, 1000))
+	user := models.User{Id: userId, Name: "quotatest", UserLevel: models.UserLevelUser}
+	database.SaveUser(user, false)
+	test.IsNil(t, CheckQuota(userId, 1000))
+
+	database.SaveMetaData(models.File{Id: "quotatest5", SizeBytes: 100, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true})
+	user.QuotaBytes = 150
+	database.SaveUser(user, false)
+	test.IsNil(t, CheckQuota(userId, 50))
+	test.IsEqual(t, CheckQuota(userId, 51), ErrorQuotaSizeExceeded)
+
+	user.QuotaBytes = 0
+	user.QuotaFiles = 2
+	database.SaveUser(user, false)
+	test.IsNil(t, CheckQuota(userId, 1000))
+	database.SaveMetaData(models.File{Id: "quotatest6", SizeBytes: 100, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true})
+	test.IsEqual(t, CheckQuota(userId, 0), ErrorQuotaFilesExceeded)
+
+	database.DeleteMetaData("quotatest5")
+	database.DeleteMetaData("quotatest6")
+	database.DeleteUser(userId)
+}
@@ -0,0 +1,203 @@
+package storage
+
+/**
+Hashing stored files again, if they were hashed with a different algorithm than the configured one
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/hashing"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"io"
+	"strconv"
+	"time"
+)
+
+// RunRehashing hashes all files again that were hashed with a different algorithm than the configured one,
+// e.g. files that were uploaded with an older version. The stored files are renamed to the new hash and
+// deduplicated with files that have been uploaded since. If the parameter periodic is true, this function
+// is recursive and calls itself every hour.
+func RunRehashing(periodic bool) {
+	// Files are not moved by tiering and rehashing at the same time
+	tieringMutex.Lock()
+	rehashedFiles := rehashFiles(configuration.Get().HashAlgorithm, time.Now().Unix())
+	tieringMutex.Unlock()
+	if rehashedFiles > 0 {
+		fmt.Println("Hashed " + strconv.Itoa(rehashedFiles) + " stored files with " + configuration.Get().HashAlgorithm)
+	}
+	if periodic {
+		go func() {
+			select {
+			case <-time.After(time.Hour):
+				RunRehashing(periodic)
+			}
+		}()
+	}
+}
+
+// rehashFiles hashes all files again, that were hashed with a different algorithm. Files that are currently
+// downloaded are skipped. Returns the number of stored files that have been hashed again
+func rehashFiles(algorithm string, timeNow int64) int {
+	rehashedFiles := 0
+	groups := getSiblingGroups(func(file models.File) bool {
+		return hashing.GetAlgorithm(file) != algorithm && !file.Encryption.IsEndToEndEncrypted && !file.IsQuarantined &&
+			!IsExpiredFile(file, timeNow) && !file.IsPendingForDeletion()
+	})
+	for _, siblings := range groups {
+		if isAnySiblingDownloading(siblings) {
+			continue
+		}
+		err := rehashSiblings(siblings, algorithm)
+		if err != nil {
+			fmt.Println("Warning: Cannot hash file " + siblings[0].Id + " again: " + err.Error())
+			continue
+		}
+		rehashedFiles++
+	}
+	return rehashedFiles
+}
+
+// isAnySiblingDownloading returns true if one of the files that share the stored content is currently being downloaded
+func isAnySiblingDownloading(siblings []models.File) bool {
+	for _, file := range siblings {
+		if downloadstatus.IsCurrentlyDownloading(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// rehashSiblings hashes the stored content of the siblings with the algorithm, stores it under the new hash,
+// updates the metadata of all siblings and removes the previously stored file afterwards. If a file with the
+// same content has already been stored under the new hash, the stored content is reused
+func rehashSiblings(siblings []models.File, algorithm string) error {
+	source := siblings[0]
+	newHash, err := getNewHash(source, algorithm)
+	if err != nil {
+		return err
+	}
+	destination := source
+	destination.SHA1 = newHash
+	destination.HashAlgorithm = algorithm
+
+	existingFile, ok := getExistingStoredSibling(destination)
+	if ok {
+		destination.Encryption = existingFile.Encryption
+		destination.Compression = existingFile.Compression
+		destination.CompressedBytes = existingFile.CompressedBytes
+	} else {
+		err = copyStoredContent(source, destination)
+		if err != nil {
+			return err
+		}
+	}
+
+	movedFiles := make([]models.File, 0, len(siblings))
+	for _, file := range siblings {
+		current, ok := database.GetMetaDataById(file.Id)
+		if !ok || current.SHA1 != source.SHA1 || !isSameStorageLocation(current, source) {
+			continue
+		}
+		current.SHA1 = destination.SHA1
+		current.HashAlgorithm = destination.HashAlgorithm
+		current.Encryption = destination.Encryption
+		current.Compression = destination.Compression
+		current.CompressedBytes = destination.CompressedBytes
+		if current.HotlinkId != "" && current.RequiresClientDecryption() {
+			database.DeleteHotlink(current.HotlinkId)
+			current.HotlinkId = ""
+		}
+		current.HasThumbnail = false
+		database.SaveMetaData(current)
+		movedFiles = append(movedFiles, current)
+	}
+	recreateThumbnails(movedFiles)
+
+	if isStillReferenced(source) {
+		return nil
+	}
+	deleteThumbnail(source)
+	return filesystem.GetForFile(source).DeleteFile(source)
+}
+
+// getNewHash returns the hash of the stored content, created with the algorithm. The stored content is
+// only hashed again, if it still matches the previous hash. As with new uploads, the salt is added
+// if files are encrypted on upload, so that the file is deduplicated with files that are uploaded later
+func getNewHash(file models.File, algorithm string) (string, error) {
+	previousHash := hashing.New(hashing.GetAlgorithm(file))
+	newHash := hashing.New(algorithm)
+	err := readStoredContent(file, io.MultiWriter(previousHash, newHash))
+	if err != nil {
+		return "", err
+	}
+	hash, hashSalted := getHashValues(previousHash)
+	if file.SHA1 != hash && file.SHA1 != hashSalted {
+		return "", errors.New("stored content does not match the hash")
+	}
+	result, resultSalted := getHashValues(newHash)
+	if isEncryptionRequested(file) {
+		return resultSalted, nil
+	}
+	return result, nil
+}
+
+// newLegacyHashes returns hashes for the algorithms of stored files that have not been hashed with the
+// configured algorithm yet. New uploads are hashed with these algorithms as well, so that they are
+// deduplicated with these files until they have been hashed again
+func newLegacyHashes() hashing.Multi {
+	algorithm := configuration.Get().HashAlgorithm
+	legacyAlgorithms := make([]string, 0)
+	for _, file := range database.GetAllMetadata() {
+		fileAlgorithm := hashing.GetAlgorithm(file)
+		if fileAlgorithm != algorithm && !file.Encryption.IsEndToEndEncrypted {
+			legacyAlgorithms = append(legacyAlgorithms, fileAlgorithm)
+		}
+	}
+	return hashing.NewMulti(legacyAlgorithms...)
+}
+
+// applyLegacyHash uses the hash and the algorithm of a stored file with the same content and storage location,
+// if the stored file has been hashed with a different algorithm. legacyHashes contains the hashes of the content
+// of the new file with the algorithm as key
+func applyLegacyHash(file *models.File, legacyHashes map[string]string) {
+	if len(legacyHashes) == 0 || file.Encryption.IsEndToEndEncrypted {
+		return
+	}
+	for _, existingFile := range database.GetAllMetadata() {
+		legacyHash, ok := legacyHashes[hashing.GetAlgorithm(existingFile)]
+		if ok && existingFile.SHA1 == legacyHash && isSameStorageLocation(existingFile, *file) {
+			file.SHA1 = existingFile.SHA1
+			file.HashAlgorithm = existingFile.HashAlgorithm
+			return
+		}
+	}
+}
+
+// getHashSalt returns the salt that is added to the hash of the file. The salt is only added, if the file
+// is encrypted on upload
+func getHashSalt(file models.File) string {
+	if isEncryptionRequested(file) {
+		return configuration.Get().Authentication.SaltFiles
+	}
+	return ""
+}
+
+// copyStoredContent stores the content of source as destination without decrypting it. Objects on S3 are
+// copied by the server, so that the content does not need to be transferred again
+func copyStoredContent(source, destination models.File) error {
+	if source.AwsBucket != "" {
+		return aws.CopyObject(source, destination)
+	}
+	reader, err := filesystem.GetForFile(source).OpenFile(source, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return filesystem.GetForFile(destination).WriteToFilesystem(reader, destination)
+}
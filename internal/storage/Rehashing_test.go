@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/hashing"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createLegacyTestFile stores the content as a new file, that is hashed with SHA1 like in older versions
+func createLegacyTestFile(t *testing.T, content []byte) models.File {
+	t.Helper()
+	configuration.Get().HashAlgorithm = hashing.AlgorithmSha1
+	defer func() { configuration.Get().HashAlgorithm = hashing.AlgorithmSha256 }()
+	header, request := createRawTestFile(content)
+	request.AllowedDownloads = 10
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.HashAlgorithm, hashing.AlgorithmSha1)
+	return file
+}
+
+func TestRehashSiblings(t *testing.T) {
+	content := []byte("This is a file for rehashing")
+	const expectedHash = "de37d36239b28bf889de0561d500d86a57f57ac1c4f98c8f3ff4145660c4ff0d"
+	file1 := createLegacyTestFile(t, content)
+	file2, err := DuplicateFile(file1, 0, "", models.UploadRequest{})
+	test.IsNil(t, err)
+	test.FileExists(t, getBlobPath("test/data", file1.SHA1))
+
+	err = rehashSiblings([]models.File{file1, file2}, hashing.AlgorithmSha256)
+	test.IsNil(t, err)
+	test.FileDoesNotExist(t, getBlobPath("test/data", file1.SHA1))
+	test.FileExists(t, getBlobPath("test/data", expectedHash))
+	for _, id := range []string{file1.Id, file2.Id} {
+		retrievedFile, ok := database.GetMetaDataById(id)
+		test.IsEqualBool(t, ok, true)
+		test.IsEqualString(t, retrievedFile.SHA1, expectedHash)
+		test.IsEqualString(t, retrievedFile.HashAlgorithm, hashing.AlgorithmSha256)
+		w := httptest.NewRecorder()
+		ServeFile(retrievedFile, w, httptest.NewRequest("GET", "/", nil), false)
+		test.IsEqualString(t, w.Body.String(), string(content))
+	}
+
+	// A legacy file is deduplicated with a file that has been uploaded with the new algorithm.
+	// New uploads with the same content reuse the new hash, so the legacy file is restored manually
+	file3 := file1
+	file3.Id = createNewId()
+	database.SaveMetaData(file3)
+	test.IsNil(t, os.MkdirAll(filepath.Dir(getBlobPath("test/data", file3.SHA1)), 0700))
+	test.IsNil(t, os.WriteFile(getBlobPath("test/data", file3.SHA1), content, 0600))
+	err = rehashSiblings([]models.File{file3}, hashing.AlgorithmSha256)
+	test.IsNil(t, err)
+	test.FileDoesNotExist(t, getBlobPath("test/data", file3.SHA1))
+	file3, ok := database.GetMetaDataById(file3.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file3.SHA1, expectedHash)
+
+	for _, id := range []string{file1.Id, file2.Id, file3.Id} {
+		database.DeleteMetaData(id)
+	}
+	deleteSource(file3)
+}
+
+func TestLegacyHashDeduplication(t *testing.T) {
+	content := []byte("This is a file for chunk testing purposes")
+	legacyFile := createLegacyTestFile(t, content)
+	test.IsEqualInt(t, len(newLegacyHashes()), 1)
+
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.SHA1, legacyFile.SHA1)
+	test.IsEqualString(t, file.HashAlgorithm, hashing.AlgorithmSha1)
+
+	chunkId, fileHeader, request, err := createTestChunk()
+	test.IsNil(t, err)
+	chunkFile, err := NewFileFromChunk(chunkId, fileHeader, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, chunkFile.SHA1, legacyFile.SHA1)
+	test.IsEqualString(t, chunkFile.HashAlgorithm, hashing.AlgorithmSha1)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+chunkId)
+
+	// Content that differs from the legacy file is stored with the configured algorithm
+	otherContent := []byte("This is a file for legacy hash testing")
+	header, request = createRawTestFile(otherContent)
+	otherFile, err := NewFile(bytes.NewReader(otherContent), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, otherFile.HashAlgorithm, hashing.AlgorithmSha256)
+
+	for _, id := range []string{legacyFile.Id, file.Id, chunkFile.Id, otherFile.Id} {
+		database.DeleteMetaData(id)
+	}
+	deleteSource(legacyFile)
+	deleteSource(otherFile)
+}
+
+func TestRehashSiblingsModified(t *testing.T) {
+	file := createLegacyTestFile(t, []byte("This is a file for rehashing, that is modified"))
+	err := os.WriteFile(getBlobPath("test/data", file.SHA1), []byte("modified content"), 0600)
+	test.IsNil(t, err)
+	err = rehashSiblings([]models.File{file}, hashing.AlgorithmSha256)
+	test.IsNotNil(t, err)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.SHA1, file.SHA1)
+	test.FileExists(t, getBlobPath("test/data", file.SHA1))
+	database.DeleteMetaData(file.Id)
+	deleteSource(file)
+}
+
+func TestRehashSiblingsEncrypted(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.LocalEncryptionStored,
+		Cipher: cipher,
+	}})
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionStored
+	defer func() { configuration.Get().Encryption.Level = encryption.NoEncryption }()
+	content := []byte("This is an encrypted file for rehashing")
+	file := createLegacyTestFile(t, content)
+	test.IsEqualBool(t, file.Encryption.IsEncrypted, true)
+
+	err = rehashSiblings([]models.File{file}, hashing.AlgorithmBlake3)
+	test.IsNil(t, err)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.HashAlgorithm, hashing.AlgorithmBlake3)
+	test.IsEqualBool(t, retrievedFile.SHA1 != file.SHA1, true)
+	_, ok = checkStoredContent(retrievedFile)
+	test.IsEqualBool(t, ok, true)
+	database.DeleteMetaData(file.Id)
+	deleteSource(retrievedFile)
+}
+
+func TestRehashSiblingsS3(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	testconfiguration.EnableS3()
+	config, ok := cloudconfig.Load()
+	test.IsEqualBool(t, ok, true)
+	ok = aws.Init(config.Aws)
+	test.IsEqualBool(t, ok, true)
+	filesystem.SetAws()
+	defer testconfiguration.DisableS3()
+
+	content := []byte("This is a file on S3 for rehashing")
+	file := createLegacyTestFile(t, content)
+	test.IsEqualString(t, file.AwsBucket, aws.GetDefaultBucketName())
+	err := rehashSiblings([]models.File{file}, hashing.AlgorithmSha256)
+	test.IsNil(t, err)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.HashAlgorithm, hashing.AlgorithmSha256)
+	exists, _, err := aws.FileExists(file)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	exists, _, err = aws.FileExists(retrievedFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	database.DeleteMetaData(file.Id)
+	deleteSource(retrievedFile)
+}
+
+func TestIsAnySiblingDownloading(t *testing.T) {
+	file := models.File{Id: "rehashdownload"}
+	test.IsEqualBool(t, isAnySiblingDownloading([]models.File{file}), false)
+	statusId := downloadstatus.SetDownload(file)
+	test.IsEqualBool(t, isAnySiblingDownloading([]models.File{{Id: "other"}, file}), true)
+	downloadstatus.SetComplete(statusId)
+	test.IsEqualBool(t, isAnySiblingDownloading([]models.File{file}), false)
+}
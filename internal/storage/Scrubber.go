@@ -0,0 +1,234 @@
+package storage
+
+/**
+Checking the integrity of stored files by comparing the hash of the stored content with the saved hash
+*/
+
+import (
+	"encoding/hex"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/compression"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/hashing"
+	"hash"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+var scrubMutex sync.Mutex
+var scrubReport = models.ScrubReport{Problems: make([]models.ScrubProblem, 0)}
+var scrubReportMutex sync.RWMutex
+
+// RunScrubber checks the integrity of all stored files every intervalHours hours. If quarantine is true,
+// files that fail the check are not served anymore. Disabled if intervalHours is 0
+func RunScrubber(intervalHours int, quarantine bool) {
+	if intervalHours < 1 {
+		return
+	}
+	go func() {
+		select {
+		case <-time.After(time.Duration(intervalHours) * time.Hour):
+			StartScrub(quarantine)
+			RunScrubber(intervalHours, quarantine)
+		}
+	}()
+}
+
+// StartScrub checks the integrity of all stored files in the background. If quarantine is true,
+// files that fail the check are not served anymore. Returns false, if a check is already running
+func StartScrub(quarantine bool) bool {
+	if !scrubMutex.TryLock() {
+		return false
+	}
+	scrubReportMutex.Lock()
+	scrubReport = models.ScrubReport{
+		StartTime: time.Now().Unix(),
+		IsRunning: true,
+		Problems:  make([]models.ScrubProblem, 0),
+	}
+	scrubReportMutex.Unlock()
+	go func() {
+		defer scrubMutex.Unlock()
+		scrubAllFiles(quarantine)
+	}()
+	return true
+}
+
+// GetScrubReport returns the result of the last integrity check
+func GetScrubReport() models.ScrubReport {
+	scrubReportMutex.RLock()
+	defer scrubReportMutex.RUnlock()
+	result := scrubReport
+	result.Problems = slices.Clone(scrubReport.Problems)
+	return result
+}
+
+func scrubAllFiles(quarantine bool) {
+	timeNow := time.Now().Unix()
+	groups := getSiblingGroups(func(file models.File) bool {
+		return !IsExpiredFile(file, timeNow) && !file.IsPendingForDeletion()
+	})
+	for _, siblings := range groups {
+		problem, ok := scrubSiblings(siblings, quarantine)
+		scrubReportMutex.Lock()
+		scrubReport.CheckedFiles++
+		if !ok {
+			scrubReport.Problems = append(scrubReport.Problems, problem)
+		}
+		scrubReportMutex.Unlock()
+	}
+	scrubReportMutex.Lock()
+	scrubReport.IsRunning = false
+	scrubReport.EndTime = time.Now().Unix()
+	report := scrubReport
+	scrubReportMutex.Unlock()
+	logging.LogScrubFinished(report)
+}
+
+// scrubSiblings checks the stored content of files that share the same stored file. Returns false
+// and the problem, if the check failed. Files are released from quarantine, if the check passed
+func scrubSiblings(siblings []models.File, quarantine bool) (models.ScrubProblem, bool) {
+	problem, ok := checkStoredContent(siblings[0])
+	if ok {
+		setQuarantine(siblings, false)
+		return models.ScrubProblem{}, true
+	}
+	// The files might have been deleted or moved to a different storage during the check
+	siblings = getCurrentSiblings(siblings)
+	if len(siblings) == 0 {
+		return models.ScrubProblem{}, true
+	}
+	for _, file := range siblings {
+		problem.FileIds = append(problem.FileIds, file.Id)
+		if file.IsQuarantined {
+			problem.IsQuarantined = true
+		}
+	}
+	// Files are not quarantined if the content could not be read, as this is usually a temporary error
+	if quarantine && problem.Type != models.ScrubProblemError {
+		setQuarantine(siblings, true)
+		problem.IsQuarantined = true
+	}
+	logging.LogScrubProblem(problem)
+	return problem, false
+}
+
+// checkStoredContent returns false and the problem, if the stored content of the file is missing
+// or does not match the hash of the file
+func checkStoredContent(file models.File) (models.ScrubProblem, bool) {
+	problem := models.ScrubProblem{SHA1: file.SHA1, FileIds: make([]string, 0)}
+	if !FileExists(file) {
+		problem.Type = models.ScrubProblemMissing
+		problem.Details = "stored file does not exist"
+		return problem, false
+	}
+	if file.Encryption.IsEndToEndEncrypted {
+		// The hash of end-to-end encrypted files is random, therefore only the existence can be checked
+		return problem, true
+	}
+	hash, hashSalted, err := hashStoredContent(file)
+	if err != nil {
+		if encryption.IsNotAuthentic(err) {
+			problem.Type = models.ScrubProblemMismatch
+			problem.Details = "stored file cannot be decrypted, as it has been modified"
+			return problem, false
+		}
+		problem.Type = models.ScrubProblemError
+		problem.Details = "cannot read stored file: " + err.Error()
+		return problem, false
+	}
+	if file.SHA1 != hash && file.SHA1 != hashSalted {
+		problem.Type = models.ScrubProblemMismatch
+		problem.Details = "stored file has hash " + hash
+		return problem, false
+	}
+	return problem, true
+}
+
+// hashStoredContent returns the hash of the stored content, without and with the salt that is added
+// if a file is encrypted on upload. Encrypted files are decrypted before hashing. Both variants are
+// returned, as the encryption of a file may change after the upload, e.g. when it is moved to S3
+func hashStoredContent(file models.File) (string, string, error) {
+	hash := hashing.New(hashing.GetAlgorithm(file))
+	err := readStoredContent(file, hash)
+	if err != nil {
+		return "", "", err
+	}
+	result, resultSalted := getHashValues(hash)
+	return result, resultSalted, nil
+}
+
+// readStoredContent writes the stored content of the file to output. Encrypted files are decrypted
+// and compressed files are decompressed
+func readStoredContent(file models.File, output io.Writer) error {
+	reader, err := filesystem.GetForFile(file).OpenFile(file, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if file.Encryption.IsEncrypted && file.Compression == "" {
+		return encryption.DecryptReader(file.Encryption, reader, output)
+	}
+	var input io.Reader = reader
+	if file.Encryption.IsEncrypted {
+		cipher, err := encryption.GetCipherFromFile(file.Encryption)
+		if err != nil {
+			return err
+		}
+		input, err = encryption.GetDecryptReader(cipher, reader)
+		if err != nil {
+			return err
+		}
+	}
+	if file.Compression != "" {
+		decompressed, err := compression.GetDecompressReader(file.Compression, input)
+		if err != nil {
+			return err
+		}
+		defer decompressed.Close()
+		input = decompressed
+	}
+	_, err = io.Copy(output, input)
+	return err
+}
+
+// getHashValues returns the hex encoded hash, without and with the salt for files that are encrypted on upload
+func getHashValues(fileHash hash.Hash) (string, string) {
+	result := hex.EncodeToString(fileHash.Sum(nil))
+	fileHash.Write([]byte(configuration.Get().Authentication.SaltFiles))
+	return result, hex.EncodeToString(fileHash.Sum(nil))
+}
+
+// getCurrentSiblings returns the current metadata of all siblings that still exist and use the same stored file
+func getCurrentSiblings(siblings []models.File) []models.File {
+	result := make([]models.File, 0)
+	for _, file := range siblings {
+		current, ok := database.GetMetaDataById(file.Id)
+		if !ok || current.SHA1 != file.SHA1 || !isSameStorageLocation(current, file) || current.IsPendingForDeletion() {
+			continue
+		}
+		result = append(result, current)
+	}
+	return result
+}
+
+// setQuarantine sets the quarantine status of all siblings
+func setQuarantine(siblings []models.File, isQuarantined bool) {
+	for _, file := range siblings {
+		if file.IsQuarantined == isQuarantined {
+			continue
+		}
+		current, ok := database.GetMetaDataById(file.Id)
+		if !ok || current.IsQuarantined == isQuarantined {
+			continue
+		}
+		current.IsQuarantined = isQuarantined
+		database.SaveMetaData(current)
+	}
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheckStoredContent(t *testing.T) {
+	content := []byte("This is a file for scrubbing")
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	_, ok := checkStoredContent(file)
+	test.IsEqualBool(t, ok, true)
+
+	err = os.WriteFile(getBlobPath("test/data", file.SHA1), []byte("This is a file for scrubbinG"), 0600)
+	test.IsNil(t, err)
+	problem, ok := checkStoredContent(file)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, problem.Type, models.ScrubProblemMismatch)
+	test.IsEqualString(t, problem.SHA1, file.SHA1)
+
+	deleteSource(file)
+	problem, ok = checkStoredContent(file)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, problem.Type, models.ScrubProblemMissing)
+
+	// Only the existence is checked for end-to-end encrypted files
+	file.Encryption.IsEndToEndEncrypted = true
+	err = os.WriteFile(getBlobPath("test/data", file.SHA1), []byte("encrypted content"), 0600)
+	test.IsNil(t, err)
+	_, ok = checkStoredContent(file)
+	test.IsEqualBool(t, ok, true)
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+}
+
+func TestCheckStoredContentEncrypted(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.LocalEncryptionStored,
+		Cipher: cipher,
+	}})
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionStored
+	content := []byte("This is an encrypted file for scrubbing")
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	configuration.Get().Encryption.Level = encryption.NoEncryption
+	test.IsNil(t, err)
+	test.IsEqualBool(t, file.Encryption.IsEncrypted, true)
+
+	// The hash is salted, as the file was encrypted on upload
+	hash, hashSalted, err := hashStoredContent(file)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, hash != file.SHA1, true)
+	test.IsEqualString(t, hashSalted, file.SHA1)
+	_, ok := checkStoredContent(file)
+	test.IsEqualBool(t, ok, true)
+
+	stored, err := os.ReadFile(getBlobPath("test/data", file.SHA1))
+	test.IsNil(t, err)
+	stored[len(stored)-1] = stored[len(stored)-1] ^ 0xff
+	err = os.WriteFile(getBlobPath("test/data", file.SHA1), stored, 0600)
+	test.IsNil(t, err)
+	problem, ok := checkStoredContent(file)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, problem.Type, models.ScrubProblemMismatch)
+
+	file.Encryption.DecryptionKey = []byte("invalid")
+	problem, ok = checkStoredContent(file)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, problem.Type, models.ScrubProblemError)
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+}
+
+func TestScrubSiblings(t *testing.T) {
+	content := []byte("This is a file for quarantine testing")
+	header, request := createRawTestFile(content)
+	request.AllowedDownloads = 10
+	file1, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	file2, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	siblings := []models.File{file1, file2}
+
+	_, ok := scrubSiblings(siblings, true)
+	test.IsEqualBool(t, ok, true)
+
+	err = os.WriteFile(getBlobPath("test/data", file1.SHA1), []byte("modified content"), 0600)
+	test.IsNil(t, err)
+	problem, ok := scrubSiblings(siblings, false)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, problem.IsQuarantined, false)
+	test.IsEqualInt(t, len(problem.FileIds), 2)
+	_, ok = GetFile(file1.Id)
+	test.IsEqualBool(t, ok, true)
+
+	problem, ok = scrubSiblings(siblings, true)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, problem.IsQuarantined, true)
+	for _, id := range []string{file1.Id, file2.Id} {
+		retrievedFile, ok := database.GetMetaDataById(id)
+		test.IsEqualBool(t, ok, true)
+		test.IsEqualBool(t, retrievedFile.IsQuarantined, true)
+		_, ok = GetFile(id)
+		test.IsEqualBool(t, ok, false)
+	}
+
+	// Files stay quarantined while the check fails
+	siblings = getCurrentSiblings(siblings)
+	problem, ok = scrubSiblings(siblings, false)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, problem.IsQuarantined, true)
+
+	// Files are released, once the stored content is valid again
+	err = os.WriteFile(getBlobPath("test/data", file1.SHA1), content, 0600)
+	test.IsNil(t, err)
+	_, ok = scrubSiblings(siblings, true)
+	test.IsEqualBool(t, ok, true)
+	_, ok = GetFile(file1.Id)
+	test.IsEqualBool(t, ok, true)
+	_, ok = GetFile(file2.Id)
+	test.IsEqualBool(t, ok, true)
+
+	// Files that were deleted during the check are not reported
+	database.DeleteMetaData(file1.Id)
+	database.DeleteMetaData(file2.Id)
+	deleteSource(file1)
+	_, ok = scrubSiblings(siblings, true)
+	test.IsEqualBool(t, ok, true)
+}
+
+func TestStartScrub(t *testing.T) {
+	content := []byte("This is a file for scrub run testing")
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	err = os.WriteFile(getBlobPath("test/data", file.SHA1), []byte("modified content"), 0600)
+	test.IsNil(t, err)
+
+	test.IsEqualBool(t, StartScrub(false), true)
+	test.IsEqualBool(t, GetScrubReport().StartTime > 0, true)
+	for GetScrubReport().IsRunning {
+		test.IsEqualBool(t, StartScrub(false), false)
+		time.Sleep(10 * time.Millisecond)
+	}
+	report := GetScrubReport()
+	test.IsEqualBool(t, report.EndTime >= report.StartTime, true)
+	test.IsEqualBool(t, report.CheckedFiles > 0, true)
+	isReported := false
+	for _, problem := range report.Problems {
+		if problem.SHA1 == file.SHA1 {
+			isReported = true
+			test.IsEqualString(t, problem.Type, models.ScrubProblemMismatch)
+			test.IsEqualBool(t, problem.IsQuarantined, false)
+			test.IsEqualString(t, problem.FileIds[0], file.Id)
+		}
+	}
+	test.IsEqualBool(t, isReported, true)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, retrievedFile.IsQuarantined, false)
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+}
@@ -0,0 +1,57 @@
+package storage
+
+/**
+Migrating stored files from the flat layout of older versions to the sharded directory layout
+*/
+
+import (
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"os"
+)
+
+// MigrateStorageLayout moves all locally stored files to the sharded directory layout and all temporary
+// files to the temp directory. Files that cannot be moved are skipped and can still be read from the
+// old location. Returns the number of moved stored files and the number of errors
+func MigrateStorageLayout() (int, int) {
+	movedFiles := 0
+	errorCount := 0
+	for _, file := range database.GetAllMetadata() {
+		isMoved, err := filesystem.MigrateToShardedLayout(file)
+		if err != nil {
+			fmt.Println("Warning, cannot move file " + file.Id + ": " + err.Error())
+			errorCount++
+			continue
+		}
+		if isMoved {
+			movedFiles++
+		}
+	}
+	errorCount = errorCount + moveLegacyTempFiles()
+	return movedFiles, errorCount
+}
+
+// moveLegacyTempFiles moves temporary chunk or upload files from the data directory to the temp directory.
+// Returns the number of errors
+func moveLegacyTempFiles() int {
+	dataDir := configuration.Get().DataDir
+	files, err := os.ReadDir(dataDir)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	errorCount := 0
+	for _, file := range files {
+		if !isTempFile(file) {
+			continue
+		}
+		err = os.Rename(dataDir+"/"+file.Name(), configuration.GetTempDir()+"/"+file.Name())
+		if err != nil {
+			fmt.Println(err)
+			errorCount++
+		}
+	}
+	return errorCount
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"os"
+	"testing"
+)
+
+// getBlobPath returns the path of a stored file in the sharded layout of the given directory
+func getBlobPath(directory, sha1 string) string {
+	return directory + "/blobs/" + sha1[0:2] + "/" + sha1[2:4] + "/" + sha1
+}
+
+func TestMigrateStorageLayout(t *testing.T) {
+	const sha1 = "9a4c1b2e5e0d3fb4a8f1e6b7c2d5a3f9e0b1c2d3"
+	file := models.File{Id: "layouttest", SHA1: sha1, SizeBytes: 14, UnlimitedTime: true, UnlimitedDownloads: true}
+	database.SaveMetaData(file)
+	err := os.WriteFile("test/data/"+sha1, []byte("Legacy content"), 0600)
+	test.IsNil(t, err)
+	err = os.WriteFile("test/data/chunk-layouttest", []byte("Chunk content"), 0600)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, FileExists(file), true)
+
+	moved, errors := MigrateStorageLayout()
+	test.IsEqualInt(t, errors, 0)
+	test.IsEqualBool(t, moved > 0, true)
+	test.FileDoesNotExist(t, "test/data/"+sha1)
+	test.FileExists(t, getBlobPath("test/data", sha1))
+	test.FileDoesNotExist(t, "test/data/chunk-layouttest")
+	test.FileExists(t, "test/data/tmp/chunk-layouttest")
+	test.IsEqualBool(t, FileExists(file), true)
+
+	// Running the migration again does not move any files
+	moved, errors = MigrateStorageLayout()
+	test.IsEqualInt(t, errors, 0)
+	test.IsEqualInt(t, moved, 0)
+
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+	test.FileDoesNotExist(t, getBlobPath("test/data", sha1))
+	test.IsNil(t, os.Remove("test/data/tmp/chunk-layouttest"))
+}
@@ -0,0 +1,236 @@
+package storage
+
+/**
+Creating and serving thumbnails of uploaded images and PDF files
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // Registers the PNG decoder
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// thumbnailSize is the maximum width and height of a thumbnail in pixels
+const thumbnailSize = 320
+
+// thumbnailSuffix is appended to the hash of a file to get the name of the stored thumbnail
+const thumbnailSuffix = "-thumb"
+
+// maxThumbnailSourceBytes is the maximum size of an image that a thumbnail is created for
+const maxThumbnailSourceBytes = 50 * 1024 * 1024
+
+// maxThumbnailSourcePixels is the maximum resolution of an image that a thumbnail is created for
+const maxThumbnailSourcePixels = 40 * 1000 * 1000
+
+// thumbnailFileExtensions contains all file extensions that thumbnails can be created for
+var thumbnailFileExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".pdf"}
+
+// ErrorNoThumbnail is raised when a thumbnail is requested for a file that does not have one
+var ErrorNoThumbnail = errors.New("no thumbnail available")
+
+// IsThumbnailSupported returns true, if a thumbnail can be created for the file. Thumbnails are not
+// created for encrypted files, as they would be stored unencrypted
+func IsThumbnailSupported(file models.File) bool {
+	return !file.Encryption.IsEncrypted &&
+		file.SizeBytes <= maxThumbnailSourceBytes &&
+		helper.IsInArray(thumbnailFileExtensions, getFileExtension(file.Name))
+}
+
+// createThumbnailInBackground creates the thumbnail for a new upload without blocking
+func createThumbnailInBackground(file models.File) {
+	recreateThumbnails([]models.File{file})
+}
+
+// recreateThumbnails creates the thumbnails for the files in the background, e.g. after their
+// stored content has been moved
+func recreateThumbnails(files []models.File) {
+	supportedFiles := make([]models.File, 0)
+	for _, file := range files {
+		if IsThumbnailSupported(file) {
+			supportedFiles = append(supportedFiles, file)
+		}
+	}
+	if len(supportedFiles) == 0 {
+		return
+	}
+	go func() {
+		for _, file := range supportedFiles {
+			err := createThumbnail(file)
+			if err != nil {
+				fmt.Println("Warning: Could not create thumbnail for file " + file.Id + ": " + err.Error())
+			}
+		}
+	}()
+}
+
+// createThumbnail creates and stores the thumbnail of the file. Files with the same content share
+// the thumbnail, so it is only created if it does not exist yet
+func createThumbnail(file models.File) error {
+	thumbnail := getThumbnailMetadata(file)
+	exists, _, err := filesystem.GetForFile(thumbnail).StatFile(thumbnail)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		var content bytes.Buffer
+		err = readStoredContent(file, &content)
+		if err != nil {
+			return err
+		}
+		var result bytes.Buffer
+		if getFileExtension(file.Name) == ".pdf" {
+			err = encodePdfThumbnail(content.Bytes(), &result)
+		} else {
+			err = encodeThumbnail(bytes.NewReader(content.Bytes()), &result)
+		}
+		if err != nil {
+			return err
+		}
+		err = filesystem.GetForFile(thumbnail).WriteToFilesystem(&result, thumbnail)
+		if err != nil {
+			return err
+		}
+	}
+	database.SetThumbnailStatus(file.Id, true)
+	return nil
+}
+
+// encodeThumbnail decodes the image from input and writes a scaled down JPEG image to output
+func encodeThumbnail(input io.ReadSeeker, output io.Writer) error {
+	config, _, err := image.DecodeConfig(input)
+	if err != nil {
+		return err
+	}
+	if int64(config.Width)*int64(config.Height) > maxThumbnailSourcePixels {
+		return errors.New("image resolution is too high")
+	}
+	_, err = input.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	source, _, err := image.Decode(input)
+	if err != nil {
+		return err
+	}
+	return jpeg.Encode(output, scaleImage(source, thumbnailSize), &jpeg.Options{Quality: 80})
+}
+
+// encodePdfThumbnail writes a scaled down JPEG image of the first image embedded in the PDF content to output
+func encodePdfThumbnail(content []byte, output io.Writer) error {
+	source, err := extractPdfImage(content)
+	if err != nil {
+		return err
+	}
+	return jpeg.Encode(output, scaleImage(source, thumbnailSize), &jpeg.Options{Quality: 80})
+}
+
+// getThumbnailDimensions returns the width and height of the thumbnail, keeping the aspect ratio.
+// Images are not enlarged
+func getThumbnailDimensions(width, height, maxSize int) (int, int) {
+	if width <= maxSize && height <= maxSize {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	}
+	return max(1, width*maxSize/height), maxSize
+}
+
+// scaleImage scales the image down to fit into maxSize, by averaging the pixels of the source
+// that make up a pixel of the result. Transparent areas are drawn on a white background,
+// as JPEG does not support transparency
+func scaleImage(source image.Image, maxSize int) *image.RGBA {
+	bounds := source.Bounds()
+	width, height := getThumbnailDimensions(bounds.Dx(), bounds.Dy(), maxSize)
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sourceY0 := bounds.Min.Y + y*bounds.Dy()/height
+		sourceY1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, sourceY0+1)
+		for x := 0; x < width; x++ {
+			sourceX0 := bounds.Min.X + x*bounds.Dx()/width
+			sourceX1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, sourceX0+1)
+			var r, g, b, a, count uint64
+			for sourceY := sourceY0; sourceY < sourceY1; sourceY++ {
+				for sourceX := sourceX0; sourceX < sourceX1; sourceX++ {
+					pr, pg, pb, pa := source.At(sourceX, sourceY).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			// The colours are premultiplied with the alpha value, so only the white background has to be added
+			background := count*0xffff - a
+			result.SetRGBA(x, y, color.RGBA{
+				R: uint8(((r + background) / count) >> 8),
+				G: uint8(((g + background) / count) >> 8),
+				B: uint8(((b + background) / count) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return result
+}
+
+// getThumbnailMetadata returns the metadata that is used to store the thumbnail next to the content of the file
+func getThumbnailMetadata(file models.File) models.File {
+	return models.File{
+		Id:            file.Id,
+		Name:          file.Name + ".jpg",
+		SHA1:          file.SHA1 + thumbnailSuffix,
+		ContentType:   "image/jpeg",
+		AwsBucket:     file.AwsBucket,
+		StorageDriver: file.StorageDriver,
+		StorageTarget: file.StorageTarget,
+	}
+}
+
+// ServeThumbnail sends the thumbnail of the file to the client. Viewing a thumbnail does not count as a download
+func ServeThumbnail(file models.File, w http.ResponseWriter) error {
+	if !file.HasThumbnail || file.Encryption.IsEndToEndEncrypted {
+		return ErrorNoThumbnail
+	}
+	thumbnail := getThumbnailMetadata(file)
+	exists, size, err := filesystem.GetForFile(thumbnail).StatFile(thumbnail)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrorNoThumbnail
+	}
+	reader, err := filesystem.GetForFile(thumbnail).OpenFile(thumbnail, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", thumbnail.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// deleteThumbnail removes the stored thumbnail of the file, if it exists
+func deleteThumbnail(file models.File) {
+	if !file.HasThumbnail && !IsThumbnailSupported(file) {
+		return
+	}
+	thumbnail := getThumbnailMetadata(file)
+	exists, _, err := filesystem.GetForFile(thumbnail).StatFile(thumbnail)
+	if err != nil || !exists {
+		return
+	}
+	err = filesystem.GetForFile(thumbnail).DeleteFile(thumbnail)
+	if err != nil {
+		fmt.Println("Warning, cannot delete thumbnail of file " + file.Id + ": " + err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/test"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+)
+
+func createTestImage(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x >= width/2 {
+				img.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	test.IsNil(t, err)
+	return buf.Bytes()
+}
+
+func storeTestImage(t *testing.T, id, name string, content []byte) models.File {
+	file := models.File{
+		Id:                 id,
+		Name:               name,
+		SHA1:               id + "content",
+		SizeBytes:          int64(len(content)),
+		ExpireAt:           2147483600,
+		DownloadsRemaining: 1,
+	}
+	err := filesystem.GetForFile(file).WriteToFilesystem(bytes.NewReader(content), file)
+	test.IsNil(t, err)
+	database.SaveMetaData(file)
+	return file
+}
+
+func TestIsThumbnailSupported(t *testing.T) {
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.jpg"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.PNG"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.gif"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.svg"}), false)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.pdf"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.jpg", SizeBytes: maxThumbnailSourceBytes + 1}), false)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "test.jpg", Encryption: models.EncryptionInfo{IsEncrypted: true}}), false)
+}
+
+func TestGetThumbnailDimensions(t *testing.T) {
+	width, height := getThumbnailDimensions(100, 50, 320)
+	test.IsEqualInt(t, width, 100)
+	test.IsEqualInt(t, height, 50)
+	width, height = getThumbnailDimensions(1000, 500, 320)
+	test.IsEqualInt(t, width, 320)
+	test.IsEqualInt(t, height, 160)
+	width, height = getThumbnailDimensions(500, 1000, 320)
+	test.IsEqualInt(t, width, 160)
+	test.IsEqualInt(t, height, 320)
+	width, height = getThumbnailDimensions(10000, 1, 320)
+	test.IsEqualInt(t, width, 320)
+	test.IsEqualInt(t, height, 1)
+}
+
+func TestScaleImage(t *testing.T) {
+	source, err := png.Decode(bytes.NewReader(createTestImage(t, 4, 2)))
+	test.IsNil(t, err)
+	result := scaleImage(source, 2)
+	test.IsEqualInt(t, result.Bounds().Dx(), 2)
+	test.IsEqualInt(t, result.Bounds().Dy(), 1)
+	// The transparent half is drawn on a white background
+	test.IsEqual(t, result.RGBAAt(0, 0), color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	test.IsEqual(t, result.RGBAAt(1, 0), color.RGBA{R: 0xff, A: 0xff})
+}
+
+func TestCreateThumbnail(t *testing.T) {
+	file := storeTestImage(t, "thumbnailtest", "image.png", createTestImage(t, 640, 320))
+	w := httptest.NewRecorder()
+	test.IsEqual(t, ServeThumbnail(file, w), ErrorNoThumbnail)
+
+	err := createThumbnail(file)
+	test.IsNil(t, err)
+	file, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, file.HasThumbnail, true)
+
+	w = httptest.NewRecorder()
+	err = ServeThumbnail(file, w)
+	test.IsNil(t, err)
+	test.IsEqualString(t, w.Header().Get("Content-Type"), "image/jpeg")
+	thumbnail, err := jpeg.Decode(w.Body)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, thumbnail.Bounds().Dx(), 320)
+	test.IsEqualInt(t, thumbnail.Bounds().Dy(), 160)
+
+	// A file with the same content reuses the existing thumbnail
+	duplicate := file
+	duplicate.Id = "thumbnailtestduplicate"
+	duplicate.HasThumbnail = false
+	database.SaveMetaData(duplicate)
+	err = createThumbnail(duplicate)
+	test.IsNil(t, err)
+	duplicate, _ = database.GetMetaDataById(duplicate.Id)
+	test.IsEqualBool(t, duplicate.HasThumbnail, true)
+
+	deleteThumbnail(file)
+	w = httptest.NewRecorder()
+	test.IsEqual(t, ServeThumbnail(file, w), ErrorNoThumbnail)
+	file.Encryption.IsEndToEndEncrypted = true
+	test.IsEqual(t, ServeThumbnail(file, w), ErrorNoThumbnail)
+
+	invalidFile := storeTestImage(t, "thumbnailinvalid", "image.jpg", []byte("This is not an image"))
+	err = createThumbnail(invalidFile)
+	test.IsNotNil(t, err)
+	invalidFile, _ = database.GetMetaDataById(invalidFile.Id)
+	test.IsEqualBool(t, invalidFile.HasThumbnail, false)
+
+	deleteSource(file)
+	database.DeleteMetaData(file.Id)
+	database.DeleteMetaData(duplicate.Id)
+	deleteSource(invalidFile)
+	database.DeleteMetaData(invalidFile.Id)
+}
@@ -0,0 +1,293 @@
+package storage
+
+/**
+Moving files that are not used anymore from local storage to S3
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/compression"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"io"
+	"sync"
+	"time"
+)
+
+// TieringPolicy defines which files are moved from local storage to S3
+type TieringPolicy struct {
+	// MinAgeDays moves files that have been uploaded at least this many days ago. Disabled if 0
+	MinAgeDays int
+	// IdleDays moves files that have not been downloaded for this many days. Disabled if 0
+	IdleDays int
+	// Target is the name of an S3 storage target the files are moved to. If empty, the default bucket is used
+	Target string
+}
+
+// IsEnabled returns true if files are moved to S3 by this policy
+func (p TieringPolicy) IsEnabled() bool {
+	return p.MinAgeDays > 0 || p.IdleDays > 0
+}
+
+// isMatching returns true if the file is old enough or has not been downloaded for long enough
+func (p TieringPolicy) isMatching(file models.File, timeNow int64) bool {
+	const secondsPerDay = 24 * 60 * 60
+	if p.MinAgeDays > 0 && timeNow-file.UploadDate >= int64(p.MinAgeDays)*secondsPerDay {
+		return true
+	}
+	lastActivity := max(file.UploadDate, file.LastDownload)
+	return p.IdleDays > 0 && timeNow-lastActivity >= int64(p.IdleDays)*secondsPerDay
+}
+
+var tieringMutex sync.Mutex
+
+// RunTiering moves all local files that match the policy to S3.
+// If the parameter periodic is true, this function is recursive and calls itself every hour.
+func RunTiering(policy TieringPolicy, periodic bool) {
+	if policy.IsEnabled() {
+		tieringMutex.Lock()
+		moveOldFilesToCloud(policy, time.Now().Unix())
+		tieringMutex.Unlock()
+	}
+	if periodic {
+		go func() {
+			select {
+			case <-time.After(time.Hour):
+				RunTiering(policy, periodic)
+			}
+		}()
+	}
+}
+
+func moveOldFilesToCloud(policy TieringPolicy, timeNow int64) {
+	setDestination, err := getTieringDestination(policy)
+	if err != nil {
+		fmt.Println("Warning: Cannot move files to cloud storage: " + err.Error())
+		return
+	}
+	for _, siblings := range getLocalSiblingGroups(timeNow) {
+		if !isGroupReadyForTiering(siblings, policy, timeNow) {
+			continue
+		}
+		err = moveToCloud(siblings, setDestination)
+		if err != nil {
+			fmt.Println("Warning: Cannot move file " + siblings[0].Id + " to cloud storage: " + err.Error())
+		}
+	}
+}
+
+// getTieringDestination returns a function that sets the values of a file that are required
+// to store it on the S3 destination of the policy
+func getTieringDestination(policy TieringPolicy) (func(file *models.File), error) {
+	if policy.Target != "" {
+		targetType, ok := filesystem.GetTargetType(policy.Target)
+		if !ok {
+			return nil, errors.New("storage target " + policy.Target + " does not exist")
+		}
+		if targetType != models.StorageTypeS3 {
+			return nil, errors.New("storage target " + policy.Target + " is not an S3 target")
+		}
+		return func(file *models.File) {
+			filesystem.ApplyTarget(policy.Target, file)
+		}, nil
+	}
+	if !aws.IsAvailable() {
+		return nil, errors.New("no valid S3 configuration has been provided")
+	}
+	return func(file *models.File) {
+		file.StorageTarget = ""
+		file.StorageDriver = ""
+		aws.AddBucketName(file)
+	}, nil
+}
+
+// getLocalSiblingGroups returns all files that are stored locally, grouped by the stored content.
+// Files of a group are deduplicated and share the same stored file
+func getLocalSiblingGroups(timeNow int64) [][]models.File {
+	return getSiblingGroups(func(file models.File) bool {
+		return file.IsLocalStorage() && !IsExpiredFile(file, timeNow) && !file.IsPendingForDeletion()
+	})
+}
+
+// isGroupReadyForTiering returns true if all files that share the stored content match the policy
+// and none of them is currently being downloaded
+func isGroupReadyForTiering(siblings []models.File, policy TieringPolicy, timeNow int64) bool {
+	for _, file := range siblings {
+		if configuration.Get().PicturesAlwaysLocal && isPictureFile(file.Name) {
+			return false
+		}
+		if !policy.isMatching(file, timeNow) || downloadstatus.IsCurrentlyDownloading(file) {
+			return false
+		}
+	}
+	return true
+}
+
+// moveToCloud uploads the stored content of the siblings to S3, updates the metadata of all siblings
+// and removes the local file afterwards
+func moveToCloud(siblings []models.File, setDestination func(file *models.File)) error {
+	source := siblings[0]
+	destination := source
+	setDestination(&destination)
+	if destination.IsLocalStorage() {
+		return errors.New("destination is not a cloud storage")
+	}
+
+	existingFile, ok := getExistingStoredSibling(destination)
+	if ok {
+		// The content is already stored on the destination, e.g. if it was uploaded again after the
+		// first upload had been moved. The stored content is reused
+		destination.Encryption = existingFile.Encryption
+		destination.Compression = existingFile.Compression
+		destination.CompressedBytes = existingFile.CompressedBytes
+	} else {
+		err := uploadForTiering(source, &destination)
+		if err != nil {
+			return err
+		}
+	}
+
+	movedFiles := make([]models.File, 0, len(siblings))
+	for _, file := range siblings {
+		current, ok := database.GetMetaDataById(file.Id)
+		if !ok {
+			continue
+		}
+		current.AwsBucket = destination.AwsBucket
+		current.StorageDriver = destination.StorageDriver
+		current.StorageTarget = destination.StorageTarget
+		current.Encryption = destination.Encryption
+		current.Compression = destination.Compression
+		current.CompressedBytes = destination.CompressedBytes
+		if current.HotlinkId != "" && current.RequiresClientDecryption() {
+			database.DeleteHotlink(current.HotlinkId)
+			current.HotlinkId = ""
+		}
+		current.HasThumbnail = false
+		database.SaveMetaData(current)
+		movedFiles = append(movedFiles, current)
+	}
+	recreateThumbnails(movedFiles)
+
+	if isStillReferenced(source) {
+		return nil
+	}
+	deleteThumbnail(source)
+	return filesystem.GetForFile(source).DeleteFile(source)
+}
+
+// getExistingStoredSibling returns a file that is already stored with the same content on the destination
+func getExistingStoredSibling(destination models.File) (models.File, bool) {
+	for _, file := range database.GetAllMetadata() {
+		if file.SHA1 == destination.SHA1 && isSameStorageLocation(file, destination) && FileExists(file) {
+			return file, true
+		}
+	}
+	return models.File{}, false
+}
+
+// isStillReferenced returns true if a file was added during the upload, that uses the local stored content
+func isStillReferenced(source models.File) bool {
+	for _, file := range database.GetAllMetadata() {
+		if file.SHA1 == source.SHA1 && isSameStorageLocation(file, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadForTiering uploads the stored content of source to the destination. Depending on the encryption
+// level, the content is decrypted or encrypted before uploading. The encryption and compression info
+// of destination is updated accordingly
+func uploadForTiering(source models.File, destination *models.File) error {
+	reader, err := filesystem.GetForFile(source).OpenFile(source, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var input io.Reader = reader
+	isEncryptionForCloudRequested := isCloudEncryptionRequested()
+	if source.Compression != "" && isEncryptionForCloudRequested {
+		// Encrypted files on cloud storage are decrypted by the browser, which cannot decompress them
+		return uploadDecompressedForTiering(source, reader, destination)
+	}
+	switch {
+	case source.Encryption.IsEndToEndEncrypted:
+		// End-to-end encrypted files can only be decrypted by the client
+	case source.Encryption.IsEncrypted && !isEncryptionForCloudRequested:
+		cipher, err := encryption.GetCipherFromFile(source.Encryption)
+		if err != nil {
+			return err
+		}
+		input, err = encryption.GetDecryptReader(cipher, reader)
+		if err != nil {
+			return err
+		}
+		destination.Encryption = models.EncryptionInfo{}
+	case !source.Encryption.IsEncrypted && isEncryptionForCloudRequested:
+		return uploadEncryptedForTiering(reader, destination)
+	}
+	return filesystem.GetForFile(*destination).WriteToFilesystem(input, *destination)
+}
+
+// uploadDecompressedForTiering decrypts and decompresses the stored content of source and uploads it
+// encrypted to the destination
+func uploadDecompressedForTiering(source models.File, reader io.Reader, destination *models.File) error {
+	input := reader
+	if source.Encryption.IsEncrypted {
+		cipher, err := encryption.GetCipherFromFile(source.Encryption)
+		if err != nil {
+			return err
+		}
+		input, err = encryption.GetDecryptReader(cipher, reader)
+		if err != nil {
+			return err
+		}
+	}
+	decompressed, err := compression.GetDecompressReader(source.Compression, input)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+	err = uploadEncryptedForTiering(decompressed, destination)
+	if err != nil {
+		return err
+	}
+	destination.Compression = ""
+	destination.CompressedBytes = 0
+	return nil
+}
+
+// uploadEncryptedForTiering encrypts the input and uploads it to the destination
+func uploadEncryptedForTiering(input io.Reader, destination *models.File) error {
+	pipeReader, pipeWriter := io.Pipe()
+	encInfo := models.EncryptionInfo{}
+	go func() {
+		pipeWriter.CloseWithError(encryption.Encrypt(&encInfo, input, pipeWriter))
+	}()
+	err := filesystem.GetForFile(*destination).WriteToFilesystem(pipeReader, *destination)
+	_ = pipeReader.CloseWithError(errors.New("upload has been aborted"))
+	if err != nil {
+		return err
+	}
+	encInfo.IsEncrypted = true
+	destination.Encryption = encInfo
+	return nil
+}
+
+// isCloudEncryptionRequested returns true if files that are stored on cloud storage are encrypted server-side
+func isCloudEncryptionRequested() bool {
+	switch configuration.Get().Encryption.Level {
+	case encryption.FullEncryptionStored, encryption.FullEncryptionInput:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/compression"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"testing"
+)
+
+func TestTieringPolicy(t *testing.T) {
+	const day = 24 * 60 * 60
+	policy := TieringPolicy{}
+	test.IsEqualBool(t, policy.IsEnabled(), false)
+	policy.MinAgeDays = 10
+	test.IsEqualBool(t, policy.IsEnabled(), true)
+	file := models.File{UploadDate: 100 * day}
+	test.IsEqualBool(t, policy.isMatching(file, 105*day), false)
+	test.IsEqualBool(t, policy.isMatching(file, 110*day), true)
+
+	policy = TieringPolicy{IdleDays: 5}
+	test.IsEqualBool(t, policy.IsEnabled(), true)
+	test.IsEqualBool(t, policy.isMatching(file, 104*day), false)
+	test.IsEqualBool(t, policy.isMatching(file, 105*day), true)
+	file.LastDownload = 103 * day
+	test.IsEqualBool(t, policy.isMatching(file, 105*day), false)
+	test.IsEqualBool(t, policy.isMatching(file, 108*day), true)
+
+	// Does not move any files
+	RunTiering(TieringPolicy{}, false)
+	RunTiering(TieringPolicy{MinAgeDays: 1, Target: "invalid"}, false)
+	RunTiering(TieringPolicy{MinAgeDays: 1, Target: "local-fast"}, false)
+}
+
+func TestGetTieringDestination(t *testing.T) {
+	_, err := getTieringDestination(TieringPolicy{MinAgeDays: 1, Target: "invalid"})
+	test.IsNotNil(t, err)
+	err = filesystem.AddTarget(models.StorageTargetConfig{
+		Name:      "local-fast",
+		Type:      models.StorageTypeLocal,
+		Directory: "test/fast",
+	}, models.AwsConfig{})
+	test.IsNil(t, err)
+	defer filesystem.ClearTargets()
+	_, err = getTieringDestination(TieringPolicy{MinAgeDays: 1, Target: "local-fast"})
+	test.IsNotNil(t, err)
+	if !aws.IsAvailable() {
+		_, err = getTieringDestination(TieringPolicy{MinAgeDays: 1})
+		test.IsNotNil(t, err)
+	}
+}
+
+func TestGetLocalSiblingGroups(t *testing.T) {
+	content := []byte("This is a file for tiering group testing")
+	header, request := createRawTestFile(content)
+	file1, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	file2, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file1.SHA1, file2.SHA1)
+
+	var siblings []models.File
+	for _, group := range getLocalSiblingGroups(0) {
+		if group[0].SHA1 == file1.SHA1 {
+			test.IsEqualBool(t, siblings == nil, true)
+			siblings = group
+		}
+	}
+	test.IsEqualInt(t, len(siblings), 2)
+
+	policy := TieringPolicy{MinAgeDays: 1}
+	timeNow := file1.UploadDate + 2*24*60*60
+	test.IsEqualBool(t, isGroupReadyForTiering(siblings, policy, timeNow), true)
+	test.IsEqualBool(t, isGroupReadyForTiering(siblings, policy, file1.UploadDate), false)
+	downloadstatus.SetDownload(file2)
+	test.IsEqualBool(t, isGroupReadyForTiering(siblings, policy, timeNow), false)
+	downloadstatus.DeleteAll()
+	configuration.Get().PicturesAlwaysLocal = true
+	siblings[0].Name = "picture.jpg"
+	test.IsEqualBool(t, isGroupReadyForTiering(siblings, policy, timeNow), false)
+	configuration.Get().PicturesAlwaysLocal = false
+	test.IsEqualBool(t, isGroupReadyForTiering(siblings, policy, timeNow), true)
+
+	deleteSource(file1)
+	deleteSource(file2)
+}
+
+func TestMoveToCloud(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	content := []byte("This is a file for tiering testing")
+	header, request := createRawTestFile(content)
+	file1, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	file2, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, file1.IsLocalStorage(), true)
+	test.FileExists(t, getBlobPath("test/data", file1.SHA1))
+
+	testconfiguration.EnableS3()
+	config, ok := cloudconfig.Load()
+	test.IsEqualBool(t, ok, true)
+	ok = aws.Init(config.Aws)
+	test.IsEqualBool(t, ok, true)
+	defer testconfiguration.DisableS3()
+
+	setDestination, err := getTieringDestination(TieringPolicy{MinAgeDays: 1})
+	test.IsNil(t, err)
+	err = moveToCloud([]models.File{file1, file2}, setDestination)
+	test.IsNil(t, err)
+	for _, id := range []string{file1.Id, file2.Id} {
+		retrievedFile, ok := database.GetMetaDataById(id)
+		test.IsEqualBool(t, ok, true)
+		test.IsEqualString(t, retrievedFile.AwsBucket, config.Aws.Bucket)
+		test.IsEqualBool(t, retrievedFile.IsLocalStorage(), false)
+		test.IsEqualBool(t, FileExists(retrievedFile), true)
+	}
+	test.FileDoesNotExist(t, getBlobPath("test/data", file1.SHA1))
+
+	// The content is already stored in the bucket and is not uploaded again
+	file3 := file1
+	file3.Id = "tieringtest3"
+	err = filesystem.GetForFile(file3).WriteToFilesystem(bytes.NewReader(content), file3)
+	test.IsNil(t, err)
+	test.FileExists(t, getBlobPath("test/data", file3.SHA1))
+	database.SaveMetaData(file3)
+	err = moveToCloud([]models.File{file3}, setDestination)
+	test.IsNil(t, err)
+	retrievedFile, ok := database.GetMetaDataById(file3.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.AwsBucket, config.Aws.Bucket)
+	test.FileDoesNotExist(t, getBlobPath("test/data", file3.SHA1))
+
+	database.DeleteMetaData(file1.Id)
+	database.DeleteMetaData(file2.Id)
+	database.DeleteMetaData(file3.Id)
+	deleteSource(retrievedFile)
+
+	// Files are encrypted if the encryption level requires it for cloud storage
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.FullEncryptionStored,
+		Cipher: cipher,
+	}})
+	configuration.Get().Encryption.Level = encryption.FullEncryptionStored
+	file4 := file1
+	file4.Id = "tieringtest4"
+	file4.AwsBucket = ""
+	file4.Encryption = models.EncryptionInfo{}
+	err = filesystem.GetForFile(file4).WriteToFilesystem(bytes.NewReader(content), file4)
+	test.IsNil(t, err)
+	database.SaveMetaData(file4)
+	err = moveToCloud([]models.File{file4}, setDestination)
+	test.IsNil(t, err)
+	configuration.Get().Encryption.Level = encryption.NoEncryption
+	retrievedFile, ok = database.GetMetaDataById(file4.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.AwsBucket, config.Aws.Bucket)
+	test.IsEqualBool(t, retrievedFile.Encryption.IsEncrypted, true)
+	test.FileDoesNotExist(t, getBlobPath("test/data", file4.SHA1))
+	reader, err := filesystem.GetForFile(retrievedFile).OpenFile(retrievedFile, 0, -1)
+	test.IsNil(t, err)
+	var decrypted bytes.Buffer
+	err = encryption.DecryptReader(retrievedFile.Encryption, reader, &decrypted)
+	test.IsNil(t, err)
+	reader.Close()
+	test.IsEqualString(t, decrypted.String(), string(content))
+	database.DeleteMetaData(file4.Id)
+	deleteSource(retrievedFile)
+}
+
+func TestMoveCompressedToCloud(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	configuration.Get().Compression = compression.AlgorithmZstd
+	content := []byte("This is a compressed file for tiering testing")
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 63, request)
+	configuration.Get().Compression = ""
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.Compression, compression.AlgorithmZstd)
+
+	testconfiguration.EnableS3()
+	config, ok := cloudconfig.Load()
+	test.IsEqualBool(t, ok, true)
+	ok = aws.Init(config.Aws)
+	test.IsEqualBool(t, ok, true)
+	defer testconfiguration.DisableS3()
+
+	// Files are decompressed, if they are encrypted on cloud storage
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.FullEncryptionStored,
+		Cipher: cipher,
+	}})
+	configuration.Get().Encryption.Level = encryption.FullEncryptionStored
+	setDestination, err := getTieringDestination(TieringPolicy{MinAgeDays: 1})
+	test.IsNil(t, err)
+	err = moveToCloud([]models.File{file}, setDestination)
+	configuration.Get().Encryption.Level = encryption.NoEncryption
+	test.IsNil(t, err)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, retrievedFile.AwsBucket, config.Aws.Bucket)
+	test.IsEqualString(t, retrievedFile.Compression, "")
+	test.IsEqualInt64(t, retrievedFile.CompressedBytes, 0)
+	test.IsEqualBool(t, retrievedFile.Encryption.IsEncrypted, true)
+	test.FileDoesNotExist(t, getBlobPath("test/data", file.SHA1))
+	reader, err := filesystem.GetForFile(retrievedFile).OpenFile(retrievedFile, 0, -1)
+	test.IsNil(t, err)
+	var decrypted bytes.Buffer
+	err = encryption.DecryptReader(retrievedFile.Encryption, reader, &decrypted)
+	test.IsNil(t, err)
+	reader.Close()
+	test.IsEqualString(t, decrypted.String(), string(content))
+	database.DeleteMetaData(file.Id)
+	deleteSource(retrievedFile)
+}
@@ -0,0 +1,205 @@
+package storage
+
+/**
+Streaming several files as a single ZIP file
+*/
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/notifications"
+	"github.com/forceu/gokapi/internal/webserver/bandwidth"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"github.com/forceu/gokapi/internal/webserver/sse"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorNoFilePermission is raised when a user requests a file that was uploaded by another user
+// without having the permission to list other uploads
+var ErrorNoFilePermission = errors.New("no permission to access file")
+
+// ErrorZipE2EFile is raised when an end-to-end encrypted file is requested as part of a ZIP file.
+// These files cannot be added, as they can only be decrypted by the client
+var ErrorZipE2EFile = errors.New("end-to-end encrypted files cannot be added to a ZIP file")
+
+// GetFilesForUser returns the files with the given IDs. An error is returned, if a file does not exist,
+// cannot be downloaded anymore or was uploaded by another user and the user is not allowed to list other uploads
+func GetFilesForUser(ids []string, user models.User) ([]models.File, error) {
+	return getFilesWithPermission(ids, user, models.UserPermListOtherUploads)
+}
+
+// getFilesWithPermission returns the files with the given IDs. An error is returned, if a file does not exist,
+// cannot be downloaded anymore or was uploaded by another user and the user does not have the permission
+func getFilesWithPermission(ids []string, user models.User, permission models.UserPermission) ([]models.File, error) {
+	files := make([]models.File, 0, len(ids))
+	for _, id := range ids {
+		file, ok := GetFile(id)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrorFileNotFound, id)
+		}
+		if file.UserId != user.Id && !user.HasPermission(permission) {
+			return nil, fmt.Errorf("%w: %s", ErrorNoFilePermission, id)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// ServeFilesAsZip subtracts a download allowance of every file and sends the files as a single ZIP file.
+// The ZIP file is streamed to the client without creating a temporary file. Returns an error before
+// anything is sent, if one of the files is end-to-end encrypted, or ErrorTooManyDownloads, if one of the
+// files or the client already has the maximum number of simultaneous downloads
+func ServeFilesAsZip(files []models.File, w http.ResponseWriter, r *http.Request) error {
+	for _, file := range files {
+		if file.Encryption.IsEndToEndEncrypted {
+			return ErrorZipE2EFile
+		}
+	}
+	statusIds, err := setZipDownloads(files, r)
+	if err != nil {
+		return err
+	}
+	defer setZipDownloadsComplete(statusIds)
+	saveIp := configuration.Get().SaveIp
+	events := make([]models.DownloadEvent, len(files))
+	for i, file := range files {
+		file = countZipFileDownload(file)
+		logging.LogDownload(file, r, saveIp)
+		events[i] = newDownloadEvent(file, r)
+		notifications.SendDownloadNotification(file, events[i])
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+getZipFilename("")+"\"")
+	w, releaseBandwidth := limitZipWriter(w)
+	defer releaseBandwidth()
+	err = writeZipAndSaveEvents(w, files, events, r)
+	if err != nil {
+		fmt.Println("Error while sending ZIP file: " + err.Error())
+	}
+	return nil
+}
+
+// writeZipAndSaveEvents writes the ZIP file and stores the download event of every file with the number
+// of bytes that have been sent. The events have to be in the same order as the files
+func writeZipAndSaveEvents(w io.Writer, files []models.File, events []models.DownloadEvent, r *http.Request) error {
+	isEventSaved := make([]bool, len(files))
+	err := writeZip(w, files, func(index int, bytesWritten int64, err error) {
+		saveDownloadEvent(events[index], bytesWritten, r, err == nil)
+		isEventSaved[index] = true
+	})
+	// Files that have not been reached, because sending the ZIP file failed before
+	for i, event := range events {
+		if !isEventSaved[i] {
+			saveDownloadEvent(event, 0, r, false)
+		}
+	}
+	return err
+}
+
+// countZipFileDownload subtracts a download allowance of the file and increases its download counter.
+// Returns the file with the updated counters
+func countZipFileDownload(file models.File) models.File {
+	file.DownloadsRemaining = file.DownloadsRemaining - 1
+	file.DownloadCount = database.IncreaseDownloadCount(file.Id, !file.UnlimitedDownloads)
+	go sse.PublishDownloadCount(file)
+	return file
+}
+
+// setZipDownloads creates a download status for every file of a ZIP file. Returns ErrorTooManyDownloads,
+// if one of the files or the client already has the maximum number of simultaneous downloads.
+// The ZIP file counts as a single download of the client
+func setZipDownloads(files []models.File, r *http.Request) ([]string, error) {
+	maxFile := func(file models.File) int {
+		result, _ := getParallelDownloadLimits(file)
+		return result
+	}
+	statusIds, ok := downloadstatus.SetDownloadsIfAllowed(files, logging.GetIpAddress(r), maxFile, configuration.Get().ParallelDownloadIp)
+	if !ok {
+		return nil, ErrorTooManyDownloads
+	}
+	return statusIds, nil
+}
+
+// setZipDownloadsComplete removes the download status of every file of a ZIP file
+func setZipDownloadsComplete(statusIds []string) {
+	for _, statusId := range statusIds {
+		downloadstatus.SetComplete(statusId)
+	}
+}
+
+// limitZipWriter returns a writer that does not exceed the global bandwidth limit and the limit per download.
+// The limit per file is applied to every file in writeZip. The returned function has to be called,
+// once the ZIP file has been sent
+func limitZipWriter(w http.ResponseWriter) (http.ResponseWriter, func()) {
+	config := configuration.Get()
+	return bandwidth.LimitWriter(w, "", bandwidth.Limits{
+		Global:   config.BandwidthGlobalKB,
+		Download: config.BandwidthRequestKB,
+	})
+}
+
+// writeZip writes a ZIP file with the stored content of the files to output. Encrypted files are
+// decrypted and compressed files are decompressed. Remote files are downloaded by the server.
+// The content of every file does not exceed its bandwidth limit per file.
+// If onFileWritten is not nil, it is called with the index of every file, after its content has been written
+func writeZip(output io.Writer, files []models.File, onFileWritten func(index int, bytesWritten int64, err error)) error {
+	zipWriter := zip.NewWriter(output)
+	usedNames := make(map[string]bool)
+	for i, file := range files {
+		header := &zip.FileHeader{
+			Name:     getUniqueZipEntryName(file.Name, usedNames),
+			Method:   zip.Deflate,
+			Modified: time.Unix(file.UploadDate, 0),
+		}
+		entry, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		counter := &countingWriter{Writer: entry}
+		limitedEntry, releaseBandwidth := bandwidth.LimitStream(counter, file.Id, bandwidth.Limits{File: getBandwidthLimits(file).File})
+		err = readStoredContent(file, limitedEntry)
+		releaseBandwidth()
+		if onFileWritten != nil {
+			onFileWritten(i, counter.bytesWritten, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// getUniqueZipEntryName returns the filename without path separators. If a file with the same name
+// has already been added to the ZIP file, a number is appended to the name
+func getUniqueZipEntryName(filename string, usedNames map[string]bool) string {
+	filename = strings.NewReplacer("/", "_", "\\", "_").Replace(filename)
+	if filename == "" || filename == "." || filename == ".." {
+		filename = "file"
+	}
+	result := filename
+	extension := path.Ext(filename)
+	base := strings.TrimSuffix(filename, extension)
+	for i := 1; usedNames[strings.ToLower(result)]; i++ {
+		result = base + " (" + strconv.Itoa(i) + ")" + extension
+	}
+	usedNames[strings.ToLower(result)] = true
+	return result
+}
+
+func getZipFilename(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_", "\"", "'").Replace(name)
+	if name == "" {
+		name = "files"
+	}
+	return name + ".zip"
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetFilesForUser(t *testing.T) {
+	newFile, err := createTestFile()
+	test.IsNil(t, err)
+	user := models.User{Id: 63}
+	files, err := GetFilesForUser([]string{newFile.File.Id}, user)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(files), 1)
+	test.IsEqualString(t, files[0].Id, newFile.File.Id)
+
+	_, err = GetFilesForUser([]string{newFile.File.Id, "invalid"}, user)
+	test.IsEqualBool(t, errors.Is(err, ErrorFileNotFound), true)
+	test.IsEqualString(t, err.Error(), "file not found: invalid")
+
+	otherUser := models.User{Id: 64, UserLevel: models.UserLevelUser}
+	_, err = GetFilesForUser([]string{newFile.File.Id}, otherUser)
+	test.IsEqualBool(t, errors.Is(err, ErrorNoFilePermission), true)
+	otherUser.GrantPermission(models.UserPermListOtherUploads)
+	_, err = GetFilesForUser([]string{newFile.File.Id}, otherUser)
+	test.IsNil(t, err)
+	database.DeleteMetaData(newFile.File.Id)
+}
+
+func TestGetUniqueZipEntryName(t *testing.T) {
+	usedNames := make(map[string]bool)
+	test.IsEqualString(t, getUniqueZipEntryName("test.txt", usedNames), "test.txt")
+	test.IsEqualString(t, getUniqueZipEntryName("Test.txt", usedNames), "Test (1).txt")
+	test.IsEqualString(t, getUniqueZipEntryName("test.txt", usedNames), "test (2).txt")
+	test.IsEqualString(t, getUniqueZipEntryName("../dir/file", usedNames), ".._dir_file")
+	test.IsEqualString(t, getUniqueZipEntryName("..", usedNames), "file")
+	test.IsEqualString(t, getZipFilename("a/\"b\""), "a_'b'.zip")
+	test.IsEqualString(t, getZipFilename(""), "files.zip")
+}
+
+func TestServeFilesAsZip(t *testing.T) {
+	e2eFile := models.File{Id: "zipe2e", Encryption: models.EncryptionInfo{IsEndToEndEncrypted: true}}
+	w := httptest.NewRecorder()
+	err := ServeFilesAsZip([]models.File{e2eFile}, w, httptest.NewRequest("GET", "/", nil))
+	test.IsEqual(t, err, ErrorZipE2EFile)
+	test.IsEqualInt(t, w.Body.Len(), 0)
+
+	limitedFile, err := createTestFile()
+	test.IsNil(t, err)
+	unlimitedFile, err := createTestFile()
+	test.IsNil(t, err)
+	unlimitedFile.File.UnlimitedDownloads = true
+	database.SaveMetaData(unlimitedFile.File)
+
+	// Nothing is sent, if the client has the maximum number of simultaneous downloads
+	config := configuration.Get()
+	config.ParallelDownloadIp = 1
+	request := httptest.NewRequest("GET", "/", nil)
+	statusId, ok := downloadstatus.SetDownloadIfAllowed(models.File{Id: "other"}, logging.GetIpAddress(request), 0, 0)
+	test.IsEqualBool(t, ok, true)
+	w = httptest.NewRecorder()
+	err = ServeFilesAsZip([]models.File{limitedFile.File, unlimitedFile.File}, w, request)
+	test.IsEqual(t, err, ErrorTooManyDownloads)
+	test.IsEqualInt(t, w.Body.Len(), 0)
+	_, ok = GetFile(limitedFile.File.Id)
+	test.IsEqualBool(t, ok, true)
+	downloadstatus.SetComplete(statusId)
+
+	// The ZIP file counts as a single download of the client
+	w = httptest.NewRecorder()
+	err = ServeFilesAsZip([]models.File{limitedFile.File, unlimitedFile.File}, w, request)
+	config.ParallelDownloadIp = 0
+	test.IsNil(t, err)
+	test.IsEqualBool(t, downloadstatus.IsCurrentlyDownloading(unlimitedFile.File), false)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Type"), "application/zip")
+	test.IsEqualString(t, w.Result().Header.Get("Content-Disposition"), "attachment; filename=\"files.zip\"")
+	content, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(zipReader.File), 2)
+	test.IsEqualString(t, zipReader.File[1].Name, "test (1).dat")
+	entry, err := zipReader.File[0].Open()
+	test.IsNil(t, err)
+	entryContent, err := io.ReadAll(entry)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(entryContent), "This is a file for testing purposes")
+
+	// The download counter of every file is reduced
+	_, ok = GetFile(limitedFile.File.Id)
+	test.IsEqualBool(t, ok, false)
+	file, ok := GetFile(unlimitedFile.File.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, file.DownloadCount, 1)
+	for _, id := range []string{limitedFile.File.Id, unlimitedFile.File.Id} {
+		events := database.GetDownloadEvents(id)
+		test.IsEqualInt(t, len(events), 1)
+		test.IsEqualInt64(t, events[0].BytesSent, 35)
+		test.IsEqualBool(t, events[0].IsComplete, true)
+		database.DeleteDownloadEvents(id)
+	}
+	database.DeleteMetaData(limitedFile.File.Id)
+	database.DeleteMetaData(unlimitedFile.File.Id)
+}
+
+func TestZipBandwidthLimits(t *testing.T) {
+	w := httptest.NewRecorder()
+	result, release := limitZipWriter(w)
+	test.IsEqualBool(t, result == w, true)
+	release()
+	config := configuration.Get()
+	config.BandwidthRequestKB = 1024
+	result, release = limitZipWriter(w)
+	config.BandwidthRequestKB = 0
+	test.IsEqualBool(t, result == w, false)
+	release()
+
+	// The limit per file is applied to the content of every file in the ZIP file
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	file, err := createTestArchive("limited.dat", content)
+	test.IsNil(t, err)
+	file.BandwidthLimitKB = 1
+	var buf bytes.Buffer
+	start := time.Now()
+	err = writeZip(&buf, []models.File{file}, nil)
+	test.IsNil(t, err)
+	// 256 bytes can be sent immediately, the remaining 768 bytes take 0.75 seconds
+	test.IsEqualBool(t, time.Since(start) >= 500*time.Millisecond, true)
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	test.IsNil(t, err)
+	entry, err := zipReader.File[0].Open()
+	test.IsNil(t, err)
+	entryContent, err := io.ReadAll(entry)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, entryContent, content)
+	database.DeleteMetaData(file.Id)
+}
@@ -0,0 +1,111 @@
+package chunking
+
+/**
+Verifying checksums that are sent by the client, so that corrupted chunks are rejected before they are written
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// ChecksumSha256 verifies the content with SHA-256
+const ChecksumSha256 = "sha256"
+
+// ChecksumCrc32c verifies the content with CRC-32C (Castagnoli), which is faster but only detects accidental corruption
+const ChecksumCrc32c = "crc32c"
+
+// ErrorChecksumMismatch is raised when the received content does not match the checksum sent by the client.
+// The client should upload the content again
+var ErrorChecksumMismatch = errors.New("checksum does not match")
+
+// ErrorInvalidChecksum is raised when the checksum sent by the client cannot be parsed
+var ErrorInvalidChecksum = errors.New("invalid checksum provided, expected format is sha256:<hex> or crc32c:<hex>")
+
+// Checksum is a checksum sent by the client. It is empty, if the client did not send a checksum
+type Checksum struct {
+	Algorithm string
+	Value     []byte
+}
+
+// ParseChecksum parses a checksum in the format algorithm:hexvalue. Returns an empty checksum, if input is empty
+func ParseChecksum(input string) (Checksum, error) {
+	if input == "" {
+		return Checksum{}, nil
+	}
+	algorithm, value, found := strings.Cut(input, ":")
+	if !found {
+		return Checksum{}, ErrorInvalidChecksum
+	}
+	result := Checksum{Algorithm: strings.ToLower(strings.TrimSpace(algorithm))}
+	contentHash, ok := result.newHash()
+	if !ok {
+		return Checksum{}, ErrorInvalidChecksum
+	}
+	decoded, err := hex.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(decoded) != contentHash.Size() {
+		return Checksum{}, ErrorInvalidChecksum
+	}
+	result.Value = decoded
+	return result, nil
+}
+
+// IsEmpty returns true, if the client did not send a checksum
+func (c Checksum) IsEmpty() bool {
+	return c.Algorithm == ""
+}
+
+// newHash returns a hash for the algorithm of the checksum. Returns false, if the algorithm is not supported
+func (c Checksum) newHash() (hash.Hash, bool) {
+	switch c.Algorithm {
+	case ChecksumSha256:
+		return sha256.New(), true
+	case ChecksumCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), true
+	default:
+		return nil, false
+	}
+}
+
+// Verify reads the content and returns ErrorChecksumMismatch, if it does not match the checksum.
+// Does not read the content, if the checksum is empty
+func (c Checksum) Verify(content io.Reader) error {
+	if c.IsEmpty() {
+		return nil
+	}
+	contentHash, ok := c.newHash()
+	if !ok {
+		return ErrorInvalidChecksum
+	}
+	_, err := io.Copy(contentHash, content)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(contentHash.Sum(nil), c.Value) {
+		return ErrorChecksumMismatch
+	}
+	return nil
+}
+
+// verifyChunk checks the content of the chunk against the checksum and resets the content to the start afterwards
+func verifyChunk(chunkContent io.Reader, checksum Checksum) error {
+	if checksum.IsEmpty() {
+		return nil
+	}
+	seeker, ok := chunkContent.(io.ReadSeeker)
+	if !ok {
+		return errors.New("checksum cannot be verified for this chunk")
+	}
+	err := checksum.Verify(seeker)
+	if err != nil {
+		return err
+	}
+	_, err = seeker.Seek(0, io.SeekStart)
+	return err
+}
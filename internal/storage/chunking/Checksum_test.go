@@ -0,0 +1,73 @@
+package chunking
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+const testContentSha256 = "sha256:985bee5cee8b11457985415cb3864ddb04e167f9ade692af9ad859ffb6e2d8ca"
+const testContentCrc32c = "crc32c:0aff32c4"
+
+func TestParseChecksum(t *testing.T) {
+	checksum, err := ParseChecksum("")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, checksum.IsEmpty(), true)
+
+	checksum, err = ParseChecksum(testContentSha256)
+	test.IsNil(t, err)
+	test.IsEqualString(t, checksum.Algorithm, ChecksumSha256)
+	test.IsEqualInt(t, len(checksum.Value), 32)
+	checksum, err = ParseChecksum("CRC32C:E3069283")
+	test.IsNil(t, err)
+	test.IsEqualString(t, checksum.Algorithm, ChecksumCrc32c)
+	test.IsEqualBool(t, checksum.IsEmpty(), false)
+
+	for _, invalid := range []string{"sha256", "md5:e3069283", "crc32c:e30692", "crc32c:invalid!", "sha256:e3069283"} {
+		_, err = ParseChecksum(invalid)
+		test.IsEqual(t, err, ErrorInvalidChecksum)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	for _, input := range []string{testContentSha256, testContentCrc32c} {
+		checksum, err := ParseChecksum(input)
+		test.IsNil(t, err)
+		test.IsNil(t, checksum.Verify(strings.NewReader("This is a test content")))
+		test.IsEqual(t, checksum.Verify(strings.NewReader("This is a test c0ntent")), ErrorChecksumMismatch)
+	}
+	checksum, err := ParseChecksum("crc32c:e3069283")
+	test.IsNil(t, err)
+	test.IsNil(t, checksum.Verify(strings.NewReader("123456789")))
+	test.IsNil(t, Checksum{}.Verify(strings.NewReader("123456789")))
+}
+
+func TestNewChunkWithChecksum(t *testing.T) {
+	checksum, err := ParseChecksum(testContentCrc32c)
+	test.IsNil(t, err)
+	info := ChunkInfo{
+		TotalFilesizeBytes: 100,
+		UUID:               "testuuidchecksum",
+		Checksum:           checksum,
+	}
+	header := multipart.FileHeader{Size: 22}
+
+	// A corrupted chunk is not written
+	err = NewChunk(strings.NewReader("This is a test c0ntent"), &header, info)
+	test.IsEqual(t, err, ErrorChecksumMismatch)
+	test.IsEqualBool(t, FileExists(info.UUID), false)
+
+	err = NewChunk(strings.NewReader("This is a test content"), &header, info)
+	test.IsNil(t, err)
+	status, ok := GetStatus(info.UUID)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, status.ReceivedBytes, 22)
+	DeleteChunk(info.UUID)
+
+	// The content has to be read twice for the verification
+	err = verifyChunk(io.MultiReader(strings.NewReader("This is a test content")), checksum)
+	test.IsNotNil(t, err)
+	test.IsNil(t, verifyChunk(io.MultiReader(strings.NewReader("This is a test content")), Checksum{}))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -19,6 +20,10 @@ type ChunkInfo struct {
 	TotalFilesizeBytes int64
 	Offset             int64
 	UUID               string
+	// UserId is the ID of the user that uploads the chunk
+	UserId int
+	// Checksum is verified before the chunk is written, if it was sent by the client
+	Checksum Checksum
 }
 
 // FileHeader contains info about the uploaded file
@@ -69,6 +74,10 @@ func ParseChunkInfo(r *http.Request, isApiCall bool) (ChunkInfo, error) {
 		return ChunkInfo{}, errors.New("invalid uuid submitted, needs to be at least 10 characters long")
 	}
 	info.UUID = sanitiseUuid(info.UUID)
+	info.Checksum, err = ParseChecksum(r.Header.Get("checksum"))
+	if err != nil {
+		return ChunkInfo{}, err
+	}
 	return info, nil
 }
 
@@ -152,7 +161,7 @@ func ParseMultipartHeader(header *multipart.FileHeader) (FileHeader, error) {
 }
 
 func getChunkFilePath(id string) string {
-	return configuration.Get().DataDir + "/chunk-" + id
+	return configuration.GetTempDir() + "/chunk-" + id
 }
 
 // GetFileByChunkId returns a handle to the chunk file
@@ -175,25 +184,45 @@ func FileExists(id string) bool {
 	return helper.FileExists(getChunkFilePath(id))
 }
 
-// NewChunk allocates the space for the new file and writes the chunk
+// NewChunk allocates the space for the new file and writes the chunk. If S3 is used for storage,
+// the chunk is uploaded as part of a multipart upload instead
 func NewChunk(chunkContent io.Reader, fileHeader *multipart.FileHeader, info ChunkInfo) error {
-	err := allocateFile(info)
+	err := verifyChunk(chunkContent, info.Checksum)
+	if err != nil {
+		return err
+	}
+	upload, ok := getMultipartUpload(info)
+	if ok {
+		return upload.addChunk(chunkContent, fileHeader.Size, info)
+	}
+	err = allocateFile(info)
 	if err != nil {
 		return err
 	}
 	return writeChunk(chunkContent, fileHeader, info)
 }
 
+// allocateFile creates the file for a new chunked upload with the total size of the upload.
+// Returns an error, if the free space of the data directory would drop below the reserve
 func allocateFile(info ChunkInfo) error {
 	if FileExists(info.UUID) {
 		return nil
 	}
+	// The space is reserved, as the sparse file does not use disk space until the chunks have been written
+	err := diskspace.Reserve(info.UUID, info.TotalFilesizeBytes)
+	if err != nil {
+		return err
+	}
 	file, err := os.OpenFile(getChunkFilePath(info.UUID), os.O_RDWR|os.O_CREATE, 0600)
-	defer file.Close()
 	if err != nil {
+		diskspace.Release(info.UUID)
 		return err
 	}
+	defer file.Close()
 	err = file.Truncate(info.TotalFilesizeBytes)
+	if err != nil {
+		diskspace.Release(info.UUID)
+	}
 	return err
 }
 
@@ -213,6 +242,21 @@ func writeChunk(chunkContent io.Reader, fileHeader *multipart.FileHeader, info C
 	if newOffset != info.Offset {
 		return errors.New("seek returned invalid offset")
 	}
-	_, err = io.Copy(file, chunkContent)
-	return err
+	written, err := io.Copy(file, chunkContent)
+	// Bytes that were received before the connection was interrupted are kept, so that the upload can be resumed
+	errManifest := addReceivedRange(info, info.Offset+written)
+	if err != nil {
+		return err
+	}
+	return errManifest
+}
+
+// DeleteChunk removes the chunk file and the manifest of an upload that will not be completed.
+// If the chunks are streamed to S3, the multipart upload is aborted
+func DeleteChunk(id string) {
+	id = sanitiseUuid(id)
+	abortMultipartUpload(id)
+	DeleteManifest(id)
+	_ = os.Remove(getChunkFilePath(id))
+	diskspace.Release(id)
 }
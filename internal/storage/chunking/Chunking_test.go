@@ -4,25 +4,38 @@ import (
 	"bytes"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 	"github.com/juju/ratelimit"
 	"golang.org/x/sync/errgroup"
+	"io"
+	"math"
 	"mime/multipart"
+	"net/http/httptest"
 	"net/textproto"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestMain(m *testing.M) {
 	testconfiguration.Create(false)
 	configuration.Load()
+	var testserver *httptest.Server
+	if testconfiguration.UseMockS3Server() {
+		testserver = testconfiguration.StartS3TestServer()
+	}
 	exitVal := m.Run()
 	testconfiguration.Delete()
+	if testserver != nil {
+		testserver.Close()
+	}
 	os.Exit(exitVal)
 }
 
@@ -124,6 +137,22 @@ func TestParseChunkInfo(t *testing.T) {
 	test.IsEqualInt64(t, info.TotalFilesizeBytes, 100000)
 	test.IsEqualInt64(t, info.Offset, 10)
 	test.IsEqualString(t, info.UUID, "fweflwfejkfwejf-wekjefwjfwej")
+	test.IsEqualBool(t, info.Checksum.IsEmpty(), true)
+
+	_, r = test.GetRecorder("POST", "/uploadChunk", nil, []test.Header{
+		{Name: "Content-type", Value: "application/x-www-form-urlencoded"},
+		{Name: "checksum", Value: "crc32c:e3069283"}},
+		strings.NewReader(data.Encode()))
+	info, err = ParseChunkInfo(r, true)
+	test.IsNil(t, err)
+	test.IsEqualString(t, info.Checksum.Algorithm, ChecksumCrc32c)
+
+	_, r = test.GetRecorder("POST", "/uploadChunk", nil, []test.Header{
+		{Name: "Content-type", Value: "application/x-www-form-urlencoded"},
+		{Name: "checksum", Value: "md5:e3069283"}},
+		strings.NewReader(data.Encode()))
+	_, err = ParseChunkInfo(r, true)
+	test.IsEqual(t, err, ErrorInvalidChecksum)
 }
 
 func TestParseContentType(t *testing.T) {
@@ -258,19 +287,19 @@ func TestParseMultipartHeader(t *testing.T) {
 }
 
 func TestGetChunkFilePath(t *testing.T) {
-	test.IsEqualString(t, getChunkFilePath("test"), "test/data/chunk-test")
+	test.IsEqualString(t, getChunkFilePath("test"), "test/data/tmp/chunk-test")
 }
 
 func TestGetFileByChunkId(t *testing.T) {
 	test.FileDoesNotExist(t, "testchunk")
 	_, err := GetFileByChunkId("testchunk")
 	test.IsNotNil(t, err)
-	err = os.WriteFile("test/data/chunk-testchunk", []byte("conent"), 0777)
+	err = os.WriteFile("test/data/tmp/chunk-testchunk", []byte("conent"), 0777)
 	test.IsNil(t, err)
 	file, err := GetFileByChunkId("testchunk")
-	test.IsEqualString(t, file.Name(), "test/data/chunk-testchunk")
+	test.IsEqualString(t, file.Name(), "test/data/tmp/chunk-testchunk")
 	test.IsNil(t, err)
-	err = os.Chmod("test/data/chunk-testchunk", 0222)
+	err = os.Chmod("test/data/tmp/chunk-testchunk", 0222)
 	_, err = GetFileByChunkId("testchunk")
 	test.IsNotNil(t, err)
 	err = os.Remove(file.Name())
@@ -288,7 +317,7 @@ func TestNewChunk(t *testing.T) {
 	}
 	err := NewChunk(strings.NewReader("This is a test content"), &header, info)
 	test.IsNil(t, err)
-	test.IsEqualString(t, sha1sumFile("test/data/chunk-testuuid12345"), "a69ec3c3a031e3540d0c2a864ca931f3d54e2c13")
+	test.IsEqualString(t, sha1sumFile("test/data/tmp/chunk-testuuid12345"), "a69ec3c3a031e3540d0c2a864ca931f3d54e2c13")
 
 	info.Offset = 52
 	header = multipart.FileHeader{
@@ -296,13 +325,13 @@ func TestNewChunk(t *testing.T) {
 	}
 	err = NewChunk(strings.NewReader("More content"), &header, info)
 	test.IsNil(t, err)
-	test.IsEqualString(t, sha1sumFile("test/data/chunk-testuuid12345"), "8794d8352fae46b83bab83d3e613dde8f0244ded")
+	test.IsEqualString(t, sha1sumFile("test/data/tmp/chunk-testuuid12345"), "8794d8352fae46b83bab83d3e613dde8f0244ded")
 
 	info.Offset = 99
 	err = NewChunk(strings.NewReader("More content"), &header, info)
 	test.IsNotNil(t, err)
 
-	err = os.Remove("test/data/chunk-testuuid12345")
+	err = os.Remove("test/data/tmp/chunk-testuuid12345")
 	test.IsNil(t, err)
 
 	info.TotalFilesizeBytes = -4
@@ -314,6 +343,14 @@ func TestNewChunk(t *testing.T) {
 	err = NewChunk(strings.NewReader("More content"), &header, info)
 	test.IsNotNil(t, err)
 
+	info.UUID = "testuuidnospace"
+	info.Offset = 0
+	configuration.Get().MinFreeSpaceMB = math.MaxInt32
+	err = NewChunk(strings.NewReader("More content"), &header, info)
+	configuration.Get().MinFreeSpaceMB = 0
+	test.IsEqual(t, err, diskspace.ErrorNotEnoughSpace)
+	test.IsEqualBool(t, FileExists(info.UUID), false)
+
 	// Testing simultaneous writes
 	egroup := new(errgroup.Group)
 	egroup.Go(func() error {
@@ -345,6 +382,42 @@ func writeRateLimitedChunk(firstHalf bool) error {
 	return err
 }
 
+func TestConcurrentAllocations(t *testing.T) {
+	configuration.Get().MinFreeSpaceMB = 0
+	space, ok := diskspace.Get()
+	test.IsEqualBool(t, ok, true)
+	// Only one of the files fits, as the second allocation has to include the reserved space of the first one
+	size := space.FreeBytes / 10 * 6
+	ids := []string{"concurrentallocation1", "concurrentallocation2"}
+	errs := make([]error, len(ids))
+	egroup := new(errgroup.Group)
+	for i, id := range ids {
+		egroup.Go(func() error {
+			errs[i] = allocateFile(ChunkInfo{TotalFilesizeBytes: size, UUID: id})
+			return nil
+		})
+	}
+	test.IsNil(t, egroup.Wait())
+	successfulId := ""
+	failedId := ""
+	for i, err := range errs {
+		if err == nil {
+			successfulId = ids[i]
+			continue
+		}
+		test.IsEqual(t, err, diskspace.ErrorNotEnoughSpace)
+		failedId = ids[i]
+	}
+	test.IsEqualBool(t, successfulId != "", true)
+	test.IsEqualBool(t, failedId != "", true)
+	test.IsEqualBool(t, FileExists(failedId), false)
+
+	// The reservation is released after the upload has been deleted
+	DeleteChunk(successfulId)
+	test.IsNil(t, allocateFile(ChunkInfo{TotalFilesizeBytes: size, UUID: failedId}))
+	DeleteChunk(failedId)
+}
+
 func TestWriteChunk(t *testing.T) {
 	err := writeChunk(nil, &multipart.FileHeader{Size: 10}, ChunkInfo{
 		UUID:               "",
@@ -353,6 +426,25 @@ func TestWriteChunk(t *testing.T) {
 	test.IsNotNil(t, err)
 }
 
+func TestInterruptedChunk(t *testing.T) {
+	info := ChunkInfo{
+		TotalFilesizeBytes: 100,
+		UUID:               "testuuidinterrupted",
+	}
+	content := io.MultiReader(strings.NewReader("Partial content"), iotest.ErrReader(errors.New("connection reset")))
+	err := NewChunk(content, &multipart.FileHeader{Size: 50}, info)
+	test.IsNotNil(t, err)
+	status, ok := GetStatus(info.UUID)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, status.ReceivedBytes, 15)
+
+	DeleteChunk(info.UUID)
+	test.IsEqualBool(t, FileExists(info.UUID), false)
+	test.IsEqualBool(t, helper.FileExists(getManifestPath(info.UUID)), false)
+	_, ok = GetStatus(info.UUID)
+	test.IsEqualBool(t, ok, false)
+}
+
 func sha1sumFile(filename string) string {
 	sha := sha1.New()
 	filecontent, err := os.ReadFile(filename)
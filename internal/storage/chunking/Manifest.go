@@ -0,0 +1,141 @@
+package chunking
+
+/**
+Recording which byte ranges of a chunked upload have been received, so that an interrupted upload can be resumed
+*/
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrorMissingChunks is raised when a chunked upload is completed before all chunks have been received
+var ErrorMissingChunks = errors.New("not all chunks have been received")
+
+// ErrorUnknownUpload is raised when the status of a chunked upload is requested that does not exist
+var ErrorUnknownUpload = errors.New("chunked upload does not exist")
+
+// manifest is stored next to the chunk file and contains the byte ranges that have been written to it
+// and the user that started the upload
+type manifest struct {
+	TotalSize int64              `json:"TotalSize"`
+	Ranges    []models.ByteRange `json:"Ranges"`
+	UserId    int                `json:"UserId"`
+}
+
+var manifestMutex sync.Mutex
+
+func getManifestPath(id string) string {
+	return getChunkFilePath(id) + ".manifest"
+}
+
+// loadManifest reads the manifest of the chunk file. Returns false, if no valid manifest exists
+func loadManifest(id string) (manifest, bool) {
+	content, err := os.ReadFile(getManifestPath(id))
+	if err != nil {
+		return manifest{}, false
+	}
+	var result manifest
+	err = json.Unmarshal(content, &result)
+	if err != nil {
+		return manifest{}, false
+	}
+	return result, true
+}
+
+// addReceivedRange records that the bytes from the offset of the chunk to end have been written to the
+// chunk file. The user of the first chunk is recorded as the owner of the upload
+func addReceivedRange(info ChunkInfo, end int64) error {
+	manifestMutex.Lock()
+	defer manifestMutex.Unlock()
+	result, ok := loadManifest(info.UUID)
+	if !ok {
+		result.UserId = info.UserId
+	}
+	result.TotalSize = info.TotalFilesizeBytes
+	result.Ranges = mergeRange(result.Ranges, models.ByteRange{Start: info.Offset, End: end})
+	content, err := json.Marshal(result)
+	helper.Check(err)
+	return os.WriteFile(getManifestPath(info.UUID), content, 0600)
+}
+
+// mergeRange adds newRange to the sorted ranges and merges all ranges that overlap or are adjacent
+func mergeRange(ranges []models.ByteRange, newRange models.ByteRange) []models.ByteRange {
+	if newRange.End > newRange.Start {
+		ranges = append(ranges, newRange)
+	}
+	slices.SortFunc(ranges, func(a, b models.ByteRange) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	result := make([]models.ByteRange, 0, len(ranges))
+	for _, byteRange := range ranges {
+		last := len(result) - 1
+		if last >= 0 && byteRange.Start <= result[last].End {
+			result[last].End = max(result[last].End, byteRange.End)
+			continue
+		}
+		result = append(result, byteRange)
+	}
+	return result
+}
+
+// DeleteManifest removes the manifest of the chunk file, once the upload has been processed
+func DeleteManifest(id string) {
+	manifestMutex.Lock()
+	defer manifestMutex.Unlock()
+	_ = os.Remove(getManifestPath(id))
+}
+
+// GetStatus returns the byte ranges of the chunked upload that have been received.
+// Returns false, if the upload does not exist
+func GetStatus(id string) (models.ChunkStatus, bool) {
+	id = sanitiseUuid(id)
+	multipartMutex.Lock()
+	upload, isMultipart := multipartUploads[id]
+	multipartMutex.Unlock()
+	if isMultipart {
+		return upload.getStatus(id), true
+	}
+	manifestMutex.Lock()
+	defer manifestMutex.Unlock()
+	received, ok := loadManifest(id)
+	if !ok || !FileExists(id) {
+		return models.ChunkStatus{}, false
+	}
+	return createStatus(id, received.UserId, received.TotalSize, received.Ranges), true
+}
+
+// CheckComplete returns ErrorMissingChunks, if not all chunks of the upload have been received
+func CheckComplete(id string) error {
+	status, ok := GetStatus(id)
+	if !ok {
+		return ErrorUnknownUpload
+	}
+	if !status.IsComplete {
+		return ErrorMissingChunks
+	}
+	return nil
+}
+
+func createStatus(id string, userId int, totalSize int64, ranges []models.ByteRange) models.ChunkStatus {
+	result := models.ChunkStatus{
+		Uuid:      id,
+		TotalSize: totalSize,
+		Ranges:    ranges,
+		UserId:    userId,
+	}
+	if result.Ranges == nil {
+		result.Ranges = make([]models.ByteRange, 0)
+	}
+	for _, byteRange := range result.Ranges {
+		result.ReceivedBytes = result.ReceivedBytes + byteRange.End - byteRange.Start
+	}
+	result.IsComplete = result.ReceivedBytes == totalSize
+	return result
+}
@@ -0,0 +1,66 @@
+package chunking
+
+import (
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMergeRange(t *testing.T) {
+	ranges := mergeRange(nil, models.ByteRange{Start: 10, End: 20})
+	test.IsEqual(t, ranges, []models.ByteRange{{Start: 10, End: 20}})
+	ranges = mergeRange(ranges, models.ByteRange{Start: 30, End: 40})
+	test.IsEqual(t, ranges, []models.ByteRange{{Start: 10, End: 20}, {Start: 30, End: 40}})
+	ranges = mergeRange(ranges, models.ByteRange{Start: 0, End: 5})
+	test.IsEqual(t, ranges, []models.ByteRange{{Start: 0, End: 5}, {Start: 10, End: 20}, {Start: 30, End: 40}})
+	ranges = mergeRange(ranges, models.ByteRange{Start: 5, End: 10})
+	test.IsEqual(t, ranges, []models.ByteRange{{Start: 0, End: 20}, {Start: 30, End: 40}})
+	ranges = mergeRange(ranges, models.ByteRange{Start: 15, End: 35})
+	test.IsEqual(t, ranges, []models.ByteRange{{Start: 0, End: 40}})
+	ranges = mergeRange(ranges, models.ByteRange{Start: 50, End: 50})
+	test.IsEqual(t, ranges, []models.ByteRange{{Start: 0, End: 40}})
+}
+
+func TestGetStatus(t *testing.T) {
+	const uuid = "manifesttest123"
+	_, ok := GetStatus(uuid)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqual(t, CheckComplete(uuid), ErrorUnknownUpload)
+
+	err := sendChunk(strings.NewReader("0123456789"), 10, 20, 30, uuid)
+	test.IsNil(t, err)
+	test.FileExists(t, "test/data/tmp/chunk-"+uuid+".manifest")
+	status, ok := GetStatus(uuid)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, status.Uuid, uuid)
+	test.IsEqualInt(t, status.UserId, testChunkUserId)
+	test.IsEqualInt64(t, status.TotalSize, 30)
+	test.IsEqualInt64(t, status.ReceivedBytes, 10)
+	test.IsEqualBool(t, status.IsComplete, false)
+	test.IsEqual(t, status.Ranges, []models.ByteRange{{Start: 20, End: 30}})
+	test.IsEqual(t, CheckComplete(uuid), ErrorMissingChunks)
+
+	err = sendChunk(strings.NewReader("0123456789"), 10, 0, 30, uuid)
+	test.IsNil(t, err)
+	status, _ = GetStatus(uuid)
+	test.IsEqual(t, status.Ranges, []models.ByteRange{{Start: 0, End: 10}, {Start: 20, End: 30}})
+	test.IsEqual(t, CheckComplete(uuid), ErrorMissingChunks)
+
+	err = sendChunk(strings.NewReader("0123456789"), 10, 10, 30, uuid)
+	test.IsNil(t, err)
+	status, _ = GetStatus(uuid)
+	test.IsEqualInt64(t, status.ReceivedBytes, 30)
+	test.IsEqualBool(t, status.IsComplete, true)
+	test.IsEqual(t, status.Ranges, []models.ByteRange{{Start: 0, End: 30}})
+	test.IsNil(t, CheckComplete(uuid))
+
+	// The status is only available while the chunk file exists
+	err = os.Remove("test/data/tmp/chunk-" + uuid)
+	test.IsNil(t, err)
+	_, ok = GetStatus(uuid)
+	test.IsEqualBool(t, ok, false)
+	DeleteManifest(uuid)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid+".manifest")
+}
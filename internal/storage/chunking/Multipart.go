@@ -0,0 +1,453 @@
+package chunking
+
+/**
+Streaming chunks directly to S3 as parts of a multipart upload, instead of assembling the file locally first
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/hashing"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// multipartUpload contains the state of a chunked upload that is streamed to S3
+type multipartUpload struct {
+	mutex sync.Mutex
+	// object is the temporary S3 object the parts are uploaded to
+	object   models.File
+	uploadId string
+	// totalSize is the size of the complete file in bytes
+	totalSize int64
+	// userId is the ID of the user that started the upload
+	userId int
+	// offset is the number of bytes that have been received in order. Everything before the offset
+	// has been hashed and either uploaded or written to partBuffer. The offset is only advanced once
+	// a chunk has been stored completely, so that a failed chunk can be sent again
+	offset int64
+	// hashes contains the hash of the content with the configured algorithm and with SHA-1, so that the
+	// file can be deduplicated with files that were uploaded with older versions
+	hashes hashing.Multi
+	// partBuffer contains data that has not been uploaded yet, as it is smaller than the minimum part size
+	// or the upload of the part failed. Only the first bufferedBytes bytes of the file are valid
+	partBuffer    *os.File
+	bufferedBytes int64
+	parts         []aws.CompletedPart
+	// pendingChunks contains the paths of chunks that have been received out of order, with the offset as key
+	pendingChunks map[int64]string
+	lastActivity  int64
+	// err is set if the state of the upload cannot be recovered. All further requests for this upload return the error
+	err error
+}
+
+var multipartUploads = make(map[string]*multipartUpload)
+var multipartMutex sync.Mutex
+
+// isStreamingToS3 returns true if new chunks are uploaded directly to S3. This is only the case if
+// S3 is the default storage and the files are not encrypted server-side
+func isStreamingToS3(totalSize int64) bool {
+	if totalSize < aws.MinPartSize || !aws.IsAvailable() {
+		return false
+	}
+	if filesystem.ActiveStorageSystem == nil || filesystem.ActiveStorageSystem.GetSystemName() != interfaces.DriverAws {
+		return false
+	}
+	encLevel := configuration.Get().Encryption.Level
+	return encLevel != encryption.FullEncryptionStored && encLevel != encryption.FullEncryptionInput
+}
+
+// getMultipartUpload returns the multipart upload for the chunk. If no upload exists yet and the chunk is
+// not assembled locally already, a new multipart upload is started if S3 is used for storage.
+// Returns false, if the chunk has to be stored locally
+func getMultipartUpload(info ChunkInfo) (*multipartUpload, bool) {
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	upload, ok := multipartUploads[info.UUID]
+	if ok {
+		return upload, true
+	}
+	if FileExists(info.UUID) || !isStreamingToS3(info.TotalFilesizeBytes) {
+		return nil, false
+	}
+	object := models.File{SHA1: "chunk-" + info.UUID}
+	aws.AddBucketName(&object)
+	uploadId, err := aws.CreateMultipartUpload(object)
+	if err != nil {
+		fmt.Println("Warning: Cannot start multipart upload, storing chunks locally instead: " + err.Error())
+		return nil, false
+	}
+	upload = &multipartUpload{
+		object:        object,
+		uploadId:      uploadId,
+		totalSize:     info.TotalFilesizeBytes,
+		userId:        info.UserId,
+		hashes:        hashing.NewMulti(configuration.Get().HashAlgorithm, hashing.AlgorithmSha1),
+		pendingChunks: make(map[int64]string),
+		lastActivity:  time.Now().Unix(),
+	}
+	multipartUploads[info.UUID] = upload
+	return upload, true
+}
+
+// IsMultipartUpload returns true if the chunks of the given chunk ID are streamed to S3
+func IsMultipartUpload(id string) bool {
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	_, ok := multipartUploads[id]
+	return ok
+}
+
+func (u *multipartUpload) addChunk(chunkContent io.Reader, size int64, info ChunkInfo) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.err != nil {
+		return u.err
+	}
+	u.lastActivity = time.Now().Unix()
+	if info.TotalFilesizeBytes != u.totalSize {
+		return errors.New("total filesize does not match")
+	}
+	if info.Offset+size > u.totalSize {
+		return errors.New("chunksize will be bigger than total filesize from this offset")
+	}
+	if info.Offset < u.offset {
+		// Chunk has been received already
+		return nil
+	}
+	if info.Offset > u.offset {
+		return u.storePendingChunk(chunkContent, info.Offset)
+	}
+	err := u.appendChunk(chunkContent, size)
+	if err != nil {
+		return err
+	}
+	return u.appendPendingChunks()
+}
+
+// appendChunk hashes and uploads a chunk that starts at the current offset. If the chunk is smaller than the
+// minimum part size, it is buffered until enough data has been received. If an error is returned, the offset
+// is not advanced and the chunk can be sent again
+func (u *multipartUpload) appendChunk(chunkContent io.Reader, size int64) error {
+	seeker, isSeeker := chunkContent.(io.ReadSeeker)
+	if isSeeker && u.bufferedBytes == 0 && (size >= aws.MinPartSize || u.offset+size == u.totalSize) {
+		return u.appendChunkAsPart(seeker, size)
+	}
+
+	if u.partBuffer == nil {
+		var err error
+		u.partBuffer, err = os.OpenFile(u.getTempFilePath("part"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	// Data after bufferedBytes is overwritten by the next chunk, if this chunk is incomplete
+	written, err := io.Copy(io.NewOffsetWriter(u.partBuffer, u.bufferedBytes), chunkContent)
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return errors.New("chunk size does not match")
+	}
+	_, err = io.Copy(u.hashes, io.NewSectionReader(u.partBuffer, u.bufferedBytes, written))
+	if err != nil {
+		u.err = err
+		return err
+	}
+	u.offset = u.offset + written
+	u.bufferedBytes = u.bufferedBytes + written
+	if u.bufferedBytes >= aws.MinPartSize || u.offset == u.totalSize {
+		// If the upload fails, the data stays in the buffer and is uploaded with the next chunk
+		// or when the upload is completed
+		return u.uploadBufferedPart()
+	}
+	return nil
+}
+
+// appendChunkAsPart uploads a chunk that is stored locally directly as a new part
+func (u *multipartUpload) appendChunkAsPart(chunk io.ReadSeeker, size int64) error {
+	actualSize, err := chunk.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if actualSize != size {
+		return errors.New("chunk size does not match")
+	}
+	_, err = chunk.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	err = u.uploadPart(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = chunk.Seek(0, io.SeekStart)
+	if err == nil {
+		_, err = io.Copy(u.hashes, chunk)
+	}
+	if err != nil {
+		// The part has been uploaded already, but the hash is incomplete
+		u.err = err
+		return err
+	}
+	u.offset = u.offset + size
+	return nil
+}
+
+func (u *multipartUpload) uploadPart(input io.ReadSeeker) error {
+	if len(u.parts) >= aws.MaxParts {
+		return errors.New("file consists of too many chunks")
+	}
+	partNumber := int64(len(u.parts) + 1)
+	etag, err := aws.UploadPart(u.object, u.uploadId, partNumber, input)
+	if err != nil {
+		return err
+	}
+	u.parts = append(u.parts, aws.CompletedPart{PartNumber: partNumber, ETag: etag})
+	return nil
+}
+
+func (u *multipartUpload) uploadBufferedPart() error {
+	err := u.uploadPart(io.NewSectionReader(u.partBuffer, 0, u.bufferedBytes))
+	if err != nil {
+		return err
+	}
+	u.bufferedBytes = 0
+	return nil
+}
+
+// storePendingChunk writes a chunk that has been received out of order to a temporary file,
+// until all previous chunks have been received
+func (u *multipartUpload) storePendingChunk(chunkContent io.Reader, offset int64) error {
+	path := u.getTempFilePath(strconv.FormatInt(offset, 10))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, chunkContent)
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	u.pendingChunks[offset] = path
+	return nil
+}
+
+// appendPendingChunks appends all chunks that were received out of order and follow the current offset.
+// If a chunk cannot be appended, it is kept and appended again with the next chunk
+func (u *multipartUpload) appendPendingChunks() error {
+	for {
+		offset := u.offset
+		path, ok := u.pendingChunks[offset]
+		if !ok {
+			return nil
+		}
+		isValid, err := u.appendPendingChunk(path)
+		if err != nil && isValid {
+			return err
+		}
+		delete(u.pendingChunks, offset)
+		_ = os.Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// appendPendingChunk appends a chunk that was received out of order. Returns false, if the chunk is invalid
+// and has to be sent again
+func (u *multipartUpload) appendPendingChunk(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if u.offset+info.Size() > u.totalSize {
+		return false, errors.New("chunksize will be bigger than total filesize from this offset")
+	}
+	return true, u.appendChunk(file, info.Size())
+}
+
+// getStatus returns the byte ranges that have been received in order or are pending
+func (u *multipartUpload) getStatus(id string) models.ChunkStatus {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	ranges := mergeRange(nil, models.ByteRange{Start: 0, End: u.offset})
+	for offset, path := range u.pendingChunks {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		ranges = mergeRange(ranges, models.ByteRange{Start: offset, End: offset + info.Size()})
+	}
+	return createStatus(id, u.userId, u.totalSize, ranges)
+}
+
+func (u *multipartUpload) getTempFilePath(suffix string) string {
+	return getChunkFilePath(u.object.SHA1[len("chunk-"):]) + "." + suffix
+}
+
+// removeTempFiles deletes the part buffer and all chunks that have been received out of order
+func (u *multipartUpload) removeTempFiles() {
+	if u.partBuffer != nil {
+		_ = u.partBuffer.Close()
+		_ = os.Remove(u.partBuffer.Name())
+		u.partBuffer = nil
+	}
+	for offset, path := range u.pendingChunks {
+		_ = os.Remove(path)
+		delete(u.pendingChunks, offset)
+	}
+}
+
+// CompleteMultipartUpload finishes the multipart upload of the given chunk ID. Returns the hashes of the
+// content with the algorithm as key and the temporary S3 object that contains the uploaded file. The content
+// is hashed with the configured algorithm and with SHA-1. The upload is aborted if not all chunks have been received
+func CompleteMultipartUpload(id string, expectedSize int64) (map[string]string, models.File, error) {
+	multipartMutex.Lock()
+	upload, ok := multipartUploads[id]
+	delete(multipartUploads, id)
+	multipartMutex.Unlock()
+	if !ok {
+		return nil, models.File{}, errors.New("multipart upload does not exist")
+	}
+
+	upload.mutex.Lock()
+	defer upload.mutex.Unlock()
+	defer upload.removeTempFiles()
+	err := upload.complete(expectedSize)
+	if err != nil {
+		_ = aws.AbortMultipartUpload(upload.object, upload.uploadId)
+		return nil, models.File{}, err
+	}
+	return upload.hashes.Sums(""), upload.object, nil
+}
+
+func (u *multipartUpload) complete(expectedSize int64) error {
+	if u.err != nil {
+		return u.err
+	}
+	if expectedSize != u.totalSize || u.offset != u.totalSize {
+		return errors.New("total filesize does not match")
+	}
+	if u.bufferedBytes > 0 {
+		err := u.uploadBufferedPart()
+		if err != nil {
+			return err
+		}
+	}
+	return aws.CompleteMultipartUpload(u.object, u.uploadId, u.parts)
+}
+
+// abortMultipartUpload cancels the multipart upload of the given chunk ID, if it exists
+func abortMultipartUpload(id string) {
+	multipartMutex.Lock()
+	upload, ok := multipartUploads[id]
+	delete(multipartUploads, id)
+	multipartMutex.Unlock()
+	if !ok {
+		return
+	}
+	upload.mutex.Lock()
+	defer upload.mutex.Unlock()
+	upload.removeTempFiles()
+	err := aws.AbortMultipartUpload(upload.object, upload.uploadId)
+	if err != nil {
+		fmt.Println("Warning: Cannot abort multipart upload " + id + ": " + err.Error())
+	}
+}
+
+// StoreObjectAsChunkFile downloads a completed multipart upload to the local chunk file and deletes
+// the temporary S3 object afterwards. This is required if the file is not stored in the default bucket
+func StoreObjectAsChunkFile(id string, object models.File) error {
+	reader, err := aws.GetObject(object, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	file, err := os.OpenFile(getChunkFilePath(id), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	_, err = aws.DeleteObject(object)
+	return err
+}
+
+// AbortOrphanedMultipartUploads cancels all multipart uploads of chunks in the default bucket that have not
+// been started by this instance, e.g. because the upload was interrupted by a restart. Their state is only kept
+// in memory, therefore they cannot be resumed
+func AbortOrphanedMultipartUploads() {
+	if !aws.IsAvailable() {
+		return
+	}
+	var bucket models.File
+	aws.AddBucketName(&bucket)
+	uploads, err := aws.ListMultipartUploads(bucket.AwsBucket, "chunk-")
+	if err != nil {
+		fmt.Println("Warning: Cannot list multipart uploads: " + err.Error())
+		return
+	}
+	for _, upload := range uploads {
+		if isActiveMultipartUpload(upload.UploadId) {
+			continue
+		}
+		err = aws.AbortMultipartUpload(upload.Object, upload.UploadId)
+		if err != nil {
+			fmt.Println("Warning: Cannot abort multipart upload " + upload.Object.SHA1 + ": " + err.Error())
+		}
+	}
+}
+
+func isActiveMultipartUpload(uploadId string) bool {
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	for _, upload := range multipartUploads {
+		if upload.uploadId == uploadId {
+			return true
+		}
+	}
+	return false
+}
+
+// AbortStaleMultipartUploads cancels all multipart uploads that have not received a chunk for 24 hours
+func AbortStaleMultipartUploads() {
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	cutOff := time.Now().Add(-24 * time.Hour).Unix()
+	for id, upload := range multipartUploads {
+		upload.mutex.Lock()
+		if upload.lastActivity < cutOff {
+			delete(multipartUploads, id)
+			upload.removeTempFiles()
+			err := aws.AbortMultipartUpload(upload.object, upload.uploadId)
+			if err != nil {
+				fmt.Println("Warning: Cannot abort multipart upload " + id + ": " + err.Error())
+			}
+		}
+		upload.mutex.Unlock()
+	}
+}
@@ -0,0 +1,273 @@
+package chunking
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/hashing"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"io"
+	"mime/multipart"
+	"os"
+	"testing"
+	"time"
+)
+
+func enableS3(t *testing.T) {
+	testconfiguration.EnableS3()
+	config, ok := cloudconfig.Load()
+	test.IsEqualBool(t, ok, true)
+	ok = aws.Init(config.Aws)
+	test.IsEqualBool(t, ok, true)
+	filesystem.SetAws()
+}
+
+// testChunkUserId is the user that sends the chunks of the tests
+const testChunkUserId = 5
+
+func sendChunk(content io.Reader, size, offset, totalSize int64, uuid string) error {
+	return NewChunk(content, &multipart.FileHeader{Size: size}, ChunkInfo{
+		TotalFilesizeBytes: totalSize,
+		Offset:             offset,
+		UUID:               uuid,
+		UserId:             testChunkUserId,
+	})
+}
+
+func getObjectContent(t *testing.T, object models.File) []byte {
+	reader, err := aws.GetObject(object, 0, -1)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsNil(t, reader.Close())
+	return content
+}
+
+func TestIsStreamingToS3(t *testing.T) {
+	test.IsEqualBool(t, isStreamingToS3(10*1024*1024), false)
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	enableS3(t)
+	defer testconfiguration.DisableS3()
+	test.IsEqualBool(t, isStreamingToS3(10*1024*1024), true)
+	test.IsEqualBool(t, isStreamingToS3(1024), false)
+	configuration.Get().Encryption.Level = 3
+	test.IsEqualBool(t, isStreamingToS3(10*1024*1024), false)
+	configuration.Get().Encryption.Level = 1
+	test.IsEqualBool(t, isStreamingToS3(10*1024*1024), true)
+	configuration.Get().Encryption.Level = 0
+	filesystem.SetLocal()
+	test.IsEqualBool(t, isStreamingToS3(10*1024*1024), false)
+}
+
+func TestMultipartUploadInOrder(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	enableS3(t)
+	defer testconfiguration.DisableS3()
+
+	const uuid = "multipartinorder"
+	content := []byte(helper.GenerateRandomString(13 * 1024 * 1024))
+	totalSize := int64(len(content))
+	chunkSize := int64(6 * 1024 * 1024)
+	for offset := int64(0); offset < totalSize; offset = offset + chunkSize {
+		end := min(offset+chunkSize, totalSize)
+		err := sendChunk(bytes.NewReader(content[offset:end]), end-offset, offset, totalSize, uuid)
+		test.IsNil(t, err)
+		test.IsEqualBool(t, IsMultipartUpload(uuid), true)
+	}
+	test.IsEqualBool(t, FileExists(uuid), false)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid+".part")
+
+	// Chunks that have been received already are ignored
+	err := sendChunk(bytes.NewReader(content[:chunkSize]), chunkSize, 0, totalSize, uuid)
+	test.IsNil(t, err)
+
+	hashes, object, err := CompleteMultipartUpload(uuid, totalSize)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, IsMultipartUpload(uuid), false)
+	expectedHash := sha256.Sum256(content)
+	test.IsEqualString(t, hashes[hashing.AlgorithmSha256], hex.EncodeToString(expectedHash[:]))
+	expectedLegacyHash := sha1.Sum(content)
+	test.IsEqualString(t, hashes[hashing.AlgorithmSha1], hex.EncodeToString(expectedLegacyHash[:]))
+	test.IsEqualString(t, object.SHA1, "chunk-"+uuid)
+	test.IsEqualBool(t, bytes.Equal(getObjectContent(t, object), content), true)
+
+	err = StoreObjectAsChunkFile(uuid, object)
+	test.IsNil(t, err)
+	storedContent, err := os.ReadFile("test/data/tmp/chunk-" + uuid)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, bytes.Equal(storedContent, content), true)
+	exists, _, err := aws.FileExists(object)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	test.IsNil(t, os.Remove("test/data/tmp/chunk-"+uuid))
+
+	_, _, err = CompleteMultipartUpload(uuid, totalSize)
+	test.IsNotNil(t, err)
+}
+
+func TestMultipartUploadOutOfOrder(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	enableS3(t)
+	defer testconfiguration.DisableS3()
+
+	const uuid = "multipartoutoforder"
+	content := []byte(helper.GenerateRandomString(7*1024*1024 + 100))
+	totalSize := int64(len(content))
+	chunkSize := int64(2 * 1024 * 1024)
+	for _, index := range []int64{3, 1, 0, 2} {
+		offset := index * chunkSize
+		end := min(offset+chunkSize, totalSize)
+		// io.MultiReader hides the io.Seeker interface, so the chunks have to be buffered
+		err := sendChunk(io.MultiReader(bytes.NewReader(content[offset:end])), end-offset, offset, totalSize, uuid)
+		test.IsNil(t, err)
+		if index == 3 {
+			test.FileExists(t, "test/data/tmp/chunk-"+uuid+".6291456")
+		}
+		if index == 1 {
+			status, ok := GetStatus(uuid)
+			test.IsEqualBool(t, ok, true)
+			test.IsEqualBool(t, status.IsComplete, false)
+			test.IsEqual(t, status.Ranges, []models.ByteRange{{Start: chunkSize, End: 2 * chunkSize}, {Start: 3 * chunkSize, End: totalSize}})
+			test.IsEqual(t, CheckComplete(uuid), ErrorMissingChunks)
+		}
+	}
+	test.IsNil(t, CheckComplete(uuid))
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid+".6291456")
+
+	hashes, object, err := CompleteMultipartUpload(uuid, totalSize)
+	test.IsNil(t, err)
+	expectedHash := sha256.Sum256(content)
+	test.IsEqualString(t, hashes[hashing.AlgorithmSha256], hex.EncodeToString(expectedHash[:]))
+	test.IsEqualBool(t, bytes.Equal(getObjectContent(t, object), content), true)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid+".part")
+	_, err = aws.DeleteObject(object)
+	test.IsNil(t, err)
+}
+
+func TestMultipartUploadErrors(t *testing.T) {
+	if !aws.IsIncludedInBuild {
+		return
+	}
+	enableS3(t)
+	defer testconfiguration.DisableS3()
+
+	const uuid = "multiparterrors"
+	totalSize := int64(6 * 1024 * 1024)
+	content := bytes.Repeat([]byte("a"), 1024)
+	err := sendChunk(bytes.NewReader(content), 1024, 0, totalSize, uuid)
+	test.IsNil(t, err)
+	err = sendChunk(bytes.NewReader(content), 1024, 1024, totalSize+1, uuid)
+	test.IsNotNil(t, err)
+	err = sendChunk(bytes.NewReader(content), 1024, totalSize-10, totalSize, uuid)
+	test.IsNotNil(t, err)
+	test.FileExists(t, "test/data/tmp/chunk-"+uuid+".part")
+	// Not all chunks have been received
+	_, _, err = CompleteMultipartUpload(uuid, totalSize)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, IsMultipartUpload(uuid), false)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid+".part")
+
+	err = sendChunk(bytes.NewReader(content), 1024, 2048, totalSize, uuid)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, IsMultipartUpload(uuid), true)
+	AbortStaleMultipartUploads()
+	test.IsEqualBool(t, IsMultipartUpload(uuid), true)
+	multipartUploads[uuid].lastActivity = time.Now().Add(-25 * time.Hour).Unix()
+	AbortStaleMultipartUploads()
+	test.IsEqualBool(t, IsMultipartUpload(uuid), false)
+	test.FileDoesNotExist(t, "test/data/tmp/chunk-"+uuid+".2048")
+}
+
+type failingReader struct{}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection lost")
+}
+
+func TestMultipartUploadRetry(t *testing.T) {
+	if !aws.IsMockApi {
+		return
+	}
+	enableS3(t)
+	defer testconfiguration.DisableS3()
+
+	const uuid = "multipartretry"
+	content := []byte(helper.GenerateRandomString(12 * 1024 * 1024))
+	totalSize := int64(len(content))
+	chunkSize := int64(6 * 1024 * 1024)
+
+	// The connection is lost while receiving the chunk
+	err := sendChunk(io.MultiReader(bytes.NewReader(content[:1024]), failingReader{}), chunkSize, 0, totalSize, uuid)
+	test.IsNotNil(t, err)
+	err = sendChunk(io.MultiReader(bytes.NewReader(content[:1024])), chunkSize, 0, totalSize, uuid)
+	test.IsNotNil(t, err)
+	status, ok := GetStatus(uuid)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqual(t, status.Ranges, []models.ByteRange{})
+	test.IsEqualInt(t, status.UserId, testChunkUserId)
+	err = sendChunk(io.MultiReader(bytes.NewReader(content[:chunkSize])), chunkSize, 0, totalSize, uuid)
+	test.IsNil(t, err)
+
+	// The part cannot be uploaded
+	test.IsNil(t, os.Setenv("GOKAPI_AWS_KEY", "invalid"))
+	err = sendChunk(bytes.NewReader(content[chunkSize:]), chunkSize, chunkSize, totalSize, uuid)
+	test.IsNotNil(t, err)
+	status, _ = GetStatus(uuid)
+	test.IsEqual(t, status.Ranges, []models.ByteRange{{Start: 0, End: chunkSize}})
+	test.IsNil(t, os.Setenv("GOKAPI_AWS_KEY", "accId"))
+	err = sendChunk(bytes.NewReader(content[chunkSize:]), chunkSize, chunkSize, totalSize, uuid)
+	test.IsNil(t, err)
+
+	hashes, object, err := CompleteMultipartUpload(uuid, totalSize)
+	test.IsNil(t, err)
+	expectedHash := sha256.Sum256(content)
+	test.IsEqualString(t, hashes[hashing.AlgorithmSha256], hex.EncodeToString(expectedHash[:]))
+	test.IsEqualBool(t, bytes.Equal(getObjectContent(t, object), content), true)
+	_, err = aws.DeleteObject(object)
+	test.IsNil(t, err)
+}
+
+func TestAbortOrphanedMultipartUploads(t *testing.T) {
+	AbortOrphanedMultipartUploads()
+	if !aws.IsMockApi {
+		return
+	}
+	enableS3(t)
+	defer testconfiguration.DisableS3()
+
+	const uuid = "multipartactive"
+	err := sendChunk(bytes.NewReader(bytes.Repeat([]byte("a"), 1024)), 1024, 0, 6*1024*1024, uuid)
+	test.IsNil(t, err)
+	orphanedObject := models.File{SHA1: "chunk-orphaned"}
+	aws.AddBucketName(&orphanedObject)
+	_, err = aws.CreateMultipartUpload(orphanedObject)
+	test.IsNil(t, err)
+	uploads, err := aws.ListMultipartUploads(orphanedObject.AwsBucket, "chunk-")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(uploads), 2)
+
+	AbortOrphanedMultipartUploads()
+	uploads, err = aws.ListMultipartUploads(orphanedObject.AwsBucket, "chunk-")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(uploads), 1)
+	test.IsEqualString(t, uploads[0].Object.SHA1, "chunk-"+uuid)
+	abortMultipartUpload(uuid)
+	uploads, err = aws.ListMultipartUploads(orphanedObject.AwsBucket, "chunk-")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(uploads), 0)
+}
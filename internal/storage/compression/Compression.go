@@ -0,0 +1,134 @@
+package compression
+
+/**
+Compressing stored files and decompressing them on download
+*/
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AlgorithmGzip compresses files with gzip, which is supported by all clients
+const AlgorithmGzip = "gzip"
+
+// AlgorithmZstd compresses files with zstd, which is faster and compresses better than gzip
+const AlgorithmZstd = "zstd"
+
+// ErrorInvalidAlgorithm is raised when an unknown compression algorithm is passed
+var ErrorInvalidAlgorithm = errors.New("invalid compression algorithm")
+
+// IsValidAlgorithm returns true if files can be compressed with the algorithm
+func IsValidAlgorithm(algorithm string) bool {
+	return algorithm == AlgorithmGzip || algorithm == AlgorithmZstd
+}
+
+// IsCompressibleType returns true if the content type matches one of the types. A type that ends
+// with a slash matches all subtypes, e.g. "text/" matches "text/csv"
+func IsCompressibleType(contentType string, types []string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if contentType == "" {
+		return false
+	}
+	for _, compressibleType := range types {
+		compressibleType = strings.ToLower(strings.TrimSpace(compressibleType))
+		if compressibleType == "" {
+			continue
+		}
+		if strings.HasSuffix(compressibleType, "/") && strings.HasPrefix(contentType, compressibleType) {
+			return true
+		}
+		if contentType == compressibleType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAcceptedByClient returns true if the client accepts content that is encoded with the algorithm
+func IsAcceptedByClient(algorithm string, r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parameters := strings.Split(encoding, ";")
+		if strings.ToLower(strings.TrimSpace(parameters[0])) != algorithm {
+			continue
+		}
+		for _, parameter := range parameters[1:] {
+			quality, isQuality := strings.CutPrefix(strings.TrimSpace(parameter), "q=")
+			if !isQuality {
+				continue
+			}
+			value, err := strconv.ParseFloat(quality, 64)
+			if err == nil && value == 0 {
+				// The client explicitly does not accept the encoding
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// GetCompressWriter returns a writer that compresses all content with the algorithm and writes it to output.
+// The writer has to be closed to write the remaining data
+func GetCompressWriter(algorithm string, output io.Writer) (io.WriteCloser, error) {
+	switch algorithm {
+	case AlgorithmGzip:
+		return gzip.NewWriter(output), nil
+	case AlgorithmZstd:
+		return zstd.NewWriter(output)
+	default:
+		return nil, ErrorInvalidAlgorithm
+	}
+}
+
+// GetDecompressReader returns a reader that decompresses the input with the algorithm
+func GetDecompressReader(algorithm string, input io.Reader) (io.ReadCloser, error) {
+	switch algorithm {
+	case AlgorithmGzip:
+		return gzip.NewReader(input)
+	case AlgorithmZstd:
+		decoder, err := zstd.NewReader(input)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, ErrorInvalidAlgorithm
+	}
+}
+
+// Compress compresses the input with the algorithm and writes it to output. Returns the size of the compressed content
+func Compress(algorithm string, input io.Reader, output io.Writer) (int64, error) {
+	counter := &countingWriter{Writer: output}
+	writer, err := GetCompressWriter(algorithm, counter)
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.Copy(writer, input)
+	if err != nil {
+		_ = writer.Close()
+		return 0, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return 0, err
+	}
+	return counter.size, nil
+}
+
+// countingWriter counts the bytes that have been written
+type countingWriter struct {
+	io.Writer
+	size int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.size = w.size + int64(n)
+	return n, err
+}
@@ -0,0 +1,63 @@
+package compression
+
+import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/test"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsValidAlgorithm(t *testing.T) {
+	test.IsEqualBool(t, IsValidAlgorithm(AlgorithmGzip), true)
+	test.IsEqualBool(t, IsValidAlgorithm(AlgorithmZstd), true)
+	test.IsEqualBool(t, IsValidAlgorithm(""), false)
+	test.IsEqualBool(t, IsValidAlgorithm("brotli"), false)
+}
+
+func TestIsCompressibleType(t *testing.T) {
+	types := []string{"text/", "application/json", " Application/XML "}
+	test.IsEqualBool(t, IsCompressibleType("text/csv", types), true)
+	test.IsEqualBool(t, IsCompressibleType("text/plain; charset=utf-8", types), true)
+	test.IsEqualBool(t, IsCompressibleType("application/json", types), true)
+	test.IsEqualBool(t, IsCompressibleType("application/xml", types), true)
+	test.IsEqualBool(t, IsCompressibleType("application/jsonx", types), false)
+	test.IsEqualBool(t, IsCompressibleType("image/png", types), false)
+	test.IsEqualBool(t, IsCompressibleType("", types), false)
+	test.IsEqualBool(t, IsCompressibleType("text/plain", []string{""}), false)
+}
+
+func TestIsAcceptedByClient(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	test.IsEqualBool(t, IsAcceptedByClient(AlgorithmGzip, r), false)
+	r.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+	test.IsEqualBool(t, IsAcceptedByClient(AlgorithmGzip, r), true)
+	test.IsEqualBool(t, IsAcceptedByClient(AlgorithmZstd, r), true)
+	r.Header.Set("Accept-Encoding", "deflate, GZIP;q=0.5, zstd; q=0")
+	test.IsEqualBool(t, IsAcceptedByClient(AlgorithmGzip, r), true)
+	test.IsEqualBool(t, IsAcceptedByClient(AlgorithmZstd, r), false)
+}
+
+func TestCompress(t *testing.T) {
+	content := []byte(strings.Repeat("This is a line of a log file\n", 1000))
+	for _, algorithm := range []string{AlgorithmGzip, AlgorithmZstd} {
+		var compressed bytes.Buffer
+		size, err := Compress(algorithm, bytes.NewReader(content), &compressed)
+		test.IsNil(t, err)
+		test.IsEqualInt(t, int(size), compressed.Len())
+		test.IsEqualBool(t, size < int64(len(content))/10, true)
+		reader, err := GetDecompressReader(algorithm, &compressed)
+		test.IsNil(t, err)
+		decompressed, err := io.ReadAll(reader)
+		test.IsNil(t, err)
+		test.IsNil(t, reader.Close())
+		test.IsEqualBool(t, bytes.Equal(decompressed, content), true)
+	}
+	_, err := Compress("invalid", bytes.NewReader(content), io.Discard)
+	test.IsEqualBool(t, err == ErrorInvalidAlgorithm, true)
+	_, err = GetDecompressReader("invalid", bytes.NewReader(content))
+	test.IsEqualBool(t, err == ErrorInvalidAlgorithm, true)
+	_, err = GetDecompressReader(AlgorithmGzip, bytes.NewReader(content))
+	test.IsNotNil(t, err)
+}
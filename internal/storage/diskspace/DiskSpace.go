@@ -0,0 +1,107 @@
+package diskspace
+
+/**
+Keeping a reserve of free space in the data directory
+*/
+
+import (
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrorNotEnoughSpace is raised when storing a file would reduce the free space of the data directory below the reserve
+var ErrorNotEnoughSpace = errors.New("not enough free disk space on the server")
+
+// isWarningLogged is true, if the usage passed the warning threshold and a warning has already been logged
+var isWarningLogged atomic.Bool
+
+// reservations contains the total size of chunked uploads in progress, with the chunk ID as key. Chunk files
+// are created as sparse files, therefore their size is not included in the free space until they are written
+var reservations = make(map[string]int64)
+var reservationMutex sync.Mutex
+
+// Get returns the free space of the data directory. Returns false, if it cannot be determined on this system
+func Get() (models.DiskSpace, bool) {
+	config := configuration.Get()
+	free, total, err := helper.GetDiskSpace(config.DataDir)
+	if err != nil {
+		return models.DiskSpace{}, false
+	}
+	return models.DiskSpace{
+		FreeBytes:    int64(free),
+		TotalBytes:   int64(total),
+		ReserveBytes: int64(config.MinFreeSpaceMB) * 1024 * 1024,
+	}, true
+}
+
+// CheckUpload returns ErrorNotEnoughSpace, if storing a file with the given size would reduce
+// the free space of the data directory below the reserve. The space that is reserved for chunked
+// uploads in progress is not counted as free. A warning is logged, if the usage passed the configured threshold
+func CheckUpload(size int64) error {
+	reservationMutex.Lock()
+	defer reservationMutex.Unlock()
+	return checkSpace(size)
+}
+
+// Reserve checks the free space like CheckUpload and reserves the size for the chunked upload with the given ID,
+// until Release is called. Returns nil without checking again, if space has been reserved for the upload already
+func Reserve(id string, size int64) error {
+	reservationMutex.Lock()
+	defer reservationMutex.Unlock()
+	_, ok := reservations[id]
+	if ok {
+		return nil
+	}
+	err := checkSpace(size)
+	if err != nil {
+		return err
+	}
+	reservations[id] = size
+	return nil
+}
+
+// Release frees the space that has been reserved for the chunked upload with the given ID,
+// after it has been completed or deleted
+func Release(id string) {
+	reservationMutex.Lock()
+	defer reservationMutex.Unlock()
+	delete(reservations, id)
+}
+
+// checkSpace returns ErrorNotEnoughSpace, if the size does not fit. reservationMutex must be locked
+func checkSpace(size int64) error {
+	space, ok := Get()
+	if !ok {
+		return nil
+	}
+	checkWarningThreshold(space)
+	var reservedBytes int64
+	for _, reservedSize := range reservations {
+		reservedBytes = reservedBytes + reservedSize
+	}
+	if space.FreeBytes-reservedBytes-size < space.ReserveBytes {
+		return ErrorNotEnoughSpace
+	}
+	return nil
+}
+
+// checkWarningThreshold logs a warning once the usage passes the threshold. Another warning is only
+// logged after the usage dropped below the threshold in the meantime
+func checkWarningThreshold(space models.DiskSpace) {
+	threshold := configuration.Get().DiskWarningPercent
+	if threshold < 1 {
+		return
+	}
+	if space.GetUsedPercent() < threshold {
+		isWarningLogged.Store(false)
+		return
+	}
+	if isWarningLogged.CompareAndSwap(false, true) {
+		logging.LogDiskSpaceWarning(space)
+	}
+}
@@ -0,0 +1,85 @@
+package diskspace
+
+import (
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	exitVal := m.Run()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+func TestGet(t *testing.T) {
+	space, ok := Get()
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, space.TotalBytes > 0, true)
+	test.IsEqualInt64(t, space.ReserveBytes, 100*1024*1024)
+
+	configuration.Get().DataDir = "invalid/folder"
+	_, ok = Get()
+	test.IsEqualBool(t, ok, false)
+	test.IsNil(t, CheckUpload(math.MaxInt64))
+	configuration.Get().DataDir = "test/data"
+}
+
+func TestCheckUpload(t *testing.T) {
+	test.IsNil(t, CheckUpload(1024))
+	space, _ := Get()
+	test.IsEqual(t, CheckUpload(space.FreeBytes), ErrorNotEnoughSpace)
+
+	configuration.Get().MinFreeSpaceMB = math.MaxInt32
+	test.IsEqual(t, CheckUpload(0), ErrorNotEnoughSpace)
+	configuration.Get().MinFreeSpaceMB = 0
+	test.IsNil(t, CheckUpload(space.FreeBytes/2))
+}
+
+func TestReserve(t *testing.T) {
+	space, _ := Get()
+	size := space.FreeBytes / 10 * 6
+	test.IsNil(t, Reserve("reservation1", size))
+	test.IsNil(t, Reserve("reservation1", size))
+	test.IsEqual(t, Reserve("reservation2", size), ErrorNotEnoughSpace)
+	test.IsEqual(t, CheckUpload(size), ErrorNotEnoughSpace)
+	test.IsNil(t, CheckUpload(1024))
+	Release("reservation1")
+	test.IsNil(t, CheckUpload(size))
+	test.IsNil(t, Reserve("reservation2", size))
+	Release("reservation2")
+	Release("invalid")
+	test.IsEqualInt(t, len(reservations), 0)
+}
+
+func TestCheckWarningThreshold(t *testing.T) {
+	configuration.Get().DiskWarningPercent = 90
+	space := models.DiskSpace{FreeBytes: 500, TotalBytes: 1000}
+	checkWarningThreshold(space)
+	test.IsEqualBool(t, isWarningLogged.Load(), false)
+	space.FreeBytes = 50
+	checkWarningThreshold(space)
+	test.IsEqualBool(t, isWarningLogged.Load(), true)
+	checkWarningThreshold(space)
+	space.FreeBytes = 500
+	checkWarningThreshold(space)
+	test.IsEqualBool(t, isWarningLogged.Load(), false)
+
+	configuration.Get().DiskWarningPercent = 0
+	space.FreeBytes = 0
+	checkWarningThreshold(space)
+	test.IsEqualBool(t, isWarningLogged.Load(), false)
+
+	// Need sleep, as logging is non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ := os.ReadFile("test/data/log.txt")
+	test.IsEqualInt(t, strings.Count(string(content), "Disk usage of the data directory is at 95%"), 1)
+}
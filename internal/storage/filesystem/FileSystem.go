@@ -1,18 +1,28 @@
 package filesystem
 
 import (
+	"errors"
+	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
 	"github.com/forceu/gokapi/internal/storage/filesystem/localstorage"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem/sftp"
+	"github.com/forceu/gokapi/internal/storage/filesystem/webdavfilesystem"
+	"github.com/forceu/gokapi/internal/storage/filesystem/webdavfilesystem/webdav"
 	"log"
+	"os"
+	"sort"
 )
 
 var dataFilesystem interfaces.System
-var s3FileSystem interfaces.System
+var s3FileSystem = s3filesystem.GetDriver()
+var webdavFileSystem = webdavfilesystem.GetDriver()
+var sftpFileSystem = sftpfilesystem.GetDriver()
 
 // ActiveStorageSystem is a driver for the storage system that is in use currently. Can be either
-// the local filesystem or S3, depending on the configuration
+// the local filesystem, S3, WebDAV or SFTP, depending on the configuration
 var ActiveStorageSystem interfaces.System
 
 // Init initializes the filesystems and must be called on start
@@ -27,7 +37,6 @@ func Init(pathData string) {
 // SetAws sets the AWS filesystem as the default storage
 func SetAws() {
 	if aws.IsIncludedInBuild {
-		s3FileSystem = s3filesystem.GetDriver()
 		ok := s3FileSystem.Init(s3filesystem.Config{Bucket: aws.GetDefaultBucketName()})
 		if !ok && !isUnitTesting {
 			log.Println("Unable to set AWS S3 as filesystem")
@@ -37,6 +46,26 @@ func SetAws() {
 	}
 }
 
+// SetWebdav sets the WebDAV filesystem as the default storage
+func SetWebdav() {
+	ok := webdavFileSystem.Init(webdavfilesystem.Config{Url: webdav.GetUrl()})
+	if !ok && !isUnitTesting {
+		log.Println("Unable to set WebDAV as filesystem")
+		return
+	}
+	ActiveStorageSystem = webdavFileSystem
+}
+
+// SetSftp sets the SFTP filesystem as the default storage
+func SetSftp() {
+	ok := sftpFileSystem.Init(sftpfilesystem.Config{Directory: sftp.GetDirectory()})
+	if !ok && !isUnitTesting {
+		log.Println("Unable to set SFTP as filesystem")
+		return
+	}
+	ActiveStorageSystem = sftpFileSystem
+}
+
 // SetLocal sets the local filesystem as the default storage
 func SetLocal() {
 	ActiveStorageSystem = dataFilesystem
@@ -48,5 +77,145 @@ func GetLocal() interfaces.System {
 	return dataFilesystem
 }
 
+// GetForFile returns the filesystem the given file is stored on, regardless of what filesystem is used by default.
+// All operations on the stored content of a file should be done with the returned driver
+func GetForFile(file models.File) interfaces.System {
+	target, ok := storageTargets[file.StorageTarget]
+	if ok && target.localDriver != nil && file.StorageDriver == "" && file.AwsBucket == "" {
+		return target.localDriver
+	}
+	switch file.StorageDriver {
+	case interfaces.DriverWebdav:
+		return webdavFileSystem
+	case interfaces.DriverSftp:
+		return sftpFileSystem
+	}
+	if file.AwsBucket != "" {
+		return s3FileSystem
+	}
+	return dataFilesystem
+}
+
+// shardedLayoutMigrator is implemented by drivers that store files in a sharded directory layout
+type shardedLayoutMigrator interface {
+	MigrateFile(metaData models.File) (bool, error)
+}
+
+// MigrateToShardedLayout moves the stored content of the file to the sharded directory layout, if it is
+// stored locally in the legacy layout. Returns true, if the file has been moved
+func MigrateToShardedLayout(file models.File) (bool, error) {
+	driver, ok := GetForFile(file).(shardedLayoutMigrator)
+	if !ok {
+		return false, nil
+	}
+	return driver.MigrateFile(file)
+}
+
+// storageTarget is a named storage target that new files can be stored on
+type storageTarget struct {
+	config models.StorageTargetConfig
+	// localDriver is only set for local targets that use a different directory than the data directory
+	localDriver interfaces.System
+}
+
+var storageTargets = make(map[string]storageTarget)
+
+// AddTarget adds a named storage target. Values of an S3 target that are not set are taken from defaultAws.
+// WebDAV and SFTP targets use the server that has been configured for the instance
+func AddTarget(config models.StorageTargetConfig, defaultAws models.AwsConfig) error {
+	if config.Name == "" {
+		return errors.New("a storage target without a name has been configured")
+	}
+	_, exists := storageTargets[config.Name]
+	if exists {
+		return errors.New("storage target " + config.Name + " has been configured more than once")
+	}
+	target := storageTarget{config: config}
+	switch config.Type {
+	case models.StorageTypeLocal:
+		if config.Directory != "" {
+			err := os.MkdirAll(config.Directory, 0770)
+			if err != nil {
+				return err
+			}
+			target.localDriver = localstorage.GetDriver()
+			target.localDriver.Init(localstorage.Config{DataPath: config.Directory})
+		}
+	case models.StorageTypeS3:
+		if !aws.IsIncludedInBuild {
+			return errors.New("storage target " + config.Name + " requires AWS, which is not supported in this build")
+		}
+		awsConfig := config.ToAwsConfig(defaultAws)
+		err := aws.AddBucketConfig(awsConfig)
+		if err != nil {
+			return err
+		}
+		target.config.Bucket = awsConfig.Bucket
+	case models.StorageTypeWebdav:
+		if !webdav.IsAvailable() {
+			return errors.New("storage target " + config.Name + " requires WebDAV, but no valid WebDAV server has been configured")
+		}
+	case models.StorageTypeSftp:
+		if !sftp.IsAvailable() {
+			return errors.New("storage target " + config.Name + " requires SFTP, but no valid SFTP server has been configured")
+		}
+	default:
+		return errors.New("storage target " + config.Name + " has an unknown type: " + config.Type)
+	}
+	storageTargets[config.Name] = target
+	return nil
+}
+
+// ClearTargets removes all named storage targets
+func ClearTargets() {
+	storageTargets = make(map[string]storageTarget)
+}
+
+// GetTargetNames returns the names of all storage targets, sorted alphabetically
+func GetTargetNames() []string {
+	result := make([]string, 0, len(storageTargets))
+	for name := range storageTargets {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// IsValidTarget returns true if a storage target with the given name exists
+func IsValidTarget(name string) bool {
+	_, ok := storageTargets[name]
+	return ok
+}
+
+// GetTargetType returns the type of the named storage target or false, if the target does not exist
+func GetTargetType(name string) (string, bool) {
+	target, ok := storageTargets[name]
+	if !ok {
+		return "", false
+	}
+	return target.config.Type, true
+}
+
+// ApplyTarget sets the values of the file that are required to store it on the named storage target.
+// Returns false if the target does not exist
+func ApplyTarget(name string, file *models.File) bool {
+	target, ok := storageTargets[name]
+	if !ok {
+		return false
+	}
+	file.StorageTarget = name
+	file.AwsBucket = ""
+	file.StorageDriver = ""
+	switch target.config.Type {
+	case models.StorageTypeS3:
+		file.AwsBucket = target.config.Bucket
+	case models.StorageTypeWebdav:
+		file.StorageDriver = interfaces.DriverWebdav
+	case models.StorageTypeSftp:
+		file.StorageDriver = interfaces.DriverSftp
+	}
+	return true
+}
+
 // isUnitTesting is only set to true when testing, to avoid login with aws
 var isUnitTesting = false
@@ -5,6 +5,8 @@ import (
 	fileInterfaces "github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
 	"github.com/forceu/gokapi/internal/test"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -44,3 +46,64 @@ func TestSetAws(t *testing.T) {
 	test.IsEqualString(t, ActiveStorageSystem.GetSystemName(), fileInterfaces.DriverAws)
 
 }
+
+func TestGetForFile(t *testing.T) {
+	Init("./test")
+	test.IsEqualBool(t, GetForFile(models.File{SHA1: "test"}) == dataFilesystem, true)
+	test.IsEqualBool(t, GetForFile(models.File{SHA1: "test", AwsBucket: "test"}) == s3FileSystem, true)
+	test.IsEqualString(t, GetForFile(models.File{AwsBucket: "test"}).GetSystemName(), fileInterfaces.DriverAws)
+	test.IsEqualBool(t, GetForFile(models.File{SHA1: "test", StorageDriver: fileInterfaces.DriverWebdav}) == webdavFileSystem, true)
+	test.IsEqualBool(t, GetForFile(models.File{SHA1: "test", StorageDriver: fileInterfaces.DriverSftp}) == sftpFileSystem, true)
+	test.IsEqualString(t, GetForFile(models.File{StorageDriver: fileInterfaces.DriverSftp}).GetSystemName(), fileInterfaces.DriverSftp)
+}
+
+func TestTargets(t *testing.T) {
+	Init("./test")
+	ClearTargets()
+	defer ClearTargets()
+	defer os.RemoveAll("./test-fast")
+	test.IsNotNil(t, AddTarget(models.StorageTargetConfig{Type: models.StorageTypeLocal}, models.AwsConfig{}))
+	test.IsNil(t, AddTarget(models.StorageTargetConfig{Name: "local-default", Type: models.StorageTypeLocal}, models.AwsConfig{}))
+	test.IsNil(t, AddTarget(models.StorageTargetConfig{Name: "local-fast", Type: models.StorageTypeLocal, Directory: "./test-fast"}, models.AwsConfig{}))
+	test.FolderExists(t, "./test-fast")
+	test.IsNotNil(t, AddTarget(models.StorageTargetConfig{Name: "local-fast", Type: models.StorageTypeLocal}, models.AwsConfig{}))
+	test.IsNotNil(t, AddTarget(models.StorageTargetConfig{Name: "webdav", Type: models.StorageTypeWebdav}, models.AwsConfig{}))
+	test.IsNotNil(t, AddTarget(models.StorageTargetConfig{Name: "sftp", Type: models.StorageTypeSftp}, models.AwsConfig{}))
+	test.IsNotNil(t, AddTarget(models.StorageTargetConfig{Name: "invalid", Type: "invalid"}, models.AwsConfig{}))
+	test.IsEqualString(t, strings.Join(GetTargetNames(), ","), "local-default,local-fast")
+	test.IsEqualBool(t, IsValidTarget("local-fast"), true)
+	test.IsEqualBool(t, IsValidTarget("invalid"), false)
+	targetType, ok := GetTargetType("local-fast")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, targetType, models.StorageTypeLocal)
+	_, ok = GetTargetType("invalid")
+	test.IsEqualBool(t, ok, false)
+
+	file := models.File{SHA1: "test", AwsBucket: "test"}
+	test.IsEqualBool(t, ApplyTarget("invalid", &file), false)
+	test.IsEqualString(t, file.AwsBucket, "test")
+	test.IsEqualBool(t, ApplyTarget("local-fast", &file), true)
+	test.IsEqualString(t, file.StorageTarget, "local-fast")
+	test.IsEqualString(t, file.AwsBucket, "")
+	test.IsEqualBool(t, GetForFile(file) == dataFilesystem, false)
+	test.IsEqualBool(t, GetForFile(file) == storageTargets["local-fast"].localDriver, true)
+	test.IsEqualBool(t, ApplyTarget("local-default", &file), true)
+	test.IsEqualBool(t, GetForFile(file) == dataFilesystem, true)
+
+	if aws.IsMockApi {
+		test.IsNotNil(t, AddTarget(models.StorageTargetConfig{Name: "s3-archive", Type: models.StorageTypeS3}, models.AwsConfig{}))
+		t.Setenv("GOKAPI_AWS_BUCKET", "gokapi-test")
+		t.Setenv("GOKAPI_AWS_REGION", "mock-region-1")
+		t.Setenv("GOKAPI_AWS_KEY", "accId")
+		t.Setenv("GOKAPI_AWS_KEY_SECRET", "accKey")
+		test.IsNil(t, AddTarget(models.StorageTargetConfig{Name: "s3-archive", Type: models.StorageTypeS3, Bucket: "archive"}, models.AwsConfig{Bucket: "gokapi-test"}))
+		test.IsEqualBool(t, ApplyTarget("s3-archive", &file), true)
+		test.IsEqualString(t, file.AwsBucket, "archive")
+		test.IsEqualBool(t, GetForFile(file) == s3FileSystem, true)
+	}
+
+	ClearTargets()
+	test.IsEqualInt(t, len(GetTargetNames()), 0)
+	file = models.File{SHA1: "test", StorageTarget: "local-fast"}
+	test.IsEqualBool(t, GetForFile(file) == dataFilesystem, true)
+}
@@ -2,6 +2,8 @@ package interfaces
 
 import (
 	"github.com/forceu/gokapi/internal/models"
+	"io"
+	"net/http"
 	"os"
 )
 
@@ -11,6 +13,12 @@ const DriverLocal = "localstorage"
 // DriverAws is returned as a name for the AWS Storage driver
 const DriverAws = "awss3"
 
+// DriverWebdav is returned as a name for the WebDAV Storage driver
+const DriverWebdav = "webdav"
+
+// DriverSftp is returned as a name for the SFTP Storage driver
+const DriverSftp = "sftp"
+
 // File contains information about the stored file
 type File interface {
 	// Exists returns true if the file exists
@@ -33,4 +41,16 @@ type System interface {
 	GetFile(filename string) File
 	// FileExists returns true if the system contains a file with the given relative filepath
 	FileExists(filepath string) (bool, error)
+	// WriteToFilesystem stores the content of the reader as the file described by the metadata
+	WriteToFilesystem(input io.Reader, metaData models.File) error
+	// OpenFile returns a reader for the stored file, starting at offset. If length is negative,
+	// the file is read until the end
+	OpenFile(metaData models.File, offset, length int64) (io.ReadCloser, error)
+	// DeleteFile removes the stored file
+	DeleteFile(metaData models.File) error
+	// StatFile returns true and the size of the stored file, if it exists
+	StatFile(metaData models.File) (bool, int64, error)
+	// ServeFile sends the stored file to the client without any further processing. Returns true
+	// if the operation was blocking, or false if the client was redirected to the file instead
+	ServeFile(w http.ResponseWriter, r *http.Request, metaData models.File, forceDownload bool) (bool, error)
 }
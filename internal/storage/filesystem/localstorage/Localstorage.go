@@ -5,10 +5,22 @@ import (
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	fileInterfaces "github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
+	"github.com/forceu/gokapi/internal/webserver/headers"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// BlobDirectory is the subdirectory of the data path that contains the stored files. Files are sharded by
+// the first four characters of their hash, e.g. blobs/ab/cd/abcdef..., to keep the number of files per
+// directory low. Files that were stored directly in the data path by older versions can still be read,
+// until they have been moved with MigrateFile
+const BlobDirectory = "blobs"
+
 // GetDriver returns a driver for the local file system
 func GetDriver() fileInterfaces.System {
 	return &localStorageDriver{}
@@ -36,7 +48,116 @@ func (d *localStorageDriver) MoveToFilesystem(sourceFile *os.File, metaData mode
 	if metaData.SHA1 == "" {
 		return errors.New("empty metadata passed")
 	}
-	return os.Rename(sourceFile.Name(), d.getPath()+d.filePrefix+metaData.SHA1)
+	err = d.createShardDirectory(metaData)
+	if err != nil {
+		return err
+	}
+	return os.Rename(sourceFile.Name(), d.getFilePath(metaData))
+}
+
+// WriteToFilesystem stores the content of the reader in the data path
+func (d *localStorageDriver) WriteToFilesystem(input io.Reader, metaData models.File) error {
+	if metaData.SHA1 == "" {
+		return errors.New("empty metadata passed")
+	}
+	err := d.createShardDirectory(metaData)
+	if err != nil {
+		return err
+	}
+	destinationFile, err := os.OpenFile(d.getFilePath(metaData), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destinationFile, input)
+	if err != nil {
+		_ = destinationFile.Close()
+		return err
+	}
+	return destinationFile.Close()
+}
+
+// OpenFile returns a reader for the stored file, starting at offset. If length is negative,
+// the file is read until the end
+func (d *localStorageDriver) OpenFile(metaData models.File, offset, length int64) (io.ReadCloser, error) {
+	file, err := os.Open(d.getExistingFilePath(metaData))
+	if err != nil {
+		return nil, err
+	}
+	if offset != 0 {
+		_, err = file.Seek(offset, io.SeekStart)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return &limitedFile{Reader: io.LimitReader(file, length), file: file}, nil
+}
+
+// DeleteFile removes the file from the data path. If the file is also stored in the legacy layout,
+// that copy is removed as well
+func (d *localStorageDriver) DeleteFile(metaData models.File) error {
+	err := os.Remove(d.getFilePath(metaData))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	errLegacy := os.Remove(d.getLegacyFilePath(metaData))
+	if errLegacy == nil || !os.IsNotExist(errLegacy) {
+		return errLegacy
+	}
+	return err
+}
+
+// MigrateFile moves a file that is stored in the legacy layout directly in the data path to the
+// sharded layout. Returns true, if the file has been moved
+func (d *localStorageDriver) MigrateFile(metaData models.File) (bool, error) {
+	legacyPath := d.getLegacyFilePath(metaData)
+	if metaData.SHA1 == "" || !helper.FileExists(legacyPath) {
+		return false, nil
+	}
+	if helper.FileExists(d.getFilePath(metaData)) {
+		// The file has been uploaded again after the update, the legacy copy is not required anymore
+		return true, os.Remove(legacyPath)
+	}
+	err := d.createShardDirectory(metaData)
+	if err != nil {
+		return false, err
+	}
+	return true, os.Rename(legacyPath, d.getFilePath(metaData))
+}
+
+// StatFile returns true and the size of the file, if it exists in the data path
+func (d *localStorageDriver) StatFile(metaData models.File) (bool, int64, error) {
+	info, err := os.Stat(d.getExistingFilePath(metaData))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	if info.IsDir() {
+		return false, 0, nil
+	}
+	return true, info.Size(), nil
+}
+
+// ServeFile sends the stored file to the client. This is always a blocking operation
+func (d *localStorageDriver) ServeFile(w http.ResponseWriter, r *http.Request, metaData models.File, forceDownload bool) (bool, error) {
+	file, err := os.Open(d.getExistingFilePath(metaData))
+	if err != nil {
+		return true, err
+	}
+	defer file.Close()
+	size, err := helper.GetFileSize(file)
+	if err != nil {
+		return true, err
+	}
+	headers.Write(metaData, w, forceDownload)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	http.ServeContent(w, r, metaData.Name, time.Now(), file)
+	return true, nil
 }
 
 // Init sets the driver configurations and returns true if successful
@@ -64,16 +185,13 @@ func (d *localStorageDriver) IsAvailable() bool {
 
 // GetFile returns a File struct for the corresponding filename
 func (d *localStorageDriver) GetFile(filename string) fileInterfaces.File {
-	return &localFile{Directory: d.getPath(), Filename: d.filePrefix + filename}
+	path := d.getExistingFilePath(models.File{SHA1: filename})
+	return &localFile{Directory: filepath.Dir(path) + string(os.PathSeparator), Filename: filepath.Base(path)}
 }
 
 // FileExists returns true if the system contains a file with the given relative filepath
 func (d *localStorageDriver) FileExists(filename string) (bool, error) {
-	file := localFile{
-		Directory: d.getPath(),
-		Filename:  d.filePrefix + filename,
-	}
-	return file.Exists(), nil
+	return d.GetFile(filename).Exists(), nil
 }
 
 // GetSystemName returns the name of the driver
@@ -81,6 +199,42 @@ func (d *localStorageDriver) GetSystemName() string {
 	return fileInterfaces.DriverLocal
 }
 
+// getFilePath returns the path of the file in the sharded layout
+func (d *localStorageDriver) getFilePath(metaData models.File) string {
+	return d.getShardDirectory(metaData) + d.filePrefix + metaData.SHA1
+}
+
+// getLegacyFilePath returns the path of the file, if it was stored directly in the data path
+func (d *localStorageDriver) getLegacyFilePath(metaData models.File) string {
+	return d.getPath() + d.filePrefix + metaData.SHA1
+}
+
+// getExistingFilePath returns the path of the file in the sharded layout, unless it only exists in the legacy layout
+func (d *localStorageDriver) getExistingFilePath(metaData models.File) string {
+	path := d.getFilePath(metaData)
+	if helper.FileExists(path) {
+		return path
+	}
+	legacyPath := d.getLegacyFilePath(metaData)
+	if helper.FileExists(legacyPath) {
+		return legacyPath
+	}
+	return path
+}
+
+// getShardDirectory returns the directory of the file in the sharded layout, e.g. blobs/ab/cd/ for the hash abcdef
+func (d *localStorageDriver) getShardDirectory(metaData models.File) string {
+	directory := d.getPath() + BlobDirectory + string(os.PathSeparator)
+	if len(metaData.SHA1) < 4 {
+		return directory
+	}
+	return directory + metaData.SHA1[0:2] + string(os.PathSeparator) + metaData.SHA1[2:4] + string(os.PathSeparator)
+}
+
+func (d *localStorageDriver) createShardDirectory(metaData models.File) error {
+	return os.MkdirAll(d.getShardDirectory(metaData), 0770)
+}
+
 func (d *localStorageDriver) getPath() string {
 	if d.dataPath == "" {
 		panic("no path has been set!")
@@ -102,3 +256,14 @@ func (f *localFile) Exists() bool {
 func (f *localFile) GetName() string {
 	return f.Filename
 }
+
+// limitedFile reads only a part of a file, but closes the underlying file
+type limitedFile struct {
+	io.Reader
+	file *os.File
+}
+
+// Close closes the underlying file
+func (l *limitedFile) Close() error {
+	return l.file.Close()
+}
@@ -1,8 +1,11 @@
 package localstorage
 
 import (
+	"bytes"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
+	"io"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -97,15 +100,15 @@ func TestLocalStorageDriver_MoveToFilesystem(t *testing.T) {
 	err = driver.MoveToFilesystem(file, metaData)
 	test.IsNil(t, err)
 	test.FileDoesNotExist(t, "test/testfile")
-	test.FileExists(t, "test/data/123testsha")
+	test.FileExists(t, "test/data/blobs/te/st/123testsha")
 
 }
 
 func TestLocalFile_Exists(t *testing.T) {
 	driver := getTestDriver(t)
 	initDriver(t, driver)
-	test.FileExists(t, "test/data/123testsha")
-	test.FileDoesNotExist(t, "test/data/testsha")
+	test.FileExists(t, "test/data/blobs/te/st/123testsha")
+	test.FileDoesNotExist(t, "test/data/blobs/te/st/testsha")
 	file := driver.GetFile("testsha")
 	test.IsEqualBool(t, file.Exists(), true)
 	test.IsEqualString(t, file.GetName(), "123testsha")
@@ -114,8 +117,8 @@ func TestLocalFile_Exists(t *testing.T) {
 func TestLocalStorageDriver_FileExists(t *testing.T) {
 	driver := getTestDriver(t)
 	initDriver(t, driver)
-	test.FileExists(t, "test/data/123testsha")
-	test.FileDoesNotExist(t, "test/data/testsha")
+	test.FileExists(t, "test/data/blobs/te/st/123testsha")
+	test.FileDoesNotExist(t, "test/data/blobs/te/st/testsha")
 	exist, err := driver.FileExists("testsha")
 	test.IsNil(t, err)
 	test.IsEqualBool(t, exist, true)
@@ -124,6 +127,155 @@ func TestLocalStorageDriver_FileExists(t *testing.T) {
 	test.IsEqualBool(t, exist, false)
 }
 
+func TestLocalStorageDriver_WriteToFilesystem(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	err := driver.WriteToFilesystem(bytes.NewReader([]byte("Written content")), models.File{})
+	test.IsNotNil(t, err)
+	err = driver.WriteToFilesystem(bytes.NewReader([]byte("Written content")), models.File{SHA1: "testwrite"})
+	test.IsNil(t, err)
+	content, err := os.ReadFile("test/data/blobs/te/st/123testwrite")
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "Written content")
+}
+
+func TestLocalStorageDriver_OpenFile(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	metaData := models.File{SHA1: "testsha"}
+	reader, err := driver.OpenFile(metaData, 0, -1)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "This is a test")
+	test.IsNil(t, reader.Close())
+
+	reader, err = driver.OpenFile(metaData, 5, 2)
+	test.IsNil(t, err)
+	content, err = io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "is")
+	test.IsNil(t, reader.Close())
+
+	reader, err = driver.OpenFile(metaData, 10, -1)
+	test.IsNil(t, err)
+	content, err = io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "test")
+	test.IsNil(t, reader.Close())
+
+	_, err = driver.OpenFile(models.File{SHA1: "invalid"}, 0, -1)
+	test.IsNotNil(t, err)
+}
+
+func TestLocalStorageDriver_StatFile(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	exists, size, err := driver.StatFile(models.File{SHA1: "testsha"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt(t, int(size), 14)
+	exists, size, err = driver.StatFile(models.File{SHA1: "invalid"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	test.IsEqualInt(t, int(size), 0)
+	driver.filePrefix = ""
+	exists, _, err = driver.StatFile(models.File{})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
+
+func TestLocalStorageDriver_ServeFile(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/download", nil)
+	metaData := models.File{SHA1: "testsha", Name: "test.txt", ContentType: "text/plain"}
+	isBlocking, err := driver.ServeFile(w, r, metaData, true)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isBlocking, true)
+	test.IsEqualString(t, w.Body.String(), "This is a test")
+	test.IsEqualString(t, w.Header().Get("Content-Length"), "14")
+	test.IsEqualString(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"test.txt\"")
+
+	w = httptest.NewRecorder()
+	_, err = driver.ServeFile(w, r, models.File{SHA1: "invalid"}, false)
+	test.IsNotNil(t, err)
+}
+
+func TestLocalStorageDriver_DeleteFile(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	test.FileExists(t, "test/data/blobs/te/st/123testwrite")
+	err := driver.DeleteFile(models.File{SHA1: "testwrite"})
+	test.IsNil(t, err)
+	test.FileDoesNotExist(t, "test/data/blobs/te/st/123testwrite")
+	err = driver.DeleteFile(models.File{SHA1: "testwrite"})
+	test.IsNotNil(t, err)
+}
+
+func TestLegacyLayout(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	metaData := models.File{SHA1: "legacysha"}
+	err := os.WriteFile("test/data/123legacysha", []byte("Legacy content"), 0600)
+	test.IsNil(t, err)
+	exists, size, err := driver.StatFile(metaData)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt(t, int(size), 14)
+	reader, err := driver.OpenFile(metaData, 7, -1)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "content")
+	test.IsNil(t, reader.Close())
+	file := driver.GetFile("legacysha")
+	test.IsEqualBool(t, file.Exists(), true)
+	test.IsEqualString(t, file.GetName(), "123legacysha")
+
+	ok, err := driver.MigrateFile(metaData)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	test.FileDoesNotExist(t, "test/data/123legacysha")
+	test.FileExists(t, "test/data/blobs/le/ga/123legacysha")
+	ok, err = driver.MigrateFile(metaData)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = driver.MigrateFile(models.File{})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+
+	// The legacy copy is removed, if the file already exists in the sharded layout
+	err = os.WriteFile("test/data/123legacysha", []byte("Legacy content"), 0600)
+	test.IsNil(t, err)
+	ok, err = driver.MigrateFile(metaData)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	test.FileDoesNotExist(t, "test/data/123legacysha")
+	test.FileExists(t, "test/data/blobs/le/ga/123legacysha")
+
+	// Both copies are deleted
+	err = os.WriteFile("test/data/123legacysha", []byte("Legacy content"), 0600)
+	test.IsNil(t, err)
+	err = driver.DeleteFile(metaData)
+	test.IsNil(t, err)
+	test.FileDoesNotExist(t, "test/data/123legacysha")
+	test.FileDoesNotExist(t, "test/data/blobs/le/ga/123legacysha")
+	err = os.WriteFile("test/data/123legacysha", []byte("Legacy content"), 0600)
+	test.IsNil(t, err)
+	err = driver.DeleteFile(metaData)
+	test.IsNil(t, err)
+	test.FileDoesNotExist(t, "test/data/123legacysha")
+}
+
+func TestGetShardDirectory(t *testing.T) {
+	driver := getTestDriver(t)
+	initDriver(t, driver)
+	test.IsEqualString(t, driver.getShardDirectory(models.File{SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7"}), "test/data/blobs/e0/17/")
+	test.IsEqualString(t, driver.getShardDirectory(models.File{SHA1: "abc"}), "test/data/blobs/")
+}
+
 func TestLocalStorageDriver_GetSystemName(t *testing.T) {
 	driver := getTestDriver(t)
 	test.IsEqualString(t, driver.GetSystemName(), "localstorage")
@@ -5,6 +5,8 @@ import (
 	"github.com/forceu/gokapi/internal/models"
 	fileInterfaces "github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"io"
+	"net/http"
 	"os"
 )
 
@@ -36,6 +38,35 @@ func (d *s3StorageDriver) MoveToFilesystem(sourceFile *os.File, metaData models.
 	return os.Remove(sourceFile.Name())
 }
 
+// WriteToFilesystem uploads the content of the reader to the bucket specified in the metadata
+func (d *s3StorageDriver) WriteToFilesystem(input io.Reader, metaData models.File) error {
+	_, err := aws.Upload(input, metaData)
+	return err
+}
+
+// OpenFile returns a reader for the object, starting at offset. If length is negative,
+// the object is read until the end
+func (d *s3StorageDriver) OpenFile(metaData models.File, offset, length int64) (io.ReadCloser, error) {
+	return aws.GetObject(metaData, offset, length)
+}
+
+// DeleteFile removes the object from the bucket specified in the metadata
+func (d *s3StorageDriver) DeleteFile(metaData models.File) error {
+	_, err := aws.DeleteObject(metaData)
+	return err
+}
+
+// StatFile returns true and the size of the object, if it exists in the bucket specified in the metadata
+func (d *s3StorageDriver) StatFile(metaData models.File) (bool, int64, error) {
+	return aws.FileExists(metaData)
+}
+
+// ServeFile either redirects the client to a pre-signed url or proxies the download, depending on the
+// configuration. Returns true if the operation was blocking
+func (d *s3StorageDriver) ServeFile(w http.ResponseWriter, r *http.Request, metaData models.File, forceDownload bool) (bool, error) {
+	return aws.ServeFile(w, r, metaData, forceDownload)
+}
+
 // Init sets the driver configurations and returns true if successful
 // Requires a Config struct as input
 func (d *s3StorageDriver) Init(input any) bool {
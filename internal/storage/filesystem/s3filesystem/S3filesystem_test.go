@@ -1,7 +1,13 @@
 package s3filesystem
 
 import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
 	"github.com/forceu/gokapi/internal/test"
+	"io"
+	"net/http/httptest"
+	"os"
 	"testing"
 )
 
@@ -49,3 +55,47 @@ func TestAwsFile_GetName(t *testing.T) {
 	file := driver.GetFile("testfile")
 	test.IsEqualString(t, file.GetName(), "testfile")
 }
+
+func TestS3StorageDriver_FileOperations(t *testing.T) {
+	if !aws.IsMockApi {
+		return
+	}
+	driver := getTestDriver(t)
+	metaData := models.File{SHA1: "testdriver", AwsBucket: "gokapi-test", Name: "test.txt"}
+	err := driver.WriteToFilesystem(bytes.NewReader([]byte("This is a test")), metaData)
+	test.IsNotNil(t, err)
+
+	os.Setenv("GOKAPI_AWS_BUCKET", "gokapi-test")
+	os.Setenv("GOKAPI_AWS_REGION", "mock-region-1")
+	os.Setenv("GOKAPI_AWS_KEY", "accId")
+	os.Setenv("GOKAPI_AWS_KEY_SECRET", "accKey")
+	defer os.Unsetenv("GOKAPI_AWS_BUCKET")
+	defer os.Unsetenv("GOKAPI_AWS_REGION")
+	defer os.Unsetenv("GOKAPI_AWS_KEY")
+	defer os.Unsetenv("GOKAPI_AWS_KEY_SECRET")
+
+	err = driver.WriteToFilesystem(bytes.NewReader([]byte("This is a test")), metaData)
+	test.IsNil(t, err)
+	exists, _, err := driver.StatFile(metaData)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+
+	reader, err := driver.OpenFile(metaData, 5, 2)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "is")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/download", nil)
+	isBlocking, err := driver.ServeFile(w, r, metaData, false)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isBlocking, false)
+	test.IsEqualInt(t, w.Code, 307)
+
+	err = driver.DeleteFile(metaData)
+	test.IsNil(t, err)
+	exists, _, err = driver.StatFile(metaData)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
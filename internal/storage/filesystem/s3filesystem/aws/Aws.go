@@ -17,6 +17,7 @@ import (
 	"github.com/forceu/gokapi/internal/webserver/headers"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,6 +26,17 @@ var awsConfig models.AwsConfig
 
 var isCorrectLogin bool
 
+// maxCopySize is the maximum size of an object that can be copied with a single request.
+// Bigger objects have to be copied in parts
+const maxCopySize = 5 * 1024 * 1024 * 1024
+
+// copyPartSize is the size of a part when copying an object in parts
+const copyPartSize = 512 * 1024 * 1024
+
+// bucketConfigs contains the configuration of storage targets that use a different bucket
+// or different credentials than the default configuration
+var bucketConfigs = make(map[string]models.AwsConfig)
+
 // IsIncludedInBuild is true if Gokapi has been compiled with AWS support or the API is being mocked
 const IsIncludedInBuild = true
 
@@ -58,9 +70,24 @@ func IsAvailable() bool {
 	return isCorrectLogin
 }
 
+// AddBucketConfig adds the configuration for a bucket that is used by a storage target. Files that are
+// stored in this bucket are accessed with the given credentials
+func AddBucketConfig(config models.AwsConfig) error {
+	ok, err := IsValidLogin(config)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("not all required values for the S3 bucket " + config.Bucket + " have been provided")
+	}
+	bucketConfigs[config.Bucket] = config
+	return nil
+}
+
 // LogOut resets the credentials
 func LogOut() {
 	awsConfig = models.AwsConfig{}
+	bucketConfigs = make(map[string]models.AwsConfig)
 	isCorrectLogin = false
 }
 
@@ -69,21 +96,28 @@ func IsValidLogin(config models.AwsConfig) (bool, error) {
 	if !config.IsAllProvided() {
 		return false, nil
 	}
-	tempConfig := awsConfig
-	awsConfig = config
-	_, _, err := FileExists(models.File{AwsBucket: awsConfig.Bucket, SHA1: "invalid"})
-	awsConfig = tempConfig
+	_, _, err := fileExists(config, config.Bucket, "invalid")
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func createSession() *session.Session {
+// getConfig returns the configuration for the given bucket. If no configuration for the bucket has been
+// added, the default configuration is returned
+func getConfig(bucket string) models.AwsConfig {
+	config, ok := bucketConfigs[bucket]
+	if ok {
+		return config
+	}
+	return awsConfig
+}
+
+func createSession(config models.AwsConfig) *session.Session {
 	s3Config := &aws.Config{
-		Credentials:      credentials.NewStaticCredentials(awsConfig.KeyId, awsConfig.KeySecret, ""),
-		Endpoint:         aws.String(awsConfig.Endpoint),
-		Region:           aws.String(awsConfig.Region),
+		Credentials:      credentials.NewStaticCredentials(config.KeyId, config.KeySecret, ""),
+		Endpoint:         aws.String(config.Endpoint),
+		Region:           aws.String(config.Region),
 		S3ForcePathStyle: aws.Bool(true),
 	}
 	return session.Must(session.NewSession(s3Config))
@@ -91,7 +125,7 @@ func createSession() *session.Session {
 
 // Upload uploads a file to AWS
 func Upload(input io.Reader, file models.File) (string, error) {
-	sess := createSession()
+	sess := createSession(getConfig(file.AwsBucket))
 	uploader := s3manager.NewUploader(sess)
 
 	result, err := uploader.Upload(&s3manager.UploadInput{
@@ -105,9 +139,146 @@ func Upload(input io.Reader, file models.File) (string, error) {
 	return result.Location, nil
 }
 
+// CreateMultipartUpload starts a new multipart upload for the file and returns the upload ID
+func CreateMultipartUpload(file models.File) (string, error) {
+	svc := s3.New(createSession(getConfig(file.AwsBucket)))
+	result, err := svc.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(file.AwsBucket),
+		Key:    aws.String(file.SHA1),
+	})
+	if err != nil {
+		return "", err
+	}
+	return *result.UploadId, nil
+}
+
+// UploadPart uploads a part of a multipart upload and returns the ETag of the part
+func UploadPart(file models.File, uploadId string, partNumber int64, input io.ReadSeeker) (string, error) {
+	svc := s3.New(createSession(getConfig(file.AwsBucket)))
+	result, err := svc.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(file.AwsBucket),
+		Key:        aws.String(file.SHA1),
+		UploadId:   aws.String(uploadId),
+		PartNumber: aws.Int64(partNumber),
+		Body:       input,
+	})
+	if err != nil {
+		return "", err
+	}
+	return *result.ETag, nil
+}
+
+// CompleteMultipartUpload combines the uploaded parts to the object of the file
+func CompleteMultipartUpload(file models.File, uploadId string, parts []CompletedPart) error {
+	svc := s3.New(createSession(getConfig(file.AwsBucket)))
+	_, err := svc.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(file.AwsBucket),
+		Key:             aws.String(file.SHA1),
+		UploadId:        aws.String(uploadId),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: toS3Parts(parts)},
+	})
+	return err
+}
+
+func toS3Parts(parts []CompletedPart) []*s3.CompletedPart {
+	result := make([]*s3.CompletedPart, len(parts))
+	for i, part := range parts {
+		result[i] = &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.PartNumber),
+		}
+	}
+	return result
+}
+
+// AbortMultipartUpload cancels a multipart upload and removes all uploaded parts
+func AbortMultipartUpload(file models.File, uploadId string) error {
+	svc := s3.New(createSession(getConfig(file.AwsBucket)))
+	_, err := svc.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(file.AwsBucket),
+		Key:      aws.String(file.SHA1),
+		UploadId: aws.String(uploadId),
+	})
+	return err
+}
+
+// ListMultipartUploads returns all multipart uploads of the bucket that have not been completed or aborted
+// yet and whose key starts with the prefix
+func ListMultipartUploads(bucket, prefix string) ([]MultipartUpload, error) {
+	svc := s3.New(createSession(getConfig(bucket)))
+	result := make([]MultipartUpload, 0)
+	err := svc.ListMultipartUploadsPages(&s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+		for _, upload := range page.Uploads {
+			result = append(result, MultipartUpload{
+				Object:   models.File{SHA1: *upload.Key, AwsBucket: bucket},
+				UploadId: *upload.UploadId,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CopyObject copies the object of the source file to the object of the destination file. Both files have to be
+// accessible with the credentials of the destination bucket
+func CopyObject(source, destination models.File) error {
+	exists, size, err := FileExists(source)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("source object does not exist")
+	}
+	svc := s3.New(createSession(getConfig(destination.AwsBucket)))
+	copySource := aws.String(source.AwsBucket + "/" + source.SHA1)
+	if size <= maxCopySize {
+		_, err = svc.CopyObject(&s3.CopyObjectInput{
+			Bucket:     aws.String(destination.AwsBucket),
+			Key:        aws.String(destination.SHA1),
+			CopySource: copySource,
+		})
+		return err
+	}
+
+	// Objects bigger than 5GB cannot be copied with a single request
+	uploadId, err := CreateMultipartUpload(destination)
+	if err != nil {
+		return err
+	}
+	var parts []CompletedPart
+	for offset := int64(0); offset < size; offset = offset + copyPartSize {
+		lastByte := min(offset+copyPartSize, size) - 1
+		partNumber := int64(len(parts) + 1)
+		result, err := svc.UploadPartCopy(&s3.UploadPartCopyInput{
+			Bucket:          aws.String(destination.AwsBucket),
+			Key:             aws.String(destination.SHA1),
+			UploadId:        aws.String(uploadId),
+			PartNumber:      aws.Int64(partNumber),
+			CopySource:      copySource,
+			CopySourceRange: aws.String("bytes=" + strconv.FormatInt(offset, 10) + "-" + strconv.FormatInt(lastByte, 10)),
+		})
+		if err != nil {
+			_ = AbortMultipartUpload(destination, uploadId)
+			return err
+		}
+		parts = append(parts, CompletedPart{PartNumber: partNumber, ETag: *result.CopyPartResult.ETag})
+	}
+	err = CompleteMultipartUpload(destination, uploadId, parts)
+	if err != nil {
+		_ = AbortMultipartUpload(destination, uploadId)
+	}
+	return err
+}
+
 // Download downloads a file from AWS, used for encrypted files and testing
 func Download(writer io.WriterAt, file models.File) (int64, error) {
-	sess := createSession()
+	sess := createSession(getConfig(file.AwsBucket))
 	downloader := s3manager.NewDownloader(sess)
 
 	size, err := downloader.Download(writer, &s3.GetObjectInput{
@@ -120,17 +291,44 @@ func Download(writer io.WriterAt, file models.File) (int64, error) {
 	return size, nil
 }
 
+// GetObject returns a reader for the object stored in S3, starting at offset. If length is negative,
+// the object is read until the end
+func GetObject(file models.File, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	sess := createSession(getConfig(file.AwsBucket))
+	svc := s3.New(sess)
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(file.AwsBucket),
+		Key:    aws.String(file.SHA1),
+	}
+	if offset != 0 || length > 0 {
+		byteRange := "bytes=" + strconv.FormatInt(offset, 10) + "-"
+		if length > 0 {
+			byteRange = byteRange + strconv.FormatInt(offset+length-1, 10)
+		}
+		input.Range = aws.String(byteRange)
+	}
+	result, err := svc.GetObject(input)
+	if err != nil {
+		return nil, err
+	}
+	return result.Body, nil
+}
+
 // ServeFile either redirects the user to a pre-signed download url (default) or downloads the file and serves it as a proxy (depending
 // on configuration). Returns true if blocking operation (in order to set download status) or false if non-blocking.
 func ServeFile(w http.ResponseWriter, r *http.Request, file models.File, forceDownload bool) (bool, error) {
-	if !awsConfig.ProxyDownload {
+	if !getConfig(file.AwsBucket).ProxyDownload {
 		return false, redirectToDownload(w, r, file, forceDownload)
 	}
 	return true, proxyDownload(w, file, forceDownload)
 }
 
 func getPresignedUrl(file models.File, forceDownload bool) (string, error) {
-	sess := createSession()
+	sess := createSession(getConfig(file.AwsBucket))
 	s3svc := s3.New(sess)
 
 	contentDisposition := "inline; filename=\"" + file.Name + "\""
@@ -190,11 +388,11 @@ func getTimeoutContext() (context.Context, context.CancelFunc) {
 
 // FileExists returns true if the object is stored in S3
 func FileExists(file models.File) (bool, int64, error) {
-	return fileExists(file.AwsBucket, file.SHA1)
+	return fileExists(getConfig(file.AwsBucket), file.AwsBucket, file.SHA1)
 }
 
-func fileExists(bucket, filename string) (bool, int64, error) {
-	sess := createSession()
+func fileExists(config models.AwsConfig, bucket, filename string) (bool, int64, error) {
+	sess := createSession(config)
 	svc := s3.New(sess)
 
 	ctx, cancelCtx := getTimeoutContext()
@@ -223,7 +421,7 @@ func fileExists(bucket, filename string) (bool, int64, error) {
 
 // DeleteObject deletes a file from S3
 func DeleteObject(file models.File) (bool, error) {
-	sess := createSession()
+	sess := createSession(getConfig(file.AwsBucket))
 	svc := s3.New(sess)
 
 	ctx, cancelCtx := getTimeoutContext()
@@ -242,7 +440,7 @@ func DeleteObject(file models.File) (bool, error) {
 
 // IsCorsCorrectlySet returns true if CORS rules allow download from Gokapi
 func IsCorsCorrectlySet(bucket, gokapiUrl string) (bool, error) {
-	sess := createSession()
+	sess := createSession(getConfig(bucket))
 	svc := s3.New(sess)
 	input := &s3.GetBucketCorsInput{
 		Bucket: aws.String(bucket),
@@ -9,11 +9,17 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 var uploadedFiles []models.File
+var uploadedContent = make(map[string][]byte)
+var multipartUploads = make(map[string]map[int64][]byte)
+var multipartObjects = make(map[string]models.File)
+var multipartMutex sync.Mutex
 var isCorrectLogin bool
 
 const (
@@ -54,6 +60,14 @@ func IsValidLogin(config models.AwsConfig) (bool, error) {
 	return isValidCredentials(), nil
 }
 
+// AddBucketConfig adds the configuration for a bucket that is used by a storage target
+func AddBucketConfig(config models.AwsConfig) error {
+	if !isValidCredentials() {
+		return errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	return nil
+}
+
 // LogOut resets the credentials
 func LogOut() {
 	isCorrectLogin = false
@@ -100,12 +114,141 @@ func Upload(input io.Reader, file models.File) (string, error) {
 		return "", errors.New("invalid credentials / invalid bucket / invalid region")
 	}
 
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return "", err
+	}
 	if !isUploaded(file) {
 		uploadedFiles = append(uploadedFiles, file)
 	}
+	uploadedContent[file.SHA1] = content
 	return "", nil
 }
 
+// CreateMultipartUpload starts a new multipart upload for the file and returns the upload ID
+func CreateMultipartUpload(file models.File) (string, error) {
+	if !isValidCredentials() {
+		return "", errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	uploadId := "upload-" + file.SHA1 + "-" + strconv.Itoa(len(multipartUploads))
+	multipartUploads[uploadId] = make(map[int64][]byte)
+	multipartObjects[uploadId] = file
+	return uploadId, nil
+}
+
+// UploadPart uploads a part of a multipart upload and returns the ETag of the part
+func UploadPart(file models.File, uploadId string, partNumber int64, input io.ReadSeeker) (string, error) {
+	if !isValidCredentials() {
+		return "", errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return "", err
+	}
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	parts, ok := multipartUploads[uploadId]
+	if !ok {
+		return "", errors.New("upload not found")
+	}
+	if partNumber < 1 || partNumber > MaxParts {
+		return "", errors.New("invalid part number")
+	}
+	parts[partNumber] = content
+	return "etag-" + strconv.FormatInt(partNumber, 10), nil
+}
+
+// CompleteMultipartUpload combines the uploaded parts to the object of the file
+func CompleteMultipartUpload(file models.File, uploadId string, parts []CompletedPart) error {
+	if !isValidCredentials() {
+		return errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	multipartMutex.Lock()
+	uploadedParts, ok := multipartUploads[uploadId]
+	delete(multipartUploads, uploadId)
+	delete(multipartObjects, uploadId)
+	multipartMutex.Unlock()
+	if !ok {
+		return errors.New("upload not found")
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	var content bytes.Buffer
+	for _, part := range parts {
+		partContent, ok := uploadedParts[part.PartNumber]
+		if !ok || part.ETag != "etag-"+strconv.FormatInt(part.PartNumber, 10) {
+			return errors.New("invalid part")
+		}
+		content.Write(partContent)
+	}
+	_, err := Upload(&content, file)
+	return err
+}
+
+// AbortMultipartUpload cancels a multipart upload and removes all uploaded parts
+func AbortMultipartUpload(file models.File, uploadId string) error {
+	if !isValidCredentials() {
+		return errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	delete(multipartUploads, uploadId)
+	delete(multipartObjects, uploadId)
+	return nil
+}
+
+// ListMultipartUploads returns all multipart uploads of the bucket that have not been completed or aborted
+// yet and whose key starts with the prefix
+func ListMultipartUploads(bucket, prefix string) ([]MultipartUpload, error) {
+	if !isValidCredentials() {
+		return nil, errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	multipartMutex.Lock()
+	defer multipartMutex.Unlock()
+	result := make([]MultipartUpload, 0)
+	for uploadId, object := range multipartObjects {
+		if object.AwsBucket == bucket && strings.HasPrefix(object.SHA1, prefix) {
+			result = append(result, MultipartUpload{Object: object, UploadId: uploadId})
+		}
+	}
+	return result, nil
+}
+
+// CopyObject copies the object of the source file to the object of the destination file
+func CopyObject(source, destination models.File) error {
+	if !isValidCredentials() {
+		return errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	if !isUploaded(source) {
+		return errors.New("file not found")
+	}
+	_, err := Upload(bytes.NewReader(uploadedContent[source.SHA1]), destination)
+	return err
+}
+
+// GetObject returns a reader for the object stored in S3, starting at offset. If length is negative,
+// the object is read until the end
+func GetObject(file models.File, offset, length int64) (io.ReadCloser, error) {
+	if !isValidCredentials() {
+		return nil, errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	if !isUploaded(file) {
+		return nil, errors.New("file not found")
+	}
+	content := uploadedContent[file.SHA1]
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	content = content[offset:]
+	if length >= 0 && length < int64(len(content)) {
+		content = content[:length]
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
 // Download downloads a file from AWS
 func Download(writer io.WriterAt, file models.File) (int64, error) {
 	if !isValidCredentials() {
@@ -173,6 +316,7 @@ func DeleteObject(file models.File) (bool, error) {
 		}
 	}
 	uploadedFiles = buffer
+	delete(uploadedContent, file.SHA1)
 
 	return true, nil
 }
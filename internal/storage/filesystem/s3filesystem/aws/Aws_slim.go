@@ -47,6 +47,48 @@ func Download(writer io.WriterAt, file models.File) (int64, error) {
 	return 0, errors.New(errorString)
 }
 
+// GetObject returns a reader for the object stored in S3, starting at offset. If length is negative,
+// the object is read until the end
+func GetObject(file models.File, offset, length int64) (io.ReadCloser, error) {
+	return nil, errors.New(errorString)
+}
+
+// CreateMultipartUpload starts a new multipart upload for the file and returns the upload ID
+func CreateMultipartUpload(file models.File) (string, error) {
+	return "", errors.New(errorString)
+}
+
+// UploadPart uploads a part of a multipart upload and returns the ETag of the part
+func UploadPart(file models.File, uploadId string, partNumber int64, input io.ReadSeeker) (string, error) {
+	return "", errors.New(errorString)
+}
+
+// CompleteMultipartUpload combines the uploaded parts to the object of the file
+func CompleteMultipartUpload(file models.File, uploadId string, parts []CompletedPart) error {
+	return errors.New(errorString)
+}
+
+// AbortMultipartUpload cancels a multipart upload and removes all uploaded parts
+func AbortMultipartUpload(file models.File, uploadId string) error {
+	return errors.New(errorString)
+}
+
+// ListMultipartUploads returns all multipart uploads of the bucket that have not been completed or aborted
+// yet and whose key starts with the prefix
+func ListMultipartUploads(bucket, prefix string) ([]MultipartUpload, error) {
+	return nil, errors.New(errorString)
+}
+
+// CopyObject copies the object of the source file to the object of the destination file
+func CopyObject(source, destination models.File) error {
+	return errors.New(errorString)
+}
+
+// AddBucketConfig adds the configuration for a bucket that is used by a storage target
+func AddBucketConfig(config models.AwsConfig) error {
+	return errors.New(errorString)
+}
+
 // LogOut resets the credentials
 func LogOut() {
 }
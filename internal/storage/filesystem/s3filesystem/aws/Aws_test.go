@@ -3,6 +3,7 @@
 package aws
 
 import (
+	"bytes"
 	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
@@ -49,6 +50,7 @@ func startMockServer() *httptest.Server {
 	backend := s3mem.New()
 	_ = backend.CreateBucket("gokapi")
 	_ = backend.CreateBucket("gokapi-test")
+	_ = backend.CreateBucket("gokapi-second")
 	faker := gofakes3.New(backend)
 	return httptest.NewServer(faker.Server())
 }
@@ -95,6 +97,86 @@ func TestDownloadFromAws(t *testing.T) {
 	os.Remove("test")
 }
 
+func TestGetObject(t *testing.T) {
+	reader, err := GetObject(testFile, 0, -1)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "testfile-content")
+	test.IsNil(t, reader.Close())
+
+	reader, err = GetObject(testFile, 9, 4)
+	test.IsNil(t, err)
+	content, err = io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "cont")
+	test.IsNil(t, reader.Close())
+
+	reader, err = GetObject(testFile, 9, -1)
+	test.IsNil(t, err)
+	content, err = io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "content")
+	test.IsNil(t, reader.Close())
+
+	_, err = GetObject(invalidFile, 0, -1)
+	test.IsNotNil(t, err)
+}
+
+func TestMultipartUpload(t *testing.T) {
+	multipartFile := models.File{AwsBucket: testFile.AwsBucket, SHA1: "chunk-multipartupload"}
+	uploadId, err := CreateMultipartUpload(multipartFile)
+	test.IsNil(t, err)
+	test.IsNotEmpty(t, uploadId)
+
+	firstPart := bytes.Repeat([]byte("a"), MinPartSize)
+	etag1, err := UploadPart(multipartFile, uploadId, 1, bytes.NewReader(firstPart))
+	test.IsNil(t, err)
+	etag2, err := UploadPart(multipartFile, uploadId, 2, bytes.NewReader([]byte("last part")))
+	test.IsNil(t, err)
+	err = CompleteMultipartUpload(multipartFile, uploadId, []CompletedPart{
+		{PartNumber: 1, ETag: etag1},
+		{PartNumber: 2, ETag: etag2},
+	})
+	test.IsNil(t, err)
+	exists, size, err := FileExists(multipartFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt64(t, size, MinPartSize+9)
+	reader, err := GetObject(multipartFile, MinPartSize-1, -1)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "alast part")
+	test.IsNil(t, reader.Close())
+
+	copiedFile := models.File{AwsBucket: testFile.AwsBucket, SHA1: "copiedmultipartupload"}
+	err = CopyObject(multipartFile, copiedFile)
+	test.IsNil(t, err)
+	exists, size, err = FileExists(copiedFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt64(t, size, MinPartSize+9)
+	err = CopyObject(invalidFile, copiedFile)
+	test.IsNotNil(t, err)
+	_, err = DeleteObject(multipartFile)
+	test.IsNil(t, err)
+	_, err = DeleteObject(copiedFile)
+	test.IsNil(t, err)
+
+	uploadId, err = CreateMultipartUpload(multipartFile)
+	test.IsNil(t, err)
+	_, err = UploadPart(multipartFile, uploadId, 1, bytes.NewReader([]byte("aborted")))
+	test.IsNil(t, err)
+	err = AbortMultipartUpload(multipartFile, uploadId)
+	test.IsNil(t, err)
+	err = CompleteMultipartUpload(multipartFile, uploadId, []CompletedPart{{PartNumber: 1, ETag: etag1}})
+	test.IsNotNil(t, err)
+	exists, _, err = FileExists(multipartFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
+
 func TestServeFile(t *testing.T) {
 	awsConfig.ProxyDownload = false
 	testServing(t, true, false)
@@ -188,10 +270,28 @@ func TestDeleteObject(t *testing.T) {
 	test.IsEqualBool(t, result, true)
 	test.IsNil(t, err)
 }
+
+func TestAddBucketConfig(t *testing.T) {
+	err := AddBucketConfig(models.AwsConfig{Bucket: "gokapi-test"})
+	test.IsNotNil(t, err)
+	// second bucket only exists on the mock server
+	if isRealAwsServer {
+		return
+	}
+	config := awsConfig
+	config.Bucket = "gokapi-second"
+	config.ProxyDownload = true
+	err = AddBucketConfig(config)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, getConfig("gokapi-second").ProxyDownload, true)
+	test.IsEqualBool(t, getConfig(testFile.AwsBucket).ProxyDownload, false)
+}
+
 func TestLogOut(t *testing.T) {
 	test.IsEqualBool(t, isCorrectLogin, true)
 	LogOut()
 	test.IsEqualBool(t, isCorrectLogin, false)
+	test.IsEqualInt(t, len(bucketConfigs), 0)
 }
 
 func TestGetDefaultBucketName(t *testing.T) {
@@ -0,0 +1,25 @@
+package aws
+
+import "github.com/forceu/gokapi/internal/models"
+
+// MinPartSize is the minimum size of a part of a multipart upload. Only the last part may be smaller
+const MinPartSize = 5 * 1024 * 1024
+
+// MaxParts is the maximum number of parts a multipart upload can consist of
+const MaxParts = 10000
+
+// CompletedPart is a part of a multipart upload that has been uploaded successfully
+type CompletedPart struct {
+	// PartNumber is the number of the part, starting with 1
+	PartNumber int64
+	// ETag is the entity tag that was returned by the server for the part
+	ETag string
+}
+
+// MultipartUpload is a multipart upload that has been started, but not completed or aborted yet
+type MultipartUpload struct {
+	// Object is the file the parts are uploaded to
+	Object models.File
+	// UploadId is the ID that was returned by the server when the upload was started
+	UploadId string
+}
@@ -0,0 +1,120 @@
+package sftpfilesystem
+
+import (
+	"fmt"
+	"github.com/forceu/gokapi/internal/models"
+	fileInterfaces "github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem/sftp"
+	"io"
+	"net/http"
+	"os"
+)
+
+// GetDriver returns a driver for the SFTP file system
+func GetDriver() fileInterfaces.System {
+	return &sftpStorageDriver{}
+}
+
+type sftpStorageDriver struct {
+	Directory string
+}
+
+// Config is the required configuration for the driver
+type Config struct {
+	// Directory is the directory on the SFTP server where new files are stored
+	Directory string
+}
+
+// MoveToFilesystem uploads a file from the local filesystem to the SFTP server
+func (d *sftpStorageDriver) MoveToFilesystem(sourceFile *os.File, metaData models.File) error {
+	err := sftp.Upload(sourceFile, metaData)
+	if err != nil {
+		return err
+	}
+	err = sourceFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(sourceFile.Name())
+}
+
+// WriteToFilesystem uploads the content of the reader to the SFTP server
+func (d *sftpStorageDriver) WriteToFilesystem(input io.Reader, metaData models.File) error {
+	return sftp.Upload(input, metaData)
+}
+
+// OpenFile returns a reader for the file, starting at offset. If length is negative,
+// the file is read until the end
+func (d *sftpStorageDriver) OpenFile(metaData models.File, offset, length int64) (io.ReadCloser, error) {
+	return sftp.GetObject(metaData, offset, length)
+}
+
+// DeleteFile removes the file from the SFTP server
+func (d *sftpStorageDriver) DeleteFile(metaData models.File) error {
+	_, err := sftp.DeleteObject(metaData)
+	return err
+}
+
+// StatFile returns true and the size of the file, if it exists on the SFTP server
+func (d *sftpStorageDriver) StatFile(metaData models.File) (bool, int64, error) {
+	return sftp.FileExists(metaData)
+}
+
+// ServeFile downloads the file from the SFTP server and serves it as a proxy. Returns true,
+// as this is always a blocking operation
+func (d *sftpStorageDriver) ServeFile(w http.ResponseWriter, r *http.Request, metaData models.File, forceDownload bool) (bool, error) {
+	return sftp.ServeFile(w, r, metaData, forceDownload)
+}
+
+// Init sets the driver configurations and returns true if successful
+// Requires a Config struct as input
+func (d *sftpStorageDriver) Init(input any) bool {
+	config, ok := input.(Config)
+	if !ok {
+		panic("runtime exception: input for sftp filesystem is not a config object")
+	}
+	if config.Directory == "" {
+		panic("empty directory has been passed")
+	}
+	d.Directory = config.Directory
+	return sftp.IsAvailable()
+}
+
+// IsAvailable returns true if the SFTP server is available and login was successful once
+func (d *sftpStorageDriver) IsAvailable() bool {
+	return sftp.IsAvailable()
+}
+
+// GetFile returns a File struct for the corresponding filename
+func (d *sftpStorageDriver) GetFile(filename string) fileInterfaces.File {
+	return &sftpFile{Directory: d.Directory, Filename: filename}
+}
+
+// FileExists returns true if the SFTP server contains a file with the given relative filepath
+func (d *sftpStorageDriver) FileExists(filename string) (bool, error) {
+	exists, _, err := sftp.FileExists(models.File{SHA1: filename})
+	return exists, err
+}
+
+// GetSystemName returns the name of the driver
+func (d *sftpStorageDriver) GetSystemName() string {
+	return fileInterfaces.DriverSftp
+}
+
+type sftpFile struct {
+	Directory string
+	Filename  string
+}
+
+func (f *sftpFile) Exists() bool {
+	exists, _, err := sftp.FileExists(models.File{SHA1: f.Filename})
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return exists
+}
+
+func (f *sftpFile) GetName() string {
+	return f.Filename
+}
@@ -0,0 +1,50 @@
+package sftpfilesystem
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func getTestDriver(t *testing.T) *sftpStorageDriver {
+	t.Helper()
+	driver := GetDriver()
+	result, ok := driver.(*sftpStorageDriver)
+	test.IsEqualBool(t, ok, true)
+	return result
+}
+
+func TestGetDriver(t *testing.T) {
+	getTestDriver(t)
+}
+
+func TestSftpStorageDriver_Init(t *testing.T) {
+	driver := getTestDriver(t)
+	defer test.ExpectPanic(t)
+	driver.Init("test")
+}
+
+func TestSftpStorageDriver_Init2(t *testing.T) {
+	driver := getTestDriver(t)
+	defer test.ExpectPanic(t)
+	driver.Init(Config{Directory: ""})
+}
+
+func TestSftpStorageDriver_Init3(t *testing.T) {
+	driver := getTestDriver(t)
+	ok := driver.Init(Config{Directory: "/gokapi"})
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, driver.Directory, "/gokapi")
+	test.IsEqualBool(t, driver.IsAvailable(), false)
+}
+
+func TestSftpStorageDriver_GetSystemName(t *testing.T) {
+	driver := getTestDriver(t)
+	test.IsEqualString(t, driver.GetSystemName(), "sftp")
+}
+
+func TestSftpFile_GetName(t *testing.T) {
+	driver := getTestDriver(t)
+	driver.Init(Config{Directory: "/gokapi"})
+	file := driver.GetFile("testfile")
+	test.IsEqualString(t, file.GetName(), "testfile")
+}
@@ -0,0 +1,329 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
+	"github.com/forceu/gokapi/internal/webserver/headers"
+	pkgsftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var sftpConfig models.SftpConfig
+
+var isCorrectLogin bool
+
+// activeConnection is the connection that is currently in use. It is reset if the connection is lost
+// and re-established on the next request
+var activeConnection *connection
+var connectionMutex sync.Mutex
+
+// Init reads the credentials for SFTP. Returns true if valid
+func Init(config models.SftpConfig) bool {
+	LogOut()
+	sftpConfig = normaliseConfig(config)
+	ok, err := IsValidLogin(config)
+	if err != nil {
+		fmt.Println("WARNING: SFTP login not successful")
+		fmt.Println(err.Error())
+		isCorrectLogin = false
+		return false
+	}
+	if ok {
+		fmt.Println("SFTP login successful")
+	}
+	isCorrectLogin = ok
+	return ok
+}
+
+// AddStorageDriver marks the file to be stored on the SFTP server
+func AddStorageDriver(file *models.File) {
+	file.StorageDriver = interfaces.DriverSftp
+}
+
+// IsAvailable returns true if valid credentials have been passed
+func IsAvailable() bool {
+	return isCorrectLogin
+}
+
+// LogOut closes the connection and resets the credentials
+func LogOut() {
+	connectionMutex.Lock()
+	if activeConnection != nil {
+		activeConnection.Close()
+		activeConnection = nil
+	}
+	connectionMutex.Unlock()
+	sftpConfig = models.SftpConfig{}
+	isCorrectLogin = false
+}
+
+// IsValidLogin checks if a valid login was provided and the directory exists on the server
+func IsValidLogin(config models.SftpConfig) (bool, error) {
+	if !config.IsAllProvided() {
+		return false, nil
+	}
+	config = normaliseConfig(config)
+	newConnection, err := connect(config)
+	if err != nil {
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return false, nil
+		}
+		return false, err
+	}
+	defer newConnection.Close()
+	info, err := newConnection.sftpClient.Stat(config.Directory)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		return false, errors.New("path on SFTP server is not a directory: " + config.Directory)
+	}
+	return true, nil
+}
+
+// Upload uploads a file to the SFTP server. The content is written to a temporary file first,
+// so that incomplete uploads are never visible under the final name
+func Upload(input io.Reader, file models.File) error {
+	sftpClient, err := getClient()
+	if err != nil {
+		return err
+	}
+	filePath := getFilePath(file)
+	tempPath := filePath + ".part"
+	remoteFile, err := sftpClient.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(remoteFile, input)
+	if err != nil {
+		_ = remoteFile.Close()
+		_ = sftpClient.Remove(tempPath)
+		return err
+	}
+	err = remoteFile.Close()
+	if err != nil {
+		_ = sftpClient.Remove(tempPath)
+		return err
+	}
+	err = sftpClient.PosixRename(tempPath, filePath)
+	if err == nil {
+		return nil
+	}
+	// The server does not support the posix-rename extension, which allows overwriting the target
+	_ = sftpClient.Remove(filePath)
+	err = sftpClient.Rename(tempPath, filePath)
+	if err != nil {
+		_ = sftpClient.Remove(tempPath)
+	}
+	return err
+}
+
+// GetObject returns a reader for the file stored on the SFTP server, starting at offset. If length is negative,
+// the file is read until the end
+func GetObject(file models.File, offset, length int64) (io.ReadCloser, error) {
+	sftpClient, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	remoteFile, err := sftpClient.Open(getFilePath(file))
+	if err != nil {
+		return nil, err
+	}
+	if offset != 0 {
+		_, err = remoteFile.Seek(offset, io.SeekStart)
+		if err != nil {
+			_ = remoteFile.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return remoteFile, nil
+	}
+	return &limitedFile{Reader: io.LimitReader(remoteFile, length), file: remoteFile}, nil
+}
+
+// ServeFile downloads the file from the SFTP server and serves it as a proxy. Range requests
+// are supported. This is always a blocking operation
+func ServeFile(w http.ResponseWriter, r *http.Request, file models.File, forceDownload bool) (bool, error) {
+	sftpClient, err := getClient()
+	if err != nil {
+		return true, err
+	}
+	remoteFile, err := sftpClient.Open(getFilePath(file))
+	if err != nil {
+		return true, err
+	}
+	defer remoteFile.Close()
+	info, err := remoteFile.Stat()
+	if err != nil {
+		return true, err
+	}
+	headers.Write(file, w, forceDownload)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	http.ServeContent(w, r, file.Name, info.ModTime(), remoteFile)
+	return true, nil
+}
+
+// FileExists returns true if the file is stored on the SFTP server and its size
+func FileExists(file models.File) (bool, int64, error) {
+	sftpClient, err := getClient()
+	if err != nil {
+		return false, 0, err
+	}
+	info, err := sftpClient.Stat(getFilePath(file))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	if info.IsDir() {
+		return false, 0, nil
+	}
+	return true, info.Size(), nil
+}
+
+// DeleteObject deletes a file from the SFTP server
+func DeleteObject(file models.File) (bool, error) {
+	sftpClient, err := getClient()
+	if err != nil {
+		return false, err
+	}
+	err = sftpClient.Remove(getFilePath(file))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDirectory returns the directory on the SFTP server where new files are stored
+func GetDirectory() string {
+	return sftpConfig.Directory
+}
+
+func normaliseConfig(config models.SftpConfig) models.SftpConfig {
+	if config.Host != "" {
+		_, _, err := net.SplitHostPort(config.Host)
+		if err != nil {
+			config.Host = net.JoinHostPort(config.Host, "22")
+		}
+	}
+	if config.Directory == "" {
+		config.Directory = "."
+	}
+	return config
+}
+
+func getFilePath(file models.File) string {
+	return path.Join(sftpConfig.Directory, file.SHA1)
+}
+
+// getClient returns the current connection or connects to the server, if there is no active connection
+func getClient() (*pkgsftp.Client, error) {
+	if !isCorrectLogin {
+		return nil, errors.New("not logged in to SFTP server")
+	}
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
+	if activeConnection != nil {
+		return activeConnection.sftpClient, nil
+	}
+	newConnection, err := connect(sftpConfig)
+	if err != nil {
+		return nil, err
+	}
+	activeConnection = newConnection
+	go func() {
+		_ = newConnection.sshClient.Wait()
+		connectionMutex.Lock()
+		if activeConnection == newConnection {
+			activeConnection = nil
+		}
+		connectionMutex.Unlock()
+	}()
+	return newConnection.sftpClient, nil
+}
+
+// connection is an SSH connection with an SFTP session
+type connection struct {
+	sshClient  *ssh.Client
+	sftpClient *pkgsftp.Client
+}
+
+// Close closes the SFTP session and the underlying SSH connection
+func (c *connection) Close() {
+	_ = c.sftpClient.Close()
+	_ = c.sshClient.Close()
+}
+
+// ParseHostKey returns the public key of the server in authorized_keys format. As the identity of the
+// server is always verified, an error is returned if no host key has been set
+func ParseHostKey(hostKey string) (ssh.PublicKey, error) {
+	if strings.TrimSpace(hostKey) == "" {
+		return nil, errors.New("no SFTP host key has been set")
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, errors.New("invalid SFTP host key: " + err.Error())
+	}
+	return key, nil
+}
+
+func connect(config models.SftpConfig) (*connection, error) {
+	authMethods := make([]ssh.AuthMethod, 0)
+	if config.KeyFile != "" {
+		key, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	if config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(config.Password))
+	}
+	hostKey, err := ParseHostKey(config.HostKey)
+	if err != nil {
+		return nil, err
+	}
+	sshClient, err := ssh.Dial("tcp", config.Host, &ssh.ClientConfig{
+		User:            config.Username,
+		Auth:            authMethods,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         10 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sftpClient, err := pkgsftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, err
+	}
+	return &connection{sshClient: sshClient, sftpClient: sftpClient}, nil
+}
+
+// limitedFile reads only a part of a remote file, but closes the underlying file
+type limitedFile struct {
+	io.Reader
+	file *pkgsftp.File
+}
+
+// Close closes the underlying file
+func (l *limitedFile) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,356 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	pkgsftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testListener net.Listener
+var validConfig models.SftpConfig
+var serverHostKey string
+
+var testFile = models.File{
+	Id:          "testfile",
+	Name:        "Testfile.txt",
+	SHA1:        "testfilehash",
+	ContentType: "text/plain",
+}
+
+func TestMain(m *testing.M) {
+	testListener, serverHostKey = startMockServer()
+	validConfig = models.SftpConfig{
+		Host:      testListener.Addr().String(),
+		Username:  "sftpuser",
+		Password:  "sftppassword",
+		HostKey:   serverHostKey,
+		Directory: "/gokapi",
+	}
+	exitVal := m.Run()
+	testListener.Close()
+	os.Exit(exitVal)
+}
+
+// startMockServer starts an SSH server with an in-memory SFTP subsystem. Returns the listener
+// and the public host key of the server in authorized_keys format
+func startMockServer() (net.Listener, string) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		panic(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "sftpuser" && string(password) == "sftppassword" {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "sftpuser" && bytes.Equal(key.Marshal(), clientKey.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("invalid key")
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	handler := pkgsftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMockConnection(conn, config, handler)
+		}
+	}()
+	setupClient, err := connect(models.SftpConfig{
+		Host:     listener.Addr().String(),
+		Username: "sftpuser",
+		Password: "sftppassword",
+		HostKey:  string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+	})
+	if err != nil {
+		panic(err)
+	}
+	err = setupClient.sftpClient.Mkdir("/gokapi")
+	if err != nil {
+		panic(err)
+	}
+	setupClient.Close()
+	return listener, string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+}
+
+func serveMockConnection(conn net.Conn, config *ssh.ServerConfig, handler pkgsftp.Handlers) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range channelRequests {
+				_ = req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}()
+		server := pkgsftp.NewRequestServer(channel, handler)
+		go func() {
+			_ = server.Serve()
+			_ = server.Close()
+		}()
+	}
+}
+
+var clientKey = createClientKey()
+
+func createClientKey() ssh.Signer {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		panic(err)
+	}
+	return signer
+}
+
+func writeClientKey(t *testing.T) string {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	test.IsNil(t, err)
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	test.IsNil(t, err)
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	test.IsNil(t, err)
+	clientKey = signer
+	path := filepath.Join(t.TempDir(), "testkey")
+	err = os.WriteFile(path, pem.EncodeToMemory(block), 0600)
+	test.IsNil(t, err)
+	return path
+}
+
+func TestIsValidLogin(t *testing.T) {
+	ok, err := IsValidLogin(models.SftpConfig{})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.SftpConfig{Host: validConfig.Host, Username: "sftpuser", Password: "invalid", HostKey: serverHostKey})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.SftpConfig{Host: validConfig.Host, Username: "sftpuser", Password: "sftppassword", HostKey: serverHostKey, Directory: "/invalid"})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.SftpConfig{Host: "127.0.0.1:1", Username: "sftpuser", Password: "sftppassword", HostKey: serverHostKey})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.SftpConfig{Host: validConfig.Host, Username: "sftpuser", Password: "sftppassword", HostKey: "invalid"})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.SftpConfig{Host: validConfig.Host, Username: "sftpuser", Password: "sftppassword"})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	test.IsNil(t, err)
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	test.IsNil(t, err)
+	wrongHostKey := validConfig
+	wrongHostKey.HostKey = string(ssh.MarshalAuthorizedKey(otherSigner.PublicKey()))
+	ok, err = IsValidLogin(wrongHostKey)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+
+	keyFileConfig := models.SftpConfig{Host: validConfig.Host, Username: "sftpuser", KeyFile: "invalid", HostKey: serverHostKey}
+	ok, err = IsValidLogin(keyFileConfig)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	keyFileConfig.KeyFile = writeClientKey(t)
+	ok, err = IsValidLogin(keyFileConfig)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+
+	ok, err = IsValidLogin(validConfig)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+}
+
+func TestParseHostKey(t *testing.T) {
+	_, err := ParseHostKey("")
+	test.IsNotNil(t, err)
+	_, err = ParseHostKey("invalid")
+	test.IsNotNil(t, err)
+	key, err := ParseHostKey(serverHostKey)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(ssh.MarshalAuthorizedKey(key)), serverHostKey)
+}
+
+func TestNormaliseConfig(t *testing.T) {
+	config := normaliseConfig(models.SftpConfig{Host: "example.com"})
+	test.IsEqualString(t, config.Host, "example.com:22")
+	test.IsEqualString(t, config.Directory, ".")
+	config = normaliseConfig(models.SftpConfig{Host: "example.com:2222", Directory: "/data"})
+	test.IsEqualString(t, config.Host, "example.com:2222")
+	test.IsEqualString(t, config.Directory, "/data")
+}
+
+func TestInit(t *testing.T) {
+	test.IsEqualBool(t, Init(models.SftpConfig{Host: validConfig.Host, Username: "sftpuser", Password: "sftppassword", HostKey: serverHostKey, Directory: "/invalid"}), false)
+	test.IsEqualBool(t, IsAvailable(), false)
+	_, _, err := FileExists(testFile)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, Init(validConfig), true)
+	test.IsEqualBool(t, IsAvailable(), true)
+	test.IsEqualString(t, GetDirectory(), "/gokapi")
+}
+
+func TestAddStorageDriver(t *testing.T) {
+	file := models.File{Name: "Test"}
+	AddStorageDriver(&file)
+	test.IsEqualString(t, file.StorageDriver, "sftp")
+}
+
+func TestUpload(t *testing.T) {
+	err := Upload(bytes.NewReader([]byte("testfile-content")), testFile)
+	test.IsNil(t, err)
+	err = Upload(bytes.NewReader([]byte("testfile-content2")), models.File{SHA1: "testfilehash2"})
+	test.IsNil(t, err)
+	// Overwriting an existing file
+	err = Upload(bytes.NewReader([]byte("testfile-content3")), models.File{SHA1: "testfilehash2"})
+	test.IsNil(t, err)
+	err = Upload(bytes.NewReader([]byte("content")), models.File{SHA1: "invalid/testfile"})
+	test.IsNotNil(t, err)
+}
+
+func TestFileExists(t *testing.T) {
+	exists, size, err := FileExists(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt(t, int(size), 16)
+	exists, size, err = FileExists(models.File{SHA1: "testfilehash2"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt(t, int(size), 17)
+	exists, _, err = FileExists(models.File{SHA1: "testfilehash2.part"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	exists, _, err = FileExists(models.File{SHA1: "invalid"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
+
+func TestGetObject(t *testing.T) {
+	testGetObject(t, 0, -1, "testfile-content")
+	testGetObject(t, 9, -1, "content")
+	testGetObject(t, 9, 4, "cont")
+	testGetObject(t, 0, 8, "testfile")
+	testGetObject(t, 4, 0, "")
+	_, err := GetObject(models.File{SHA1: "invalid"}, 0, -1)
+	test.IsNotNil(t, err)
+}
+
+func testGetObject(t *testing.T, offset, length int64, expected string) {
+	t.Helper()
+	reader, err := GetObject(testFile, offset, length)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), expected)
+	test.IsNil(t, reader.Close())
+}
+
+func TestServeFile(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/download", nil)
+	isBlocking, err := ServeFile(w, r, testFile, true)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isBlocking, true)
+	test.IsEqualInt(t, w.Code, 200)
+	test.IsEqualString(t, w.Body.String(), "testfile-content")
+	test.IsEqualString(t, w.Header().Get("Content-Length"), "16")
+	test.IsEqualString(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"Testfile.txt\"")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/download", nil)
+	r.Header.Set("Range", "bytes=9-12")
+	_, err = ServeFile(w, r, testFile, false)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, w.Code, 206)
+	test.IsEqualString(t, w.Body.String(), "cont")
+	test.IsEqualString(t, w.Header().Get("Content-Length"), "4")
+	test.IsEqualString(t, w.Header().Get("Content-Range"), "bytes 9-12/16")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/download", nil)
+	_, err = ServeFile(w, r, models.File{SHA1: "invalid"}, false)
+	test.IsNotNil(t, err)
+}
+
+func TestReconnect(t *testing.T) {
+	connectionMutex.Lock()
+	activeConnection.Close()
+	connectionMutex.Unlock()
+	// Wait until the lost connection has been detected
+	for i := 0; i < 100 && isConnected(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	test.IsEqualBool(t, isConnected(), false)
+	exists, _, err := FileExists(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+}
+
+func isConnected() bool {
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
+	return activeConnection != nil
+}
+
+func TestDeleteObject(t *testing.T) {
+	ok, err := DeleteObject(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	exists, _, err := FileExists(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	ok, err = DeleteObject(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+}
+
+func TestLogOut(t *testing.T) {
+	test.IsEqualBool(t, IsAvailable(), true)
+	LogOut()
+	test.IsEqualBool(t, IsAvailable(), false)
+	test.IsEqualString(t, GetDirectory(), "")
+	exists, _, err := FileExists(models.File{SHA1: "testfilehash2"})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
@@ -0,0 +1,120 @@
+package webdavfilesystem
+
+import (
+	"fmt"
+	"github.com/forceu/gokapi/internal/models"
+	fileInterfaces "github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
+	"github.com/forceu/gokapi/internal/storage/filesystem/webdavfilesystem/webdav"
+	"io"
+	"net/http"
+	"os"
+)
+
+// GetDriver returns a driver for the WebDAV file system
+func GetDriver() fileInterfaces.System {
+	return &webdavStorageDriver{}
+}
+
+type webdavStorageDriver struct {
+	Url string
+}
+
+// Config is the required configuration for the driver
+type Config struct {
+	// Url is the address of the WebDAV directory to store new files
+	Url string
+}
+
+// MoveToFilesystem uploads a file from the local filesystem to the WebDAV server
+func (d *webdavStorageDriver) MoveToFilesystem(sourceFile *os.File, metaData models.File) error {
+	err := webdav.Upload(sourceFile, metaData)
+	if err != nil {
+		return err
+	}
+	err = sourceFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(sourceFile.Name())
+}
+
+// WriteToFilesystem uploads the content of the reader to the WebDAV server
+func (d *webdavStorageDriver) WriteToFilesystem(input io.Reader, metaData models.File) error {
+	return webdav.Upload(input, metaData)
+}
+
+// OpenFile returns a reader for the file, starting at offset. If length is negative,
+// the file is read until the end
+func (d *webdavStorageDriver) OpenFile(metaData models.File, offset, length int64) (io.ReadCloser, error) {
+	return webdav.GetObject(metaData, offset, length)
+}
+
+// DeleteFile removes the file from the WebDAV server
+func (d *webdavStorageDriver) DeleteFile(metaData models.File) error {
+	_, err := webdav.DeleteObject(metaData)
+	return err
+}
+
+// StatFile returns true and the size of the file, if it exists on the WebDAV server
+func (d *webdavStorageDriver) StatFile(metaData models.File) (bool, int64, error) {
+	return webdav.FileExists(metaData)
+}
+
+// ServeFile downloads the file from the WebDAV server and serves it as a proxy. Returns true,
+// as this is always a blocking operation
+func (d *webdavStorageDriver) ServeFile(w http.ResponseWriter, r *http.Request, metaData models.File, forceDownload bool) (bool, error) {
+	return webdav.ServeFile(w, r, metaData, forceDownload)
+}
+
+// Init sets the driver configurations and returns true if successful
+// Requires a Config struct as input
+func (d *webdavStorageDriver) Init(input any) bool {
+	config, ok := input.(Config)
+	if !ok {
+		panic("runtime exception: input for webdav filesystem is not a config object")
+	}
+	if config.Url == "" {
+		panic("empty url has been passed")
+	}
+	d.Url = config.Url
+	return webdav.IsAvailable()
+}
+
+// IsAvailable returns true if the WebDAV server is available and login was successful once
+func (d *webdavStorageDriver) IsAvailable() bool {
+	return webdav.IsAvailable()
+}
+
+// GetFile returns a File struct for the corresponding filename
+func (d *webdavStorageDriver) GetFile(filename string) fileInterfaces.File {
+	return &webdavFile{Url: d.Url, Filename: filename}
+}
+
+// FileExists returns true if the WebDAV server contains a file with the given relative filepath
+func (d *webdavStorageDriver) FileExists(filename string) (bool, error) {
+	exists, _, err := webdav.FileExists(models.File{SHA1: filename})
+	return exists, err
+}
+
+// GetSystemName returns the name of the driver
+func (d *webdavStorageDriver) GetSystemName() string {
+	return fileInterfaces.DriverWebdav
+}
+
+type webdavFile struct {
+	Url      string
+	Filename string
+}
+
+func (f *webdavFile) Exists() bool {
+	exists, _, err := webdav.FileExists(models.File{SHA1: f.Filename})
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return exists
+}
+
+func (f *webdavFile) GetName() string {
+	return f.Filename
+}
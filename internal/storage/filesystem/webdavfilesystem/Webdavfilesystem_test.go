@@ -0,0 +1,50 @@
+package webdavfilesystem
+
+import (
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func getTestDriver(t *testing.T) *webdavStorageDriver {
+	t.Helper()
+	driver := GetDriver()
+	result, ok := driver.(*webdavStorageDriver)
+	test.IsEqualBool(t, ok, true)
+	return result
+}
+
+func TestGetDriver(t *testing.T) {
+	getTestDriver(t)
+}
+
+func TestWebdavStorageDriver_Init(t *testing.T) {
+	driver := getTestDriver(t)
+	defer test.ExpectPanic(t)
+	driver.Init("test")
+}
+
+func TestWebdavStorageDriver_Init2(t *testing.T) {
+	driver := getTestDriver(t)
+	defer test.ExpectPanic(t)
+	driver.Init(Config{Url: ""})
+}
+
+func TestWebdavStorageDriver_Init3(t *testing.T) {
+	driver := getTestDriver(t)
+	ok := driver.Init(Config{Url: "http://127.0.0.1/dav/"})
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, driver.Url, "http://127.0.0.1/dav/")
+	test.IsEqualBool(t, driver.IsAvailable(), false)
+}
+
+func TestWebdavStorageDriver_GetSystemName(t *testing.T) {
+	driver := getTestDriver(t)
+	test.IsEqualString(t, driver.GetSystemName(), "webdav")
+}
+
+func TestWebdavFile_GetName(t *testing.T) {
+	driver := getTestDriver(t)
+	driver.Init(Config{Url: "http://127.0.0.1/dav/"})
+	file := driver.GetFile("testfile")
+	test.IsEqualString(t, file.GetName(), "testfile")
+}
@@ -0,0 +1,275 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/interfaces"
+	"github.com/forceu/gokapi/internal/webserver/headers"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var webdavConfig models.WebdavConfig
+
+var isCorrectLogin bool
+
+// Init reads the credentials for WebDAV. Returns true if valid
+func Init(config models.WebdavConfig) bool {
+	webdavConfig = normaliseConfig(config)
+	ok, err := IsValidLogin(config)
+	if err != nil {
+		fmt.Println("WARNING: WebDAV login not successful")
+		fmt.Println(err.Error())
+		isCorrectLogin = false
+		return false
+	}
+	if ok {
+		fmt.Println("WebDAV login successful")
+	}
+	isCorrectLogin = ok
+	return ok
+}
+
+// AddStorageDriver marks the file to be stored on the WebDAV server
+func AddStorageDriver(file *models.File) {
+	file.StorageDriver = interfaces.DriverWebdav
+}
+
+// IsAvailable returns true if valid credentials have been passed
+func IsAvailable() bool {
+	return isCorrectLogin
+}
+
+// LogOut resets the credentials
+func LogOut() {
+	webdavConfig = models.WebdavConfig{}
+	isCorrectLogin = false
+}
+
+// IsValidLogin checks if a valid login was provided and the directory exists on the server
+func IsValidLogin(config models.WebdavConfig) (bool, error) {
+	if !config.IsAllProvided() {
+		return false, nil
+	}
+	config = normaliseConfig(config)
+	ctx, cancelCtx := getTimeoutContext()
+	defer cancelCtx()
+	req, err := newRequest(ctx, config, "PROPFIND", config.Url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Depth", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusMultiStatus, http.StatusOK:
+		return true, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, errors.New("unexpected response from WebDAV server: " + resp.Status)
+	}
+}
+
+// Upload uploads a file to the WebDAV server
+func Upload(input io.Reader, file models.File) error {
+	req, err := newRequest(context.Background(), webdavConfig, http.MethodPut, getFileUrl(file), input)
+	if err != nil {
+		return err
+	}
+	// Not all servers support chunked uploads, therefore the size is sent if possible
+	if inputFile, ok := input.(*os.File); ok {
+		info, err := inputFile.Stat()
+		if err != nil {
+			return err
+		}
+		position, err := inputFile.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		req.ContentLength = info.Size() - position
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return errors.New("could not upload file to WebDAV server: " + resp.Status)
+	}
+	return nil
+}
+
+// GetObject returns a reader for the file stored on the WebDAV server, starting at offset. If length is negative,
+// the file is read until the end
+func GetObject(file models.File, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	req, err := newRequest(context.Background(), webdavConfig, http.MethodGet, getFileUrl(file), nil)
+	if err != nil {
+		return nil, err
+	}
+	isRangeRequest := offset != 0 || length > 0
+	if isRangeRequest {
+		byteRange := "bytes=" + strconv.FormatInt(offset, 10) + "-"
+		if length > 0 {
+			byteRange = byteRange + strconv.FormatInt(offset+length-1, 10)
+		}
+		req.Header.Set("Range", byteRange)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusOK:
+		if !isRangeRequest {
+			return resp.Body, nil
+		}
+		// The server does not support range requests, therefore the range is read from the complete file
+		_, err = io.CopyN(io.Discard, resp.Body, offset)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if length < 0 {
+			return resp.Body, nil
+		}
+		return &limitedBody{Reader: io.LimitReader(resp.Body, length), body: resp.Body}, nil
+	default:
+		resp.Body.Close()
+		return nil, errors.New("could not download file from WebDAV server: " + resp.Status)
+	}
+}
+
+// ServeFile downloads the file from the WebDAV server and serves it as a proxy. Range requests
+// are passed on to the server. This is always a blocking operation
+func ServeFile(w http.ResponseWriter, r *http.Request, file models.File, forceDownload bool) (bool, error) {
+	req, err := newRequest(r.Context(), webdavConfig, http.MethodGet, getFileUrl(file), nil)
+	if err != nil {
+		return true, err
+	}
+	for _, header := range []string{"Range", "If-Range"} {
+		if r.Header.Get(header) != "" {
+			req.Header.Set(header, r.Header.Get(header))
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+	default:
+		return true, errors.New("could not download file from WebDAV server: " + resp.Status)
+	}
+	headers.Write(file, w, forceDownload)
+	w.Header().Set("Accept-Ranges", "bytes")
+	for _, header := range []string{"Content-Length", "Content-Range", "Last-Modified", "ETag"} {
+		if resp.Header.Get(header) != "" {
+			w.Header().Set(header, resp.Header.Get(header))
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+	return true, nil
+}
+
+// FileExists returns true if the file is stored on the WebDAV server and its size
+func FileExists(file models.File) (bool, int64, error) {
+	ctx, cancelCtx := getTimeoutContext()
+	defer cancelCtx()
+	req, err := newRequest(ctx, webdavConfig, http.MethodHead, getFileUrl(file), nil)
+	if err != nil {
+		return false, 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, resp.ContentLength, nil
+	case http.StatusNotFound:
+		return false, 0, nil
+	default:
+		return false, 0, errors.New("unexpected response from WebDAV server: " + resp.Status)
+	}
+}
+
+// DeleteObject deletes a file from the WebDAV server
+func DeleteObject(file models.File) (bool, error) {
+	ctx, cancelCtx := getTimeoutContext()
+	defer cancelCtx()
+	req, err := newRequest(ctx, webdavConfig, http.MethodDelete, getFileUrl(file), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return true, nil
+	default:
+		return false, errors.New("could not delete file from WebDAV server: " + resp.Status)
+	}
+}
+
+// GetUrl returns the url of the WebDAV directory where new files are stored
+func GetUrl() string {
+	return webdavConfig.Url
+}
+
+func normaliseConfig(config models.WebdavConfig) models.WebdavConfig {
+	if config.Url != "" && !strings.HasSuffix(config.Url, "/") {
+		config.Url = config.Url + "/"
+	}
+	return config
+}
+
+func getFileUrl(file models.File) string {
+	return webdavConfig.Url + file.SHA1
+}
+
+func newRequest(ctx context.Context, config models.WebdavConfig, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if config.Username != "" || config.Password != "" {
+		req.SetBasicAuth(config.Username, config.Password)
+	}
+	return req, nil
+}
+
+func getTimeoutContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+// limitedBody reads only a part of a response body, but closes the underlying body
+type limitedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+// Close closes the underlying response body
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
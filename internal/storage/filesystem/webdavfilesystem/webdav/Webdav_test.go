@@ -0,0 +1,194 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	xwebdav "golang.org/x/net/webdav"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testServer *httptest.Server
+var validConfig models.WebdavConfig
+
+var testFile = models.File{
+	Id:          "testfile",
+	Name:        "Testfile.txt",
+	SHA1:        "testfilehash",
+	ContentType: "text/plain",
+}
+
+func TestMain(m *testing.M) {
+	testServer = startMockServer()
+	validConfig = models.WebdavConfig{
+		Url:      testServer.URL + "/gokapi",
+		Username: "webdavuser",
+		Password: "webdavpassword",
+	}
+	exitVal := m.Run()
+	testServer.Close()
+	os.Exit(exitVal)
+}
+
+func startMockServer() *httptest.Server {
+	fileSystem := xwebdav.NewMemFS()
+	_ = fileSystem.Mkdir(context.Background(), "/gokapi", 0755)
+	handler := &xwebdav.Handler{
+		FileSystem: fileSystem,
+		LockSystem: xwebdav.NewMemLS(),
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "webdavuser" || password != "webdavpassword" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+func TestIsValidLogin(t *testing.T) {
+	ok, err := IsValidLogin(models.WebdavConfig{})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.WebdavConfig{Url: testServer.URL + "/gokapi", Username: "invalid"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.WebdavConfig{Url: testServer.URL + "/invalid", Username: "webdavuser", Password: "webdavpassword"})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(models.WebdavConfig{Url: "http://127.0.0.1:1/", Username: "webdavuser", Password: "webdavpassword"})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	ok, err = IsValidLogin(validConfig)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+}
+
+func TestInit(t *testing.T) {
+	test.IsEqualBool(t, Init(models.WebdavConfig{Url: testServer.URL + "/invalid"}), false)
+	test.IsEqualBool(t, IsAvailable(), false)
+	test.IsEqualBool(t, Init(validConfig), true)
+	test.IsEqualBool(t, IsAvailable(), true)
+	test.IsEqualString(t, GetUrl(), testServer.URL+"/gokapi/")
+}
+
+func TestAddStorageDriver(t *testing.T) {
+	file := models.File{Name: "Test"}
+	AddStorageDriver(&file)
+	test.IsEqualString(t, file.StorageDriver, "webdav")
+}
+
+func TestUpload(t *testing.T) {
+	err := Upload(bytes.NewReader([]byte("testfile-content")), testFile)
+	test.IsNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "testupload")
+	err = os.WriteFile(path, []byte("testfile-content2"), 0600)
+	test.IsNil(t, err)
+	file, err := os.Open(path)
+	test.IsNil(t, err)
+	err = Upload(file, models.File{SHA1: "testfilehash2"})
+	test.IsNil(t, err)
+	file.Close()
+
+	err = Upload(bytes.NewReader([]byte("content")), models.File{SHA1: "invalid/testfile"})
+	test.IsNotNil(t, err)
+}
+
+func TestFileExists(t *testing.T) {
+	exists, size, err := FileExists(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt(t, int(size), 16)
+	exists, size, err = FileExists(models.File{SHA1: "testfilehash2"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	test.IsEqualInt(t, int(size), 17)
+	exists, _, err = FileExists(models.File{SHA1: "invalid"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
+
+func TestGetObject(t *testing.T) {
+	testGetObject(t, 0, -1, "testfile-content")
+	testGetObject(t, 9, -1, "content")
+	testGetObject(t, 9, 4, "cont")
+	testGetObject(t, 0, 8, "testfile")
+	testGetObject(t, 4, 0, "")
+	_, err := GetObject(models.File{SHA1: "invalid"}, 0, -1)
+	test.IsNotNil(t, err)
+}
+
+func testGetObject(t *testing.T, offset, length int64, expected string) {
+	t.Helper()
+	reader, err := GetObject(testFile, offset, length)
+	test.IsNil(t, err)
+	content, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), expected)
+	test.IsNil(t, reader.Close())
+}
+
+func TestServeFile(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/download", nil)
+	isBlocking, err := ServeFile(w, r, testFile, true)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isBlocking, true)
+	test.IsEqualInt(t, w.Code, 200)
+	test.IsEqualString(t, w.Body.String(), "testfile-content")
+	test.IsEqualString(t, w.Header().Get("Content-Length"), "16")
+	test.IsEqualString(t, w.Header().Get("Accept-Ranges"), "bytes")
+	test.IsEqualString(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"Testfile.txt\"")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/download", nil)
+	r.Header.Set("Range", "bytes=9-12")
+	_, err = ServeFile(w, r, testFile, false)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, w.Code, 206)
+	test.IsEqualString(t, w.Body.String(), "cont")
+	test.IsEqualString(t, w.Header().Get("Content-Length"), "4")
+	test.IsEqualString(t, w.Header().Get("Content-Range"), "bytes 9-12/16")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/download", nil)
+	r.Header.Set("Range", "bytes=100-")
+	_, err = ServeFile(w, r, testFile, false)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, w.Code, 416)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/download", nil)
+	_, err = ServeFile(w, r, models.File{SHA1: "invalid"}, false)
+	test.IsNotNil(t, err)
+}
+
+func TestDeleteObject(t *testing.T) {
+	ok, err := DeleteObject(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	exists, _, err := FileExists(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	ok, err = DeleteObject(testFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+}
+
+func TestLogOut(t *testing.T) {
+	test.IsEqualBool(t, IsAvailable(), true)
+	LogOut()
+	test.IsEqualBool(t, IsAvailable(), false)
+	test.IsEqualString(t, GetUrl(), "")
+	exists, _, err := FileExists(testFile)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, exists, false)
+}
@@ -0,0 +1,95 @@
+package hashing
+
+/**
+Creating the hashes that are used to deduplicate stored files
+*/
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/forceu/gokapi/internal/models"
+	"hash"
+	"lukechampine.com/blake3"
+)
+
+// AlgorithmSha1 is the algorithm that was used by older versions. It is not recommended anymore,
+// as collisions can be created
+const AlgorithmSha1 = "sha1"
+
+// AlgorithmSha256 hashes files with SHA-256
+const AlgorithmSha256 = "sha256"
+
+// AlgorithmBlake3 hashes files with BLAKE3, which is faster than SHA-256 on most systems
+const AlgorithmBlake3 = "blake3"
+
+// DefaultAlgorithm is used for new files, if no other algorithm has been set
+const DefaultAlgorithm = AlgorithmSha256
+
+// IsValidAlgorithm returns true if files can be hashed with the algorithm
+func IsValidAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case AlgorithmSha1, AlgorithmSha256, AlgorithmBlake3:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetAlgorithm returns the algorithm that was used to create the hash of the file
+func GetAlgorithm(file models.File) string {
+	if file.HashAlgorithm == "" {
+		return AlgorithmSha1
+	}
+	return file.HashAlgorithm
+}
+
+// New returns a new hash for the algorithm. Panics if the algorithm is invalid
+func New(algorithm string) hash.Hash {
+	switch algorithm {
+	case AlgorithmSha1, "":
+		return sha1.New()
+	case AlgorithmSha256:
+		return sha256.New()
+	case AlgorithmBlake3:
+		return blake3.New(32, nil)
+	default:
+		panic("invalid hash algorithm " + algorithm)
+	}
+}
+
+// Multi creates hashes of the same content with several algorithms at once
+type Multi map[string]hash.Hash
+
+// NewMulti returns hashes for the algorithms. Algorithms that are passed more than once are only hashed once.
+// Panics if an algorithm is invalid
+func NewMulti(algorithms ...string) Multi {
+	result := make(Multi)
+	for _, algorithm := range algorithms {
+		if _, ok := result[algorithm]; !ok {
+			result[algorithm] = New(algorithm)
+		}
+	}
+	return result
+}
+
+// Write adds the content to all hashes
+func (m Multi) Write(p []byte) (int, error) {
+	for _, fileHash := range m {
+		fileHash.Write(p)
+	}
+	return len(p), nil
+}
+
+// Sums returns the hex-encoded hashes with the algorithm as key. If salt is not empty, it is added to all
+// hashes first. No further content can be added afterwards
+func (m Multi) Sums(salt string) map[string]string {
+	result := make(map[string]string, len(m))
+	for algorithm, fileHash := range m {
+		if salt != "" {
+			fileHash.Write([]byte(salt))
+		}
+		result[algorithm] = hex.EncodeToString(fileHash.Sum(nil))
+	}
+	return result
+}
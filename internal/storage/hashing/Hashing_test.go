@@ -0,0 +1,53 @@
+package hashing
+
+import (
+	"encoding/hex"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"testing"
+)
+
+func TestIsValidAlgorithm(t *testing.T) {
+	test.IsEqualBool(t, IsValidAlgorithm(AlgorithmSha1), true)
+	test.IsEqualBool(t, IsValidAlgorithm(AlgorithmSha256), true)
+	test.IsEqualBool(t, IsValidAlgorithm(AlgorithmBlake3), true)
+	test.IsEqualBool(t, IsValidAlgorithm(""), false)
+	test.IsEqualBool(t, IsValidAlgorithm("md5"), false)
+}
+
+func TestGetAlgorithm(t *testing.T) {
+	test.IsEqualString(t, GetAlgorithm(models.File{}), AlgorithmSha1)
+	test.IsEqualString(t, GetAlgorithm(models.File{HashAlgorithm: AlgorithmBlake3}), AlgorithmBlake3)
+}
+
+func TestNew(t *testing.T) {
+	hashes := map[string]string{
+		"":              "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+		AlgorithmSha1:   "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+		AlgorithmSha256: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		AlgorithmBlake3: "d74981efa70a0c880b8d8c1985d075dbcbf679b99a5f9914e5aaf96b831a9e24",
+	}
+	for algorithm, expected := range hashes {
+		hash := New(algorithm)
+		hash.Write([]byte("hello world"))
+		test.IsEqualString(t, hex.EncodeToString(hash.Sum(nil)), expected)
+	}
+	defer test.ExpectPanic(t)
+	New("md5")
+}
+
+func TestMulti(t *testing.T) {
+	hashes := NewMulti(AlgorithmSha256, AlgorithmSha1, AlgorithmSha256)
+	test.IsEqualInt(t, len(hashes), 2)
+	_, err := hashes.Write([]byte("hello "))
+	test.IsNil(t, err)
+	_, err = hashes.Write([]byte("world"))
+	test.IsNil(t, err)
+	sums := hashes.Sums("")
+	test.IsEqualString(t, sums[AlgorithmSha1], "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed")
+	test.IsEqualString(t, sums[AlgorithmSha256], "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+
+	hashes = NewMulti(AlgorithmSha1)
+	_, _ = hashes.Write([]byte("hello world"))
+	test.IsEqualString(t, hashes.Sums("salt")[AlgorithmSha1], "5e8030a2a88ea2b9f1aea77f53896b7abf04ce75")
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/forceu/gokapi/internal/webserver/sse"
 )
 
+// StatusDownloading indicates that the file is downloaded from a remote URL, before it is processed by Gokapi
+const StatusDownloading = -1
+
 // StatusHashingOrEncrypting indicates that the file has been completely uploaded, but is now processed by Gokapi
 const StatusHashingOrEncrypting = 0
 
@@ -31,3 +34,15 @@ func Set(id string, status int, file models.File, err error) {
 	pstatusdb.Set(newStatus)
 	go sse.PublishNewStatus(newStatus)
 }
+
+// SetProgress sets the download progress for an id, while a file is downloaded from a remote URL
+func SetProgress(id string, transferredBytes, totalBytes int64) {
+	newStatus := models.UploadStatus{
+		ChunkId:          id,
+		CurrentStatus:    StatusDownloading,
+		TransferredBytes: transferredBytes,
+		TotalBytes:       totalBytes,
+	}
+	pstatusdb.Set(newStatus)
+	go sse.PublishNewStatus(newStatus)
+}
@@ -33,6 +33,23 @@ func TestSetStatus(t *testing.T) {
 	test.IsEqualString(t, status.ErrorMessage, "test")
 }
 
+func TestSetProgress(t *testing.T) {
+	const id = "testprogress"
+	SetProgress(id, 10, 100)
+	status, ok := getStatus(id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, status.CurrentStatus, StatusDownloading)
+	test.IsEqualInt64(t, status.TransferredBytes, 10)
+	test.IsEqualInt64(t, status.TotalBytes, 100)
+	SetProgress(id, 50, 100)
+	status, _ = getStatus(id)
+	test.IsEqualInt64(t, status.TransferredBytes, 50)
+	Set(id, StatusHashingOrEncrypting, models.File{}, nil)
+	SetProgress(id, 100, 100)
+	status, _ = getStatus(id)
+	test.IsEqualInt(t, status.CurrentStatus, StatusHashingOrEncrypting)
+}
+
 func getStatus(id string) (models.UploadStatus, bool) {
 	for _, status := range pstatusdb.GetAll() {
 		if status.ChunkId == id {
@@ -3,6 +3,9 @@
 package testconfiguration
 
 import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
 	"crypto/sha1"
 	"encoding/hex"
 	"errors"
@@ -12,10 +15,18 @@ import (
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/filesystem/sftpfilesystem/sftp"
+	"github.com/forceu/gokapi/internal/storage/filesystem/webdavfilesystem/webdav"
 	"github.com/forceu/gokapi/internal/storage/processingstatus/pstatusdb"
 	"github.com/johannesboyne/gofakes3"
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	pkgsftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	xwebdav "golang.org/x/net/webdav"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"os"
 	"strings"
 	"time"
@@ -206,6 +217,199 @@ func DisableS3() {
 	filesystem.SetLocal()
 }
 
+// StartWebdavTestServer starts an in-memory WebDAV server, which requires the
+// username "webdavuser" and the password "webdavpassword"
+func StartWebdavTestServer() *httptest.Server {
+	handler := &xwebdav.Handler{
+		FileSystem: xwebdav.NewMemFS(),
+		LockSystem: xwebdav.NewMemLS(),
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "webdavuser" || password != "webdavpassword" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+// EnableWebdav logs in to the WebDAV test server and sets it as the default storage
+func EnableWebdav(server *httptest.Server) {
+	webdav.Init(models.WebdavConfig{
+		Url:      server.URL,
+		Username: "webdavuser",
+		Password: "webdavpassword",
+	})
+	filesystem.SetWebdav()
+}
+
+// DisableWebdav logs out from the WebDAV test server and sets the local storage as default
+func DisableWebdav() {
+	webdav.LogOut()
+	filesystem.SetLocal()
+}
+
+// StartSftpTestServer starts an SSH server with an in-memory SFTP subsystem, which requires the
+// username "sftpuser" and the password "sftppassword". Returns the listener and the host key of the server
+func StartSftpTestServer() (net.Listener, string) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		panic(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "sftpuser" && string(password) == "sftppassword" {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	handler := pkgsftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSftpConnection(conn, config, handler)
+		}
+	}()
+	return listener, string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+}
+
+func serveSftpConnection(conn net.Conn, config *ssh.ServerConfig, handler pkgsftp.Handlers) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range channelRequests {
+				_ = req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}()
+		server := pkgsftp.NewRequestServer(channel, handler)
+		go func() {
+			_ = server.Serve()
+			_ = server.Close()
+		}()
+	}
+}
+
+// EnableSftp logs in to the SFTP test server and sets it as the default storage
+func EnableSftp(listener net.Listener, hostKey string) {
+	sftp.Init(models.SftpConfig{
+		Host:      listener.Addr().String(),
+		Username:  "sftpuser",
+		Password:  "sftppassword",
+		HostKey:   hostKey,
+		Directory: "/",
+	})
+	filesystem.SetSftp()
+}
+
+// DisableSftp logs out from the SFTP test server and sets the local storage as default
+func DisableSftp() {
+	sftp.LogOut()
+	filesystem.SetLocal()
+}
+
+// SmtpTestMessage is an email that has been received by the SMTP test server
+type SmtpTestMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// StartSmtpTestServer starts an SMTP server, which accepts all emails without authentication.
+// Returns the listener and a channel that receives all emails that have been sent to the server
+func StartSmtpTestServer() (net.Listener, chan SmtpTestMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	messages := make(chan SmtpTestMessage, 100)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSmtpConnection(conn, messages)
+		}
+	}()
+	return listener, messages
+}
+
+func serveSmtpConnection(conn net.Conn, messages chan SmtpTestMessage) {
+	defer conn.Close()
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost SMTP test server")
+	message := SmtpTestMessage{}
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			message = SmtpTestMessage{From: strings.Trim(line[len("MAIL FROM:"):], " <>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			message.To = append(message.To, strings.Trim(line[len("RCPT TO:"):], " <>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := reader.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.Data = string(data)
+			messages <- message
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// GetSmtpTestConfig returns the settings for sending emails with the SMTP test server
+func GetSmtpTestConfig(listener net.Listener) models.SmtpConfig {
+	return models.SmtpConfig{
+		Host:   "127.0.0.1",
+		Port:   listener.Addr().(*net.TCPAddr).Port,
+		Sender: "gokapi@example.com",
+	}
+}
+
 func writeTestSessions() {
 	database.SaveSession("validsession", models.Session{
 		RenewAt:    2147483645,
@@ -519,4 +723,10 @@ aws:
   Endpoint: "test-endpoint"
   KeyId: "test-keyid"
   KeySecret: "test-secret"
+targets:
+  - Name: "local-fast"
+    Type: "local"
+  - Name: "s3-archive"
+    Type: "s3"
+    Bucket: "archive"
 `)
@@ -9,6 +9,7 @@ import (
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
 	"github.com/forceu/gokapi/internal/test"
+	"net/smtp"
 	"os"
 	"testing"
 )
@@ -86,3 +87,17 @@ func TestStartS3TestServer(t *testing.T) {
 	server := StartS3TestServer()
 	test.IsNotNil(t, server)
 }
+
+func TestSmtpTestServer(t *testing.T) {
+	listener, messages := StartSmtpTestServer()
+	defer listener.Close()
+	config := GetSmtpTestConfig(listener)
+	test.IsEqualBool(t, config.IsAllProvided(), true)
+	err := smtp.SendMail(config.GetAddress(), nil, config.Sender, []string{"user@example.com"}, []byte("Subject: Test\r\n\r\nContent\r\n"))
+	test.IsNil(t, err)
+	message := <-messages
+	test.IsEqualString(t, message.From, "gokapi@example.com")
+	test.IsEqualInt(t, len(message.To), 1)
+	test.IsEqualString(t, message.To[0], "user@example.com")
+	test.IsEqualString(t, message.Data, "Subject: Test\n\nContent\n")
+}
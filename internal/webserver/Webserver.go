@@ -19,7 +19,9 @@ import (
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
 	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/notifications"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
 	"github.com/forceu/gokapi/internal/webserver/api"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"github.com/forceu/gokapi/internal/webserver/authentication/oauth"
@@ -92,9 +94,12 @@ func Start() {
 	mux.HandleFunc("/admin", requireLogin(showAdminMenu, true, false))
 	mux.HandleFunc("/api/", processApi)
 	mux.HandleFunc("/apiKeys", requireLogin(showApiAdmin, true, false))
+	mux.HandleFunc("/b", showBundle)
 	mux.HandleFunc("/changePassword", requireLogin(changePassword, true, true))
 	mux.HandleFunc("/d", showDownload)
+	mux.HandleFunc("/downloadBundle", downloadBundle)
 	mux.HandleFunc("/downloadFile", downloadFile)
+	mux.HandleFunc("/downloadZip", requireLogin(downloadZip, true, false))
 	mux.HandleFunc("/e2eInfo", requireLogin(e2eInfo, false, false))
 	mux.HandleFunc("/e2eSetup", requireLogin(showE2ESetup, true, false))
 	mux.HandleFunc("/error", showError)
@@ -115,6 +120,7 @@ func Start() {
 	mux.Handle("/e2e.wasm", gziphandler.GzipHandler(http.HandlerFunc(serveE2EWasm)))
 	mux.HandleFunc("/d/{id}/{filename}", redirectFromFilename)
 	mux.HandleFunc("/dh/{id}/{filename}", downloadFileWithNameInUrl)
+	mux.HandleFunc("/thumb/{id}", showThumbnail)
 
 	addMuxForCustomContent(mux)
 
@@ -524,10 +530,71 @@ func showDownload(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	view.ArchiveEntries, view.IsArchive = storage.GetArchiveEntries(file)
+	view.HasThumbnail = file.HasThumbnail && !file.Encryption.IsEndToEndEncrypted
 	err := templateFolder.ExecuteTemplate(w, "download", view)
 	helper.CheckIgnoreTimeout(err)
 }
 
+// Handling of /b
+// Checks if a bundle exists for the submitted ID
+// If it exists, the files of the bundle are listed, or a password needs to be entered.
+func showBundle(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	keyId := queryUrl(w, r, "error")
+	bundle, ok := storage.GetBundle(keyId)
+	if !ok {
+		redirect(w, "error")
+		return
+	}
+	files := storage.GetBundleFiles(bundle)
+	if len(files) == 0 {
+		redirect(w, "error")
+		return
+	}
+
+	config := configuration.Get()
+
+	view := DownloadView{
+		Name:           bundle.Name,
+		Size:           storage.GetBundleSize(files),
+		Id:             bundle.Id,
+		BundleFiles:    files,
+		IsDownloadView: true,
+		IsBundle:       true,
+		PublicName:     config.PublicName,
+		BaseUrl:        config.ServerUrl,
+		IsFailedLogin:  false,
+		UsesHttps:      configuration.UsesHttps(),
+		CustomContent:  customStaticInfo,
+	}
+
+	if bundle.PasswordHash != "" {
+		_ = r.ParseForm()
+		enteredPassword := r.Form.Get("password")
+		if configuration.HashPassword(enteredPassword, true) != bundle.PasswordHash && !isValidBundlePwCookie(r, bundle) {
+			if enteredPassword != "" {
+				view.IsFailedLogin = true
+				select {
+				case <-time.After(1 * time.Second):
+				}
+			}
+			view.IsPasswordView = true
+			err := templateFolder.ExecuteTemplate(w, "download_password", view)
+			helper.CheckIgnoreTimeout(err)
+			return
+		}
+		if !isValidBundlePwCookie(r, bundle) {
+			writeBundlePwCookie(w, bundle)
+			// redirect so that there is no post data to be resent if user refreshes page
+			redirect(w, "b?id="+bundle.Id)
+			return
+		}
+	}
+	err := templateFolder.ExecuteTemplate(w, "download_bundle", view)
+	helper.CheckIgnoreTimeout(err)
+}
+
 // Handling of /h/ and /hotlink/
 // Hotlinks an image or returns a static error image if image has expired
 func showHotlink(w http.ResponseWriter, r *http.Request) {
@@ -540,7 +607,40 @@ func showHotlink(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write(imageExpiredPicture)
 		return
 	}
-	storage.ServeFile(file, w, r, false)
+	err := storage.ServeFile(file, w, r, false)
+	if errors.Is(err, storage.ErrorTooManyDownloads) {
+		w.Header().Set("Retry-After", storage.RetryAfterTooManyDownloads)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// Handling of /thumb/{id}
+// Sends the thumbnail of the file, if it has not expired. For password-protected files, the password
+// has to be entered first, unless the file is viewed by a logged-in user with access to it
+func showThumbnail(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	file, ok := storage.GetFile(r.PathValue("id"))
+	if !ok || !isThumbnailAllowed(w, r, file) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	err := storage.ServeThumbnail(file, w)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// isThumbnailAllowed returns true, if the file is not password-protected, the password has been entered
+// or the user is logged in and allowed to view the file
+func isThumbnailAllowed(w http.ResponseWriter, r *http.Request, file models.File) bool {
+	if file.PasswordHash == "" {
+		return true
+	}
+	user, isLoggedIn := authentication.IsAuthenticated(w, r)
+	if isLoggedIn && (file.UserId == user.Id || user.HasPermission(models.UserPermListOtherUploads)) {
+		return true
+	}
+	return isValidPwCookie(r, file)
 }
 
 // Handling of /e2eInfo
@@ -673,12 +773,17 @@ type DownloadView struct {
 	Size                 string
 	Id                   string
 	Cipher               string
+	BundleFiles          []models.File
+	ArchiveEntries       []models.ArchiveEntry
 	PublicName           string
 	BaseUrl              string
 	IsFailedLogin        bool
 	IsAdminView          bool
 	IsDownloadView       bool
 	IsPasswordView       bool
+	IsBundle             bool
+	IsArchive            bool
+	HasThumbnail         bool
 	ClientSideDecryption bool
 	EndToEndEncryption   bool
 	UsesHttps            bool
@@ -702,7 +807,9 @@ type AdminView struct {
 	UserMap            map[int]*models.User
 	ServerUrl          string
 	Logs               string
+	DiskSpace          models.DiskSpace
 	PublicName         string
+	StorageTargets     []string
 	SystemKey          string
 	IsAdminView        bool
 	IsDownloadView     bool
@@ -712,6 +819,8 @@ type AdminView struct {
 	EndToEndEncryption bool
 	IncludeFilename    bool
 	IsInternalAuth     bool
+	IsDiskSpaceShown   bool
+	IsMailEnabled      bool
 	MaxFileSize        int
 	ActiveView         int
 	ChunkSize          int
@@ -764,6 +873,7 @@ func (u *AdminView) convertGlobalConfig(view int, user models.User) *AdminView {
 			metaDataList = append(metaDataList, fileInfo)
 		}
 		metaDataList = sortMetaData(metaDataList)
+		u.StorageTargets = storage.GetStorageTargetsForUser(user)
 	case ViewAPI:
 		for _, apiKey := range database.GetAllApiKeys() {
 			// Double-checking if user of API key exists
@@ -782,13 +892,16 @@ func (u *AdminView) convertGlobalConfig(view int, user models.User) *AdminView {
 		apiKeyList = sortApiKeys(apiKeyList)
 	case ViewLogs:
 		u.Logs, _ = logging.GetAll()
+		if user.UserLevel != models.UserLevelUser {
+			u.DiskSpace, u.IsDiskSpaceShown = diskspace.Get()
+		}
 	case ViewUsers:
-		uploadCounts := storage.GetUploadCounts()
+		storageUsage := storage.GetStorageUsage()
 		u.Users = make([]userInfo, 0)
 		for _, userEntry := range database.GetAllUsers() {
 			userWithUploads := userInfo{
-				UploadCount: uploadCounts[userEntry.Id],
-				User:        userEntry,
+				Usage: storageUsage[userEntry.Id],
+				User:  userEntry,
 			}
 			// Otherwise the user is not shown as online, if /users is opened as first page
 			if userEntry.Id == user.Id {
@@ -812,6 +925,7 @@ func (u *AdminView) convertGlobalConfig(view int, user models.User) *AdminView {
 	u.MaxParallelUploads = config.MaxParallelUploads
 	u.ChunkSize = config.ChunkSize
 	u.IncludeFilename = config.IncludeFilename
+	u.IsMailEnabled = notifications.IsEnabled()
 	u.SystemKey = api.GetSystemKey(user.Id)
 	return u
 }
@@ -844,8 +958,8 @@ func sortApiKeys(input []models.ApiKey) []models.ApiKey {
 }
 
 type userInfo struct {
-	UploadCount int
-	User        models.User
+	Usage models.StorageUsage
+	User  models.User
 }
 
 // Handling of /uploadChunk
@@ -856,8 +970,13 @@ func uploadChunk(w http.ResponseWriter, r *http.Request) {
 	if r.ContentLength > maxUpload {
 		responseError(w, storage.ErrorFileTooLarge)
 	}
+	user, err := authentication.GetUserFromRequest(r)
+	if err != nil {
+		responseError(w, err)
+		return
+	}
 	r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
-	err := fileupload.ProcessNewChunk(w, r, false)
+	err = fileupload.ProcessNewChunk(w, r, user.Id, false)
 	responseError(w, err)
 }
 
@@ -907,7 +1026,74 @@ func serveFile(id string, isRootUrl bool, w http.ResponseWriter, r *http.Request
 			return
 		}
 	}
-	storage.ServeFile(savedFile, w, r, true)
+	err := storage.ServeFile(savedFile, w, r, true)
+	if errors.Is(err, storage.ErrorTooManyDownloads) {
+		showTooManyDownloads(w)
+	}
+}
+
+// showTooManyDownloads tells the client to try again shortly, as the maximum number of
+// simultaneous downloads has been reached
+func showTooManyDownloads(w http.ResponseWriter) {
+	const tooManyDownloads = 3
+	w.Header().Set("Retry-After", storage.RetryAfterTooManyDownloads)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	err := templateFolder.ExecuteTemplate(w, "error", genericView{
+		ErrorId:       tooManyDownloads,
+		PublicName:    configuration.Get().PublicName,
+		CustomContent: customStaticInfo})
+	helper.CheckIgnoreTimeout(err)
+}
+
+// Handling of /downloadBundle
+// Outputs all files of the bundle as a ZIP file and reduces the download remaining count for the bundle
+func downloadBundle(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	id := queryUrl(w, r, "error")
+	bundle, ok := storage.GetBundle(id)
+	if !ok {
+		redirect(w, "error")
+		return
+	}
+	if bundle.PasswordHash != "" && !isValidBundlePwCookie(r, bundle) {
+		redirect(w, "b?id="+bundle.Id)
+		return
+	}
+	files := storage.GetBundleFiles(bundle)
+	if len(files) == 0 {
+		redirect(w, "error")
+		return
+	}
+	err := storage.ServeBundle(bundle, files, w, r)
+	if errors.Is(err, storage.ErrorTooManyDownloads) {
+		showTooManyDownloads(w)
+	}
+}
+
+// Handling of /downloadZip
+// Outputs the files that were selected in the admin menu as a single ZIP file
+func downloadZip(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.GetUserFromRequest(r)
+	if err != nil {
+		panic(err)
+	}
+	fileIds := models.SplitFileIds(r.URL.Query().Get("ids"))
+	if len(fileIds) == 0 {
+		responseError(w, errors.New("no files selected"))
+		return
+	}
+	files, err := storage.GetFilesForUser(fileIds, user)
+	if err != nil {
+		responseError(w, err)
+		return
+	}
+	err = storage.ServeFilesAsZip(files, w, r)
+	if errors.Is(err, storage.ErrorTooManyDownloads) {
+		w.Header().Set("Retry-After", storage.RetryAfterTooManyDownloads)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	responseError(w, err)
 }
 
 func requireLogin(next http.HandlerFunc, isUiCall, isPwChangeView bool) http.HandlerFunc {
@@ -968,6 +1154,30 @@ func isValidPwCookie(r *http.Request, file models.File) bool {
 	return false
 }
 
+// Write a cookie if the user has entered a correct password for a password-protected bundle
+func writeBundlePwCookie(w http.ResponseWriter, bundle models.Bundle) {
+	http.SetCookie(w, &http.Cookie{
+		Name:    "pb" + bundle.Id,
+		Value:   bundle.PasswordHash,
+		Expires: time.Now().Add(5 * time.Minute),
+	})
+}
+
+// Checks if a cookie contains the correct password hash for a password-protected bundle
+// If incorrect, a 3-second delay is introduced unless the cookie was empty.
+func isValidBundlePwCookie(r *http.Request, bundle models.Bundle) bool {
+	cookie, err := r.Cookie("pb" + bundle.Id)
+	if err == nil {
+		if cookie.Value == bundle.PasswordHash {
+			return true
+		}
+		select {
+		case <-time.After(3 * time.Second):
+		}
+	}
+	return false
+}
+
 // Adds a header to disable external caching
 func addNoCacheHeader(w http.ResponseWriter) {
 	w.Header().Set("cdn-cache-control", "no-store, no-cache")
@@ -4,15 +4,19 @@ package webserver
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/notifications"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/storage/processingstatus"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
+	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
 	"html/template"
 	"net/http"
 	"os"
@@ -157,7 +161,7 @@ func TestAdminAuth(t *testing.T) {
 	t.Parallel()
 	test.HttpPageResult(t, test.HttpTestConfig{
 		Url:             "http://localhost:53843/admin",
-		RequiredContent: []string{"Downloads remaining"},
+		RequiredContent: []string{"Downloads remaining", "modaldownloads"},
 		IsHtml:          true,
 		Cookies: []test.Cookie{{
 			Name:  "session_token",
@@ -165,6 +169,39 @@ func TestAdminAuth(t *testing.T) {
 		}},
 	})
 }
+func TestAdminNotifications(t *testing.T) {
+	user := models.User{Id: 7, UserLevel: models.UserLevelUser, NotificationEmail: "user@example.com", NotifyDownload: models.NotifyDownloadEvery}
+	view := (&AdminView{}).convertGlobalConfig(ViewMain, user)
+	test.IsEqualBool(t, view.IsMailEnabled, false)
+	var output bytes.Buffer
+	err := templateFolder.ExecuteTemplate(&output, "admin", view)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, strings.Contains(output.String(), "modalnotifications"), false)
+
+	notifications.Init(models.SmtpConfig{Host: "127.0.0.1", Port: 25, Sender: "gokapi@example.com"})
+	defer notifications.Init(models.SmtpConfig{})
+	view = (&AdminView{}).convertGlobalConfig(ViewMain, user)
+	test.IsEqualBool(t, view.IsMailEnabled, true)
+	output.Reset()
+	err = templateFolder.ExecuteTemplate(&output, "admin", view)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, strings.Contains(output.String(), "modalnotifications"), true)
+	test.IsEqualBool(t, strings.Contains(output.String(), `value="user@example.com"`), true)
+	test.IsEqualBool(t, strings.Contains(output.String(), `<option value="2" selected>Every download</option>`), true)
+}
+
+func TestLogsDiskSpace(t *testing.T) {
+	view := (&AdminView{}).convertGlobalConfig(ViewLogs, models.User{Id: 7, UserLevel: models.UserLevelUser})
+	test.IsEqualBool(t, view.IsDiskSpaceShown, false)
+	view = (&AdminView{}).convertGlobalConfig(ViewLogs, models.User{Id: 7, UserLevel: models.UserLevelAdmin})
+	test.IsEqualBool(t, view.IsDiskSpaceShown, true)
+	test.IsEqualBool(t, view.DiskSpace.TotalBytes > 0, true)
+	var output bytes.Buffer
+	err := templateFolder.ExecuteTemplate(&output, "logs", view)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, strings.Contains(output.String(), "Free disk space: "+view.DiskSpace.GetReadableFree()), true)
+}
+
 func TestAdminExpiredAuth(t *testing.T) {
 	t.Parallel()
 	test.HttpPageResult(t, test.HttpTestConfig{
@@ -447,6 +484,284 @@ func TestDownloadCorrectPassword(t *testing.T) {
 	})
 }
 
+func TestDownloadBundle(t *testing.T) {
+	t.Parallel()
+	for _, id := range []string{"bundleWebFile1", "bundleWebFile2"} {
+		database.SaveMetaData(models.File{
+			Id:                 id,
+			Name:               id + ".txt",
+			Size:               "3 B",
+			SizeBytes:          3,
+			SHA1:               "c4f9375f9834b4e7f0a528cc65c055702bf5f24a",
+			UnlimitedTime:      true,
+			UnlimitedDownloads: true,
+			UserId:             5,
+		})
+	}
+	database.SaveBundle(models.Bundle{
+		Id:                 "bundleWebNoPassword1",
+		Name:               "Bundle without password",
+		FileIds:            []string{"bundleWebFile1", "bundleWebFile2", "invalid"},
+		DownloadsRemaining: 1,
+		UnlimitedTime:      true,
+		UserId:             5,
+	})
+	database.SaveBundle(models.Bundle{
+		Id:                 "bundleWebPassword123",
+		Name:               "Bundle with password",
+		FileIds:            []string{"bundleWebFile1"},
+		PasswordHash:       "7b30508aa9b233ab4b8a11b2af5816bdb58ca3e7",
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+		UserId:             5,
+	})
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundleWebNoPassword1",
+		IsHtml:          true,
+		RequiredContent: []string{"Bundle without password", "bundleWebFile1.txt", "bundleWebFile2.txt", "2 files, total size: 6 B", "./downloadBundle?id=bundleWebNoPassword1"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadBundle?id=bundleWebNoPassword1",
+		RequiredContent: []string{"bundleWebFile1.txt", "bundleWebFile2.txt"},
+	})
+	// Bundle has no downloads remaining
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundleWebNoPassword1",
+		IsHtml:          true,
+		RequiredContent: []string{"URL=./error\""},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadBundle?id=bundleWebNoPassword1",
+		IsHtml:          true,
+		RequiredContent: []string{"URL=./error\""},
+	})
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundleWebPassword123",
+		IsHtml:          true,
+		RequiredContent: []string{"Password required", "./b?id=bundleWebPassword123"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadBundle?id=bundleWebPassword123",
+		IsHtml:          true,
+		RequiredContent: []string{"URL=./b?id=bundleWebPassword123"},
+		Cookies:         []test.Cookie{{Name: "pbbundleWebPassword123", Value: "invalid"}},
+	})
+	cookies := test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundleWebPassword123",
+		IsHtml:          true,
+		RequiredContent: []string{"URL=./b?id=bundleWebPassword123"},
+		Method:          "POST",
+		PostValues:      []test.PostBody{{Key: "password", Value: "123"}},
+	})
+	pwCookie := ""
+	for _, cookie := range cookies {
+		if (*cookie).Name == "pbbundleWebPassword123" {
+			pwCookie = (*cookie).Value
+			break
+		}
+	}
+	test.IsEqualString(t, pwCookie, "7b30508aa9b233ab4b8a11b2af5816bdb58ca3e7")
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadBundle?id=bundleWebPassword123",
+		RequiredContent: []string{"bundleWebFile1.txt"},
+		Cookies:         []test.Cookie{{Name: "pbbundleWebPassword123", Value: pwCookie}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=invalid",
+		IsHtml:          true,
+		RequiredContent: []string{"URL=./error\""},
+	})
+}
+
+func TestDownloadZip(t *testing.T) {
+	t.Parallel()
+	database.SaveMetaData(models.File{
+		Id:                 "zipWebFile1",
+		Name:               "zipWebFile1.txt",
+		Size:               "3 B",
+		SizeBytes:          3,
+		SHA1:               "c4f9375f9834b4e7f0a528cc65c055702bf5f24a",
+		UnlimitedTime:      true,
+		UnlimitedDownloads: true,
+		UserId:             7,
+	})
+	database.SaveMetaData(models.File{
+		Id:                 "zipWebFile2",
+		Name:               "zipWebFile2.txt",
+		Size:               "3 B",
+		SizeBytes:          3,
+		SHA1:               "c4f9375f9834b4e7f0a528cc65c055702bf5f24a",
+		UnlimitedTime:      true,
+		UnlimitedDownloads: true,
+		UserId:             5,
+	})
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadZip?ids=zipWebFile1",
+		IsHtml:          true,
+		RequiredContent: []string{"URL=./login\""},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadZip?ids=",
+		RequiredContent: []string{"no files selected"},
+		ResultCode:      http.StatusBadRequest,
+		Cookies:         []test.Cookie{{Name: "session_token", Value: "validsession"}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadZip?ids=zipWebFile1,zipWebFile2",
+		RequiredContent: []string{"no permission to access file: zipWebFile2"},
+		ResultCode:      http.StatusBadRequest,
+		Cookies:         []test.Cookie{{Name: "session_token", Value: "validsession"}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadZip?ids=zipWebFile1",
+		RequiredContent: []string{"zipWebFile1.txt"},
+		Cookies:         []test.Cookie{{Name: "session_token", Value: "validsession"}},
+	})
+}
+
+func TestDownloadTooManyParallel(t *testing.T) {
+	t.Parallel()
+	database.SaveMetaData(models.File{
+		Id:                 "parallelWebFile",
+		Name:               "parallelWebFile.txt",
+		Size:               "3 B",
+		SizeBytes:          3,
+		SHA1:               "c4f9375f9834b4e7f0a528cc65c055702bf5f24a",
+		UnlimitedTime:      true,
+		UnlimitedDownloads: true,
+		ParallelDownloads:  1,
+		UserId:             5,
+	})
+	file, ok := database.GetMetaDataById("parallelWebFile")
+	test.IsEqualBool(t, ok, true)
+	statusId, ok := downloadstatus.SetDownloadIfAllowed(file, "10.0.0.1", 1, 0)
+	test.IsEqualBool(t, ok, true)
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadFile?id=parallelWebFile",
+		RequiredContent: []string{"Please try again shortly."},
+		ResultCode:      http.StatusServiceUnavailable,
+	})
+	// The limits also apply to every file of a bundle
+	database.SaveBundle(models.Bundle{
+		Id:                 "bundleWebParallel",
+		Name:               "Bundle with parallel limit",
+		FileIds:            []string{"parallelWebFile"},
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+		UserId:             5,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadBundle?id=bundleWebParallel",
+		RequiredContent: []string{"Please try again shortly."},
+		ResultCode:      http.StatusServiceUnavailable,
+	})
+	downloadstatus.SetComplete(statusId)
+}
+
+func TestDownloadArchivePreview(t *testing.T) {
+	t.Parallel()
+	database.SaveMetaData(models.File{
+		Id:                 "archivePreviewWebFile",
+		Name:               "archive.zip",
+		Size:               "3 B",
+		SizeBytes:          3,
+		SHA1:               "c4f9375f9834b4e7f0a528cc65c055702bf5f24a",
+		ArchiveContent:     `[{"Name":"folder/archived.txt","Size":"16 B","ModTimeString":"2024-05-01 12:00","SizeBytes":16}]`,
+		UnlimitedTime:      true,
+		UnlimitedDownloads: true,
+		UserId:             5,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=archivePreviewWebFile",
+		IsHtml:          true,
+		RequiredContent: []string{"Archive content (1 entries)", "folder/archived.txt", "16 B", "2024-05-01 12:00"},
+	})
+	database.SaveMetaData(models.File{
+		Id:                 "archivePreviewNoArchive",
+		Name:               "archive.txt",
+		Size:               "3 B",
+		SizeBytes:          3,
+		SHA1:               "c4f9375f9834b4e7f0a528cc65c055702bf5f24a",
+		UnlimitedTime:      true,
+		UnlimitedDownloads: true,
+		UserId:             5,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=archivePreviewNoArchive",
+		IsHtml:          true,
+		RequiredContent: []string{"archive.txt"},
+		ExcludedContent: []string{"Archive content"},
+	})
+}
+
+func TestShowThumbnail(t *testing.T) {
+	t.Parallel()
+	for _, id := range []string{"thumbnailWebFile1", "thumbnailWebPassword", "thumbnailWebNoThumb"} {
+		file := models.File{
+			Id:                 id,
+			Name:               id + ".jpg",
+			Size:               "15 B",
+			SizeBytes:          15,
+			SHA1:               "thumbnailwebcontent",
+			HasThumbnail:       id != "thumbnailWebNoThumb",
+			UnlimitedTime:      true,
+			UnlimitedDownloads: true,
+			UserId:             7,
+		}
+		if id == "thumbnailWebPassword" {
+			file.PasswordHash = "7b30508aa9b233ab4b8a11b2af5816bdb58ca3e7"
+		}
+		database.SaveMetaData(file)
+	}
+	source := models.File{SHA1: "thumbnailwebcontent"}
+	err := filesystem.GetForFile(source).WriteToFilesystem(strings.NewReader("image content"), source)
+	test.IsNil(t, err)
+	thumbnail := models.File{SHA1: "thumbnailwebcontent-thumb"}
+	err = filesystem.GetForFile(thumbnail).WriteToFilesystem(strings.NewReader("thumbnail content"), thumbnail)
+	test.IsNil(t, err)
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/thumb/thumbnailWebFile1",
+		RequiredContent: []string{"thumbnail content"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=thumbnailWebFile1",
+		IsHtml:          true,
+		RequiredContent: []string{"./thumb/thumbnailWebFile1"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=thumbnailWebNoThumb",
+		IsHtml:          true,
+		ExcludedContent: []string{"./thumb/"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://127.0.0.1:53843/thumb/thumbnailWebNoThumb",
+		ResultCode: http.StatusNotFound,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://127.0.0.1:53843/thumb/invalid",
+		ResultCode: http.StatusNotFound,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/thumb/thumbnailWebPassword",
+		ResultCode:      http.StatusNotFound,
+		ExcludedContent: []string{"thumbnail content"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/thumb/thumbnailWebPassword",
+		RequiredContent: []string{"thumbnail content"},
+		Cookies:         []test.Cookie{{Name: "pthumbnailWebPassword", Value: "7b30508aa9b233ab4b8a11b2af5816bdb58ca3e7"}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/thumb/thumbnailWebPassword",
+		RequiredContent: []string{"thumbnail content"},
+		Cookies:         []test.Cookie{{Name: "session_token", Value: "validsession"}},
+	})
+}
+
 func TestPostUploadNoAuth(t *testing.T) {
 	t.Parallel()
 	test.HttpPostUploadRequest(t, test.HttpTestConfig{
@@ -490,7 +805,7 @@ func TestPostUpload(t *testing.T) {
 		UploadFieldName: "file",
 		PostValues: []test.PostBody{{
 			Key:   "dztotalfilesize",
-			Value: "50",
+			Value: "3",
 		}, {
 			Key:   "dzchunkbyteoffset",
 			Value: "0",
@@ -514,7 +829,7 @@ func TestPostUpload(t *testing.T) {
 				{"uuid", "eeng4ier3Taen7a"},
 				{"filename", "fileupload.jpg"},
 				{"filecontenttype", "test-content"},
-				{"filesize", "50"},
+				{"filesize", "3"},
 				{"nonblocking", "true"},
 			},
 			RequiredContent: []string{"{\"result\":\"OK\"}"},
@@ -9,6 +9,9 @@ import (
 	"github.com/forceu/gokapi/internal/logging"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/webserver/fileupload"
 	"io"
 	"net/http"
@@ -89,6 +92,16 @@ func apiEditFile(w http.ResponseWriter, r requestParser, user models.User) {
 	if !request.KeepPassword {
 		file.PasswordHash = configuration.HashPassword(request.Password, true)
 	}
+	if request.IsBandwidthSet {
+		if user.UserLevel == models.UserLevelUser {
+			sendError(w, http.StatusUnauthorized, "Only admins can change the bandwidth limit.")
+			return
+		}
+		file.BandwidthLimitKB = request.BandwidthLimitKB
+	}
+	if request.IsParallelSet {
+		file.ParallelDownloads = request.ParallelDownloads
+	}
 
 	if file.HotlinkId != "" && !storage.IsAbleHotlink(file) {
 		database.DeleteHotlink(file.HotlinkId)
@@ -372,7 +385,7 @@ func apiRestoreFile(w http.ResponseWriter, r requestParser, user models.User) {
 	outputFileJson(w, file)
 }
 
-func apiChunkAdd(w http.ResponseWriter, r requestParser, _ models.User) {
+func apiChunkAdd(w http.ResponseWriter, r requestParser, user models.User) {
 	request, ok := r.(*paramChunkAdd)
 	if !ok {
 		panic("invalid parameter passed")
@@ -384,7 +397,7 @@ func apiChunkAdd(w http.ResponseWriter, r requestParser, _ models.User) {
 	}
 
 	request.Request.Body = http.MaxBytesReader(w, request.Request.Body, maxUpload)
-	err := fileupload.ProcessNewChunk(w, request.Request, true)
+	err := fileupload.ProcessNewChunk(w, request.Request, user.Id, true)
 	if err != nil {
 		sendError(w, http.StatusBadRequest, err.Error())
 		return
@@ -396,6 +409,18 @@ func apiChunkComplete(w http.ResponseWriter, r requestParser, user models.User)
 	if !ok {
 		panic("invalid parameter passed")
 	}
+	var err error
+	request.StorageTarget, err = storage.GetStorageTargetForUpload(user, request.StorageTarget)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Finalising an upload with missing chunks would store a file that contains zero bytes instead
+	err = chunking.CheckComplete(request.Uuid)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if request.IsNonBlocking {
 		go doBlockingPartCompleteChunk(nil, request, user)
 		_, _ = io.WriteString(w, "{\"result\":\"OK\"}")
@@ -404,6 +429,34 @@ func apiChunkComplete(w http.ResponseWriter, r requestParser, user models.User)
 	doBlockingPartCompleteChunk(w, request, user)
 }
 
+func apiChunkStatus(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramChunkStatus)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	status, ok := chunking.GetStatus(request.Uuid)
+	if !ok || status.UserId != user.Id {
+		sendError(w, http.StatusNotFound, chunking.ErrorUnknownUpload.Error())
+		return
+	}
+	result, err := json.Marshal(status)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiTus(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramTus)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	uploadId := strings.TrimPrefix(request.RequestUrl, "/tus/")
+	request.Request.Body = http.MaxBytesReader(w, request.Request.Body, int64(configuration.Get().MaxFileSizeMB)*1024*1024)
+	status, err := fileupload.ProcessTusRequest(w, request.Request, user, uploadId)
+	if err != nil {
+		sendError(w, status, err.Error())
+	}
+}
+
 func doBlockingPartCompleteChunk(w http.ResponseWriter, request *paramChunkComplete, user models.User) {
 	uploadRequest := fileupload.CreateUploadConfig(request.AllowedDownloads,
 		request.ExpiryDays,
@@ -412,6 +465,8 @@ func doBlockingPartCompleteChunk(w http.ResponseWriter, request *paramChunkCompl
 		request.UnlimitedDownloads,
 		request.IsE2E,
 		request.FileSize)
+	uploadRequest.StorageTarget = request.StorageTarget
+	uploadRequest.Checksum = request.Checksum
 	file, err := fileupload.CompleteChunk(request.Uuid, request.FileHeader, user.Id, uploadRequest)
 	if err != nil {
 		sendError(w, http.StatusBadRequest, err.Error())
@@ -462,6 +517,64 @@ func apiListSingle(w http.ResponseWriter, r requestParser, user models.User) {
 	_, _ = w.Write(result)
 }
 
+func apiDownloadHistory(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesDownloads)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	id := strings.TrimPrefix(request.RequestUrl, "/files/downloads/")
+	events := database.GetDownloadEvents(id)
+	// The history is kept after the file has expired or has been removed, so the uploader
+	// is taken from the stored downloads, if the file does not exist anymore
+	var uploaderId int
+	file, ok := database.GetMetaDataById(id)
+	if ok {
+		uploaderId = file.UserId
+	} else {
+		if len(events) == 0 {
+			sendError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		uploaderId = events[0].UserId
+	}
+	if uploaderId != user.Id && !user.HasPermission(models.UserPermListOtherUploads) {
+		sendError(w, http.StatusUnauthorized, "No permission to view file")
+		return
+	}
+	result, err := json.Marshal(events)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiArchiveContent(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesArchive)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	file, ok := storage.GetFile(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	if file.UserId != user.Id && !user.HasPermission(models.UserPermListOtherUploads) {
+		sendError(w, http.StatusUnauthorized, "No permission to view file")
+		return
+	}
+	entries, ok := storage.GetArchiveEntries(file)
+	if !ok && storage.IsArchiveContentPending(file) {
+		w.Header().Set("Retry-After", "5")
+		sendError(w, http.StatusServiceUnavailable, "The content of the archive is being read, please try again later")
+		return
+	}
+	if !ok {
+		sendError(w, http.StatusBadRequest, "File is not an archive or its content cannot be read")
+		return
+	}
+	result, err := json.Marshal(entries)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
 func apiUploadFile(w http.ResponseWriter, r requestParser, user models.User) {
 	request, ok := r.(*paramFilesAdd)
 	if !ok {
@@ -481,6 +594,50 @@ func apiUploadFile(w http.ResponseWriter, r requestParser, user models.User) {
 	}
 }
 
+func apiUploadFromUrl(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesAddFromUrl)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	uploadRequest := fileupload.CreateUploadConfig(request.AllowedDownloads,
+		request.ExpiryDays,
+		request.Password,
+		request.UnlimitedTime,
+		request.UnlimitedDownloads,
+		false,
+		0)
+	uploadRequest.Checksum = request.Checksum
+	var err error
+	uploadRequest.StorageTarget, err = storage.GetStorageTargetForUpload(user, request.StorageTarget)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	remoteFile, err := fileupload.OpenRemoteFile(request.Url, request.FileName, request.ContentType, user.Id)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.IsNonBlocking {
+		// The progress can be followed with the chunk ID through the upload status events
+		go func() {
+			file, err := fileupload.ProcessRemoteFile(remoteFile, user.Id, uploadRequest)
+			if err == nil {
+				logging.LogUpload(file, user)
+			}
+		}()
+		_, _ = io.WriteString(w, "{\"Result\":\"OK\",\"ChunkId\":\""+remoteFile.ChunkId+"\"}")
+		return
+	}
+	file, err := fileupload.ProcessRemoteFile(remoteFile, user.Id, uploadRequest)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	logging.LogUpload(file, user)
+	outputFileJson(w, file)
+}
+
 func apiDuplicateFile(w http.ResponseWriter, r requestParser, user models.User) {
 	request, ok := r.(*paramFilesDuplicate)
 	if !ok {
@@ -502,6 +659,12 @@ func apiDuplicateFile(w http.ResponseWriter, r requestParser, user models.User)
 		request.UnlimitedDownloads,
 		false, // is not being used by storage.DuplicateFile
 		0)     // is not being used by storage.DuplicateFile
+	// The duplicate is owned by the uploader of the original file
+	err := storage.CheckQuota(file.UserId, file.SizeBytes)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	newFile, err := storage.DuplicateFile(file, request.RequestedChanges, request.FileName, uploadRequest)
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, err.Error())
@@ -551,6 +714,157 @@ func apiReplaceFile(w http.ResponseWriter, r requestParser, user models.User) {
 	outputFileApiInfo(w, modifiedFile)
 }
 
+func apiDownloadZip(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesDownloadZip)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	files, ok := getFilesForUser(w, request.FileIds, user, storage.GetFilesForUser)
+	if !ok {
+		return
+	}
+	err := storage.ServeFilesAsZip(files, w, request.Request)
+	if errors.Is(err, storage.ErrorTooManyDownloads) {
+		w.Header().Set("Retry-After", storage.RetryAfterTooManyDownloads)
+		sendError(w, http.StatusServiceUnavailable, "Too many simultaneous downloads, please try again later")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+func apiBundleList(w http.ResponseWriter, _ requestParser, user models.User) {
+	validBundles := make([]models.BundleApiOutput, 0)
+	timeNow := time.Now().Unix()
+	serverUrl := configuration.Get().ServerUrl
+	for _, bundle := range database.GetAllBundles() {
+		if bundle.UserId == user.Id || user.HasPermission(models.UserPermListOtherUploads) {
+			if !storage.IsExpiredBundle(bundle, timeNow) {
+				validBundles = append(validBundles, bundle.ToBundleApiOutput(serverUrl))
+			}
+		}
+	}
+	result, err := json.Marshal(validBundles)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiBundleCreate(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleCreate)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	files, ok := getFilesForUser(w, request.FileIds, user, storage.GetFilesForBundle)
+	if !ok {
+		return
+	}
+	uploadRequest := fileupload.CreateUploadConfig(request.AllowedDownloads,
+		request.ExpiryDays,
+		request.Password,
+		request.UnlimitedTime,
+		request.UnlimitedDownloads,
+		false, // is not being used by storage.CreateBundle
+		0)     // is not being used by storage.CreateBundle
+	bundle, err := storage.CreateBundle(request.Name, files, user.Id, uploadRequest)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	outputBundleApiInfo(w, bundle)
+}
+
+func apiBundleModify(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleModify)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	bundle, ok := database.GetBundle(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, "Invalid bundle ID provided.")
+		return
+	}
+	if bundle.UserId != user.Id && !user.HasPermission(models.UserPermEditOtherUploads) {
+		sendError(w, http.StatusUnauthorized, "No permission to edit bundle.")
+		return
+	}
+	if request.FileIds != nil {
+		files, ok := getFilesForUser(w, request.FileIds, user, storage.GetFilesForBundle)
+		if !ok {
+			return
+		}
+		err := storage.SetBundleFiles(&bundle, files)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if request.IsNameSet {
+		bundle.Name = request.Name
+	}
+	if request.UnlimitedDownloads {
+		bundle.UnlimitedDownloads = true
+	} else {
+		if request.AllowedDownloads != 0 {
+			bundle.DownloadsRemaining = request.AllowedDownloads
+			bundle.UnlimitedDownloads = false
+		}
+	}
+	if request.UnlimitedExpiry {
+		bundle.UnlimitedTime = true
+	} else {
+		if request.ExpiryTimestamp != 0 {
+			bundle.ExpireAt = request.ExpiryTimestamp
+			bundle.ExpireAtString = storage.FormatTimestamp(request.ExpiryTimestamp)
+			bundle.UnlimitedTime = false
+		}
+	}
+	if !request.KeepPassword {
+		bundle.PasswordHash = configuration.HashPassword(request.Password, true)
+	}
+	database.SaveBundle(bundle)
+	outputBundleApiInfo(w, bundle)
+}
+
+func apiBundleDelete(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleDelete)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	bundle, ok := database.GetBundle(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, "Invalid bundle ID provided.")
+		return
+	}
+	if bundle.UserId != user.Id && !user.HasPermission(models.UserPermDeleteOtherUploads) {
+		sendError(w, http.StatusUnauthorized, "No permission to delete this bundle")
+		return
+	}
+	database.DeleteBundle(bundle.Id)
+}
+
+// getFilesForUser returns the files for the IDs that are returned by getFiles, if all of them exist
+// and the user is allowed to access them. Otherwise, an error is sent and false is returned
+func getFilesForUser(w http.ResponseWriter, fileIds []string, user models.User,
+	getFiles func(ids []string, user models.User) ([]models.File, error)) ([]models.File, bool) {
+	files, err := getFiles(fileIds, user)
+	if err != nil {
+		if errors.Is(err, storage.ErrorNoFilePermission) {
+			sendError(w, http.StatusUnauthorized, err.Error())
+		} else {
+			sendError(w, http.StatusNotFound, err.Error())
+		}
+		return nil, false
+	}
+	return files, true
+}
+
+func outputBundleApiInfo(w http.ResponseWriter, bundle models.Bundle) {
+	result, err := json.Marshal(bundle.ToBundleApiOutput(configuration.Get().ServerUrl))
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
 func outputFileApiInfo(w http.ResponseWriter, file models.File) {
 	config := configuration.Get()
 	publicOutput, err := file.ToFileApiOutput(config.ServerUrl, config.IncludeFilename)
@@ -636,6 +950,109 @@ func apiChangeUserRank(w http.ResponseWriter, r requestParser, user models.User)
 	database.SaveUser(userEdit, false)
 }
 
+func apiChangeUserStorageTargets(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramUserStorageTargets)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	userEdit, ok := isValidUserForEditing(w, request.Id)
+	if !ok {
+		return
+	}
+	if userEdit.IsSameUser(user.Id) {
+		sendError(w, http.StatusBadRequest, "Cannot modify yourself")
+		return
+	}
+	if userEdit.IsSuperAdmin() {
+		sendError(w, http.StatusBadRequest, "Cannot modify super admin")
+		return
+	}
+	if request.foundHeaders["allowedTargets"] {
+		userEdit.StorageTargets = request.AllowedTargets
+		for _, target := range userEdit.GetStorageTargets() {
+			if !filesystem.IsValidTarget(target) {
+				sendError(w, http.StatusBadRequest, "Invalid storage target: "+target)
+				return
+			}
+		}
+	}
+	if request.foundHeaders["defaultTarget"] {
+		if request.DefaultTarget != "" && !filesystem.IsValidTarget(request.DefaultTarget) {
+			sendError(w, http.StatusBadRequest, "Invalid storage target: "+request.DefaultTarget)
+			return
+		}
+		userEdit.DefaultStorageTarget = request.DefaultTarget
+	}
+	if userEdit.DefaultStorageTarget != "" && !userEdit.IsAllowedStorageTarget(userEdit.DefaultStorageTarget) {
+		sendError(w, http.StatusBadRequest, "Default storage target is not an allowed storage target")
+		return
+	}
+	logging.LogUserEdit(userEdit, user)
+	database.SaveUser(userEdit, false)
+}
+
+func apiUserQuota(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramUserQuota)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	userEdit, ok := isValidUserForEditing(w, request.Id)
+	if !ok {
+		return
+	}
+	if request.foundHeaders["quotaBytes"] || request.foundHeaders["quotaFiles"] {
+		if userEdit.IsSameUser(user.Id) {
+			sendError(w, http.StatusBadRequest, "Cannot modify yourself")
+			return
+		}
+		if userEdit.IsSuperAdmin() {
+			sendError(w, http.StatusBadRequest, "Cannot modify super admin")
+			return
+		}
+		if request.foundHeaders["quotaBytes"] {
+			userEdit.QuotaBytes = request.QuotaBytes
+		}
+		if request.foundHeaders["quotaFiles"] {
+			userEdit.QuotaFiles = request.QuotaFiles
+		}
+		logging.LogUserEdit(userEdit, user)
+		database.SaveUser(userEdit, false)
+	}
+	result, err := json.Marshal(userEdit.GetQuotaInfo(storage.GetStorageUsageForUser(userEdit.Id)))
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiUserNotifications(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramUserNotifications)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	isModified := false
+	if request.foundHeaders["notificationEmail"] {
+		user.NotificationEmail = request.Email
+		isModified = true
+	}
+	if request.foundHeaders["notifyDownload"] {
+		user.NotifyDownload = request.Download
+		isModified = true
+	}
+	if request.foundHeaders["notifyExpiry"] {
+		user.NotifyExpiry = request.Expiry
+		isModified = true
+	}
+	if request.foundHeaders["notifyDeletion"] {
+		user.NotifyDeletion = request.Deletion
+		isModified = true
+	}
+	if isModified {
+		database.SaveUser(user, false)
+	}
+	result, err := json.Marshal(user.GetNotificationSettings())
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
 func updateApiKeyPermsOnUserPermChange(userId int, userPerm models.UserPermission, isNewlyGranted bool) {
 	var affectedPermission models.ApiPermission
 	switch userPerm {
@@ -714,6 +1131,7 @@ func apiDeleteUser(w http.ResponseWriter, r requestParser, user models.User) {
 	for _, file := range database.GetAllMetadata() {
 		if file.UserId == userToDelete.Id {
 			if request.DeleteFiles {
+				database.DeleteDownloadEvents(file.Id)
 				database.DeleteMetaData(file.Id)
 			} else {
 				file.UserId = user.Id
@@ -738,6 +1156,47 @@ func apiLogsDelete(_ http.ResponseWriter, r requestParser, user models.User) {
 	logging.DeleteLogs(user.Name, user.Id, request.Timestamp, request.Request)
 }
 
+func apiScrubStart(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramScrubStart)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if user.UserLevel == models.UserLevelUser {
+		sendError(w, http.StatusUnauthorized, "Only admins can start an integrity check")
+		return
+	}
+	if !storage.StartScrub(request.Quarantine) {
+		sendError(w, http.StatusConflict, "An integrity check is already running")
+		return
+	}
+	apiScrubStatus(w, nil, user)
+}
+
+func apiScrubStatus(w http.ResponseWriter, _ requestParser, user models.User) {
+	if user.UserLevel == models.UserLevelUser {
+		sendError(w, http.StatusUnauthorized, "Only admins can view the integrity check status")
+		return
+	}
+	result, err := json.Marshal(storage.GetScrubReport())
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiDiskSpace(w http.ResponseWriter, _ requestParser, user models.User) {
+	if user.UserLevel == models.UserLevelUser {
+		sendError(w, http.StatusUnauthorized, "Only admins can view the free disk space")
+		return
+	}
+	space, ok := diskspace.Get()
+	if !ok {
+		sendError(w, http.StatusInternalServerError, "Free disk space cannot be determined on this system")
+		return
+	}
+	result, err := json.Marshal(space)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
 func isAuthorisedForApi(r *http.Request, routing apiRoute) (models.User, bool) {
 	apiKey := r.Header.Get("apikey")
 	user, _, ok := isValidApiKey(apiKey, true, routing.ApiPerm)
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/json"
 	"github.com/forceu/gokapi/internal/configuration"
@@ -8,6 +9,9 @@ import (
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/filesystem"
+	"github.com/forceu/gokapi/internal/storage/processingstatus"
+	"github.com/forceu/gokapi/internal/storage/processingstatus/pstatusdb"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 	"io"
@@ -17,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -308,7 +313,7 @@ func TestUserCreate(t *testing.T) {
 		Value: "1234",
 	}})
 	Process(w, r)
-	test.ResponseBodyContains(t, w, `{"id":103,"name":"1234","permissions":0,"userLevel":2,"lastOnline":0,"resetPassword":false}`)
+	test.ResponseBodyContains(t, w, `{"id":103,"name":"1234","permissions":0,"userLevel":2,"lastOnline":0,"resetPassword":false,"defaultStorageTarget":"","storageTargets":"","quotaBytes":0,"quotaFiles":0,"notificationEmail":"","notifyDownload":0,"notifyExpiry":false,"notifyDeletion":false}`)
 
 	var invalidParameter = []invalidParameterValue{
 		{
@@ -392,6 +397,262 @@ func TestUserChangeRank(t *testing.T) {
 	apiChangeUserRank(w, &paramAuthCreate{}, models.User{Id: 7})
 }
 
+func TestUserStorageTargets(t *testing.T) {
+	const apiUrl = "/user/storageTargets"
+	const headerUserId = "userid"
+	const headerDefault = "defaultTarget"
+	const headerAllowed = "allowedTargets"
+
+	err := filesystem.AddTarget(models.StorageTargetConfig{Name: "local-fast", Type: models.StorageTypeLocal}, models.AwsConfig{})
+	test.IsNil(t, err)
+	err = filesystem.AddTarget(models.StorageTargetConfig{Name: "local-slow", Type: models.StorageTypeLocal}, models.AwsConfig{})
+	test.IsNil(t, err)
+	defer filesystem.ClearTargets()
+
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
+	testInvalidUserId(t, apiUrl, apiKey.Id, []test.Header{{Name: headerAllowed, Value: "local-fast"}})
+	var validHeaders = []test.Header{
+		{
+			Name:  headerUserId,
+			Value: strconv.Itoa(idAdmin),
+		},
+	}
+	invalidParameter := []invalidParameterValue{
+		{
+			Value:        "local-fast,invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Invalid storage target: invalid"}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders, headerAllowed, invalidParameter)
+	invalidParameter = []invalidParameterValue{
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Invalid storage target: invalid"}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders, headerDefault, invalidParameter)
+
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}, {
+		Name:  headerAllowed,
+		Value: " local-fast, ,local-slow ",
+	}, {
+		Name:  headerDefault,
+		Value: "local-fast",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	user, ok := database.GetUser(idAdmin)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, user.StorageTargets, "local-fast,local-slow")
+	test.IsEqualString(t, user.DefaultStorageTarget, "local-fast")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}, {
+		Name:  headerAllowed,
+		Value: "local-slow",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "Default storage target is not an allowed storage target")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}, {
+		Name:  headerAllowed,
+		Value: "",
+	}, {
+		Name:  headerDefault,
+		Value: "",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	user, ok = database.GetUser(idAdmin)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, user.StorageTargets, "")
+	test.IsEqualString(t, user.DefaultStorageTarget, "")
+
+	defer test.ExpectPanic(t)
+	apiChangeUserStorageTargets(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestUserQuota(t *testing.T) {
+	const apiUrl = "/user/quota"
+	const headerUserId = "userid"
+	const headerQuotaBytes = "quotaBytes"
+	const headerQuotaFiles = "quotaFiles"
+
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
+	testInvalidUserId(t, apiUrl, apiKey.Id, []test.Header{{Name: headerQuotaFiles, Value: "5"}})
+	var validHeaders = []test.Header{
+		{
+			Name:  headerUserId,
+			Value: strconv.Itoa(idAdmin),
+		},
+	}
+	invalidParameter := []invalidParameterValue{
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value in header quotaBytes supplied"}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "-1",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"quota must not be negative"}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders, headerQuotaBytes, invalidParameter)
+	invalidParameter = []invalidParameterValue{
+		{
+			Value:        "-1",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"quota must not be negative"}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders, headerQuotaFiles, invalidParameter)
+
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}, {
+		Name:  headerQuotaBytes,
+		Value: "2048",
+	}, {
+		Name:  headerQuotaFiles,
+		Value: "10",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	usage := storage.GetStorageUsageForUser(idAdmin)
+	test.ResponseBodyContains(t, w, `{"userId":101,"quotaBytes":2048,"quotaFiles":10,"usedBytes":`+
+		strconv.FormatInt(usage.Bytes, 10)+`,"usedFiles":`+strconv.Itoa(usage.Files)+`}`)
+	user, ok := database.GetUser(idAdmin)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, user.QuotaBytes, 2048)
+	test.IsEqualInt(t, user.QuotaFiles, 10)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}, {
+		Name:  headerQuotaFiles,
+		Value: "0",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `"quotaBytes":2048,"quotaFiles":0`)
+
+	// Only reading the quota is allowed for all users
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idSuperAdmin),
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `{"userId":100,"quotaBytes":0,"quotaFiles":0,`)
+
+	user.QuotaBytes = 0
+	database.SaveUser(user, false)
+
+	defer test.ExpectPanic(t)
+	apiUserQuota(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestUserNotifications(t *testing.T) {
+	const apiUrl = "/user/notifications"
+	const headerEmail = "notificationEmail"
+	const headerDownload = "notifyDownload"
+	const headerExpiry = "notifyExpiry"
+	const headerDeletion = "notifyDeletion"
+
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermEdit)
+	invalidParameter := []invalidParameterValue{
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid email address"}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "User <user@example.com>",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid email address"}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, apiKey.Id, []test.Header{}, headerEmail, invalidParameter)
+	invalidParameter = []invalidParameterValue{
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value in header notifyDownload supplied"}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "3",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value for notifyDownload"}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, apiKey.Id, []test.Header{}, headerDownload, invalidParameter)
+
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `{"notificationEmail":"","notifyDownload":0,"notifyExpiry":false,"notifyDeletion":false}`)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerEmail,
+		Value: " user@example.com ",
+	}, {
+		Name:  headerDownload,
+		Value: "1",
+	}, {
+		Name:  headerDeletion,
+		Value: "true",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `{"notificationEmail":"user@example.com","notifyDownload":1,"notifyExpiry":false,"notifyDeletion":true}`)
+	user, ok := database.GetUser(idUser)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, user.NotificationEmail, "user@example.com")
+	test.IsEqualInt(t, user.NotifyDownload, models.NotifyDownloadFirst)
+	test.IsEqualBool(t, user.NotifyExpiry, false)
+	test.IsEqualBool(t, user.NotifyDeletion, true)
+
+	// Only the submitted settings are changed
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerExpiry,
+		Value: "true",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `{"notificationEmail":"user@example.com","notifyDownload":1,"notifyExpiry":true,"notifyDeletion":true}`)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerEmail,
+		Value: "",
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `{"notificationEmail":"","notifyDownload":1,"notifyExpiry":true,"notifyDeletion":true}`)
+
+	user, _ = database.GetUser(idUser)
+	user.NotifyDownload = models.NotifyDownloadNever
+	user.NotifyExpiry = false
+	user.NotifyDeletion = false
+	database.SaveUser(user, false)
+
+	defer test.ExpectPanic(t)
+	apiUserNotifications(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
 func TestUserDelete(t *testing.T) {
 	const apiUrl = "/user/delete"
 	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
@@ -1366,6 +1627,61 @@ func TestListSingle(t *testing.T) {
 	apiListSingle(w, &paramAuthCreate{}, models.User{Id: 7})
 }
 
+func TestDownloadHistory(t *testing.T) {
+	const apiUrl = "/files/downloads/"
+	_ = testAuthorisation(t, apiUrl, models.ApiPermView)
+	apiKey := testAuthorisation(t, apiUrl+"newTestFile", models.ApiPermView)
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: "newTestFile", Timestamp: 100, UserAgent: "first"})
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: "newTestFile", Timestamp: 200, UserAgent: "second", BytesSent: 3, IsComplete: true})
+	var result []models.DownloadEvent
+
+	w, r := getRecorder(apiUrl+"newTestFile", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(result), 2)
+	test.IsEqualString(t, result[0].UserAgent, "second")
+	test.IsEqualInt64(t, result[0].BytesSent, 3)
+	test.IsEqualBool(t, result[0].IsComplete, true)
+	test.IsEqualString(t, result[1].UserAgent, "first")
+
+	w, r = getRecorder(apiUrl+"e4TjE7CokWK0giiLNxDL", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"No permission to view file"}`)
+	w, r = getRecorder(apiUrl+"invalid", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"File not found"}`)
+
+	grantUserPermission(t, idUser, models.UserPermListOtherUploads)
+	w, r = getRecorder(apiUrl+"e4TjE7CokWK0giiLNxDL", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, "[]")
+	removeUserPermission(t, idUser, models.UserPermListOtherUploads)
+	database.DeleteDownloadEvents("newTestFile")
+
+	// The history of a file that has been removed can still be viewed by its uploader
+	apiKey = testAuthorisation(t, apiUrl+"removedFileUser", models.ApiPermView)
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: "removedFileUser", Timestamp: 100, UserAgent: "removed", UserId: idUser})
+	database.SaveDownloadEvent(models.DownloadEvent{FileId: "removedFileAdmin", Timestamp: 100, UserId: idSuperAdmin})
+	w, r = getRecorder(apiUrl+"removedFileUser", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, "removed")
+	w, r = getRecorder(apiUrl+"removedFileAdmin", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, "No permission to view file")
+	database.DeleteDownloadEvents("removedFileUser")
+	database.DeleteDownloadEvents("removedFileAdmin")
+
+	defer test.ExpectPanic(t)
+	apiDownloadHistory(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
 func TestUpload(t *testing.T) {
 	result, body := uploadNewFile(t)
 	test.IsEqualString(t, result.Result, "OK")
@@ -1516,6 +1832,17 @@ func TestDuplicate(t *testing.T) {
 		test.IsEqualInt(t, newFile.DownloadCount, 0)
 	}
 
+	user, ok := database.GetUser(idUser)
+	test.IsEqualBool(t, ok, true)
+	user.QuotaFiles = storage.GetStorageUsageForUser(idUser).Files
+	database.SaveUser(user, false)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: headerId, Value: originalFile.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, storage.ErrorQuotaFilesExceeded.Error())
+	user.QuotaFiles = 0
+	database.SaveUser(user, false)
+
 	defer test.ExpectPanic(t)
 	apiDuplicateFile(nil, &paramAuthCreate{}, models.User{Id: 7})
 }
@@ -1605,11 +1932,715 @@ func TestChunkComplete(t *testing.T) {
 	test.IsEqualInt(t, w.Code, 400)
 	test.ResponseBodyContains(t, w, "error")
 
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "uuid", Value: "tmpupload123"},
+		{Name: "filename", Value: "test.upload"},
+		{Name: "filesize", Value: "13"},
+		{Name: "storageTarget", Value: "invalid"}}, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "invalid storage target requested")
+
 	defer test.ExpectPanic(t)
 	apiChunkComplete(w, &paramAuthCreate{}, models.User{Id: 7})
 }
 
-func TestMinorFunctions(t *testing.T) {
-	outputFileJson(nil, models.File{})
-	sendError(nil, 0, "none")
+func TestChunkStatus(t *testing.T) {
+	err := os.WriteFile("test/tmpupload", []byte("chunktestfile"), 0600)
+	test.IsNil(t, err)
+	body, formcontent := test.FileToMultipartFormBody(t, test.HttpTestConfig{
+		UploadFileName:  "test/tmpupload",
+		UploadFieldName: "file",
+		PostValues: []test.PostBody{{
+			Key:   "filesize",
+			Value: "20",
+		}, {
+			Key:   "offset",
+			Value: "0",
+		}, {
+			Key:   "uuid",
+			Value: "tmpupload456",
+		}},
+	})
+	w, r := test.GetRecorder("POST", "/api/chunk/add", nil, []test.Header{{
+		Name:  "apikey",
+		Value: "validkey",
+	}}, body)
+	r.Header.Add("Content-Type", formcontent)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+
+	w, r = test.GetRecorder("GET", "/api/chunk/status", nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "uuid", Value: "tmpupload456"}}, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var status models.ChunkStatus
+	response, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	err = json.Unmarshal(response, &status)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, status.TotalSize, 20)
+	test.IsEqualInt64(t, status.ReceivedBytes, 13)
+	test.IsEqualBool(t, status.IsComplete, false)
+	test.IsEqual(t, status.Ranges, []models.ByteRange{{Start: 0, End: 13}})
+
+	// The status of an upload is only shown to the user that started it
+	w = httptest.NewRecorder()
+	apiChunkStatus(w, &paramChunkStatus{Uuid: "tmpupload456"}, models.User{Id: 999})
+	test.IsEqualInt(t, w.Code, 404)
+	test.ResponseBodyContains(t, w, "chunked upload does not exist")
+
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "uuid", Value: "tmpupload456"},
+		{Name: "filename", Value: "test.upload"},
+		{Name: "filesize", Value: "20"},
+		{Name: "nonblocking", Value: "true"}}, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "not all chunks have been received")
+
+	w, r = test.GetRecorder("GET", "/api/chunk/status", nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "uuid", Value: "invalidupload"}}, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+
+	defer test.ExpectPanic(t)
+	apiChunkStatus(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestChunkChecksum(t *testing.T) {
+	err := os.WriteFile("test/tmpupload", []byte("123456789"), 0600)
+	test.IsNil(t, err)
+	for _, checksum := range []string{"crc32c:00000000", "crc32c:e3069283"} {
+		body, formcontent := test.FileToMultipartFormBody(t, test.HttpTestConfig{
+			UploadFileName:  "test/tmpupload",
+			UploadFieldName: "file",
+			PostValues: []test.PostBody{{
+				Key:   "filesize",
+				Value: "9",
+			}, {
+				Key:   "offset",
+				Value: "0",
+			}, {
+				Key:   "uuid",
+				Value: "tmpuploadchecksum",
+			}},
+		})
+		w, r := test.GetRecorder("POST", "/api/chunk/add", nil, []test.Header{
+			{Name: "apikey", Value: "validkey"},
+			{Name: "checksum", Value: checksum}}, body)
+		r.Header.Add("Content-Type", formcontent)
+		Process(w, r)
+		if checksum == "crc32c:00000000" {
+			test.IsEqualInt(t, w.Code, 400)
+			test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"checksum does not match"}`)
+		} else {
+			test.IsEqualInt(t, w.Code, 200)
+		}
+	}
+
+	headers := []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "uuid", Value: "tmpuploadchecksum"},
+		{Name: "filename", Value: "checksum.upload"},
+		{Name: "filesize", Value: "9"},
+		{Name: "checksum", Value: "md5:e3069283"}}
+	w, r := test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "invalid checksum provided")
+
+	headers[4].Value = "sha256:" + strings.Repeat("0", 64)
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "checksum does not match")
+
+	headers[4].Value = "sha256:15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225"
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, "checksum.upload")
+}
+
+func TestTus(t *testing.T) {
+	w, r := test.GetRecorder("POST", "/api/tus/", nil, []test.Header{
+		{Name: "Tus-Resumable", Value: "1.0.0"},
+		{Name: "Upload-Length", Value: "8"},
+		{Name: "Upload-Metadata", Value: "filename dHVzLnR4dA=="}}, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+
+	r.Header.Set("apikey", "validkey")
+	w = httptest.NewRecorder()
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 201)
+	location := w.Header().Get("Location")
+	test.IsEqualBool(t, strings.Contains(location, "/api/tus/"), true)
+	uploadUrl := location[strings.Index(location, "/api/tus/"):]
+
+	w, r = test.GetRecorder("PATCH", uploadUrl, nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "Tus-Resumable", Value: "1.0.0"},
+		{Name: "Content-Type", Value: "application/offset+octet-stream"},
+		{Name: "Upload-Offset", Value: "0"}}, strings.NewReader("tus file"))
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 204)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "8")
+	file, ok := database.GetMetaDataById(w.Header().Get("Gokapi-File-Id"))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "tus.txt")
+
+	w, r = test.GetRecorder("PATCH", uploadUrl, nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "Content-Type", Value: "application/offset+octet-stream"},
+		{Name: "Upload-Offset", Value: "0"}}, strings.NewReader("tus file"))
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 412)
+	test.ResponseBodyContains(t, w, "unsupported version of the tus protocol")
+
+	defer test.ExpectPanic(t)
+	apiTus(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestUploadFromUrl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "remote content")
+	}))
+	defer server.Close()
+	headers := []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "url", Value: server.URL + "/remote.txt"},
+		{Name: "allowedDownloads", Value: "4"}}
+
+	w, r := test.GetRecorder("POST", "/api/files/addFromUrl", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "uploads from a remote URL are disabled on this server")
+
+	configuration.Get().RemoteUploadHosts = []string{strings.TrimPrefix(server.URL, "http://")}
+	defer func() { configuration.Get().RemoteUploadHosts = []string{} }()
+	w, r = test.GetRecorder("POST", "/api/files/addFromUrl", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var result struct {
+		Result   string
+		FileInfo models.FileApiOutput
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.Result, "OK")
+	test.IsEqualString(t, result.FileInfo.Name, "remote.txt")
+	test.IsEqualInt(t, result.FileInfo.DownloadsRemaining, 4)
+
+	headers = append(headers, test.Header{Name: "nonblocking", Value: "true"}, test.Header{Name: "filename", Value: "nonblocking.txt"})
+	w, r = test.GetRecorder("POST", "/api/files/addFromUrl", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var nonBlockingResult struct {
+		Result  string
+		ChunkId string
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &nonBlockingResult)
+	test.IsNil(t, err)
+	test.IsEqualString(t, nonBlockingResult.Result, "OK")
+	test.IsEqualInt(t, len(nonBlockingResult.ChunkId), 30)
+	isFinished := false
+	for i := 0; i < 100 && !isFinished; i++ {
+		for _, status := range pstatusdb.GetAll() {
+			if status.ChunkId == nonBlockingResult.ChunkId && status.CurrentStatus == processingstatus.StatusFinished {
+				isFinished = true
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	test.IsEqualBool(t, isFinished, true)
+
+	w, r = test.GetRecorder("POST", "/api/files/addFromUrl", nil, []test.Header{
+		{Name: "apikey", Value: "validkey"},
+		{Name: "url", Value: "http://example.com/remote.txt"}}, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "the host of the URL is not allowed for remote uploads")
+
+	defer test.ExpectPanic(t)
+	apiUploadFromUrl(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestArchiveContent(t *testing.T) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	writer, err := zipWriter.Create("archived.txt")
+	test.IsNil(t, err)
+	_, err = writer.Write([]byte("archived content"))
+	test.IsNil(t, err)
+	test.IsNil(t, zipWriter.Close())
+	err = os.WriteFile("test/data/archivetestcontent", buf.Bytes(), 0600)
+	test.IsNil(t, err)
+	database.SaveMetaData(models.File{
+		Id:                 "archivefileUser",
+		Name:               "archive.zip",
+		Size:               "1 kB",
+		SizeBytes:          int64(buf.Len()),
+		SHA1:               "archivetestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		UserId:             idUser,
+	})
+	database.SaveMetaData(models.File{
+		Id:                 "archivefileNoZip",
+		Name:               "archive.txt",
+		SizeBytes:          int64(buf.Len()),
+		SHA1:               "archivetestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		UserId:             idUser,
+	})
+
+	apiKey := testAuthorisation(t, "/files/archive", models.ApiPermView)
+	w, r := getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "invalid"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	w, r = getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "archivefileNoZip"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "File is not an archive or its content cannot be read")
+	// The archive is read in the background on the first request
+	w, r = getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "archivefileUser"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 503)
+	test.IsEqualString(t, w.Header().Get("Retry-After"), "5")
+	for i := 0; i < 100 && w.Code == 503; i++ {
+		time.Sleep(50 * time.Millisecond)
+		w, r = getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "archivefileUser"}})
+		Process(w, r)
+	}
+	test.IsEqualInt(t, w.Code, 200)
+	var entries []models.ArchiveEntry
+	err = json.Unmarshal(w.Body.Bytes(), &entries)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(entries), 1)
+	test.IsEqualString(t, entries[0].Name, "archived.txt")
+	test.IsEqualInt64(t, entries[0].SizeBytes, 16)
+
+	database.SaveMetaData(models.File{
+		Id:                 "archivefileAdmin",
+		Name:               "archive.zip",
+		SizeBytes:          int64(buf.Len()),
+		SHA1:               "archivetestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		UserId:             idSuperAdmin,
+	})
+	w, r = getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "archivefileAdmin"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	grantUserPermission(t, idUser, models.UserPermListOtherUploads)
+	w, r = getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "archivefileAdmin"}})
+	Process(w, r)
+	for i := 0; i < 100 && w.Code == 503; i++ {
+		time.Sleep(50 * time.Millisecond)
+		w, r = getRecorder("/files/archive", apiKey.Id, []test.Header{{Name: "id", Value: "archivefileAdmin"}})
+		Process(w, r)
+	}
+	removeUserPermission(t, idUser, models.UserPermListOtherUploads)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, "archived.txt")
+
+	defer test.ExpectPanic(t)
+	apiArchiveContent(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestDownloadZip(t *testing.T) {
+	err := os.WriteFile("test/data/ziptestcontent", []byte("zip content"), 0600)
+	test.IsNil(t, err)
+	database.SaveMetaData(models.File{
+		Id:                 "zipfileUser",
+		Name:               "zipfileUser.txt",
+		SHA1:               "ziptestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		UserId:             idUser,
+	})
+	database.SaveMetaData(models.File{
+		Id:                 "zipfileAdmin",
+		Name:               "zipfileAdmin.txt",
+		SHA1:               "ziptestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		UserId:             idSuperAdmin,
+	})
+
+	apiKey := testAuthorisation(t, "/files/downloadZip", models.ApiPermView)
+	w, r := getRecorder("/files/downloadZip", apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"header fileIds is required"}`)
+	w, r = getRecorder("/files/downloadZip", apiKey.Id, []test.Header{{Name: "fileIds", Value: ","}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "at least one file ID is required")
+	w, r = getRecorder("/files/downloadZip", apiKey.Id, []test.Header{{Name: "fileIds", Value: "zipfileUser,invalid"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	test.ResponseBodyContains(t, w, "file not found: invalid")
+	w, r = getRecorder("/files/downloadZip", apiKey.Id, []test.Header{{Name: "fileIds", Value: "zipfileUser,zipfileAdmin"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, "no permission to access file: zipfileAdmin")
+
+	grantUserPermission(t, idUser, models.UserPermListOtherUploads)
+	w, r = getRecorder("/files/downloadZip", apiKey.Id, []test.Header{{Name: "fileIds", Value: "zipfileUser,zipfileAdmin"}})
+	Process(w, r)
+	removeUserPermission(t, idUser, models.UserPermListOtherUploads)
+	test.IsEqualInt(t, w.Code, 200)
+	test.IsEqualString(t, w.Header().Get("Content-Type"), "application/zip")
+	test.ResponseBodyContains(t, w, "zipfileAdmin.txt")
+
+	// Both files had only a single download remaining
+	w, r = getRecorder("/files/downloadZip", apiKey.Id, []test.Header{{Name: "fileIds", Value: "zipfileUser"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+
+	defer test.ExpectPanic(t)
+	apiDownloadZip(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestBundles(t *testing.T) {
+	err := os.WriteFile("test/data/bundletestcontent", []byte("bundle content"), 0600)
+	test.IsNil(t, err)
+	for _, id := range []string{"bundlefile1", "bundlefile2"} {
+		database.SaveMetaData(models.File{
+			Id:                 id,
+			Name:               id,
+			SHA1:               "bundletestcontent",
+			ExpireAt:           2147483646,
+			DownloadsRemaining: 1,
+			UserId:             idUser,
+		})
+	}
+	database.SaveMetaData(models.File{
+		Id:                 "bundlefileAdmin",
+		Name:               "bundlefileAdmin",
+		SHA1:               "bundletestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		UserId:             idSuperAdmin,
+	})
+
+	apiKeyCreate := testAuthorisation(t, "/bundle/create", models.ApiPermUpload)
+	w, r := getRecorder("/bundle/create", apiKeyCreate.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"header fileIds is required"}`)
+	w, r = getRecorder("/bundle/create", apiKeyCreate.Id, []test.Header{{Name: "fileIds", Value: " , "}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, storage.ErrorBundleNoFiles.Error())
+	w, r = getRecorder("/bundle/create", apiKeyCreate.Id, []test.Header{{Name: "fileIds", Value: "bundlefile1,invalid"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	test.ResponseBodyContains(t, w, "file not found: invalid")
+	w, r = getRecorder("/bundle/create", apiKeyCreate.Id, []test.Header{{Name: "fileIds", Value: "bundlefile1,bundlefileAdmin"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, "no permission to access file: bundlefileAdmin")
+	database.SaveMetaData(models.File{
+		Id:                 "bundlefilePassword",
+		Name:               "bundlefilePassword",
+		SHA1:               "bundletestcontent",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+		PasswordHash:       "hash",
+		UserId:             idUser,
+	})
+	w, r = getRecorder("/bundle/create", apiKeyCreate.Id, []test.Header{{Name: "fileIds", Value: "bundlefile1,bundlefilePassword"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, storage.ErrorBundlePasswordFile.Error())
+
+	w, r = getRecorder("/bundle/create", apiKeyCreate.Id, []test.Header{
+		{Name: "fileIds", Value: "bundlefile1, bundlefile2"},
+		{Name: "name", Value: "Test bundle"},
+		{Name: "allowedDownloads", Value: "5"},
+		{Name: "password", Value: "secret"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var bundle models.BundleApiOutput
+	err = json.Unmarshal(w.Body.Bytes(), &bundle)
+	test.IsNil(t, err)
+	test.IsEqualString(t, bundle.Name, "Test bundle")
+	test.IsEqualInt(t, len(bundle.FileIds), 2)
+	test.IsEqualInt(t, bundle.DownloadsRemaining, 5)
+	test.IsEqualInt(t, bundle.UploaderId, idUser)
+	test.IsEqualBool(t, bundle.IsPasswordProtected, true)
+	test.IsEqualBool(t, bundle.UnlimitedTime, false)
+
+	apiKeyList := testAuthorisation(t, "/bundle/list", models.ApiPermView)
+	w, r = getRecorder("/bundle/list", apiKeyList.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var bundles []models.BundleApiOutput
+	err = json.Unmarshal(w.Body.Bytes(), &bundles)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(bundles), 1)
+	test.IsEqualString(t, bundles[0].Id, bundle.Id)
+
+	apiKeyModify := testAuthorisation(t, "/bundle/modify", models.ApiPermEdit)
+	w, r = getRecorder("/bundle/modify", apiKeyModify.Id, []test.Header{{Name: "id", Value: "invalid"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	test.ResponseBodyContains(t, w, "Invalid bundle ID provided.")
+	w, r = getRecorder("/bundle/modify", apiKeyModify.Id, []test.Header{
+		{Name: "id", Value: bundle.Id},
+		{Name: "fileIds", Value: "bundlefile2"},
+		{Name: "name", Value: "Renamed"},
+		{Name: "allowedDownloads", Value: "0"},
+		{Name: "originalPassword", Value: "true"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &bundle)
+	test.IsNil(t, err)
+	test.IsEqualString(t, bundle.Name, "Renamed")
+	test.IsEqualString(t, strings.Join(bundle.FileIds, ","), "bundlefile2")
+	test.IsEqualBool(t, bundle.UnlimitedDownloads, true)
+	test.IsEqualBool(t, bundle.IsPasswordProtected, true)
+	w, r = getRecorder("/bundle/modify", apiKeyModify.Id, []test.Header{{Name: "id", Value: bundle.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &bundle)
+	test.IsNil(t, err)
+	test.IsEqualString(t, bundle.Name, "Renamed")
+	test.IsEqualBool(t, bundle.IsPasswordProtected, false)
+
+	adminBundle, err := storage.CreateBundle("admin", []models.File{{Id: "bundlefileAdmin"}}, idSuperAdmin, models.UploadRequest{UnlimitedTime: true, UnlimitedDownload: true})
+	test.IsNil(t, err)
+	w, r = getRecorder("/bundle/modify", apiKeyModify.Id, []test.Header{{Name: "id", Value: adminBundle.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, "No permission to edit bundle.")
+
+	apiKeyDelete := testAuthorisation(t, "/bundle/delete", models.ApiPermDelete)
+	w, r = getRecorder("/bundle/delete", apiKeyDelete.Id, []test.Header{{Name: "id", Value: adminBundle.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, "No permission to delete this bundle")
+	w, r = getRecorder("/bundle/delete", apiKeyDelete.Id, []test.Header{{Name: "id", Value: "invalid"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	w, r = getRecorder("/bundle/delete", apiKeyDelete.Id, []test.Header{{Name: "id", Value: bundle.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	_, ok := database.GetBundle(bundle.Id)
+	test.IsEqualBool(t, ok, false)
+
+	database.DeleteBundle(adminBundle.Id)
+	for _, id := range []string{"bundlefile1", "bundlefile2", "bundlefileAdmin", "bundlefilePassword"} {
+		database.DeleteMetaData(id)
+	}
+
+	defer test.ExpectPanic(t)
+	apiBundleCreate(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestModifyBandwidthLimit(t *testing.T) {
+	const apiUrl = "/files/modify"
+	database.SaveMetaData(models.File{
+		Id:                 "bandwidthfileUser",
+		Name:               "bandwidth.dat",
+		SHA1:               "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+		UserId:             idUser,
+	})
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermEdit)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{
+		{Name: "id", Value: "bandwidthfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "bandwidthLimitKB", Value: "512"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, "Only admins can change the bandwidth limit.")
+
+	w, r = getRecorder(apiUrl, idApiKeyAdmin, []test.Header{
+		{Name: "id", Value: "bandwidthfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "bandwidthLimitKB", Value: "-1"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "bandwidthLimitKB must not be negative")
+
+	w, r = getRecorder(apiUrl, idApiKeyAdmin, []test.Header{
+		{Name: "id", Value: "bandwidthfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "bandwidthLimitKB", Value: "512"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `"BandwidthLimitKB":512`)
+	file, ok := database.GetMetaDataById("bandwidthfileUser")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, file.BandwidthLimitKB, 512)
+
+	// Users can still edit other values without changing the limit
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{
+		{Name: "id", Value: "bandwidthfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "allowedDownloads", Value: "5"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	file, _ = database.GetMetaDataById("bandwidthfileUser")
+	test.IsEqualInt(t, file.BandwidthLimitKB, 512)
+	test.IsEqualInt(t, file.DownloadsRemaining, 5)
+
+	w, r = getRecorder(apiUrl, idApiKeySuperAdmin, []test.Header{
+		{Name: "id", Value: "bandwidthfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "bandwidthLimitKB", Value: "0"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	file, _ = database.GetMetaDataById("bandwidthfileUser")
+	test.IsEqualInt(t, file.BandwidthLimitKB, 0)
+	database.DeleteMetaData("bandwidthfileUser")
+}
+
+func TestModifyParallelDownloads(t *testing.T) {
+	const apiUrl = "/files/modify"
+	database.SaveMetaData(models.File{
+		Id:                 "parallelfileUser",
+		Name:               "parallel.dat",
+		SHA1:               "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+		UserId:             idUser,
+	})
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermEdit)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{
+		{Name: "id", Value: "parallelfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "parallelDownloads", Value: "-1"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyContains(t, w, "parallelDownloads must not be negative")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{
+		{Name: "id", Value: "parallelfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "parallelDownloads", Value: "2"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyContains(t, w, `"ParallelDownloads":2`)
+	file, ok := database.GetMetaDataById("parallelfileUser")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, file.ParallelDownloads, 2)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{
+		{Name: "id", Value: "parallelfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "allowedDownloads", Value: "5"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	file, _ = database.GetMetaDataById("parallelfileUser")
+	test.IsEqualInt(t, file.ParallelDownloads, 2)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{
+		{Name: "id", Value: "parallelfileUser"},
+		{Name: "originalPassword", Value: "true"},
+		{Name: "parallelDownloads", Value: "0"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	file, _ = database.GetMetaDataById("parallelfileUser")
+	test.IsEqualInt(t, file.ParallelDownloads, 0)
+	database.DeleteMetaData("parallelfileUser")
+}
+
+func TestScrub(t *testing.T) {
+	const apiUrlStart = "/scrub/start"
+	const apiUrlStatus = "/scrub/status"
+	apiKey := testAuthorisation(t, apiUrlStart, models.ApiPermManageLogs)
+	w, r := getRecorder(apiUrlStart, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"Only admins can start an integrity check"}`)
+	apiKey = testAuthorisation(t, apiUrlStatus, models.ApiPermManageLogs)
+	w, r = getRecorder(apiUrlStatus, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"Only admins can view the integrity check status"}`)
+
+	var report models.ScrubReport
+	w, r = getRecorder(apiUrlStatus, idApiKeyAdmin, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, report.IsRunning, false)
+	test.IsEqualInt(t, int(report.StartTime), 0)
+
+	testInvalidParameters(t, apiUrlStart, idApiKeyAdmin, []test.Header{}, "quarantine", []invalidParameterValue{
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value in header quarantine supplied"}`,
+			StatusCode:   400,
+		},
+	})
+
+	w, r = getRecorder(apiUrlStart, idApiKeyAdmin, []test.Header{{Name: "quarantine", Value: "false"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &report)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, report.StartTime > 0, true)
+	for report.IsRunning {
+		w, r = getRecorder(apiUrlStart, idApiKeyAdmin, []test.Header{})
+		Process(w, r)
+		if w.Code != 200 {
+			test.IsEqualInt(t, w.Code, 409)
+			test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"An integrity check is already running"}`)
+		}
+		time.Sleep(10 * time.Millisecond)
+		w, r = getRecorder(apiUrlStatus, idApiKeyAdmin, []test.Header{})
+		Process(w, r)
+		test.IsEqualInt(t, w.Code, 200)
+		err = json.Unmarshal(w.Body.Bytes(), &report)
+		test.IsNil(t, err)
+	}
+	test.IsEqualBool(t, report.CheckedFiles > 0, true)
+	test.IsEqualBool(t, report.EndTime >= report.StartTime, true)
+
+	defer test.ExpectPanic(t)
+	apiScrubStart(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestMinorFunctions(t *testing.T) {
+	outputFileJson(nil, models.File{})
+	sendError(nil, 0, "none")
+}
+
+func TestDiskSpace(t *testing.T) {
+	const apiUrl = "/system/diskSpace"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageLogs)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyContains(t, w, `{"Result":"error","ErrorMessage":"Only admins can view the free disk space"}`)
+
+	var space models.DiskSpace
+	w, r = getRecorder(apiUrl, idApiKeyAdmin, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err := json.Unmarshal(w.Body.Bytes(), &space)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, space.TotalBytes > 0, true)
+	test.IsEqualBool(t, space.FreeBytes <= space.TotalBytes, true)
+
+	configuration.Get().DataDir = "invalid/folder"
+	w, r = getRecorder(apiUrl, idApiKeyAdmin, []test.Header{})
+	Process(w, r)
+	configuration.Get().DataDir = "test/data"
+	test.IsEqualInt(t, w.Code, 500)
+	test.ResponseBodyContains(t, w, "Free disk space cannot be determined on this system")
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/forceu/gokapi/internal/storage"
 	"github.com/forceu/gokapi/internal/storage/chunking"
 	"net/http"
+	"net/mail"
 	"strconv"
 	"strings"
 )
@@ -38,6 +39,13 @@ var routes = []apiRoute{
 		HasWildcard:   true,
 		RequestParser: &paramFilesListSingle{},
 	},
+	{
+		Url:           "/files/downloads/",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiDownloadHistory,
+		HasWildcard:   true,
+		RequestParser: &paramFilesDownloads{},
+	},
 	{
 		Url:           "/chunk/add",
 		ApiPerm:       models.ApiPermUpload,
@@ -50,12 +58,31 @@ var routes = []apiRoute{
 		execution:     apiChunkComplete,
 		RequestParser: &paramChunkComplete{},
 	},
+	{
+		Url:           "/chunk/status",
+		ApiPerm:       models.ApiPermUpload,
+		execution:     apiChunkStatus,
+		RequestParser: &paramChunkStatus{},
+	},
+	{
+		Url:           "/tus/",
+		ApiPerm:       models.ApiPermUpload,
+		execution:     apiTus,
+		HasWildcard:   true,
+		RequestParser: &paramTus{},
+	},
 	{
 		Url:           "/files/add",
 		ApiPerm:       models.ApiPermUpload,
 		execution:     apiUploadFile,
 		RequestParser: &paramFilesAdd{},
 	},
+	{
+		Url:           "/files/addFromUrl",
+		ApiPerm:       models.ApiPermUpload,
+		execution:     apiUploadFromUrl,
+		RequestParser: &paramFilesAddFromUrl{},
+	},
 	{
 		Url:           "/files/delete",
 		ApiPerm:       models.ApiPermDelete,
@@ -80,12 +107,48 @@ var routes = []apiRoute{
 		execution:     apiReplaceFile,
 		RequestParser: &paramFilesReplace{},
 	},
+	{
+		Url:           "/files/archive",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiArchiveContent,
+		RequestParser: &paramFilesArchive{},
+	},
+	{
+		Url:           "/files/downloadZip",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiDownloadZip,
+		RequestParser: &paramFilesDownloadZip{},
+	},
 	{
 		Url:           "/files/restore",
 		ApiPerm:       models.ApiPermDelete,
 		execution:     apiRestoreFile,
 		RequestParser: &paramFilesRestore{},
 	},
+	{
+		Url:           "/bundle/list",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiBundleList,
+		RequestParser: nil,
+	},
+	{
+		Url:           "/bundle/create",
+		ApiPerm:       models.ApiPermUpload,
+		execution:     apiBundleCreate,
+		RequestParser: &paramBundleCreate{},
+	},
+	{
+		Url:           "/bundle/modify",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiBundleModify,
+		RequestParser: &paramBundleModify{},
+	},
+	{
+		Url:           "/bundle/delete",
+		ApiPerm:       models.ApiPermDelete,
+		execution:     apiBundleDelete,
+		RequestParser: &paramBundleDelete{},
+	},
 	{
 		Url:           "/auth/create",
 		ApiPerm:       models.ApiPermApiMod,
@@ -122,6 +185,24 @@ var routes = []apiRoute{
 		execution:     apiChangeUserRank,
 		RequestParser: &paramUserChangeRank{},
 	},
+	{
+		Url:           "/user/storageTargets",
+		ApiPerm:       models.ApiPermManageUsers,
+		execution:     apiChangeUserStorageTargets,
+		RequestParser: &paramUserStorageTargets{},
+	},
+	{
+		Url:           "/user/quota",
+		ApiPerm:       models.ApiPermManageUsers,
+		execution:     apiUserQuota,
+		RequestParser: &paramUserQuota{},
+	},
+	{
+		Url:           "/user/notifications",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiUserNotifications,
+		RequestParser: &paramUserNotifications{},
+	},
 	{
 		Url:           "/user/delete",
 		ApiPerm:       models.ApiPermManageUsers,
@@ -146,6 +227,24 @@ var routes = []apiRoute{
 		execution:     apiLogsDelete,
 		RequestParser: &paramLogsDelete{},
 	},
+	{
+		Url:           "/scrub/start",
+		ApiPerm:       models.ApiPermManageLogs,
+		execution:     apiScrubStart,
+		RequestParser: &paramScrubStart{},
+	},
+	{
+		Url:           "/scrub/status",
+		ApiPerm:       models.ApiPermManageLogs,
+		execution:     apiScrubStatus,
+		RequestParser: nil,
+	},
+	{
+		Url:           "/system/diskSpace",
+		ApiPerm:       models.ApiPermManageLogs,
+		execution:     apiDiskSpace,
+		RequestParser: nil,
+	},
 }
 
 func getRouting(requestUrl string) (apiRoute, bool) {
@@ -176,6 +275,15 @@ func (p *paramFilesListSingle) ProcessParameter(r *http.Request) error {
 	return nil
 }
 
+type paramFilesDownloads struct {
+	RequestUrl string
+}
+
+func (p *paramFilesDownloads) ProcessParameter(r *http.Request) error {
+	p.RequestUrl = parseRequestUrl(r)
+	return nil
+}
+
 type paramFilesAdd struct {
 	Request *http.Request
 }
@@ -185,6 +293,35 @@ func (p *paramFilesAdd) ProcessParameter(r *http.Request) error {
 	return nil
 }
 
+type paramFilesAddFromUrl struct {
+	Url                string `header:"url" required:"true"`
+	FileName           string `header:"filename"`
+	ContentType        string `header:"contenttype"`
+	AllowedDownloads   int    `header:"allowedDownloads"`
+	ExpiryDays         int    `header:"expiryDays"`
+	Password           string `header:"password"`
+	IsNonBlocking      bool   `header:"nonblocking"`
+	StorageTarget      string `header:"storageTarget"`
+	Checksum           string `header:"checksum"`
+	UnlimitedDownloads bool
+	UnlimitedTime      bool
+	foundHeaders       map[string]bool
+}
+
+func (p *paramFilesAddFromUrl) ProcessParameter(_ *http.Request) error {
+	// Same defaults as for /files/add
+	if !p.foundHeaders["allowedDownloads"] {
+		p.AllowedDownloads = 1
+	}
+	if !p.foundHeaders["expiryDays"] {
+		p.ExpiryDays = 14
+	}
+	p.UnlimitedDownloads = p.AllowedDownloads == 0
+	p.UnlimitedTime = p.ExpiryDays == 0
+	_, err := chunking.ParseChecksum(p.Checksum)
+	return err
+}
+
 type paramFilesDuplicate struct {
 	Id                 string `header:"id" required:"true"`
 	AllowedDownloads   int    `header:"allowedDownloads"`
@@ -228,9 +365,13 @@ type paramFilesModify struct {
 	ExpiryTimestamp    int64  `header:"expiryTimestamp"`
 	Password           string `header:"password"`
 	KeepPassword       bool   `header:"originalPassword"`
+	BandwidthLimitKB   int    `header:"bandwidthLimitKB"`
+	ParallelDownloads  int    `header:"parallelDownloads"`
 	UnlimitedDownloads bool
 	UnlimitedExpiry    bool
 	IsPasswordSet      bool
+	IsBandwidthSet     bool
+	IsParallelSet      bool
 	foundHeaders       map[string]bool
 }
 
@@ -242,6 +383,14 @@ func (p *paramFilesModify) ProcessParameter(_ *http.Request) error {
 		p.UnlimitedExpiry = true
 	}
 	p.IsPasswordSet = p.foundHeaders["password"]
+	p.IsBandwidthSet = p.foundHeaders["bandwidthLimitKB"]
+	if p.BandwidthLimitKB < 0 {
+		return errors.New("bandwidthLimitKB must not be negative")
+	}
+	p.IsParallelSet = p.foundHeaders["parallelDownloads"]
+	if p.ParallelDownloads < 0 {
+		return errors.New("parallelDownloads must not be negative")
+	}
 	return nil
 }
 
@@ -269,6 +418,109 @@ type paramFilesRestore struct {
 
 func (p *paramFilesRestore) ProcessParameter(_ *http.Request) error { return nil }
 
+type paramFilesArchive struct {
+	Id           string `header:"id" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramFilesArchive) ProcessParameter(_ *http.Request) error { return nil }
+
+type paramFilesDownloadZip struct {
+	fileIdsRaw   string `header:"fileIds" required:"true"`
+	FileIds      []string
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramFilesDownloadZip) ProcessParameter(r *http.Request) error {
+	p.FileIds = parseFileIds(p.fileIdsRaw)
+	if len(p.FileIds) == 0 {
+		return errors.New("at least one file ID is required")
+	}
+	p.Request = r
+	return nil
+}
+
+type paramBundleCreate struct {
+	fileIdsRaw         string `header:"fileIds" required:"true"`
+	Name               string `header:"name"`
+	AllowedDownloads   int    `header:"allowedDownloads"`
+	ExpiryDays         int    `header:"expiryDays"`
+	Password           string `header:"password"`
+	FileIds            []string
+	UnlimitedDownloads bool
+	UnlimitedTime      bool
+	foundHeaders       map[string]bool
+}
+
+func (p *paramBundleCreate) ProcessParameter(_ *http.Request) error {
+	// Same defaults as for /files/add
+	if !p.foundHeaders["allowedDownloads"] {
+		p.AllowedDownloads = 1
+	}
+	if !p.foundHeaders["expiryDays"] {
+		p.ExpiryDays = 14
+	}
+	p.UnlimitedDownloads = p.AllowedDownloads == 0
+	p.UnlimitedTime = p.ExpiryDays == 0
+	p.FileIds = parseFileIds(p.fileIdsRaw)
+	if len(p.FileIds) == 0 {
+		return storage.ErrorBundleNoFiles
+	}
+	return nil
+}
+
+type paramBundleModify struct {
+	Id                 string `header:"id" required:"true"`
+	Name               string `header:"name"`
+	fileIdsRaw         string `header:"fileIds"`
+	AllowedDownloads   int    `header:"allowedDownloads"`
+	ExpiryTimestamp    int64  `header:"expiryTimestamp"`
+	Password           string `header:"password"`
+	KeepPassword       bool   `header:"originalPassword"`
+	FileIds            []string
+	UnlimitedDownloads bool
+	UnlimitedExpiry    bool
+	IsNameSet          bool
+	foundHeaders       map[string]bool
+}
+
+func (p *paramBundleModify) ProcessParameter(_ *http.Request) error {
+	if p.foundHeaders["allowedDownloads"] && p.AllowedDownloads == 0 {
+		p.UnlimitedDownloads = true
+	}
+	if p.foundHeaders["expiryTimestamp"] && p.ExpiryTimestamp == 0 {
+		p.UnlimitedExpiry = true
+	}
+	if p.foundHeaders["fileIds"] {
+		p.FileIds = parseFileIds(p.fileIdsRaw)
+		if len(p.FileIds) == 0 {
+			return storage.ErrorBundleNoFiles
+		}
+	}
+	p.IsNameSet = p.foundHeaders["name"]
+	return nil
+}
+
+type paramBundleDelete struct {
+	Id           string `header:"id" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramBundleDelete) ProcessParameter(_ *http.Request) error { return nil }
+
+// parseFileIds returns the IDs of a comma-separated list, ignoring empty entries
+func parseFileIds(input string) []string {
+	result := make([]string, 0)
+	for _, id := range strings.Split(input, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
 type paramAuthCreate struct {
 	FriendlyName     string `header:"friendlyName"`
 	BasicPermissions bool   `header:"basicPermissions"`
@@ -359,6 +611,56 @@ func (p *paramUserChangeRank) ProcessParameter(_ *http.Request) error {
 	return nil
 }
 
+type paramUserStorageTargets struct {
+	Id             int    `header:"userid" required:"true"`
+	DefaultTarget  string `header:"defaultTarget"`
+	AllowedTargets string `header:"allowedTargets"`
+	foundHeaders   map[string]bool
+}
+
+func (p *paramUserStorageTargets) ProcessParameter(_ *http.Request) error {
+	user := models.User{StorageTargets: p.AllowedTargets}
+	p.AllowedTargets = strings.Join(user.GetStorageTargets(), ",")
+	p.DefaultTarget = strings.TrimSpace(p.DefaultTarget)
+	return nil
+}
+
+type paramUserQuota struct {
+	Id           int   `header:"userid" required:"true"`
+	QuotaBytes   int64 `header:"quotaBytes"`
+	QuotaFiles   int   `header:"quotaFiles"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramUserQuota) ProcessParameter(_ *http.Request) error {
+	if p.QuotaBytes < 0 || p.QuotaFiles < 0 {
+		return errors.New("quota must not be negative")
+	}
+	return nil
+}
+
+type paramUserNotifications struct {
+	Email        string `header:"notificationEmail"`
+	Download     int    `header:"notifyDownload"`
+	Expiry       bool   `header:"notifyExpiry"`
+	Deletion     bool   `header:"notifyDeletion"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramUserNotifications) ProcessParameter(_ *http.Request) error {
+	p.Email = strings.TrimSpace(p.Email)
+	if p.Email != "" {
+		address, err := mail.ParseAddress(p.Email)
+		if err != nil || address.Address != p.Email {
+			return errors.New("invalid email address")
+		}
+	}
+	if p.Download < models.NotifyDownloadNever || p.Download > models.NotifyDownloadEvery {
+		return errors.New("invalid value for notifyDownload")
+	}
+	return nil
+}
+
 type paramUserDelete struct {
 	Id           int  `header:"userid" required:"true"`
 	DeleteFiles  bool `header:"deleteFiles"`
@@ -427,6 +729,31 @@ func (p *paramLogsDelete) ProcessParameter(r *http.Request) error {
 	return nil
 }
 
+type paramScrubStart struct {
+	Quarantine   bool `header:"quarantine"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramScrubStart) ProcessParameter(_ *http.Request) error { return nil }
+
+type paramChunkStatus struct {
+	Uuid         string `header:"uuid" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramChunkStatus) ProcessParameter(_ *http.Request) error { return nil }
+
+type paramTus struct {
+	Request    *http.Request
+	RequestUrl string
+}
+
+func (p *paramTus) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	p.RequestUrl = parseRequestUrl(r)
+	return nil
+}
+
 type paramChunkAdd struct {
 	Request *http.Request
 }
@@ -447,6 +774,8 @@ type paramChunkComplete struct {
 	Password           string `header:"password"`
 	IsE2E              bool   `header:"isE2E"`
 	IsNonBlocking      bool   `header:"nonblocking"`
+	StorageTarget      string `header:"storageTarget"`
+	Checksum           string `header:"checksum"`
 	UnlimitedDownloads bool
 	UnlimitedTime      bool
 	FileHeader         chunking.FileHeader
@@ -474,7 +803,8 @@ func (p *paramChunkComplete) ProcessParameter(_ *http.Request) error {
 		ContentType: p.ContentType,
 		Size:        p.FileSize,
 	}
-	return nil
+	_, err := chunking.ParseChecksum(p.Checksum)
+	return err
 }
 
 func checkHeaderExists(r *http.Request, key string, isRequired, isString bool) (bool, error) {
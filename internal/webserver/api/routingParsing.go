@@ -20,6 +20,17 @@ func (p *paramFilesListSingle) New() requestParser {
 	return &paramFilesListSingle{}
 }
 
+// ParseRequest parses the header file. As paramFilesDownloads has no fields with the
+// tag header, this method does nothing, except calling ProcessParameter()
+func (p *paramFilesDownloads) ParseRequest(r *http.Request) error {
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesDownloads struct
+func (p *paramFilesDownloads) New() requestParser {
+	return &paramFilesDownloads{}
+}
+
 // ParseRequest parses the header file. As paramFilesAdd has no fields with the
 // tag header, this method does nothing, except calling ProcessParameter()
 func (p *paramFilesAdd) ParseRequest(r *http.Request) error {
@@ -31,6 +42,120 @@ func (p *paramFilesAdd) New() requestParser {
 	return &paramFilesAdd{}
 }
 
+// ParseRequest reads r and saves the passed header values in the paramFilesAddFromUrl struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesAddFromUrl) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "url", required: true
+	exists, err = checkHeaderExists(r, "url", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["url"] = exists
+	if exists {
+		p.Url = r.Header.Get("url")
+	}
+
+	// RequestParser header value "filename", required: false
+	exists, err = checkHeaderExists(r, "filename", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["filename"] = exists
+	if exists {
+		p.FileName = r.Header.Get("filename")
+	}
+
+	// RequestParser header value "contenttype", required: false
+	exists, err = checkHeaderExists(r, "contenttype", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["contenttype"] = exists
+	if exists {
+		p.ContentType = r.Header.Get("contenttype")
+	}
+
+	// RequestParser header value "allowedDownloads", required: false
+	exists, err = checkHeaderExists(r, "allowedDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedDownloads"] = exists
+	if exists {
+		p.AllowedDownloads, err = parseHeaderInt(r, "allowedDownloads")
+		if err != nil {
+			return fmt.Errorf("invalid value in header allowedDownloads supplied")
+		}
+	}
+
+	// RequestParser header value "expiryDays", required: false
+	exists, err = checkHeaderExists(r, "expiryDays", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryDays"] = exists
+	if exists {
+		p.ExpiryDays, err = parseHeaderInt(r, "expiryDays")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryDays supplied")
+		}
+	}
+
+	// RequestParser header value "password", required: false
+	exists, err = checkHeaderExists(r, "password", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["password"] = exists
+	if exists {
+		p.Password = r.Header.Get("password")
+	}
+
+	// RequestParser header value "nonblocking", required: false
+	exists, err = checkHeaderExists(r, "nonblocking", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["nonblocking"] = exists
+	if exists {
+		p.IsNonBlocking, err = parseHeaderBool(r, "nonblocking")
+		if err != nil {
+			return fmt.Errorf("invalid value in header nonblocking supplied")
+		}
+	}
+
+	// RequestParser header value "storageTarget", required: false
+	exists, err = checkHeaderExists(r, "storageTarget", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["storageTarget"] = exists
+	if exists {
+		p.StorageTarget = r.Header.Get("storageTarget")
+	}
+
+	// RequestParser header value "checksum", required: false
+	exists, err = checkHeaderExists(r, "checksum", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["checksum"] = exists
+	if exists {
+		p.Checksum = r.Header.Get("checksum")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesAddFromUrl struct
+func (p *paramFilesAddFromUrl) New() requestParser {
+	return &paramFilesAddFromUrl{}
+}
+
 // ParseRequest reads r and saves the passed header values in the paramFilesDuplicate struct
 // In the end, ProcessParameter() is called
 func (p *paramFilesDuplicate) ParseRequest(r *http.Request) error {
@@ -181,6 +306,32 @@ func (p *paramFilesModify) ParseRequest(r *http.Request) error {
 		}
 	}
 
+	// RequestParser header value "bandwidthLimitKB", required: false
+	exists, err = checkHeaderExists(r, "bandwidthLimitKB", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["bandwidthLimitKB"] = exists
+	if exists {
+		p.BandwidthLimitKB, err = parseHeaderInt(r, "bandwidthLimitKB")
+		if err != nil {
+			return fmt.Errorf("invalid value in header bandwidthLimitKB supplied")
+		}
+	}
+
+	// RequestParser header value "parallelDownloads", required: false
+	exists, err = checkHeaderExists(r, "parallelDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["parallelDownloads"] = exists
+	if exists {
+		p.ParallelDownloads, err = parseHeaderInt(r, "parallelDownloads")
+		if err != nil {
+			return fmt.Errorf("invalid value in header parallelDownloads supplied")
+		}
+	}
+
 	return p.ProcessParameter(r)
 }
 
@@ -300,6 +451,246 @@ func (p *paramFilesRestore) New() requestParser {
 	return &paramFilesRestore{}
 }
 
+// ParseRequest reads r and saves the passed header values in the paramFilesArchive struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesArchive) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesArchive struct
+func (p *paramFilesArchive) New() requestParser {
+	return &paramFilesArchive{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramFilesDownloadZip struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesDownloadZip) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "fileIds", required: true
+	exists, err = checkHeaderExists(r, "fileIds", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileIds"] = exists
+	if exists {
+		p.fileIdsRaw = r.Header.Get("fileIds")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesDownloadZip struct
+func (p *paramFilesDownloadZip) New() requestParser {
+	return &paramFilesDownloadZip{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramBundleCreate struct
+// In the end, ProcessParameter() is called
+func (p *paramBundleCreate) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "fileIds", required: true
+	exists, err = checkHeaderExists(r, "fileIds", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileIds"] = exists
+	if exists {
+		p.fileIdsRaw = r.Header.Get("fileIds")
+	}
+
+	// RequestParser header value "name", required: false
+	exists, err = checkHeaderExists(r, "name", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["name"] = exists
+	if exists {
+		p.Name = r.Header.Get("name")
+	}
+
+	// RequestParser header value "allowedDownloads", required: false
+	exists, err = checkHeaderExists(r, "allowedDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedDownloads"] = exists
+	if exists {
+		p.AllowedDownloads, err = parseHeaderInt(r, "allowedDownloads")
+		if err != nil {
+			return fmt.Errorf("invalid value in header allowedDownloads supplied")
+		}
+	}
+
+	// RequestParser header value "expiryDays", required: false
+	exists, err = checkHeaderExists(r, "expiryDays", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryDays"] = exists
+	if exists {
+		p.ExpiryDays, err = parseHeaderInt(r, "expiryDays")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryDays supplied")
+		}
+	}
+
+	// RequestParser header value "password", required: false
+	exists, err = checkHeaderExists(r, "password", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["password"] = exists
+	if exists {
+		p.Password = r.Header.Get("password")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramBundleCreate struct
+func (p *paramBundleCreate) New() requestParser {
+	return &paramBundleCreate{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramBundleModify struct
+// In the end, ProcessParameter() is called
+func (p *paramBundleModify) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	// RequestParser header value "name", required: false
+	exists, err = checkHeaderExists(r, "name", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["name"] = exists
+	if exists {
+		p.Name = r.Header.Get("name")
+	}
+
+	// RequestParser header value "fileIds", required: false
+	exists, err = checkHeaderExists(r, "fileIds", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileIds"] = exists
+	if exists {
+		p.fileIdsRaw = r.Header.Get("fileIds")
+	}
+
+	// RequestParser header value "allowedDownloads", required: false
+	exists, err = checkHeaderExists(r, "allowedDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedDownloads"] = exists
+	if exists {
+		p.AllowedDownloads, err = parseHeaderInt(r, "allowedDownloads")
+		if err != nil {
+			return fmt.Errorf("invalid value in header allowedDownloads supplied")
+		}
+	}
+
+	// RequestParser header value "expiryTimestamp", required: false
+	exists, err = checkHeaderExists(r, "expiryTimestamp", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryTimestamp"] = exists
+	if exists {
+		p.ExpiryTimestamp, err = parseHeaderInt64(r, "expiryTimestamp")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryTimestamp supplied")
+		}
+	}
+
+	// RequestParser header value "password", required: false
+	exists, err = checkHeaderExists(r, "password", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["password"] = exists
+	if exists {
+		p.Password = r.Header.Get("password")
+	}
+
+	// RequestParser header value "originalPassword", required: false
+	exists, err = checkHeaderExists(r, "originalPassword", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["originalPassword"] = exists
+	if exists {
+		p.KeepPassword, err = parseHeaderBool(r, "originalPassword")
+		if err != nil {
+			return fmt.Errorf("invalid value in header originalPassword supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramBundleModify struct
+func (p *paramBundleModify) New() requestParser {
+	return &paramBundleModify{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramBundleDelete struct
+// In the end, ProcessParameter() is called
+func (p *paramBundleDelete) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramBundleDelete struct
+func (p *paramBundleDelete) New() requestParser {
+	return &paramBundleDelete{}
+}
+
 // ParseRequest reads r and saves the passed header values in the paramAuthCreate struct
 // In the end, ProcessParameter() is called
 func (p *paramAuthCreate) ParseRequest(r *http.Request) error {
@@ -506,6 +897,172 @@ func (p *paramUserChangeRank) New() requestParser {
 	return &paramUserChangeRank{}
 }
 
+// ParseRequest reads r and saves the passed header values in the paramUserStorageTargets struct
+// In the end, ProcessParameter() is called
+func (p *paramUserStorageTargets) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "userid", required: true
+	exists, err = checkHeaderExists(r, "userid", true, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["userid"] = exists
+	if exists {
+		p.Id, err = parseHeaderInt(r, "userid")
+		if err != nil {
+			return fmt.Errorf("invalid value in header userid supplied")
+		}
+	}
+
+	// RequestParser header value "defaultTarget", required: false
+	exists, err = checkHeaderExists(r, "defaultTarget", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["defaultTarget"] = exists
+	if exists {
+		p.DefaultTarget = r.Header.Get("defaultTarget")
+	}
+
+	// RequestParser header value "allowedTargets", required: false
+	exists, err = checkHeaderExists(r, "allowedTargets", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedTargets"] = exists
+	if exists {
+		p.AllowedTargets = r.Header.Get("allowedTargets")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramUserStorageTargets struct
+func (p *paramUserStorageTargets) New() requestParser {
+	return &paramUserStorageTargets{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramUserQuota struct
+// In the end, ProcessParameter() is called
+func (p *paramUserQuota) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "userid", required: true
+	exists, err = checkHeaderExists(r, "userid", true, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["userid"] = exists
+	if exists {
+		p.Id, err = parseHeaderInt(r, "userid")
+		if err != nil {
+			return fmt.Errorf("invalid value in header userid supplied")
+		}
+	}
+
+	// RequestParser header value "quotaBytes", required: false
+	exists, err = checkHeaderExists(r, "quotaBytes", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quotaBytes"] = exists
+	if exists {
+		p.QuotaBytes, err = parseHeaderInt64(r, "quotaBytes")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quotaBytes supplied")
+		}
+	}
+
+	// RequestParser header value "quotaFiles", required: false
+	exists, err = checkHeaderExists(r, "quotaFiles", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quotaFiles"] = exists
+	if exists {
+		p.QuotaFiles, err = parseHeaderInt(r, "quotaFiles")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quotaFiles supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramUserQuota struct
+func (p *paramUserQuota) New() requestParser {
+	return &paramUserQuota{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramUserNotifications struct
+// In the end, ProcessParameter() is called
+func (p *paramUserNotifications) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "notificationEmail", required: false
+	exists, err = checkHeaderExists(r, "notificationEmail", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["notificationEmail"] = exists
+	if exists {
+		p.Email = r.Header.Get("notificationEmail")
+	}
+
+	// RequestParser header value "notifyDownload", required: false
+	exists, err = checkHeaderExists(r, "notifyDownload", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["notifyDownload"] = exists
+	if exists {
+		p.Download, err = parseHeaderInt(r, "notifyDownload")
+		if err != nil {
+			return fmt.Errorf("invalid value in header notifyDownload supplied")
+		}
+	}
+
+	// RequestParser header value "notifyExpiry", required: false
+	exists, err = checkHeaderExists(r, "notifyExpiry", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["notifyExpiry"] = exists
+	if exists {
+		p.Expiry, err = parseHeaderBool(r, "notifyExpiry")
+		if err != nil {
+			return fmt.Errorf("invalid value in header notifyExpiry supplied")
+		}
+	}
+
+	// RequestParser header value "notifyDeletion", required: false
+	exists, err = checkHeaderExists(r, "notifyDeletion", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["notifyDeletion"] = exists
+	if exists {
+		p.Deletion, err = parseHeaderBool(r, "notifyDeletion")
+		if err != nil {
+			return fmt.Errorf("invalid value in header notifyDeletion supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramUserNotifications struct
+func (p *paramUserNotifications) New() requestParser {
+	return &paramUserNotifications{}
+}
+
 // ParseRequest reads r and saves the passed header values in the paramUserDelete struct
 // In the end, ProcessParameter() is called
 func (p *paramUserDelete) ParseRequest(r *http.Request) error {
@@ -664,6 +1221,70 @@ func (p *paramLogsDelete) New() requestParser {
 	return &paramLogsDelete{}
 }
 
+// ParseRequest reads r and saves the passed header values in the paramScrubStart struct
+// In the end, ProcessParameter() is called
+func (p *paramScrubStart) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "quarantine", required: false
+	exists, err = checkHeaderExists(r, "quarantine", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quarantine"] = exists
+	if exists {
+		p.Quarantine, err = parseHeaderBool(r, "quarantine")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quarantine supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramScrubStart struct
+func (p *paramScrubStart) New() requestParser {
+	return &paramScrubStart{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramChunkStatus struct
+// In the end, ProcessParameter() is called
+func (p *paramChunkStatus) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "uuid", required: true
+	exists, err = checkHeaderExists(r, "uuid", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["uuid"] = exists
+	if exists {
+		p.Uuid = r.Header.Get("uuid")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramChunkStatus struct
+func (p *paramChunkStatus) New() requestParser {
+	return &paramChunkStatus{}
+}
+
+// ParseRequest parses the header file. As paramTus has no fields with the
+// tag header, this method does nothing, except calling ProcessParameter()
+func (p *paramTus) ParseRequest(r *http.Request) error {
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramTus struct
+func (p *paramTus) New() requestParser {
+	return &paramTus{}
+}
+
 // ParseRequest parses the header file. As paramChunkAdd has no fields with the
 // tag header, this method does nothing, except calling ProcessParameter()
 func (p *paramChunkAdd) ParseRequest(r *http.Request) error {
@@ -800,6 +1421,26 @@ func (p *paramChunkComplete) ParseRequest(r *http.Request) error {
 		}
 	}
 
+	// RequestParser header value "storageTarget", required: false
+	exists, err = checkHeaderExists(r, "storageTarget", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["storageTarget"] = exists
+	if exists {
+		p.StorageTarget = r.Header.Get("storageTarget")
+	}
+
+	// RequestParser header value "checksum", required: false
+	exists, err = checkHeaderExists(r, "checksum", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["checksum"] = exists
+	if exists {
+		p.Checksum = r.Header.Get("checksum")
+	}
+
 	return p.ProcessParameter(r)
 }
 
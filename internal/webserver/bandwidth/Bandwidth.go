@@ -0,0 +1,153 @@
+package bandwidth
+
+/**
+Limiting the bandwidth that is used for downloads
+*/
+
+import (
+	"github.com/juju/ratelimit"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxWriteSize is the maximum number of bytes that are written at once, so that
+// concurrent downloads share the available bandwidth evenly
+const maxWriteSize = 32 * 1024
+
+// Limits contains the bandwidth limits for a download in KB/s. A limit of 0 disables it
+type Limits struct {
+	// Global is the bandwidth that all downloads share
+	Global int
+	// File is the bandwidth that all downloads of the same file share
+	File int
+	// Download is the bandwidth of a single download
+	Download int
+}
+
+type sharedBucket struct {
+	bucket    *ratelimit.Bucket
+	limitKB   int
+	downloads int
+}
+
+var globalBucket *sharedBucket
+var fileBuckets = make(map[string]*sharedBucket)
+var mutex sync.Mutex
+
+// limitedWriter is a http.ResponseWriter that waits for all buckets before writing
+type limitedWriter struct {
+	http.ResponseWriter
+	buckets []*ratelimit.Bucket
+}
+
+// Write sends p to the client, once the bandwidth is available
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	return writeLimited(l.ResponseWriter, l.buckets, p)
+}
+
+// limitedStream is an io.Writer that waits for all buckets before writing
+type limitedStream struct {
+	writer  io.Writer
+	buckets []*ratelimit.Bucket
+}
+
+// Write writes p to the underlying writer, once the bandwidth is available
+func (l *limitedStream) Write(p []byte) (int, error) {
+	return writeLimited(l.writer, l.buckets, p)
+}
+
+// writeLimited writes p in parts of up to maxWriteSize bytes to w, waiting for all buckets before every part
+func writeLimited(w io.Writer, buckets []*ratelimit.Bucket, p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := min(written+maxWriteSize, len(p))
+		for _, bucket := range buckets {
+			bucket.Wait(int64(end - written))
+		}
+		n, err := w.Write(p[written:end])
+		written = written + n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// LimitWriter returns a writer that does not exceed the bandwidth limits for the download of
+// the file with the given ID. The returned function has to be called, once the download has finished
+func LimitWriter(w http.ResponseWriter, fileId string, limits Limits) (http.ResponseWriter, func()) {
+	buckets, release := getBuckets(fileId, limits)
+	if len(buckets) == 0 {
+		return w, release
+	}
+	return &limitedWriter{ResponseWriter: w, buckets: buckets}, release
+}
+
+// LimitStream is the same as LimitWriter, but for content that is not written directly to the client,
+// e.g. a file that is added to a ZIP file. The returned function has to be called, once the content has been written
+func LimitStream(w io.Writer, fileId string, limits Limits) (io.Writer, func()) {
+	buckets, release := getBuckets(fileId, limits)
+	if len(buckets) == 0 {
+		return w, release
+	}
+	return &limitedStream{writer: w, buckets: buckets}, release
+}
+
+// getBuckets returns all buckets that a download of the file with the given ID has to wait for and
+// a function that releases the shared buckets
+func getBuckets(fileId string, limits Limits) ([]*ratelimit.Bucket, func()) {
+	buckets := make([]*ratelimit.Bucket, 0)
+	shared := make([]*sharedBucket, 0)
+	if limits.Download > 0 {
+		buckets = append(buckets, newBucket(limits.Download))
+	}
+	mutex.Lock()
+	if limits.File > 0 {
+		fileBucket := getSharedBucket(fileBuckets[fileId], limits.File)
+		fileBuckets[fileId] = fileBucket
+		shared = append(shared, fileBucket)
+	}
+	if limits.Global > 0 {
+		globalBucket = getSharedBucket(globalBucket, limits.Global)
+		shared = append(shared, globalBucket)
+	}
+	for _, bucket := range shared {
+		bucket.downloads++
+		buckets = append(buckets, bucket.bucket)
+	}
+	mutex.Unlock()
+
+	if len(buckets) == 0 {
+		return buckets, func() {}
+	}
+	release := func() {
+		mutex.Lock()
+		for _, bucket := range shared {
+			bucket.downloads--
+		}
+		if fileBuckets[fileId] != nil && fileBuckets[fileId].downloads == 0 {
+			delete(fileBuckets, fileId)
+		}
+		mutex.Unlock()
+	}
+	return buckets, release
+}
+
+// getSharedBucket returns the existing bucket, or a new one if it does not exist yet or the
+// limit has been changed. Downloads that are already running keep using the previous bucket
+func getSharedBucket(existing *sharedBucket, limitKB int) *sharedBucket {
+	if existing != nil && existing.limitKB == limitKB {
+		return existing
+	}
+	return &sharedBucket{
+		bucket:  newBucket(limitKB),
+		limitKB: limitKB,
+	}
+}
+
+// newBucket returns a bucket for the given limit in KB/s, that allows a burst of a quarter second
+func newBucket(limitKB int) *ratelimit.Bucket {
+	rate := float64(limitKB) * 1024
+	return ratelimit.NewBucketWithRate(rate, max(int64(rate/4), 1))
+}
@@ -0,0 +1,86 @@
+package bandwidth
+
+import (
+	"bytes"
+	"github.com/forceu/gokapi/internal/test"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimitWriterUnlimited(t *testing.T) {
+	w := httptest.NewRecorder()
+	result, release := LimitWriter(w, "unlimited", Limits{})
+	test.IsEqualBool(t, result == w, true)
+	release()
+	test.IsEqualInt(t, len(fileBuckets), 0)
+}
+
+func TestLimitWriter(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	w := httptest.NewRecorder()
+	start := time.Now()
+	result, release := LimitWriter(w, "limited", Limits{Download: 16})
+	n, err := result.Write(content)
+	release()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, n, len(content))
+	test.IsEqualByteSlice(t, w.Body.Bytes(), content)
+	// 4KB can be sent immediately, the remaining 12KB take 0.75 seconds
+	test.IsEqualBool(t, time.Since(start) >= 500*time.Millisecond, true)
+	test.IsEqualInt(t, len(fileBuckets), 0)
+}
+
+func TestSharedBuckets(t *testing.T) {
+	limits := Limits{Global: 1024, File: 512}
+	first, releaseFirst := LimitWriter(httptest.NewRecorder(), "shared", limits)
+	second, releaseSecond := LimitWriter(httptest.NewRecorder(), "shared", limits)
+	other, releaseOther := LimitWriter(httptest.NewRecorder(), "other", limits)
+	firstBuckets := first.(*limitedWriter).buckets
+	secondBuckets := second.(*limitedWriter).buckets
+	otherBuckets := other.(*limitedWriter).buckets
+	test.IsEqualInt(t, len(firstBuckets), 2)
+	test.IsEqualBool(t, firstBuckets[0] == secondBuckets[0], true)
+	test.IsEqualBool(t, firstBuckets[0] == otherBuckets[0], false)
+	test.IsEqualBool(t, firstBuckets[1] == otherBuckets[1], true)
+	test.IsEqualInt(t, len(fileBuckets), 2)
+	test.IsEqualInt(t, fileBuckets["shared"].downloads, 2)
+
+	// A changed limit applies to new downloads
+	limits.File = 256
+	changed, releaseChanged := LimitWriter(httptest.NewRecorder(), "shared", limits)
+	changedBuckets := changed.(*limitedWriter).buckets
+	test.IsEqualBool(t, firstBuckets[0] == changedBuckets[0], false)
+	test.IsEqualInt(t, fileBuckets["shared"].limitKB, 256)
+
+	releaseFirst()
+	releaseSecond()
+	releaseOther()
+	test.IsEqualInt(t, len(fileBuckets), 1)
+	releaseChanged()
+	test.IsEqualInt(t, len(fileBuckets), 0)
+	test.IsEqualInt(t, globalBucket.downloads, 0)
+
+	withDownload, releaseDownload := LimitWriter(httptest.NewRecorder(), "shared", Limits{File: 256, Download: 128})
+	test.IsEqualInt(t, len(withDownload.(*limitedWriter).buckets), 2)
+	releaseDownload()
+}
+
+func TestLimitStream(t *testing.T) {
+	var buf bytes.Buffer
+	result, release := LimitStream(&buf, "stream", Limits{})
+	test.IsEqualBool(t, result == &buf, true)
+	release()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	start := time.Now()
+	result, release = LimitStream(&buf, "stream", Limits{File: 16})
+	test.IsEqualInt(t, fileBuckets["stream"].downloads, 1)
+	n, err := result.Write(content)
+	release()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, n, len(content))
+	test.IsEqualByteSlice(t, buf.Bytes(), content)
+	test.IsEqualBool(t, time.Since(start) >= 500*time.Millisecond, true)
+	test.IsEqualInt(t, len(fileBuckets), 0)
+}
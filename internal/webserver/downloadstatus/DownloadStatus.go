@@ -19,6 +19,84 @@ func SetDownload(file models.File) string {
 	return newStatus.Id
 }
 
+// SetDownloadIfAllowed creates a new DownloadStatus struct and returns its Id, if neither the file nor
+// the client exceed the maximum number of simultaneous downloads. A maximum of 0 disables the check.
+// Returns false, if a limit has been reached
+func SetDownloadIfAllowed(file models.File, clientIp string, maxFile, maxClient int) (string, bool) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+	downloadsFile, downloadsClient := getActiveDownloads(clientIp)
+	if (maxFile > 0 && downloadsFile[file.Id] >= maxFile) || (maxClient > 0 && downloadsClient >= maxClient) {
+		return "", false
+	}
+	newStatus := newDownloadStatus(file)
+	newStatus.ClientIp = clientIp
+	statusMap[newStatus.Id] = newStatus
+	return newStatus.Id, true
+}
+
+// SetDownloadsIfAllowed creates a new DownloadStatus struct for every file that is sent as part of a
+// single download, e.g. a ZIP file, and returns their Ids. Either a status is created for all files or
+// for none of them. The download counts as a single download of the client. maxFile returns the maximum
+// number of simultaneous downloads for a file. A maximum of 0 disables the check.
+// Returns false, if a limit has been reached
+func SetDownloadsIfAllowed(files []models.File, clientIp string, maxFile func(file models.File) int, maxClient int) ([]string, bool) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+	downloadsFile, downloadsClient := getActiveDownloads(clientIp)
+	if maxClient > 0 && downloadsClient >= maxClient {
+		return nil, false
+	}
+	for _, file := range files {
+		limit := maxFile(file)
+		if limit > 0 && downloadsFile[file.Id] >= limit {
+			return nil, false
+		}
+		downloadsFile[file.Id]++
+	}
+	result := make([]string, len(files))
+	for i, file := range files {
+		newStatus := newDownloadStatus(file)
+		// Only one status is assigned to the client, so that the files count as a single download
+		if i == 0 {
+			newStatus.ClientIp = clientIp
+		}
+		statusMap[newStatus.Id] = newStatus
+		result[i] = newStatus.Id
+	}
+	return result, true
+}
+
+// getActiveDownloads returns the number of active downloads for every file ID and the number of active
+// downloads of the client. Downloads that have been redirected are not counted. statusMutex has to be locked
+func getActiveDownloads(clientIp string) (map[string]int, int) {
+	downloadsFile := make(map[string]int)
+	downloadsClient := 0
+	now := time.Now().Unix()
+	for _, status := range statusMap {
+		if status.IsRedirected || status.ExpireAt < now {
+			continue
+		}
+		downloadsFile[status.FileId]++
+		if status.ClientIp == clientIp {
+			downloadsClient++
+		}
+	}
+	return downloadsFile, downloadsClient
+}
+
+// SetRedirected marks the download as redirected to a different server. The file is still regarded
+// as being downloaded, but the download does not count towards the limit of simultaneous downloads
+func SetRedirected(downloadStatusId string) {
+	statusMutex.Lock()
+	status, ok := statusMap[downloadStatusId]
+	if ok {
+		status.IsRedirected = true
+		statusMap[downloadStatusId] = status
+	}
+	statusMutex.Unlock()
+}
+
 // SetComplete removes the download object
 func SetComplete(downloadStatusId string) {
 	statusMutex.Lock()
@@ -104,3 +104,71 @@ func TestSetAllComplete(t *testing.T) {
 	_, ok = statusMap[status2]
 	test.IsEqualBool(t, ok, false)
 }
+
+func TestSetDownloadIfAllowed(t *testing.T) {
+	DeleteAll()
+	first, ok := SetDownloadIfAllowed(models.File{Id: "limited"}, "192.0.2.1", 2, 0)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, statusMap[first].ClientIp, "192.0.2.1")
+	_, ok = SetDownloadIfAllowed(models.File{Id: "limited"}, "192.0.2.2", 2, 0)
+	test.IsEqualBool(t, ok, true)
+	_, ok = SetDownloadIfAllowed(models.File{Id: "limited"}, "192.0.2.3", 2, 0)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualInt(t, len(statusMap), 2)
+	_, ok = SetDownloadIfAllowed(models.File{Id: "limited"}, "192.0.2.3", 0, 0)
+	test.IsEqualBool(t, ok, true)
+
+	// Redirected downloads do not count towards the limits
+	SetRedirected(first)
+	test.IsEqualBool(t, statusMap[first].IsRedirected, true)
+	SetRedirected("invalid")
+	_, ok = SetDownloadIfAllowed(models.File{Id: "limited"}, "192.0.2.4", 3, 0)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, IsCurrentlyDownloading(models.File{Id: "limited"}), true)
+	DeleteAll()
+
+	_, ok = SetDownloadIfAllowed(models.File{Id: "first"}, "192.0.2.1", 0, 1)
+	test.IsEqualBool(t, ok, true)
+	_, ok = SetDownloadIfAllowed(models.File{Id: "second"}, "192.0.2.1", 0, 1)
+	test.IsEqualBool(t, ok, false)
+	_, ok = SetDownloadIfAllowed(models.File{Id: "second"}, "192.0.2.2", 0, 1)
+	test.IsEqualBool(t, ok, true)
+	DeleteAll()
+}
+
+func TestSetDownloadsIfAllowed(t *testing.T) {
+	DeleteAll()
+	limitTwo := func(file models.File) int { return 2 }
+	files := []models.File{{Id: "zipfirst"}, {Id: "zipsecond"}}
+	ids, ok := SetDownloadsIfAllowed(files, "192.0.2.1", limitTwo, 1)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(ids), 2)
+	test.IsEqualString(t, statusMap[ids[0]].FileId, "zipfirst")
+	test.IsEqualString(t, statusMap[ids[1]].FileId, "zipsecond")
+	// The files count as a single download of the client
+	test.IsEqualString(t, statusMap[ids[0]].ClientIp, "192.0.2.1")
+	test.IsEqualString(t, statusMap[ids[1]].ClientIp, "")
+	_, ok = SetDownloadsIfAllowed(files, "192.0.2.1", limitTwo, 1)
+	test.IsEqualBool(t, ok, false)
+	_, ok = SetDownloadIfAllowed(models.File{Id: "other"}, "192.0.2.1", 0, 1)
+	test.IsEqualBool(t, ok, false)
+
+	// No status is created, if the limit of one of the files has been reached
+	_, ok = SetDownloadIfAllowed(models.File{Id: "zipsecond"}, "192.0.2.2", 0, 0)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(statusMap), 3)
+	_, ok = SetDownloadsIfAllowed(files, "192.0.2.3", limitTwo, 1)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualInt(t, len(statusMap), 3)
+	_, ok = SetDownloadsIfAllowed([]models.File{{Id: "zipfirst"}}, "192.0.2.3", limitTwo, 1)
+	test.IsEqualBool(t, ok, true)
+
+	// A file that is added twice counts as two downloads
+	DeleteAll()
+	_, ok = SetDownloadsIfAllowed([]models.File{{Id: "zipfirst"}, {Id: "zipfirst"}}, "192.0.2.1", func(file models.File) int { return 1 }, 0)
+	test.IsEqualBool(t, ok, false)
+	ids, ok = SetDownloadsIfAllowed([]models.File{{Id: "zipfirst"}, {Id: "zipfirst"}}, "192.0.2.1", func(file models.File) int { return 0 }, 0)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(ids), 2)
+	DeleteAll()
+}
@@ -1,12 +1,14 @@
 package fileupload
 
 import (
+	"errors"
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/logging"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
 	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
 	"io"
 	"net/http"
 	"strconv"
@@ -26,24 +28,37 @@ func ProcessCompleteFile(w http.ResponseWriter, r *http.Request, userId, maxMemo
 	if err != nil {
 		return err
 	}
+	user, _ := database.GetUser(userId)
+	config.StorageTarget, err = storage.GetStorageTargetForUpload(user, config.StorageTarget)
+	if err != nil {
+		return err
+	}
 	file, header, err := r.FormFile("file")
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+	err = storage.CheckQuota(userId, header.Size)
+	if err != nil {
+		return err
+	}
+	err = diskspace.CheckUpload(header.Size)
+	if err != nil {
+		return err
+	}
 
 	result, err := storage.NewFile(file, header, userId, config)
-	defer file.Close()
 	if err != nil {
 		return err
 	}
-	user, _ := database.GetUser(userId)
 	logging.LogUpload(result, user)
 	_, _ = io.WriteString(w, result.ToJsonResult(config.ExternalUrl, configuration.Get().IncludeFilename))
 	return nil
 }
 
-// ProcessNewChunk processes a file chunk upload request
-func ProcessNewChunk(w http.ResponseWriter, r *http.Request, isApiCall bool) error {
+// ProcessNewChunk processes a file chunk upload request. The quota of the user is checked
+// with the first chunk of a file
+func ProcessNewChunk(w http.ResponseWriter, r *http.Request, userId int, isApiCall bool) error {
 	err := r.ParseMultipartForm(int64(configuration.Get().MaxMemory) * 1024 * 1024)
 	if err != nil {
 		return err
@@ -53,6 +68,13 @@ func ProcessNewChunk(w http.ResponseWriter, r *http.Request, isApiCall bool) err
 	if err != nil {
 		return err
 	}
+	chunkInfo.UserId = userId
+	if chunkInfo.Offset == 0 {
+		err = storage.CheckQuota(userId, chunkInfo.TotalFilesizeBytes)
+		if err != nil {
+			return err
+		}
+	}
 	file, header, err := r.FormFile("file")
 	if err != nil {
 		return err
@@ -89,8 +111,22 @@ func ParseFileHeader(r *http.Request) (string, chunking.FileHeader, models.Uploa
 
 // CompleteChunk processes a file after all the chunks have been completed
 // The parameters can be generated with  ParseFileHeader()
+// If the file cannot be created, the received data is deleted and the reserved disk space is released.
+// The data is kept if only the checksum was invalid or did not match, so that the client can try again
 func CompleteChunk(chunkId string, header chunking.FileHeader, userId int, config models.UploadRequest) (models.File, error) {
-	return storage.NewFileFromChunk(chunkId, header, userId, config)
+	err := storage.CheckQuota(userId, header.Size)
+	if err != nil {
+		chunking.DeleteChunk(chunkId)
+		return models.File{}, err
+	}
+	file, err := storage.NewFileFromChunk(chunkId, header, userId, config)
+	if err != nil {
+		if !errors.Is(err, chunking.ErrorChecksumMismatch) && !errors.Is(err, chunking.ErrorInvalidChecksum) {
+			chunking.DeleteChunk(chunkId)
+		}
+		return models.File{}, err
+	}
+	return file, nil
 }
 
 // CreateUploadConfig populates a new models.UploadRequest struct
@@ -143,7 +179,14 @@ func parseConfig(values formOrHeader) (models.UploadRequest, error) {
 			return models.UploadRequest{}, err
 		}
 	}
-	return CreateUploadConfig(allowedDownloadsInt, expiryDaysInt, password, unlimitedTime, unlimitedDownload, isEnd2End, realSize), nil
+	result := CreateUploadConfig(allowedDownloadsInt, expiryDaysInt, password, unlimitedTime, unlimitedDownload, isEnd2End, realSize)
+	result.StorageTarget = values.Get("storageTarget")
+	result.Checksum = values.Get("checksum")
+	_, err = chunking.ParseChecksum(result.Checksum)
+	if err != nil {
+		return models.UploadRequest{}, err
+	}
+	return result, nil
 }
 
 type formOrHeader interface {
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/diskspace"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -43,9 +48,21 @@ func TestParseConfig(t *testing.T) {
 	test.IsEqualInt(t, config.AllowedDownloads, 9)
 	test.IsEqualString(t, config.Password, "123")
 	test.IsEqualInt(t, config.Expiry, 5)
+	test.IsEqualString(t, config.StorageTarget, "")
 
+	data.storageTarget = "local-fast"
 	config, err = parseConfig(data)
 	test.IsNil(t, err)
+	test.IsEqualString(t, config.StorageTarget, "local-fast")
+
+	data.checksum = "crc32c:e3069283"
+	config, err = parseConfig(data)
+	test.IsNil(t, err)
+	test.IsEqualString(t, config.Checksum, "crc32c:e3069283")
+	data.checksum = "crc32c:invalid"
+	_, err = parseConfig(data)
+	test.IsEqual(t, err, chunking.ErrorInvalidChecksum)
+	data.checksum = ""
 
 	data.allowedDownloads = ""
 	data.expiryDays = "invalid"
@@ -98,17 +115,17 @@ func TestProcess(t *testing.T) {
 
 func TestProcessNewChunk(t *testing.T) {
 	w, r := test.GetRecorder("POST", "/uploadChunk", nil, nil, strings.NewReader("invalid§$%&%§"))
-	err := ProcessNewChunk(w, r, false)
+	err := ProcessNewChunk(w, r, 9, false)
 	test.IsNotNil(t, err)
 
 	w = httptest.NewRecorder()
 	r = getFileUploadRecorder(false)
-	err = ProcessNewChunk(w, r, false)
+	err = ProcessNewChunk(w, r, 9, false)
 	test.IsNotNil(t, err)
 
 	w = httptest.NewRecorder()
 	r = getFileUploadRecorder(true)
-	err = ProcessNewChunk(w, r, false)
+	err = ProcessNewChunk(w, r, 9, false)
 	test.IsNil(t, err)
 	response, err := io.ReadAll(w.Result().Body)
 	test.IsNil(t, err)
@@ -165,6 +182,62 @@ func TestCompleteChunk(t *testing.T) {
 	test.IsNotNil(t, err)
 }
 
+func TestQuota(t *testing.T) {
+	const userId = 73
+	database.SaveUser(models.User{Id: userId, Name: "quotauser", UserLevel: models.UserLevelUser, QuotaFiles: 1}, false)
+	database.SaveMetaData(models.File{Id: "quotafile", SizeBytes: 10, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true})
+
+	w := httptest.NewRecorder()
+	r := getFileUploadRecorder(false)
+	err := ProcessCompleteFile(w, r, userId, 20)
+	test.IsEqual(t, err, storage.ErrorQuotaFilesExceeded)
+
+	w = httptest.NewRecorder()
+	r = getFileUploadRecorder(true)
+	err = ProcessNewChunk(w, r, userId, false)
+	test.IsEqual(t, err, storage.ErrorQuotaFilesExceeded)
+
+	_, err = CompleteChunk("randomchunkuuid", chunking.FileHeader{Filename: "random.file", Size: 13}, userId, models.UploadRequest{})
+	test.IsEqual(t, err, storage.ErrorQuotaFilesExceeded)
+
+	database.DeleteMetaData("quotafile")
+	database.DeleteUser(userId)
+}
+
+func TestCompleteChunkReleasesSpace(t *testing.T) {
+	const userId = 74
+	database.SaveUser(models.User{Id: userId, Name: "releaseuser", UserLevel: models.UserLevelUser, QuotaFiles: 1}, false)
+	database.SaveMetaData(models.File{Id: "releasefile", SizeBytes: 10, UserId: userId, UnlimitedTime: true, UnlimitedDownloads: true})
+	space, ok := diskspace.Get()
+	test.IsEqualBool(t, ok, true)
+	const margin = 1024 * 1024
+	err := diskspace.Reserve("releasechunk", space.FreeBytes-space.ReserveBytes-margin)
+	test.IsNil(t, err)
+	test.IsEqual(t, diskspace.CheckUpload(2*margin), diskspace.ErrorNotEnoughSpace)
+
+	// The space is released, if the file cannot be created
+	_, err = CompleteChunk("releasechunk", chunking.FileHeader{Filename: "random.file", Size: 13}, userId, models.UploadRequest{})
+	test.IsEqual(t, err, storage.ErrorQuotaFilesExceeded)
+	test.IsNil(t, diskspace.CheckUpload(2*margin))
+
+	database.DeleteMetaData("releasefile")
+	database.DeleteUser(userId)
+}
+
+func TestDiskSpace(t *testing.T) {
+	configuration.Get().MinFreeSpaceMB = math.MaxInt32
+	w := httptest.NewRecorder()
+	r := getFileUploadRecorder(false)
+	err := ProcessCompleteFile(w, r, 9, 20)
+	test.IsEqual(t, err, diskspace.ErrorNotEnoughSpace)
+
+	w = httptest.NewRecorder()
+	r = getFileUploadRecorder(true)
+	err = ProcessNewChunk(w, r, 9, false)
+	test.IsEqual(t, err, diskspace.ErrorNotEnoughSpace)
+	configuration.Get().MinFreeSpaceMB = 0
+}
+
 func getFileUploadRecorder(addChunkInfo bool) *http.Request {
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
@@ -187,7 +260,7 @@ func getFileUploadRecorder(addChunkInfo bool) *http.Request {
 }
 
 type testData struct {
-	allowedDownloads, expiryDays, password, isE2E, realSize string
+	allowedDownloads, expiryDays, password, isE2E, realSize, storageTarget, checksum string
 }
 
 func (t testData) Get(key string) string {
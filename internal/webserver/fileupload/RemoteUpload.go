@@ -0,0 +1,268 @@
+package fileupload
+
+/**
+Uploads from a remote URL. The file is downloaded by the server and streamed into the chunk pipeline,
+so that it is processed like any other chunked upload
+*/
+
+import (
+	"context"
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/processingstatus"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorRemoteUploadDisabled is raised when no hosts are allowed for uploads from a remote URL
+var ErrorRemoteUploadDisabled = errors.New("uploads from a remote URL are disabled on this server")
+
+// ErrorRemoteHostNotAllowed is raised when the URL or a redirect points to a host that is not allowed
+var ErrorRemoteHostNotAllowed = errors.New("the host of the URL is not allowed for remote uploads")
+
+// maxRemoteRedirects is the maximum number of redirects that are followed for a remote URL
+const maxRemoteRedirects = 5
+
+// remoteIdleTimeout is the maximum time the remote server may not send any data, before the download is aborted
+var remoteIdleTimeout = 60 * time.Second
+
+// remoteClient does not use a timeout for the whole request, as downloading a large file can take
+// a long time. Instead, the download is aborted if the remote server stops sending data. Every redirect
+// is validated against the allowed hosts as well. Compression is disabled, as the size of the file
+// is required before the download starts
+var remoteClient = &http.Client{
+	CheckRedirect: checkRemoteRedirect,
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		DisableCompression:    true,
+	},
+}
+
+// RemoteFile is a file on a remote server, which has been requested but not downloaded yet
+type RemoteFile struct {
+	// ChunkId is used to report the progress through processingstatus
+	ChunkId string
+	Header  chunking.FileHeader
+	body    io.ReadCloser
+}
+
+// OpenRemoteFile validates the URL and requests the file from the remote server. The download is
+// only started with ProcessRemoteFile, so that errors can be reported before a non-blocking upload
+// is started. filename and contentType are taken from the response, if they are empty
+func OpenRemoteFile(rawUrl, filename, contentType string, userId int) (RemoteFile, error) {
+	remoteUrl, err := parseRemoteUrl(rawUrl)
+	if err != nil {
+		return RemoteFile{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, remoteUrl.String(), nil)
+	if err != nil {
+		cancel()
+		return RemoteFile{}, err
+	}
+	response, err := remoteClient.Do(request)
+	if err != nil {
+		cancel()
+		return RemoteFile{}, err
+	}
+	body := newIdleTimeoutReader(ctx, cancel, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		_ = body.Close()
+		return RemoteFile{}, errors.New("remote server returned status " + strconv.Itoa(response.StatusCode))
+	}
+	if response.ContentLength < 0 {
+		_ = body.Close()
+		return RemoteFile{}, errors.New("remote server did not send the size of the file")
+	}
+	if response.ContentLength > getMaxUploadSize() {
+		_ = body.Close()
+		return RemoteFile{}, storage.ErrorFileTooLarge
+	}
+	err = storage.CheckQuota(userId, response.ContentLength)
+	if err != nil {
+		_ = body.Close()
+		return RemoteFile{}, err
+	}
+	if filename == "" {
+		filename = getRemoteFilename(response, remoteUrl)
+	}
+	if contentType == "" {
+		contentType = response.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+	return RemoteFile{
+		ChunkId: helper.GenerateRandomString(30),
+		Header: chunking.FileHeader{
+			Filename:    filename,
+			ContentType: contentType,
+			Size:        response.ContentLength,
+		},
+		body: body,
+	}, nil
+}
+
+// idleTimeoutReader cancels the request of the body, if a read does not return within remoteIdleTimeout.
+// Only the time waiting for the remote server is measured, not the time the data is being processed
+type idleTimeoutReader struct {
+	body   io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+func newIdleTimeoutReader(ctx context.Context, cancel context.CancelFunc, body io.ReadCloser) *idleTimeoutReader {
+	timer := time.AfterFunc(remoteIdleTimeout, cancel)
+	timer.Stop()
+	return &idleTimeoutReader{body: body, ctx: ctx, cancel: cancel, timer: timer}
+}
+
+// Read reads from the body and returns an error, if the remote server did not send any data in time
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	r.timer.Reset(remoteIdleTimeout)
+	n, err := r.body.Read(p)
+	r.timer.Stop()
+	if err != nil && r.ctx.Err() != nil {
+		return n, errors.New("remote server did not send any data for " + remoteIdleTimeout.String())
+	}
+	return n, err
+}
+
+// Close closes the body and cancels the request
+func (r *idleTimeoutReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.body.Close()
+}
+
+// ProcessRemoteFile downloads the remote file into a chunk and creates a new file from it. The progress
+// is reported through processingstatus. The received data is deleted, if the file cannot be created
+func ProcessRemoteFile(remote RemoteFile, userId int, config models.UploadRequest) (models.File, error) {
+	defer remote.body.Close()
+	err := downloadRemoteFile(remote, userId)
+	if err == nil {
+		err = chunking.CheckComplete(remote.ChunkId)
+	}
+	var file models.File
+	if err == nil {
+		file, err = CompleteChunk(remote.ChunkId, remote.Header, userId, config)
+	}
+	if err != nil {
+		chunking.DeleteChunk(remote.ChunkId)
+		processingstatus.Set(remote.ChunkId, processingstatus.StatusError, models.File{}, err)
+		return models.File{}, err
+	}
+	return file, nil
+}
+
+// downloadRemoteFile writes the response body in parts of the configured chunk size
+func downloadRemoteFile(remote RemoteFile, userId int) error {
+	totalSize := remote.Header.Size
+	partSize := int64(configuration.Get().ChunkSize) * 1024 * 1024
+	if partSize <= 0 {
+		partSize = totalSize
+	}
+	processingstatus.SetProgress(remote.ChunkId, 0, totalSize)
+	var offset int64
+	for {
+		size := min(partSize, totalSize-offset)
+		info := chunking.ChunkInfo{
+			TotalFilesizeBytes: totalSize,
+			Offset:             offset,
+			UUID:               remote.ChunkId,
+			UserId:             userId,
+		}
+		err := chunking.NewChunk(io.LimitReader(remote.body, size), &multipart.FileHeader{Size: size}, info)
+		if err != nil {
+			return err
+		}
+		offset += size
+		processingstatus.SetProgress(remote.ChunkId, offset, totalSize)
+		if offset >= totalSize {
+			return nil
+		}
+	}
+}
+
+// parseRemoteUrl returns the parsed URL, if it uses HTTP or HTTPS and points to an allowed host
+func parseRemoteUrl(rawUrl string) (*url.URL, error) {
+	if len(configuration.Get().RemoteUploadHosts) == 0 {
+		return nil, ErrorRemoteUploadDisabled
+	}
+	remoteUrl, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return nil, errors.New("invalid URL provided")
+	}
+	err = checkRemoteUrl(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+	return remoteUrl, nil
+}
+
+func checkRemoteUrl(remoteUrl *url.URL) error {
+	if remoteUrl.Scheme != "http" && remoteUrl.Scheme != "https" {
+		return errors.New("only http and https URLs are supported")
+	}
+	if remoteUrl.User != nil {
+		return errors.New("credentials in the URL are not supported")
+	}
+	if !isAllowedRemoteHost(remoteUrl) {
+		return ErrorRemoteHostNotAllowed
+	}
+	return nil
+}
+
+func checkRemoteRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= maxRemoteRedirects {
+		return errors.New("too many redirects")
+	}
+	return checkRemoteUrl(request.URL)
+}
+
+// isAllowedRemoteHost returns true, if the host matches an entry of the allowed hosts. An entry
+// may contain a port, which then has to match as well. Subdomains are allowed with *.domain
+func isAllowedRemoteHost(remoteUrl *url.URL) bool {
+	hostname := strings.ToLower(remoteUrl.Hostname())
+	if hostname == "" {
+		return false
+	}
+	hostWithPort := strings.ToLower(remoteUrl.Host)
+	for _, allowed := range configuration.Get().RemoteUploadHosts {
+		if allowed == hostname || allowed == hostWithPort {
+			return true
+		}
+		domain, isWildcard := strings.CutPrefix(allowed, "*.")
+		if isWildcard && strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRemoteFilename returns the filename from the header Content-Disposition or the URL path
+func getRemoteFilename(response *http.Response, remoteUrl *url.URL) string {
+	_, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition"))
+	if err == nil && params["filename"] != "" {
+		return path.Base(strings.ReplaceAll(params["filename"], "\\", "/"))
+	}
+	filename := path.Base(remoteUrl.Path)
+	if filename == "/" || filename == "." {
+		return "download"
+	}
+	return filename
+}
@@ -0,0 +1,205 @@
+package fileupload
+
+import (
+	"compress/gzip"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/processingstatus/pstatusdb"
+	"github.com/forceu/gokapi/internal/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRemoteTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/remote.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "This is a remote file")
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", "attachment; filename=\"../disposition.txt\"")
+		_, _ = io.WriteString(w, "Content with disposition")
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/files/remote.txt", http.StatusFound)
+	})
+	mux.HandleFunc("/redirect-external", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/file.txt", http.StatusFound)
+	})
+	mux.HandleFunc("/streamed", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "Size unknown")
+		w.(http.Flusher).Flush()
+	})
+	mux.HandleFunc("/compressible", func(w http.ResponseWriter, r *http.Request) {
+		content := "Compressible remote file"
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			// Compressed content is sent without its size
+			w.Header().Set("Content-Encoding", "gzip")
+			gzipWriter := gzip.NewWriter(w)
+			_, _ = io.WriteString(gzipWriter, content)
+			_ = gzipWriter.Close()
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	})
+	mux.HandleFunc("/stalled", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = io.WriteString(w, "Incomplete")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1073741824")
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	serverUrl, err := url.Parse(server.URL)
+	test.IsNil(t, err)
+	configuration.Get().RemoteUploadHosts = []string{serverUrl.Host}
+	t.Cleanup(func() {
+		server.Close()
+		configuration.Get().RemoteUploadHosts = []string{}
+	})
+	return server
+}
+
+func TestIsAllowedRemoteHost(t *testing.T) {
+	configuration.Get().RemoteUploadHosts = []string{"example.com", "*.files.example.org", "127.0.0.1:8080"}
+	defer func() { configuration.Get().RemoteUploadHosts = []string{} }()
+	for _, allowed := range []string{"https://example.com/file", "http://EXAMPLE.com:9000/file",
+		"https://cdn.files.example.org/file", "https://a.b.files.example.org/file", "http://127.0.0.1:8080/file"} {
+		remoteUrl, err := url.Parse(allowed)
+		test.IsNil(t, err)
+		test.IsEqualBool(t, isAllowedRemoteHost(remoteUrl), true)
+	}
+	for _, forbidden := range []string{"https://sub.example.com/file", "https://files.example.org/file",
+		"https://evilfiles.example.org/file", "http://127.0.0.1/file", "http://127.0.0.1:8081/file", "/file"} {
+		remoteUrl, err := url.Parse(forbidden)
+		test.IsNil(t, err)
+		test.IsEqualBool(t, isAllowedRemoteHost(remoteUrl), false)
+	}
+}
+
+func TestParseRemoteUrl(t *testing.T) {
+	_, err := parseRemoteUrl("https://example.com/file")
+	test.IsEqual(t, err, ErrorRemoteUploadDisabled)
+
+	configuration.Get().RemoteUploadHosts = []string{"example.com"}
+	defer func() { configuration.Get().RemoteUploadHosts = []string{} }()
+	remoteUrl, err := parseRemoteUrl(" https://example.com/file ")
+	test.IsNil(t, err)
+	test.IsEqualString(t, remoteUrl.String(), "https://example.com/file")
+	_, err = parseRemoteUrl("https://example.org/file")
+	test.IsEqual(t, err, ErrorRemoteHostNotAllowed)
+	for _, invalid := range []string{"ftp://example.com/file", "file:///etc/passwd", "https://user:pw@example.com/file", "://invalid"} {
+		_, err = parseRemoteUrl(invalid)
+		test.IsNotNil(t, err)
+	}
+}
+
+func TestOpenRemoteFile(t *testing.T) {
+	server := newRemoteTestServer(t)
+
+	remoteFile, err := OpenRemoteFile(server.URL+"/files/remote.txt", "", "", 9)
+	test.IsNil(t, err)
+	test.IsEqualString(t, remoteFile.Header.Filename, "remote.txt")
+	test.IsEqualString(t, remoteFile.Header.ContentType, "text/plain")
+	test.IsEqualInt64(t, remoteFile.Header.Size, 21)
+	test.IsEqualInt(t, len(remoteFile.ChunkId), 30)
+	_ = remoteFile.body.Close()
+
+	remoteFile, err = OpenRemoteFile(server.URL+"/download", "", "application/custom", 9)
+	test.IsNil(t, err)
+	test.IsEqualString(t, remoteFile.Header.Filename, "disposition.txt")
+	test.IsEqualString(t, remoteFile.Header.ContentType, "application/custom")
+	_ = remoteFile.body.Close()
+
+	remoteFile, err = OpenRemoteFile(server.URL+"/redirect", "custom.txt", "", 9)
+	test.IsNil(t, err)
+	test.IsEqualString(t, remoteFile.Header.Filename, "custom.txt")
+	test.IsEqualInt64(t, remoteFile.Header.Size, 21)
+	_ = remoteFile.body.Close()
+
+	_, err = OpenRemoteFile(server.URL+"/redirect-external", "", "", 9)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, strings.Contains(err.Error(), ErrorRemoteHostNotAllowed.Error()), true)
+	_, err = OpenRemoteFile(server.URL+"/invalid", "", "", 9)
+	test.IsNotNil(t, err)
+	test.IsEqualString(t, err.Error(), "remote server returned status 404")
+	_, err = OpenRemoteFile(server.URL+"/streamed", "", "", 9)
+	test.IsNotNil(t, err)
+	_, err = OpenRemoteFile(server.URL+"/large", "", "", 9)
+	test.IsEqual(t, err, storage.ErrorFileTooLarge)
+
+	// Compression is not requested, so that the server sends the size of the file
+	remoteFile, err = OpenRemoteFile(server.URL+"/compressible", "", "", 9)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, remoteFile.Header.Size, 24)
+	_ = remoteFile.body.Close()
+}
+
+func TestProcessRemoteFile(t *testing.T) {
+	server := newRemoteTestServer(t)
+
+	remoteFile, err := OpenRemoteFile(server.URL+"/files/remote.txt", "", "", 9)
+	test.IsNil(t, err)
+	file, err := ProcessRemoteFile(remoteFile, 9, CreateUploadConfig(3, 5, "", false, false, false, 0))
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.Name, "remote.txt")
+	test.IsEqualInt64(t, file.SizeBytes, 21)
+	test.IsEqualInt(t, file.DownloadsRemaining, 3)
+	test.IsEqualBool(t, chunking.FileExists(remoteFile.ChunkId), false)
+	status, ok := getRemoteStatus(remoteFile.ChunkId)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, status.CurrentStatus, 2)
+	test.IsEqualString(t, status.FileId, file.Id)
+
+	remoteFile, err = OpenRemoteFile(server.URL+"/files/remote.txt", "", "", 9)
+	test.IsNil(t, err)
+	_, err = ProcessRemoteFile(remoteFile, 9, models.UploadRequest{Checksum: "crc32c:00000000"})
+	test.IsEqual(t, err, chunking.ErrorChecksumMismatch)
+	status, ok = getRemoteStatus(remoteFile.ChunkId)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, status.CurrentStatus, 3)
+	test.IsEqualString(t, status.ErrorMessage, chunking.ErrorChecksumMismatch.Error())
+
+	// The remote server sends less data than announced
+	remoteFile = RemoteFile{
+		ChunkId: "remotetruncated",
+		Header:  chunking.FileHeader{Filename: "truncated.txt", Size: 50},
+		body:    io.NopCloser(strings.NewReader("Too short")),
+	}
+	_, err = ProcessRemoteFile(remoteFile, 9, models.UploadRequest{})
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, chunking.FileExists(remoteFile.ChunkId), false)
+
+	// The remote server stops sending data
+	remoteIdleTimeout = 100 * time.Millisecond
+	defer func() { remoteIdleTimeout = 60 * time.Second }()
+	remoteFile, err = OpenRemoteFile(server.URL+"/stalled", "", "", 9)
+	test.IsNil(t, err)
+	_, err = ProcessRemoteFile(remoteFile, 9, models.UploadRequest{})
+	test.IsNotNil(t, err)
+	test.IsEqualString(t, err.Error(), "remote server did not send any data for 100ms")
+	test.IsEqualBool(t, chunking.FileExists(remoteFile.ChunkId), false)
+}
+
+func getRemoteStatus(id string) (models.UploadStatus, bool) {
+	for _, status := range pstatusdb.GetAll() {
+		if status.ChunkId == id {
+			return status, true
+		}
+	}
+	return models.UploadStatus{}, false
+}
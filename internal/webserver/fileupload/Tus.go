@@ -0,0 +1,337 @@
+package fileupload
+
+/**
+Resumable uploads with the tus protocol version 1.0.0 (https://tus.io/protocols/resumable-upload)
+Supported extensions are creation and termination
+*/
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TusVersion is the only version of the tus protocol that is supported
+const TusVersion = "1.0.0"
+
+const tusExtensions = "creation,termination"
+const tusContentType = "application/offset+octet-stream"
+
+// HeaderTusFileId is sent after the last chunk has been received and contains the ID of the new file
+const HeaderTusFileId = "Gokapi-File-Id"
+
+// tusUpload contains the parameters that were sent on the creation of a tus upload. The upload ID is
+// also used as the chunk ID, so that the received data is stored like any other chunked upload
+type tusUpload struct {
+	Id       string      `json:"Id"`
+	UserId   int         `json:"UserId"`
+	Length   int64       `json:"Length"`
+	Metadata tusMetadata `json:"Metadata"`
+	// FileId is set, once the upload has been completed
+	FileId string `json:"FileId"`
+}
+
+// tusMetadata contains the decoded key/value pairs of the header Upload-Metadata. It uses the same
+// keys as the form of a regular upload, e.g. expiryDays, allowedDownloads and password
+type tusMetadata map[string]string
+
+// Get returns the value for the key or an empty string, if the key does not exist
+func (m tusMetadata) Get(key string) string {
+	return m[key]
+}
+
+// activeTusUploads contains the IDs of the uploads that are currently modified by a request
+var activeTusUploads = make(map[string]bool)
+var tusMutex sync.Mutex
+
+// ProcessTusRequest handles a request of the tus protocol. uploadId is the part of the URL after the
+// tus endpoint and is empty for the creation of a new upload. Returns the HTTP status code and an
+// error, if the request could not be processed
+func ProcessTusRequest(w http.ResponseWriter, r *http.Request, user models.User, uploadId string) (int, error) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Tus-Version", TusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(getMaxUploadSize(), 10))
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	}
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+		return http.StatusPreconditionFailed, errors.New("unsupported version of the tus protocol")
+	}
+	if uploadId == "" {
+		if r.Method != http.MethodPost {
+			return http.StatusMethodNotAllowed, errors.New("method not allowed")
+		}
+		return createTusUpload(w, r, user)
+	}
+	if !lockTusUpload(uploadId) {
+		return http.StatusLocked, errors.New("upload is currently being modified")
+	}
+	defer unlockTusUpload(uploadId)
+	upload, ok := loadTusUpload(uploadId)
+	if !ok || upload.UserId != user.Id {
+		return http.StatusNotFound, chunking.ErrorUnknownUpload
+	}
+	switch r.Method {
+	case http.MethodHead:
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.getOffset(), 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		if upload.FileId != "" {
+			w.Header().Set(HeaderTusFileId, upload.FileId)
+		}
+		w.WriteHeader(http.StatusOK)
+		return http.StatusOK, nil
+	case http.MethodPatch:
+		return patchTusUpload(w, r, upload, user)
+	case http.MethodDelete:
+		chunking.DeleteChunk(upload.Id)
+		_ = os.Remove(getTusUploadPath(upload.Id))
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	default:
+		return http.StatusMethodNotAllowed, errors.New("method not allowed")
+	}
+}
+
+// createTusUpload registers a new upload. The upload parameters are checked already, so that the
+// client does not upload the whole file before an invalid parameter is reported
+func createTusUpload(w http.ResponseWriter, r *http.Request, user models.User) (int, error) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		return http.StatusBadRequest, errors.New("deferred upload length is not supported")
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return http.StatusBadRequest, errors.New("invalid upload length provided")
+	}
+	if length > getMaxUploadSize() {
+		return http.StatusRequestEntityTooLarge, storage.ErrorFileTooLarge
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if metadata.Get("filename") == "" {
+		return http.StatusBadRequest, errors.New("empty filename provided")
+	}
+	config, err := parseConfig(metadata)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	_, err = storage.GetStorageTargetForUpload(user, config.StorageTarget)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	err = storage.CheckQuota(user.Id, length)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	upload := tusUpload{
+		Id:       helper.GenerateRandomString(30),
+		UserId:   user.Id,
+		Length:   length,
+		Metadata: metadata,
+	}
+	err = saveTusUpload(upload)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	w.Header().Set("Location", configuration.Get().ServerUrl+"api/tus/"+upload.Id)
+	if length == 0 {
+		// An empty file is complete on creation and does not receive a PATCH request
+		err = chunking.NewChunk(strings.NewReader(""), &multipart.FileHeader{}, upload.getChunkInfo(0))
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		status, err := completeTusUpload(w, upload, user)
+		if err != nil {
+			return status, err
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+	return http.StatusCreated, nil
+}
+
+// patchTusUpload writes the request body at the offset of the upload. The file is created, once
+// all bytes have been received
+func patchTusUpload(w http.ResponseWriter, r *http.Request, upload tusUpload, user models.User) (int, error) {
+	if r.Header.Get("Content-Type") != tusContentType {
+		return http.StatusUnsupportedMediaType, errors.New("content type has to be " + tusContentType)
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("invalid upload offset provided")
+	}
+	if offset != upload.getOffset() {
+		return http.StatusConflict, errors.New("upload offset does not match the received bytes")
+	}
+	if r.ContentLength < 0 {
+		return http.StatusLengthRequired, errors.New("content length is required")
+	}
+	if offset+r.ContentLength > upload.Length {
+		return http.StatusBadRequest, errors.New("chunksize will be bigger than total filesize from this offset")
+	}
+	if upload.FileId == "" && r.ContentLength > 0 {
+		err = chunking.NewChunk(r.Body, &multipart.FileHeader{Size: r.ContentLength}, upload.getChunkInfo(offset))
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	newOffset := upload.getOffset()
+	w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	if newOffset == upload.Length {
+		// A failed completion is retried by sending an empty PATCH request
+		status, err := completeTusUpload(w, upload, user)
+		if err != nil {
+			return status, err
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return http.StatusNoContent, nil
+}
+
+// completeTusUpload creates the file from the received data with the parameters from the
+// upload metadata. Does nothing, if the file has been created already
+func completeTusUpload(w http.ResponseWriter, upload tusUpload, user models.User) (int, error) {
+	if upload.FileId != "" {
+		w.Header().Set(HeaderTusFileId, upload.FileId)
+		return http.StatusNoContent, nil
+	}
+	config, err := parseConfig(upload.Metadata)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	config.StorageTarget, err = storage.GetStorageTargetForUpload(user, config.StorageTarget)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	header := chunking.FileHeader{
+		Filename:    upload.Metadata.Get("filename"),
+		ContentType: upload.Metadata.Get("filetype"),
+		Size:        upload.Length,
+	}
+	if header.ContentType == "" {
+		header.ContentType = "application/octet-stream"
+	}
+	file, err := CompleteChunk(upload.Id, header, user.Id, config)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	logging.LogUpload(file, user)
+	upload.FileId = file.Id
+	err = saveTusUpload(upload)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	w.Header().Set(HeaderTusFileId, file.Id)
+	return http.StatusNoContent, nil
+}
+
+// getOffset returns the number of bytes that have been received in order
+func (u tusUpload) getOffset() int64 {
+	if u.FileId != "" {
+		return u.Length
+	}
+	status, ok := chunking.GetStatus(u.Id)
+	if !ok || len(status.Ranges) == 0 || status.Ranges[0].Start != 0 {
+		return 0
+	}
+	return status.Ranges[0].End
+}
+
+func (u tusUpload) getChunkInfo(offset int64) chunking.ChunkInfo {
+	return chunking.ChunkInfo{
+		TotalFilesizeBytes: u.Length,
+		Offset:             offset,
+		UUID:               u.Id,
+		UserId:             u.UserId,
+	}
+}
+
+// parseTusMetadata decodes the header Upload-Metadata, which consists of comma separated key/value pairs.
+// The key and the base64 encoded value are separated by a space, the value may be omitted
+func parseTusMetadata(header string) (tusMetadata, error) {
+	result := make(tusMetadata)
+	if strings.TrimSpace(header) == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid upload metadata provided")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.New("invalid upload metadata provided")
+		}
+		result[key] = string(decoded)
+	}
+	return result, nil
+}
+
+func getMaxUploadSize() int64 {
+	return int64(configuration.Get().MaxFileSizeMB) * 1024 * 1024
+}
+
+// getTusUploadPath returns the path of the file that stores the upload parameters. As it starts
+// with chunk-, it is deleted together with unfinished chunk files after 24 hours
+func getTusUploadPath(id string) string {
+	return configuration.GetTempDir() + "/chunk-" + id + ".tus"
+}
+
+var tusIdRegex = regexp.MustCompile("^[a-zA-Z0-9]+$")
+
+// loadTusUpload returns the parameters of the upload. Returns false, if the upload does not exist
+func loadTusUpload(id string) (tusUpload, bool) {
+	if !tusIdRegex.MatchString(id) {
+		return tusUpload{}, false
+	}
+	content, err := os.ReadFile(getTusUploadPath(id))
+	if err != nil {
+		return tusUpload{}, false
+	}
+	var result tusUpload
+	err = json.Unmarshal(content, &result)
+	if err != nil {
+		return tusUpload{}, false
+	}
+	return result, true
+}
+
+func saveTusUpload(upload tusUpload) error {
+	content, err := json.Marshal(upload)
+	helper.Check(err)
+	return os.WriteFile(getTusUploadPath(upload.Id), content, 0600)
+}
+
+// lockTusUpload returns false, if the upload is currently modified by another request
+func lockTusUpload(id string) bool {
+	tusMutex.Lock()
+	defer tusMutex.Unlock()
+	if activeTusUploads[id] {
+		return false
+	}
+	activeTusUploads[id] = true
+	return true
+}
+
+func unlockTusUpload(id string) {
+	tusMutex.Lock()
+	defer tusMutex.Unlock()
+	delete(activeTusUploads, id)
+}
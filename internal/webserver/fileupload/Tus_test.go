@@ -0,0 +1,213 @@
+package fileupload
+
+import (
+	"encoding/base64"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var tusTestUser = models.User{Id: 9, Name: "tususer"}
+
+func tusRequest(t *testing.T, method, uploadId string, headers []test.Header, body io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+	headers = append(headers, test.Header{Name: "Tus-Resumable", Value: TusVersion})
+	w, r := test.GetRecorder(method, "/api/tus/"+uploadId, nil, headers, body)
+	status, err := ProcessTusRequest(w, r, tusTestUser, uploadId)
+	if err != nil {
+		w.Code = status
+	}
+	test.IsEqualString(t, w.Header().Get("Tus-Resumable"), TusVersion)
+	return w
+}
+
+func encodeTusMetadata(key, value string) string {
+	return key + " " + base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func createTestTusUpload(t *testing.T, length string) string {
+	t.Helper()
+	metadata := encodeTusMetadata("filename", "tus.txt") + "," +
+		encodeTusMetadata("filetype", "text/plain") + "," +
+		encodeTusMetadata("allowedDownloads", "3") + "," +
+		encodeTusMetadata("expiryDays", "2") + "," +
+		encodeTusMetadata("password", "secret")
+	w := tusRequest(t, http.MethodPost, "", []test.Header{
+		{Name: "Upload-Length", Value: length},
+		{Name: "Upload-Metadata", Value: metadata}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusCreated)
+	location := w.Header().Get("Location")
+	test.IsEqualBool(t, strings.HasPrefix(location, "http://127.0.0.1:53843/api/tus/"), true)
+	return strings.TrimPrefix(location, "http://127.0.0.1:53843/api/tus/")
+}
+
+func TestParseTusMetadata(t *testing.T) {
+	metadata, err := parseTusMetadata("")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(metadata), 0)
+
+	metadata, err = parseTusMetadata(encodeTusMetadata("filename", "test file.txt") + ", isUnlimitedTime," + encodeTusMetadata("expiryDays", "4"))
+	test.IsNil(t, err)
+	test.IsEqualString(t, metadata.Get("filename"), "test file.txt")
+	test.IsEqualString(t, metadata.Get("expiryDays"), "4")
+	test.IsEqualString(t, metadata.Get("isUnlimitedTime"), "")
+	test.IsEqualString(t, metadata.Get("password"), "")
+
+	_, err = parseTusMetadata("filename invalid§")
+	test.IsNotNil(t, err)
+	_, err = parseTusMetadata(",")
+	test.IsNotNil(t, err)
+}
+
+func TestTusOptions(t *testing.T) {
+	w, r := test.GetRecorder(http.MethodOptions, "/api/tus/", nil, nil, nil)
+	status, err := ProcessTusRequest(w, r, tusTestUser, "")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, status, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Tus-Version"), TusVersion)
+	test.IsEqualString(t, w.Header().Get("Tus-Extension"), "creation,termination")
+	test.IsEqualString(t, w.Header().Get("Tus-Max-Size"), "26214400")
+
+	w, r = test.GetRecorder(http.MethodPost, "/api/tus/", nil, []test.Header{{Name: "Tus-Resumable", Value: "0.2.2"}}, nil)
+	status, err = ProcessTusRequest(w, r, tusTestUser, "")
+	test.IsNotNil(t, err)
+	test.IsEqualInt(t, status, http.StatusPreconditionFailed)
+	test.IsEqualString(t, w.Header().Get("Tus-Version"), TusVersion)
+}
+
+func TestTusCreation(t *testing.T) {
+	w := tusRequest(t, http.MethodPost, "", []test.Header{{Name: "Upload-Metadata", Value: encodeTusMetadata("filename", "tus.txt")}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+	w = tusRequest(t, http.MethodPost, "", []test.Header{{Name: "Upload-Defer-Length", Value: "1"}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+	w = tusRequest(t, http.MethodPost, "", []test.Header{{Name: "Upload-Length", Value: "10"}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+	w = tusRequest(t, http.MethodPost, "", []test.Header{
+		{Name: "Upload-Length", Value: "104857600"},
+		{Name: "Upload-Metadata", Value: encodeTusMetadata("filename", "tus.txt")}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusRequestEntityTooLarge)
+	w = tusRequest(t, http.MethodPost, "", []test.Header{
+		{Name: "Upload-Length", Value: "10"},
+		{Name: "Upload-Metadata", Value: encodeTusMetadata("filename", "tus.txt") + "," + encodeTusMetadata("storageTarget", "invalid")}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+	w = tusRequest(t, http.MethodPut, "", nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusMethodNotAllowed)
+
+	uploadId := createTestTusUpload(t, "10")
+	upload, ok := loadTusUpload(uploadId)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, upload.Length, 10)
+	test.IsEqualInt(t, upload.UserId, tusTestUser.Id)
+	test.IsEqualString(t, upload.Metadata.Get("password"), "secret")
+	w = tusRequest(t, http.MethodDelete, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+
+	// An empty file is created immediately
+	w = tusRequest(t, http.MethodPost, "", []test.Header{
+		{Name: "Upload-Length", Value: "0"},
+		{Name: "Upload-Metadata", Value: encodeTusMetadata("filename", "empty.txt")}}, nil)
+	test.IsEqualInt(t, w.Code, http.StatusCreated)
+	file, ok := database.GetMetaDataById(w.Header().Get(HeaderTusFileId))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "empty.txt")
+	test.IsEqualInt64(t, file.SizeBytes, 0)
+}
+
+func TestTusUpload(t *testing.T) {
+	uploadId := createTestTusUpload(t, "22")
+	patchHeaders := func(offset string) []test.Header {
+		return []test.Header{
+			{Name: "Content-Type", Value: "application/offset+octet-stream"},
+			{Name: "Upload-Offset", Value: offset}}
+	}
+
+	w := tusRequest(t, http.MethodHead, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusOK)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "0")
+	test.IsEqualString(t, w.Header().Get("Upload-Length"), "22")
+
+	w = tusRequest(t, http.MethodPatch, uploadId, patchHeaders("0"), strings.NewReader("This is a "))
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "10")
+	test.IsEqualString(t, w.Header().Get(HeaderTusFileId), "")
+
+	w = tusRequest(t, http.MethodPatch, uploadId, patchHeaders("0"), strings.NewReader("This is a "))
+	test.IsEqualInt(t, w.Code, http.StatusConflict)
+	w = tusRequest(t, http.MethodPatch, uploadId, []test.Header{{Name: "Upload-Offset", Value: "10"}}, strings.NewReader("tus upload"))
+	test.IsEqualInt(t, w.Code, http.StatusUnsupportedMediaType)
+	w = tusRequest(t, http.MethodPatch, uploadId, patchHeaders("10"), strings.NewReader("tus upload that is too long"))
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+
+	// Uploads are only visible to the user that created them
+	w, r := test.GetRecorder(http.MethodHead, "/api/tus/"+uploadId, nil, []test.Header{{Name: "Tus-Resumable", Value: TusVersion}}, nil)
+	status, err := ProcessTusRequest(w, r, models.User{Id: 10}, uploadId)
+	test.IsEqual(t, err, chunking.ErrorUnknownUpload)
+	test.IsEqualInt(t, status, http.StatusNotFound)
+
+	w = tusRequest(t, http.MethodHead, uploadId, nil, nil)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "10")
+	w = tusRequest(t, http.MethodPatch, uploadId, patchHeaders("10"), strings.NewReader("tus upload"))
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "20")
+	w = tusRequest(t, http.MethodPatch, uploadId, patchHeaders("20"), strings.NewReader("!!"))
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "22")
+	fileId := w.Header().Get(HeaderTusFileId)
+	test.IsEqualBool(t, fileId != "", true)
+
+	file, ok := database.GetMetaDataById(fileId)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "tus.txt")
+	test.IsEqualString(t, file.ContentType, "text/plain")
+	test.IsEqualInt64(t, file.SizeBytes, 22)
+	test.IsEqualInt(t, file.DownloadsRemaining, 3)
+	test.IsEqualBool(t, file.PasswordHash != "", true)
+	test.IsEqualBool(t, file.UnlimitedTime, false)
+	test.IsEqualInt(t, file.UserId, tusTestUser.Id)
+
+	// The file ID is still returned after the upload has been completed
+	w = tusRequest(t, http.MethodHead, uploadId, nil, nil)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "22")
+	test.IsEqualString(t, w.Header().Get(HeaderTusFileId), fileId)
+	w = tusRequest(t, http.MethodPatch, uploadId, patchHeaders("22"), strings.NewReader(""))
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get(HeaderTusFileId), fileId)
+
+	w = tusRequest(t, http.MethodDelete, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	w = tusRequest(t, http.MethodHead, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusNotFound)
+	_, ok = database.GetMetaDataById(fileId)
+	test.IsEqualBool(t, ok, true)
+	database.DeleteMetaData(fileId)
+}
+
+func TestTusTermination(t *testing.T) {
+	uploadId := createTestTusUpload(t, "20")
+	w := tusRequest(t, http.MethodPatch, uploadId, []test.Header{
+		{Name: "Content-Type", Value: "application/offset+octet-stream"},
+		{Name: "Upload-Offset", Value: "0"}}, strings.NewReader("Partial"))
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualBool(t, chunking.FileExists(uploadId), true)
+
+	test.IsEqualBool(t, lockTusUpload(uploadId), true)
+	w = tusRequest(t, http.MethodDelete, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusLocked)
+	unlockTusUpload(uploadId)
+
+	w = tusRequest(t, http.MethodDelete, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualBool(t, chunking.FileExists(uploadId), false)
+	_, ok := chunking.GetStatus(uploadId)
+	test.IsEqualBool(t, ok, false)
+	w = tusRequest(t, http.MethodDelete, uploadId, nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusNotFound)
+	w = tusRequest(t, http.MethodHead, "../../invalid", nil, nil)
+	test.IsEqualInt(t, w.Code, http.StatusNotFound)
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // Write sets headers to either display the file inline or to force download, the content type
-// and if the file is encrypted, the creation timestamp to now
+// and if the file is encrypted, the upload timestamp as modification date
 func Write(file models.File, w http.ResponseWriter, forceDownload bool) {
 	if forceDownload {
 		w.Header().Set("Content-Disposition", "attachment; filename=\""+file.Name+"\"")
@@ -17,7 +17,6 @@ func Write(file models.File, w http.ResponseWriter, forceDownload bool) {
 	w.Header().Set("Content-Type", file.ContentType)
 
 	if file.Encryption.IsEncrypted {
-		w.Header().Set("Accept-Ranges", "bytes")
-		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Last-Modified", time.Unix(file.UploadDate, 0).UTC().Format(http.TimeFormat))
 	}
 }
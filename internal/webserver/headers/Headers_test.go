@@ -15,8 +15,10 @@ func TestWriteDownloadHeaders(t *testing.T) {
 	Write(file, w, false)
 	test.IsEqualString(t, w.Result().Header.Get("Content-Disposition"), "inline; filename=\"testname\"")
 	test.IsEqualString(t, w.Result().Header.Get("Content-Type"), "testtype")
+	test.IsEqualString(t, w.Result().Header.Get("Last-Modified"), "")
 	file.Encryption.IsEncrypted = true
+	file.UploadDate = 1700000000
 	w, _ = test.GetRecorder("GET", "/test", nil, nil, nil)
 	Write(file, w, false)
-	test.IsEqualString(t, w.Result().Header.Get("Accept-Ranges"), "bytes")
+	test.IsEqualString(t, w.Result().Header.Get("Last-Modified"), "Tue, 14 Nov 2023 22:13:20 GMT")
 }
@@ -47,6 +47,9 @@ type eventUploadStatus struct {
 	FileId       string `json:"file_id"`
 	ErrorMessage string `json:"error_message"`
 	UploadStatus int    `json:"upload_status"`
+	// TransferredBytes and TotalBytes are only sent, while a file is downloaded from a remote URL
+	TransferredBytes int64 `json:"transferred_bytes,omitempty"`
+	TotalBytes       int64 `json:"total_bytes,omitempty"`
 }
 
 type eventData interface {
@@ -56,11 +59,13 @@ type eventData interface {
 // PublishNewStatus sends a new upload status to all listeners
 func PublishNewStatus(uploadStatus models.UploadStatus) {
 	event := eventUploadStatus{
-		Event:        "uploadStatus",
-		ChunkId:      uploadStatus.ChunkId,
-		UploadStatus: uploadStatus.CurrentStatus,
-		FileId:       uploadStatus.FileId,
-		ErrorMessage: uploadStatus.ErrorMessage,
+		Event:            "uploadStatus",
+		ChunkId:          uploadStatus.ChunkId,
+		UploadStatus:     uploadStatus.CurrentStatus,
+		FileId:           uploadStatus.FileId,
+		ErrorMessage:     uploadStatus.ErrorMessage,
+		TransferredBytes: uploadStatus.TransferredBytes,
+		TotalBytes:       uploadStatus.TotalBytes,
 	}
 	publishMessage(event)
 }
@@ -53,6 +53,15 @@ func TestPublishNewStatus(t *testing.T) {
 	receivedStatus := <-replyChannel
 	test.IsEqualString(t, receivedStatus, "event: message\ndata: {\"event\":\"uploadStatus\",\"chunk_id\":\"testChunkId\",\"file_id\":\"\",\"error_message\":\"\",\"upload_status\":4}\n\n")
 
+	go PublishNewStatus(models.UploadStatus{
+		ChunkId:          "testChunkId",
+		CurrentStatus:    -1,
+		TransferredBytes: 50,
+		TotalBytes:       200,
+	})
+	receivedStatus = <-replyChannel
+	test.IsEqualString(t, receivedStatus, "event: message\ndata: {\"event\":\"uploadStatus\",\"chunk_id\":\"testChunkId\",\"file_id\":\"\",\"error_message\":\"\",\"upload_status\":-1,\"transferred_bytes\":50,\"total_bytes\":200}\n\n")
+
 	go PublishDownloadCount(models.File{
 		Id:                 "testFileId",
 		DownloadCount:      3,
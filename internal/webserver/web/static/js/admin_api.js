@@ -112,7 +112,7 @@ async function apiAuthCreate() {
 // /chunk
 
 
-async function apiChunkComplete(uuid, filename, filesize, realsize, contenttype, allowedDownloads, expiryDays, password, isE2E, nonblocking) {
+async function apiChunkComplete(uuid, filename, filesize, realsize, contenttype, allowedDownloads, expiryDays, password, isE2E, nonblocking, storageTarget) {
     const apiUrl = './api/chunk/complete';
 
     const requestOptions = {
@@ -129,7 +129,8 @@ async function apiChunkComplete(uuid, filename, filesize, realsize, contenttype,
             'expiryDays': expiryDays,
             'password': password,
             'isE2E': isE2E,
-            'nonblocking': nonblocking
+            'nonblocking': nonblocking,
+            'storageTarget': storageTarget
         },
     };
 
@@ -213,6 +214,31 @@ async function apiFilesListById(fileId) {
 }
 
 
+async function apiFilesDownloads(fileId) {
+    const apiUrl = './api/files/downloads/' + fileId;
+    const requestOptions = {
+        method: 'GET',
+        headers: {
+            'Content-Type': 'application/json',
+            'apikey': systemKey,
+
+        },
+    };
+
+    try {
+        const response = await fetch(apiUrl, requestOptions);
+        if (!response.ok) {
+            throw new Error(`Request failed with status: ${response.status}`);
+        }
+        const data = await response.json();
+        return data;
+    } catch (error) {
+        console.error("Error in apiFilesDownloads:", error);
+        throw error;
+    }
+}
+
+
 async function apiFilesModify(id, allowedDownloads, expiry, password, originalPw) {
     const apiUrl = './api/files/modify';
 
@@ -434,6 +460,36 @@ async function apiUserResetPassword(id, generatePw) {
 
 
 
+async function apiUserNotifications(email, notifyDownload, notifyExpiry, notifyDeletion) {
+    const apiUrl = './api/user/notifications';
+
+    const requestOptions = {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+            'apikey': systemKey,
+            'notificationEmail': email,
+            'notifyDownload': notifyDownload,
+            'notifyExpiry': notifyExpiry,
+            'notifyDeletion': notifyDeletion
+        },
+    };
+
+    try {
+        const response = await fetch(apiUrl, requestOptions);
+        if (!response.ok) {
+            throw new Error(`Request failed with status: ${response.status}`);
+        }
+        const data = await response.json();
+        return data;
+    } catch (error) {
+        console.error("Error in apiUserNotifications:", error);
+        throw error;
+    }
+}
+
+
+
 async function apiLogsDelete(timestamp) {
     const apiUrl = './api/logs/delete';
 
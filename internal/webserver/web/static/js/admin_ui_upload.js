@@ -193,7 +193,12 @@ function sendChunkComplete(file, done) {
     let password = document.getElementById("password").value;
     let isE2E = file.isEndToEndEncrypted === true;
     let nonblocking = true;
+    let storageTarget = "";
 
+    let storageTargetSelect = document.getElementById("storageTarget");
+    if (storageTargetSelect != null) {
+        storageTarget = storageTargetSelect.value;
+    }
     if (!document.getElementById("enableDownloadLimit").checked) {
         allowedDownloads = 0;
     }
@@ -207,7 +212,7 @@ function sendChunkComplete(file, done) {
         contenttype = "";
     }
 
-    apiChunkComplete(uuid, filename, filesize, realsize, contenttype, allowedDownloads, expiryDays, password, isE2E, nonblocking)
+    apiChunkComplete(uuid, filename, filesize, realsize, contenttype, allowedDownloads, expiryDays, password, isE2E, nonblocking, storageTarget)
         .then(data => {
             done();
             let progressText = document.getElementById(`us-progress-info-${file.upload.uuid}`);
@@ -664,13 +669,16 @@ function addRow(item) {
     let row = table.insertRow(0);
     item.Id = sanitizeId(item.Id);
     row.id = "row-" + item.Id;
-    let cellFilename = row.insertCell(0);
-    let cellFileSize = row.insertCell(1);
-    let cellRemainingDownloads = row.insertCell(2);
-    let cellStoredUntil = row.insertCell(3);
-    let cellDownloadCount = row.insertCell(4);
-    let cellUrl = row.insertCell(5);
-    let cellButtons = row.insertCell(6);
+    let cellSelect = row.insertCell(0);
+    let cellFilename = row.insertCell(1);
+    let cellFileSize = row.insertCell(2);
+    let cellRemainingDownloads = row.insertCell(3);
+    let cellStoredUntil = row.insertCell(4);
+    let cellDownloadCount = row.insertCell(5);
+    let cellUrl = row.insertCell(6);
+    let cellButtons = row.insertCell(7);
+
+    cellSelect.appendChild(createZipCheckbox(item));
 
     cellFilename.innerText = item.Name;
     cellFilename.id = "cell-name-" + item.Id;
@@ -709,6 +717,7 @@ function addRow(item) {
     cellButtons.appendChild(createButtonGroup(item));
 
 
+    cellSelect.classList.add('newItem');
     cellFilename.classList.add('newItem');
     cellFileSize.classList.add('newItem');
     cellRemainingDownloads.classList.add('newItem');
@@ -722,6 +731,47 @@ function addRow(item) {
     return item.Id;
 }
 
+function createZipCheckbox(item) {
+    const checkbox = document.createElement('input');
+    checkbox.type = 'checkbox';
+    checkbox.className = 'form-check-input zip-select';
+    checkbox.dataset.fileid = item.Id;
+    checkbox.setAttribute('aria-label', 'Select file');
+    checkbox.onchange = handleZipSelection;
+    if (item.IsEndToEndEncrypted) {
+        checkbox.disabled = true;
+        checkbox.title = 'End-to-end encrypted files cannot be added to a ZIP file';
+    }
+    return checkbox;
+}
+
+function getSelectedZipFiles() {
+    let ids = [];
+    document.querySelectorAll('.zip-select:checked').forEach(checkbox => {
+        ids.push(checkbox.dataset.fileid);
+    });
+    return ids;
+}
+
+function handleZipSelection() {
+    document.getElementById('button-download-zip').disabled = getSelectedZipFiles().length === 0;
+}
+
+function handleZipSelectAll(checkbox) {
+    document.querySelectorAll('.zip-select:not(:disabled)').forEach(element => {
+        element.checked = checkbox.checked;
+    });
+    handleZipSelection();
+}
+
+function downloadSelectedAsZip() {
+    const ids = getSelectedZipFiles();
+    if (ids.length === 0) {
+        return;
+    }
+    location.href = './downloadZip?ids=' + encodeURIComponent(ids.join(','));
+}
+
 function createButtonGroup(item) {
     const groupContainer = document.createElement("div");
     groupContainer.className = "btn-toolbar";
@@ -819,6 +869,16 @@ function createButtonGroup(item) {
     emailA.innerHTML = `<i class="bi bi-envelope"></i> Email`;
     emailLi.appendChild(emailA);
     dropdown2.appendChild(emailLi);
+
+    const downloadsLi = document.createElement("li");
+    const downloadsA = document.createElement("a");
+    downloadsA.className = "dropdown-item";
+    downloadsA.id = `downloads-${item.Id}`;
+    downloadsA.title = "Show download history";
+    downloadsA.onclick = () => showDownloadHistory(item.Id);
+    downloadsA.innerHTML = `<i class="bi bi-clock-history"></i> Download history`;
+    downloadsLi.appendChild(downloadsA);
+    dropdown2.appendChild(downloadsLi);
     group1.appendChild(dropdown2);
 
     // Button group for Edit/Delete
@@ -924,6 +984,75 @@ function showQrCode(url) {
 }
 
 
+function showDownloadHistory(id) {
+    const filename = document.getElementById("cell-name-" + id).innerText;
+    document.getElementById("m_downloadslabel").innerText = "Download history: " + filename;
+    const table = document.getElementById("downloadhistorytable");
+    table.replaceChildren();
+
+    apiFilesDownloads(id)
+        .then(events => {
+            if (events.length === 0) {
+                const cell = table.insertRow().insertCell();
+                cell.colSpan = 5;
+                cell.innerText = "This file has not been downloaded yet";
+            }
+            events.forEach(event => {
+                const row = table.insertRow();
+                row.insertCell().innerText = new Date(event.Timestamp * 1000).toLocaleString();
+                row.insertCell().innerText = event.Ip === "" ? "-" : event.Ip;
+                row.insertCell().innerText = event.UserAgent;
+                row.insertCell().innerText = getReadableSize(event.BytesSent);
+                row.insertCell().innerText = event.IsComplete ? "Complete" : "Incomplete";
+            });
+            new bootstrap.Modal('#modaldownloads', {}).show();
+        })
+        .catch(error => {
+            alert("Unable to load download history: " + error);
+            console.error('Error:', error);
+        });
+}
+
+function getReadableSize(bytes) {
+    const units = ["B", "kB", "MB", "GB", "TB"];
+    let size = bytes;
+    let unit = 0;
+    while (size >= 1000 && unit < units.length - 1) {
+        size = size / 1000;
+        unit++;
+    }
+    if (unit === 0) {
+        return size + " " + units[unit];
+    }
+    return size.toFixed(1) + " " + units[unit];
+}
+
+function saveNotificationSettings() {
+    const button = document.getElementById('mb_notify_save');
+    const emailInput = document.getElementById('mi_notify_email');
+    emailInput.value = emailInput.value.trim();
+    if (!emailInput.checkValidity()) {
+        alert("Please enter a valid email address");
+        return;
+    }
+    button.disabled = true;
+    apiUserNotifications(emailInput.value,
+            document.getElementById('mi_notify_download').value,
+            document.getElementById('mc_notify_expiry').checked,
+            document.getElementById('mc_notify_deletion').checked)
+        .then(data => {
+            bootstrap.Modal.getInstance('#modalnotifications').hide();
+            button.disabled = false;
+            showToast(1000, "Notification settings saved");
+        })
+        .catch(error => {
+            alert("Unable to save notification settings: " + error);
+            console.error('Error:', error);
+            button.disabled = false;
+        });
+}
+
+
 function showToastFileDeletion(id) {
     let notification = document.getElementById("toastnotificationUndo");
     let filename = document.getElementById("cell-name-" + id).innerText;
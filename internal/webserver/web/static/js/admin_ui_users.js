@@ -202,13 +202,15 @@ function addRowUser(userid, name) {
     let cellGroup = row.insertCell(1);
     let cellLastOnline = row.insertCell(2);
     let cellUploads = row.insertCell(3);
-    let cellPermissions = row.insertCell(4);
-    let cellActions = row.insertCell(5);
+    let cellStorage = row.insertCell(4);
+    let cellPermissions = row.insertCell(5);
+    let cellActions = row.insertCell(6);
 
     cellName.classList.add("newUser");
     cellGroup.classList.add("newUser");
     cellLastOnline.classList.add("newUser");
     cellUploads.classList.add("newUser");
+    cellStorage.classList.add("newUser");
     cellPermissions.classList.add("newUser");
     cellActions.classList.add("newUser");
 
@@ -217,6 +219,7 @@ function addRowUser(userid, name) {
     cellGroup.innerText = "User";
     cellLastOnline.innerText = "Never";
     cellUploads.innerText = "0";
+    cellStorage.innerText = "0 B / Unlimited";
 
     // Create one button group
     const btnGroup = document.createElement("div");